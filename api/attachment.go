@@ -0,0 +1,459 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/attachment"
+	"github.com/srabraham/ranger-ims-go/auth"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// maxFilesPerUpload is the most files that may be attached in one request
+const maxFilesPerUpload = 10
+
+// attachments is where attached files go, and which files are allowed.
+type attachments struct {
+	// blobs is nil if this server doesn't allow attachments
+	blobs        attachment.BlobStore
+	maxSize      int64
+	allowedTypes []string
+}
+
+func newAttachments(cfg conf.AttachmentsStore) attachments {
+	return attachments{
+		blobs:        attachment.NewBlobStore(cfg),
+		maxSize:      cfg.MaxSize,
+		allowedTypes: cfg.AllowedTypes,
+	}
+}
+
+func (a attachments) enabled() bool {
+	return a.blobs != nil
+}
+
+// uploadedFile is a file that was attached to a report entry, once it's been
+// put in the blob store.
+type uploadedFile struct {
+	key         string
+	name        string
+	contentType string
+	size        int
+}
+
+// mustStoreUploads puts each file from a multipart/form-data upload into the
+// blob store. The files are read from the "files" form field. It writes an
+// error response, deleting anything it stored, if any of the files aren't allowed.
+func (a attachments) mustStoreUploads(w http.ResponseWriter, req *http.Request, eventID int32) ([]uploadedFile, bool) {
+	if !a.enabled() {
+		handleErr(w, req, http.StatusNotImplemented, "This server doesn't support attaching files", nil)
+		return nil, false
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, maxFilesPerUpload*(a.maxSize+1<<10))
+	reader, err := req.MultipartReader()
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Expected a multipart/form-data upload", err)
+		return nil, false
+	}
+	var uploads []uploadedFile
+	cleanUp := func() {
+		a.deleteUploads(context.WithoutCancel(req.Context()), uploads)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			cleanUp()
+			handleErr(w, req, http.StatusBadRequest, "Failed to read upload", err)
+			return nil, false
+		}
+		if part.FormName() != "files" {
+			continue
+		}
+		if len(uploads) == maxFilesPerUpload {
+			cleanUp()
+			handleErr(w, req, http.StatusBadRequest, fmt.Sprintf("At most %v files may be attached at once", maxFilesPerUpload), nil)
+			return nil, false
+		}
+		data, err := io.ReadAll(io.LimitReader(part, a.maxSize+1))
+		if err != nil {
+			cleanUp()
+			handleErr(w, req, http.StatusBadRequest, "Failed to read upload", err)
+			return nil, false
+		}
+		if int64(len(data)) > a.maxSize {
+			cleanUp()
+			handleErr(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("Files may be at most %v bytes", a.maxSize), nil)
+			return nil, false
+		}
+		if len(data) == 0 {
+			cleanUp()
+			handleErr(w, req, http.StatusBadRequest, "Files may not be empty", nil)
+			return nil, false
+		}
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+		if !slices.Contains(a.allowedTypes, contentType) {
+			cleanUp()
+			handleErr(w, req, http.StatusUnsupportedMediaType, fmt.Sprintf("Files of type %v may not be attached", contentType), nil)
+			return nil, false
+		}
+		upload := uploadedFile{
+			key:         fmt.Sprintf("%v/%v", eventID, rand.Text()),
+			name:        part.FileName(),
+			contentType: contentType,
+			size:        len(data),
+		}
+		if err = a.blobs.Put(req.Context(), upload.key, bytes.NewReader(data), int64(len(data))); err != nil {
+			cleanUp()
+			handleErr(w, req, http.StatusInternalServerError, "Failed to store attachment", err)
+			return nil, false
+		}
+		uploads = append(uploads, upload)
+	}
+	if len(uploads) == 0 {
+		handleErr(w, req, http.StatusBadRequest, "No files were uploaded", nil)
+		return nil, false
+	}
+	return uploads, true
+}
+
+// deleteUploads cleans up after uploads that didn't make it into the database.
+func (a attachments) deleteUploads(ctx context.Context, uploads []uploadedFile) {
+	for _, upload := range uploads {
+		if err := a.blobs.Delete(ctx, upload.key); err != nil {
+			slog.Error("Failed to delete orphaned attachment", "key", upload.key, "error", err)
+		}
+	}
+}
+
+// reportEntry is the system-generated report entry that records the upload.
+func (u uploadedFile) reportEntry(author string) imsdb.CreateReportEntryParams {
+	return imsdb.CreateReportEntryParams{
+		Author:           author,
+		Text:             fmt.Sprintf("Attached file %q (%v, %v bytes)", u.name, u.contentType, u.size),
+		Generated:        true,
+		AttachedFile:     sql.NullString{String: u.key, Valid: true},
+		AttachedFileName: sql.NullString{String: u.name, Valid: u.name != ""},
+	}
+}
+
+// mustServeAttachment writes the file attached to the report entry given by
+// the attachmentNumber path value. Only entries are considered, so that callers
+// can limit which report entries the requestor may get at.
+func (a attachments) mustServeAttachment(w http.ResponseWriter, req *http.Request, entries []imsdb.ReportEntry) {
+	if !a.enabled() {
+		handleErr(w, req, http.StatusNotImplemented, "This server doesn't support attaching files", nil)
+		return
+	}
+	attachmentNumber, err := strconv.ParseInt(req.PathValue("attachmentNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid attachment number", err)
+		return
+	}
+	var key, name string
+	for _, entry := range entries {
+		if entry.ID == int32(attachmentNumber) && entry.AttachedFile.String != "" {
+			key, name = entry.AttachedFile.String, entry.AttachedFileName.String
+		}
+	}
+	if key == "" {
+		handleErr(w, req, http.StatusNotFound, "No such attachment", nil)
+		return
+	}
+	blob, err := a.blobs.Get(req.Context(), key)
+	if errors.Is(err, attachment.ErrNotFound) {
+		handleErr(w, req, http.StatusNotFound, "The attached file is missing", err)
+		return
+	}
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch attachment", err)
+		return
+	}
+	defer blob.Close()
+
+	// The type isn't stored, but it's what was detected from the same bytes on upload
+	r := bufio.NewReaderSize(blob, 512)
+	head, _ := r.Peek(512)
+	w.Header().Set("Content-Type", http.DetectContentType(head))
+	// Files attached before their names were kept are sent without one
+	disposition := map[string]string{}
+	if name != "" {
+		disposition["filename"] = name
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", disposition))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	if _, err = io.Copy(w, r); err != nil {
+		slog.Error("Failed to write attachment", "key", key, "error", err)
+	}
+}
+
+type AttachToIncident struct {
+	imsDB       *store.DB
+	eventSource *EventSourcerer
+	imsAdmins   []string
+	attachments attachments
+}
+
+func (action AttachToIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventWriteIncidents == 0 || eventPermissions&auth.EventAttachFiles == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to attach files to Incidents on this Event", nil)
+		return
+	}
+	ctx := req.Context()
+	incidentNumber, err := strconv.ParseInt(req.PathValue("incidentNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid incident number", err)
+		return
+	}
+	_, _, err = fetchIncident(ctx, action.imsDB, event.ID, int32(incidentNumber))
+	if errors.Is(err, sql.ErrNoRows) {
+		handleErr(w, req, http.StatusNotFound, "No such incident", err)
+		return
+	}
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+		return
+	}
+
+	uploads, ok := action.attachments.mustStoreUploads(w, req, event.ID)
+	if !ok {
+		return
+	}
+	txn, err := action.imsDB.Begin()
+	if err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+	var addedEntries []string
+	for _, upload := range uploads {
+		entry := upload.reportEntry(jwtCtx.Claims.RangerHandle())
+		err = createIncidentReportEntry(ctx, dbTxn, event.ID, int32(incidentNumber), entry)
+		if err != nil {
+			action.attachments.deleteUploads(ctx, uploads)
+			handleErr(w, req, http.StatusInternalServerError, "Error adding report entry", err)
+			return
+		}
+		addedEntries = append(addedEntries, entry.Text)
+	}
+	err = newAuditor(req).record(ctx, dbTxn, auditChange{
+		action:     "update",
+		entityType: "incident",
+		eventID:    event.ID,
+		entityID:   fmt.Sprint(incidentNumber),
+		after:      map[string]any{"report_entries": addedEntries},
+	})
+	if err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error recording attachments in audit log", err)
+		return
+	}
+	if err = txn.Commit(); err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	defer action.eventSource.notifyIncidentUpdate(event.Name, int32(incidentNumber))
+
+	http.Error(w, http.StatusText(http.StatusNoContent), http.StatusNoContent)
+}
+
+type GetIncidentAttachment struct {
+	imsDB       *store.DB
+	imsAdmins   []string
+	attachments attachments
+}
+
+func (action GetIncidentAttachment) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, _, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	incidentNumber, err := strconv.ParseInt(req.PathValue("incidentNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid incident number", err)
+		return
+	}
+	_, entries, err := fetchIncident(req.Context(), action.imsDB, event.ID, int32(incidentNumber))
+	if errors.Is(err, sql.ErrNoRows) {
+		handleErr(w, req, http.StatusNotFound, "No such incident", err)
+		return
+	}
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+		return
+	}
+	action.attachments.mustServeAttachment(w, req, entries)
+}
+
+type AttachToFieldReport struct {
+	imsDB       *store.DB
+	eventSource *EventSourcerer
+	imsAdmins   []string
+	attachments attachments
+}
+
+func (action AttachToFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&(auth.EventWriteAllFieldReports|auth.EventWriteOwnFieldReports) == 0 ||
+		eventPermissions&auth.EventAttachFiles == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to attach files to Field Reports on this Event", nil)
+		return
+	}
+	// i.e. they have EventWriteOwnFieldReports, but not EventWriteAllFieldReports
+	limitedAccess := eventPermissions&auth.EventWriteAllFieldReports == 0
+
+	ctx := req.Context()
+	fieldReportNumber, err := strconv.ParseInt(req.PathValue("fieldReportNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid field report number", err)
+		return
+	}
+	_, err = imsdb.New(action.imsDB).FieldReport(ctx, imsdb.FieldReportParams{
+		Event:  event.ID,
+		Number: int32(fieldReportNumber),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		handleErr(w, req, http.StatusNotFound, "No such field report", err)
+		return
+	}
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Field Report", err)
+		return
+	}
+	entries, ok := mustGetFieldReportEntries(w, req, action.imsDB, event.ID, int32(fieldReportNumber))
+	if !ok {
+		return
+	}
+	author := jwtCtx.Claims.RangerHandle()
+	if limitedAccess && !slices.ContainsFunc(entries, func(re imsdb.ReportEntry) bool { return re.Author == author }) {
+		handleErr(w, req, http.StatusForbidden, "The requestor may only attach files to their own Field Reports", nil)
+		return
+	}
+
+	uploads, ok := action.attachments.mustStoreUploads(w, req, event.ID)
+	if !ok {
+		return
+	}
+	txn, err := action.imsDB.Begin()
+	if err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+	var addedEntries []string
+	for _, upload := range uploads {
+		entry := upload.reportEntry(author)
+		err = createFRReportEntry(ctx, dbTxn, event.ID, int32(fieldReportNumber), entry)
+		if err != nil {
+			action.attachments.deleteUploads(ctx, uploads)
+			handleErr(w, req, http.StatusInternalServerError, "Error adding report entry", err)
+			return
+		}
+		addedEntries = append(addedEntries, entry.Text)
+	}
+	err = newAuditor(req).record(ctx, dbTxn, auditChange{
+		action:     "update",
+		entityType: "field_report",
+		eventID:    event.ID,
+		entityID:   fmt.Sprint(fieldReportNumber),
+		after:      map[string]any{"report_entries": addedEntries},
+	})
+	if err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error recording attachments in audit log", err)
+		return
+	}
+	if err = txn.Commit(); err != nil {
+		action.attachments.deleteUploads(ctx, uploads)
+		handleErr(w, req, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	defer action.eventSource.notifyFieldReportUpdate(event.Name, int32(fieldReportNumber))
+
+	http.Error(w, http.StatusText(http.StatusNoContent), http.StatusNoContent)
+}
+
+type GetFieldReportAttachment struct {
+	imsDB       *store.DB
+	imsAdmins   []string
+	attachments attachments
+}
+
+func (action GetFieldReportAttachment) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&(auth.EventReadAllFieldReports|auth.EventReadOwnFieldReports) == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to read Field Reports on this Event", nil)
+		return
+	}
+	// i.e. they have EventReadOwnFieldReports, but not EventReadAllFieldReports
+	limitedAccess := eventPermissions&auth.EventReadAllFieldReports == 0
+
+	fieldReportNumber, err := strconv.ParseInt(req.PathValue("fieldReportNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid field report number", err)
+		return
+	}
+	entries, ok := mustGetFieldReportEntries(w, req, action.imsDB, event.ID, int32(fieldReportNumber))
+	if !ok {
+		return
+	}
+	author := jwtCtx.Claims.RangerHandle()
+	if limitedAccess && !slices.ContainsFunc(entries, func(re imsdb.ReportEntry) bool { return re.Author == author }) {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to read this Field Report", nil)
+		return
+	}
+	action.attachments.mustServeAttachment(w, req, entries)
+}
+
+func mustGetFieldReportEntries(w http.ResponseWriter, req *http.Request, imsDB *store.DB, eventID, fieldReportNumber int32) ([]imsdb.ReportEntry, bool) {
+	rows, err := imsdb.New(imsDB).FieldReport_ReportEntries(req.Context(),
+		imsdb.FieldReport_ReportEntriesParams{
+			Event:             eventID,
+			FieldReportNumber: fieldReportNumber,
+		})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Field Report Report Entries", err)
+		return nil, false
+	}
+	entries := make([]imsdb.ReportEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.ReportEntry)
+	}
+	return entries, true
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/auth"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditChange describes one change to one entity, for the audit log.
+type auditChange struct {
+	// action is what was done, e.g. "create" or "update"
+	action string
+	// entityType is the kind of thing that was changed, e.g. "incident"
+	entityType string
+	// eventID is the event that the entity belongs to, or 0 if it's not in an event
+	eventID int32
+	// entityID identifies the entity among those of its type, e.g. an incident number
+	entityID string
+	// before and after are the entity's changed values, which are stored as JSON.
+	// Either may be nil where it doesn't apply or isn't known, e.g. there's no
+	// before for a creation.
+	before, after any
+}
+
+// auditor writes to the audit log on behalf of one API request.
+type auditor struct {
+	actor     string
+	requestID string
+}
+
+func newAuditor(req *http.Request) auditor {
+	a := auditor{requestID: requestID(req)}
+	jwtCtx, _ := req.Context().Value(JWTContextKey).(JWTContext)
+	if jwtCtx.Claims != nil {
+		a.actor = jwtCtx.Claims.RangerHandle()
+	}
+	return a
+}
+
+// record appends change to the audit log. Pass in the Queries of the transaction
+// that makes the change, so that the change and its record stand or fall together.
+// Records are never changed or deleted, except that a retention purge of the event
+// removes the before and after values of changes to incidents, field reports, and
+// report entries, which may hold personal information.
+func (a auditor) record(ctx context.Context, q *imsdb.Queries, change auditChange) error {
+	before, err := auditValue(change.before)
+	if err != nil {
+		return fmt.Errorf("[auditValue]: %w", err)
+	}
+	after, err := auditValue(change.after)
+	if err != nil {
+		return fmt.Errorf("[auditValue]: %w", err)
+	}
+	err = q.CreateAudit(ctx, imsdb.CreateAuditParams{
+		Created:     float64(time.Now().UnixMicro()) / 1e6,
+		Actor:       a.actor,
+		RequestID:   a.requestID,
+		Action:      change.action,
+		EntityType:  change.entityType,
+		Event:       sql.NullInt32{Int32: change.eventID, Valid: change.eventID != 0},
+		EntityID:    change.entityID,
+		BeforeValue: before,
+		AfterValue:  after,
+	})
+	if err != nil {
+		return fmt.Errorf("[CreateAudit]: %w", err)
+	}
+	return nil
+}
+
+func auditValue(v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("[Marshal]: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+type GetAudit struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP returns audit log records, newest first. All of these query
+// parameters are optional, and they narrow down the records returned:
+//
+//   - actor: the Ranger handle who made the change
+//   - entity_type and entity_id: what was changed, e.g. "incident" and "12"
+//   - event: the name of the event in which the change was made
+//   - request_id: the X-Request-ID of the request that made the change
+//   - since and until: RFC 3339 times bounding when the change was made
+//   - before_id: only records older than this one, for paging through results
+//   - limit: the most records to return
+func (action GetAudit) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, globalPermissions, ok := mustGetGlobalPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if globalPermissions&auth.GlobalReadAudit == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have GlobalReadAudit permission", nil)
+		return
+	}
+	if ok = mustParseForm(w, req); !ok {
+		return
+	}
+	ctx := req.Context()
+
+	params := imsdb.AuditEntriesParams{
+		Actor:      formNullString(req, "actor"),
+		EntityType: formNullString(req, "entity_type"),
+		EntityID:   formNullString(req, "entity_id"),
+		RequestID:  formNullString(req, "request_id"),
+		Limit:      defaultAuditLimit,
+	}
+	if eventName := req.Form.Get("event"); eventName != "" {
+		event, ok := mustGetEvent(w, req, eventName, action.imsDB)
+		if !ok {
+			return
+		}
+		params.Event = sql.NullInt32{Int32: event.ID, Valid: true}
+	}
+	for name, param := range map[string]*sql.NullFloat64{
+		"since": &params.CreatedAfter,
+		"until": &params.CreatedBefore,
+	} {
+		if value := req.Form.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				handleErr(w, req, http.StatusBadRequest, "Invalid "+name+" time", err)
+				return
+			}
+			*param = sql.NullFloat64{Float64: float64(t.UnixMicro()) / 1e6, Valid: true}
+		}
+	}
+	if beforeID := req.Form.Get("before_id"); beforeID != "" {
+		id, err := strconv.ParseInt(beforeID, 10, 64)
+		if err != nil {
+			handleErr(w, req, http.StatusBadRequest, "Invalid before_id", err)
+			return
+		}
+		params.BeforeID = sql.NullInt64{Int64: id, Valid: true}
+	}
+	if limit := req.Form.Get("limit"); limit != "" {
+		num, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil || num <= 0 || num > maxAuditLimit {
+			handleErr(w, req, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		params.Limit = int32(num)
+	}
+
+	rows, err := imsdb.New(action.imsDB).AuditEntries(ctx, params)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch audit log", err)
+		return
+	}
+	eventNames, err := eventNamesByID(ctx, action.imsDB)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Events", err)
+		return
+	}
+
+	resp := make(imsjson.AuditRecords, 0, len(rows))
+	for _, row := range rows {
+		a := row.Audit
+		record := imsjson.AuditRecord{
+			ID:         a.ID,
+			Created:    time.UnixMicro(int64(a.Created * 1e6)),
+			Actor:      a.Actor,
+			RequestID:  a.RequestID,
+			Action:     a.Action,
+			EntityType: a.EntityType,
+			EntityID:   a.EntityID,
+			Before:     rawJSONOrNil(a.BeforeValue),
+			After:      rawJSONOrNil(a.AfterValue),
+		}
+		if a.Event.Valid {
+			record.Event = eventNames[a.Event.Int32]
+		}
+		resp = append(resp, record)
+	}
+	mustWriteJSON(w, resp)
+}
+
+func formNullString(req *http.Request, name string) sql.NullString {
+	value := req.Form.Get(name)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func rawJSONOrNil(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
+
+func eventNamesByID(ctx context.Context, imsDB *store.DB) (map[int32]string, error) {
+	eventRows, err := imsdb.New(imsDB).Events(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[Events]: %w", err)
+	}
+	names := make(map[int32]string, len(eventRows))
+	for _, er := range eventRows {
+		names[er.Event.ID] = er.Event.Name
+	}
+	return names, nil
+}
@@ -1,24 +1,33 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"github.com/srabraham/ranger-ims-go/auth"
 	"github.com/srabraham/ranger-ims-go/auth/password"
 	"github.com/srabraham/ranger-ims-go/directory"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type PostAuth struct {
-	imsDB       *store.DB
-	userStore   *directory.UserStore
-	jwtSecret   string
-	jwtDuration time.Duration
+	imsDB          *store.DB
+	userStore      *directory.UserStore
+	jwter          auth.JWTer
+	jwtDuration    time.Duration
+	refreshTokens  auth.RefreshTokens
+	throttle       *auth.LoginThrottle
+	clientIPHeader string
 }
 
 type PostAuthRequest struct {
@@ -27,6 +36,9 @@ type PostAuthRequest struct {
 }
 type PostAuthResponse struct {
 	Token string `json:"token"`
+	// RefreshToken can be exchanged at /ims/api/auth/refresh for a new Token, once.
+	// It's left out when refresh tokens are turned off.
+	RefreshToken string `json:"refresh_token,omitzero"`
 }
 
 func (action PostAuth) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -57,41 +69,177 @@ func (action PostAuth) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	attempt := auth.LoginAttempt{
+		Identification: vals.Identification,
+		Client:         clientIP(req, action.clientIPHeader),
+	}
+	if matchedPerson != nil {
+		attempt.Handle = matchedPerson.Handle
+	}
+	wait, err := action.throttle.Attempt(req.Context(), attempt)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to check for failed logins", err)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		handleErr(w, req, http.StatusTooManyRequests, "Too many failed login attempts. Try again later",
+			fmt.Errorf("login throttled. Handle: %v, client: %v, wait: %v", attempt.Handle, attempt.Client, wait))
+		return
+	}
+
+	// The identification isn't logged, since it might be a password typed into the wrong field
 	if matchedPerson == nil {
 		handleErr(w, req, http.StatusUnauthorized, "Failed login attempt (bad credentials)",
-			fmt.Errorf("login attempt for nonexistent user. Identification: %v", vals.Identification))
+			fmt.Errorf("login attempt for nonexistent user. Client: %v", attempt.Client))
 		return
 	}
 
 	correct, err := password.Verify(vals.Password, matchedPerson.Password)
 	if !correct {
 		handleErr(w, req, http.StatusUnauthorized, "Failed login attempt (bad credentials)",
-			fmt.Errorf("bad password for valid user. Identification: %v", vals.Identification))
+			fmt.Errorf("bad password for valid user. Handle: %v, client: %v", matchedPerson.Handle, attempt.Client))
 		return
 	}
 	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to verify password", err)
 		return
 	}
+	if err = action.throttle.Succeeded(req.Context(), attempt); err != nil {
+		// The login is still good, so carry on
+		slog.Error("Failed to clear failed logins", "handle", matchedPerson.Handle, "error", err)
+	}
 	slog.Info("Successful login for Ranger", "identification", matchedPerson.Handle)
 
-	foundPositionNames, foundTeamNames, err := action.userStore.GetUserPositionsTeams(req.Context(), matchedPerson.DirectoryID)
+	jwt, ok := createAccessToken(w, req, action.userStore, action.jwter, action.jwtDuration, *matchedPerson)
+	if !ok {
+		return
+	}
+	resp := PostAuthResponse{Token: jwt}
+	if action.refreshTokens.Lifetime > 0 {
+		resp.RefreshToken, err = action.refreshTokens.Issue(req.Context(), matchedPerson.Handle, matchedPerson.DirectoryID)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to issue refresh token", err)
+			return
+		}
+	}
+
+	mustWriteJSON(w, resp)
+}
+
+// clientIP is the IP address that a request came from. Behind a reverse proxy,
+// that's the last address in the header that the proxy puts it in.
+func clientIP(req *http.Request, header string) string {
+	if header != "" {
+		if values := req.Header.Values(header); len(values) > 0 {
+			addrs := strings.Split(values[len(values)-1], ",")
+			addr, err := netip.ParseAddr(strings.TrimSpace(addrs[len(addrs)-1]))
+			if err == nil {
+				return addr.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// createAccessToken makes a JWT for the person, with their current positions and teams.
+func createAccessToken(
+	w http.ResponseWriter, req *http.Request, userStore *directory.UserStore, jwter auth.JWTer, jwtDuration time.Duration, person imsjson.Person,
+) (string, bool) {
+	foundPositionNames, foundTeamNames, err := userStore.GetUserPositionsTeams(req.Context(), person.DirectoryID)
 	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Clubhouse positions/teams data", err)
+		return "", false
+	}
+	return jwter.CreateJWT(person.Handle, person.DirectoryID, foundPositionNames, foundTeamNames, person.Onsite, jwtDuration), true
+}
+
+type PostAuthRefresh struct {
+	userStore     *directory.UserStore
+	jwter         auth.JWTer
+	jwtDuration   time.Duration
+	refreshTokens auth.RefreshTokens
+}
+
+type PostAuthRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (action PostAuthRefresh) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Like PostAuth, this is unauthenticated, since the access token has likely expired.
+	// The refresh token is what identifies the user.
+
+	vals, ok := mustReadBodyAs[PostAuthRefreshRequest](w, req)
+	if !ok {
+		return
+	}
+	if action.refreshTokens.Lifetime <= 0 {
+		handleErr(w, req, http.StatusNotFound, "Refresh tokens are turned off", nil)
+		return
+	}
+	session, refreshToken, err := action.refreshTokens.Exchange(req.Context(), vals.RefreshToken)
+	if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		handleErr(w, req, http.StatusUnauthorized, "Invalid refresh token", err)
+		return
+	}
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to exchange refresh token", err)
 		return
 	}
 
-	jwt := auth.JWTer{SecretKey: action.jwtSecret}.
-		CreateJWT(matchedPerson.Handle, matchedPerson.DirectoryID, foundPositionNames, foundTeamNames, matchedPerson.Onsite, action.jwtDuration)
-	resp := PostAuthResponse{Token: jwt}
+	// Look the person up again, so that changes in the directory take effect
+	rangers, err := action.userStore.GetRangers(req.Context())
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch personnel", err)
+		return
+	}
+	idx := slices.IndexFunc(rangers, func(p imsjson.Person) bool {
+		return p.DirectoryID == session.DirectoryID
+	})
+	if idx < 0 {
+		if err = action.refreshTokens.Revoke(req.Context(), session); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to revoke refresh token", err)
+			return
+		}
+		handleErr(w, req, http.StatusUnauthorized, "Invalid refresh token",
+			fmt.Errorf("refresh for a user no longer in the directory. Handle: %v", session.Handle))
+		return
+	}
+	person := rangers[idx]
+	if person.Handle != session.Handle {
+		slog.Info("Ranger's handle changed since login", "old", session.Handle, "new", person.Handle)
+	}
 
-	mustWriteJSON(w, resp)
+	jwt, ok := createAccessToken(w, req, action.userStore, action.jwter, action.jwtDuration, person)
+	if !ok {
+		return
+	}
+	mustWriteJSON(w, PostAuthResponse{Token: jwt, RefreshToken: refreshToken})
+}
+
+type GetAuthJWKS struct {
+	jwter auth.JWTer
+}
+
+func (action GetAuthJWKS) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// This endpoint is unauthenticated, since the keys are public. There are none
+	// when JWTs are signed with the shared secret, which can't be given out.
+	jwks := auth.JWKS{Keys: []auth.JWK{}}
+	if action.jwter.Keys != nil {
+		jwks = action.jwter.Keys.JWKS()
+	}
+	w.Header().Set("Cache-Control", "max-age=300")
+	mustWriteJSON(w, jwks)
 }
 
 type GetAuth struct {
-	imsDB     *store.DB
-	jwtSecret string
-	admins    []string
+	imsDB       *store.DB
+	admins      []string
+	attachments attachments
 }
 
 type GetAuthResponse struct {
@@ -149,10 +297,158 @@ func (action GetAuth) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				ReadIncidents:     eventPermissions[event.ID]&auth.EventReadIncidents != 0,
 				WriteIncidents:    eventPermissions[event.ID]&auth.EventWriteIncidents != 0,
 				WriteFieldReports: eventPermissions[event.ID]&(auth.EventWriteOwnFieldReports|auth.EventWriteAllFieldReports) != 0,
-				AttachFiles:       false,
+				AttachFiles:       action.attachments.enabled() && eventPermissions[event.ID]&auth.EventAttachFiles != 0,
 			},
 		}
 	}
 
 	mustWriteJSON(w, resp)
 }
+
+type PostAuthLogout struct {
+	refreshTokens auth.RefreshTokens
+	revocations   auth.Revocations
+}
+
+type PostAuthLogoutRequest struct {
+	// RefreshToken is optional. If it's given, its session is revoked too.
+	RefreshToken string `json:"refresh_token,omitzero"`
+}
+
+func (action PostAuthLogout) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	jwtCtx, ok := mustGetJwtCtx(w, req)
+	if !ok {
+		return
+	}
+	var vals PostAuthLogoutRequest
+	if req.ContentLength != 0 {
+		if vals, ok = mustReadBodyAs[PostAuthLogoutRequest](w, req); !ok {
+			return
+		}
+	}
+	if err := action.revocations.RevokeToken(req.Context(), *jwtCtx.Claims); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+	if vals.RefreshToken != "" {
+		if err := action.refreshTokens.RevokeToken(req.Context(), vals.RefreshToken); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to revoke refresh token", err)
+			return
+		}
+	}
+	slog.Info("Logged out", "identification", jwtCtx.Claims.RangerHandle())
+	http.Error(w, "Success", http.StatusNoContent)
+}
+
+type PostAuthRevoke struct {
+	imsDB       *store.DB
+	imsAdmins   []string
+	jwtDuration time.Duration
+}
+
+type PostAuthRevokeRequest struct {
+	Handle string `json:"handle"`
+}
+
+type PostAuthRevokeResponse struct {
+	Handle string `json:"handle"`
+	// RefreshTokens is how many refresh tokens were revoked.
+	RefreshTokens int64 `json:"refresh_tokens"`
+}
+
+// PostAuthRevoke revokes all of a Ranger's sessions, e.g. when their laptop is
+// lost. They'll have to log in again.
+func (action PostAuthRevoke) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, globalPermissions, ok := mustGetGlobalPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if globalPermissions&auth.GlobalRevokeSessions == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have GlobalRevokeSessions permission", nil)
+		return
+	}
+	vals, ok := mustReadBodyAs[PostAuthRevokeRequest](w, req)
+	if !ok {
+		return
+	}
+	if vals.Handle == "" {
+		handleErr(w, req, http.StatusBadRequest, "A Ranger handle is required", nil)
+		return
+	}
+
+	txn, err := action.imsDB.Begin()
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+	resp := PostAuthRevokeResponse{Handle: vals.Handle}
+	resp.RefreshTokens, err = auth.RevokeHandle(req.Context(), q, vals.Handle, action.jwtDuration)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	if err = newAuditor(req).record(req.Context(), q, auditChange{
+		action:     "revoke_sessions",
+		entityType: "ranger",
+		entityID:   vals.Handle,
+		after:      resp,
+	}); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to record audit", err)
+		return
+	}
+	if err = txn.Commit(); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+	slog.Info("Revoked all sessions", "handle", vals.Handle)
+	mustWriteJSON(w, resp)
+}
+
+type GetAuthLockouts struct {
+	imsDB     *store.DB
+	imsAdmins []string
+	throttle  *auth.LoginThrottle
+}
+
+type LoginLockout struct {
+	// Kind is "ranger" for a Ranger's handle, or "client" for a client IP address
+	Kind        string    `json:"kind"`
+	Name        string    `json:"name"`
+	Failures    int32     `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	Until       time.Time `json:"until"`
+	// LockedOut is whether this is a lockout, rather than a wait between attempts.
+	LockedOut bool `json:"locked_out"`
+}
+
+// GetAuthLockouts returns the Rangers and client IP addresses that can't log in
+// right now because of failed logins, the longest blocked first.
+func (action GetAuthLockouts) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, globalPermissions, ok := mustGetGlobalPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if globalPermissions&auth.GlobalReadLoginLockouts == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have GlobalReadLoginLockouts permission", nil)
+		return
+	}
+	blocked, err := action.throttle.Blocked(req.Context())
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch login lockouts", err)
+		return
+	}
+	resp := make([]LoginLockout, 0, len(blocked))
+	for _, b := range blocked {
+		resp = append(resp, LoginLockout{
+			Kind:        b.Kind,
+			Name:        b.Name,
+			Failures:    b.Failures,
+			LastFailure: b.LastFailure,
+			Until:       b.Until,
+			LockedOut:   b.LockedOut,
+		})
+	}
+	mustWriteJSON(w, resp)
+}
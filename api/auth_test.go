@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("POST", "/ims/api/auth", nil)
+	req.RemoteAddr = "192.0.2.1:5555"
+	req.Header.Add("X-Forwarded-For", "203.0.113.9")
+	req.Header.Add("X-Forwarded-For", "198.51.100.7, 2001:db8::1")
+
+	// The header is ignored unless it's configured, since anyone can set it
+	require.Equal(t, "192.0.2.1", clientIP(req, ""))
+	// Otherwise it's the last address, which the proxy added
+	require.Equal(t, "2001:db8::1", clientIP(req, "X-Forwarded-For"))
+
+	req.Header.Set("X-Forwarded-For", "not an address")
+	require.Equal(t, "192.0.2.1", clientIP(req, "X-Forwarded-For"))
+	require.Equal(t, "192.0.2.1", clientIP(req, "X-Real-IP"))
+}
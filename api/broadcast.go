@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"log/slog"
+	"time"
+)
+
+const (
+	// dbPollInterval is how often a DBPollBroadcaster checks for updates made
+	// on other IMS servers. Updates made on this server are delivered right away.
+	dbPollInterval = 1 * time.Second
+
+	// dbPollReorderWindow is how far back a DBPollBroadcaster looks on each poll.
+	// SSE_EVENT IDs come from auto_increment, so concurrent writers on different
+	// servers can commit their rows out of ID order. Rereading a few recent IDs
+	// lets us pick up a straggler rather than skipping it forever.
+	dbPollReorderWindow = 50
+)
+
+// Broadcaster delivers updates to the EventSourcerer of every IMS server,
+// including the one on which the update was made.
+type Broadcaster interface {
+	// Broadcast announces an update, which has already been stored in SSE_EVENT.
+	Broadcast(ev IMSEvent)
+	// Listen calls deliver for every update broadcast by any IMS server,
+	// until ctx is done.
+	Listen(ctx context.Context, deliver func(IMSEvent))
+}
+
+func NewBroadcaster(broadcastType conf.BroadcastType, imsDB *store.DB) Broadcaster {
+	switch broadcastType {
+	case conf.BroadcastTypeDBPoll:
+		return NewDBPollBroadcaster(imsDB, dbPollInterval)
+	default:
+		return NewLocalBroadcaster()
+	}
+}
+
+// LocalBroadcaster only reaches the EventSource clients of this server.
+type LocalBroadcaster struct {
+	events chan IMSEvent
+}
+
+func NewLocalBroadcaster() *LocalBroadcaster {
+	return &LocalBroadcaster{events: make(chan IMSEvent, 100)}
+}
+
+// Broadcast never blocks the request that made the update. If the listener has
+// fallen that far behind, the update is dropped, and clients pick it up the next
+// time they reconnect and replay.
+func (b *LocalBroadcaster) Broadcast(ev IMSEvent) {
+	select {
+	case b.events <- ev:
+	default:
+		slog.Error("EventSource broadcast queue is full. Dropping event", "EventID", ev.EventID, "EventData", ev.EventData)
+	}
+}
+
+func (b *LocalBroadcaster) Listen(ctx context.Context, deliver func(IMSEvent)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-b.events:
+			deliver(ev)
+		}
+	}
+}
+
+// DBPollBroadcaster reaches the EventSource clients of every IMS server that
+// shares the IMS database, by having each server poll the SSE_EVENT table.
+// It needs no infrastructure beyond the database that IMS already uses.
+type DBPollBroadcaster struct {
+	imsDB    *store.DB
+	interval time.Duration
+	// wake prompts an immediate poll, so that updates made on this server
+	// don't have to wait for the next tick.
+	wake chan struct{}
+}
+
+func NewDBPollBroadcaster(imsDB *store.DB, interval time.Duration) *DBPollBroadcaster {
+	return &DBPollBroadcaster{
+		imsDB:    imsDB,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (b *DBPollBroadcaster) Broadcast(IMSEvent) {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+		// a poll is already pending
+	}
+}
+
+func (b *DBPollBroadcaster) Listen(ctx context.Context, deliver func(IMSEvent)) {
+	// Start from whatever is most recent. Clients catch up on anything
+	// older than that through replay.
+	var lastID int64
+	for {
+		idRange, err := imsdb.New(b.imsDB).SSEEventIDRange(ctx)
+		if err == nil {
+			lastID = idRange.MaxID
+			break
+		}
+		slog.Error("Failed to fetch SSE_EVENT ID range. Will retry", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.interval):
+		}
+	}
+
+	startID := lastID
+	// delivered holds the IDs within the reorder window that have already been delivered
+	delivered := make(map[int64]bool)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.wake:
+		}
+		rows, err := imsdb.New(b.imsDB).SSEEventsAfter(ctx, lastID-dbPollReorderWindow)
+		if err != nil {
+			slog.Error("Failed to poll SSE_EVENT", "error", err)
+			continue
+		}
+		for _, row := range rows {
+			id := row.SseEvent.ID
+			if id <= startID || delivered[id] {
+				continue
+			}
+			delivered[id] = true
+			lastID = max(lastID, id)
+			deliver(IMSEvent{
+				EventID: id,
+				EventData: IMSEventData{
+					EventName:         row.EventName,
+					IncidentNumber:    row.SseEvent.IncidentNumber.Int32,
+					FieldReportNumber: row.SseEvent.FieldReportNumber.Int32,
+				},
+			})
+		}
+		for id := range delivered {
+			if id <= lastID-dbPollReorderWindow {
+				delete(delivered, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLocalBroadcasterDoesNotBlock(t *testing.T) {
+	b := NewLocalBroadcaster()
+
+	// Nothing is listening, so these have to be dropped once the queue fills up
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for id := range int64(1000) {
+			b.Broadcast(IMSEvent{EventID: id})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Broadcast blocked")
+	}
+
+	// Whatever was queued still gets delivered
+	ctx, cancel := context.WithCancel(t.Context())
+	var delivered []int64
+	go b.Listen(ctx, func(ev IMSEvent) {
+		delivered = append(delivered, ev.EventID)
+		if len(delivered) == cap(b.events) {
+			cancel()
+		}
+	})
+	<-ctx.Done()
+	require.Len(t, delivered, cap(b.events))
+	require.Equal(t, int64(0), delivered[0])
+}
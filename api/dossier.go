@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/auth"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/pdf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/srabraham/ranger-ims-go/web/template"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDossierIncidents is the most incidents that can go in one dossier.
+const maxDossierIncidents = 200
+
+type GetIncidentDossier struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP renders a printable record of a single incident. Its parameters are:
+//
+//   - format: "html" (the default) for a page to print, or "pdf"
+//   - stricken: "true" to include stricken report entries, struck through
+//   - exclude_system_entries: "true" to leave out the system's report entries
+func (action GetIncidentDossier) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	if !mustParseForm(w, req) {
+		return
+	}
+	incidentNumber, err := strconv.ParseInt(req.PathValue("incidentNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Failed to parse incident number", err)
+		return
+	}
+	format, ok := mustGetDossierFormat(w, req)
+	if !ok {
+		return
+	}
+
+	incidentRow, err := imsdb.New(action.imsDB).Incident(req.Context(), imsdb.IncidentParams{
+		Event:  event.ID,
+		Number: int32(incidentNumber),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			handleErr(w, req, http.StatusNotFound, "No such incident", err)
+			return
+		}
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+		return
+	}
+	dossier, err := buildDossier(req, action.imsDB, event, eventPermissions, jwtCtx, []imsdb.IncidentRow{incidentRow})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+		return
+	}
+	writeDossier(w, req, dossier, format, fmt.Sprintf("%v-IMS-%d", event.Name, incidentNumber))
+}
+
+type GetIncidentsDossier struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP renders a printable record of a set of incidents, one per page. It
+// takes the same filters and sort as the incident list, along with the
+// parameters of GetIncidentDossier. Limit and cursor are ignored.
+func (action GetIncidentsDossier) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	if !mustParseForm(w, req) {
+		return
+	}
+	format, ok := mustGetDossierFormat(w, req)
+	if !ok {
+		return
+	}
+	form := maps.Clone(req.Form)
+	delete(form, "limit")
+	delete(form, "cursor")
+	query, err := parseIncidentsQuery(event.ID, form)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	query.params.Limit = maxDossierIncidents + 1
+
+	incidentsRows, err := imsdb.New(action.imsDB).Incidents(req.Context(), query.params)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incidents", err)
+		return
+	}
+	if len(incidentsRows) > maxDossierIncidents {
+		handleErr(w, req, http.StatusBadRequest,
+			fmt.Sprintf("A dossier can have at most %d incidents. Please narrow the filters.", maxDossierIncidents), nil)
+		return
+	}
+	var rows []imsdb.IncidentRow
+	for _, r := range incidentsRows {
+		rows = append(rows, imsdb.IncidentRow(r))
+	}
+
+	dossier, err := buildDossier(req, action.imsDB, event, eventPermissions, jwtCtx, rows)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incidents", err)
+		return
+	}
+	writeDossier(w, req, dossier, format, event.Name+"-incidents")
+}
+
+func mustGetDossierFormat(w http.ResponseWriter, req *http.Request) (string, bool) {
+	format := req.Form.Get("format")
+	switch format {
+	case "":
+		return "html", true
+	case "html", "pdf":
+		return format, true
+	default:
+		handleErr(w, req, http.StatusBadRequest, "Invalid format", fmt.Errorf("unknown format %q", format))
+		return "", false
+	}
+}
+
+// buildDossier puts together the incidents, in the given order, along with their
+// report entries and, if the requestor may read them, their field reports.
+func buildDossier(
+	req *http.Request, imsDB *store.DB, event imsdb.Event, eventPermissions auth.EventPermissionMask,
+	jwtCtx JWTContext, incidentRows []imsdb.IncidentRow,
+) (template.Dossier, error) {
+	ctx := req.Context()
+	q := imsdb.New(imsDB)
+	generatedLTE := req.Form.Get("exclude_system_entries") != "true" // false means to exclude
+	dossier := template.Dossier{
+		Event:        event.Name,
+		ShowStricken: req.Form.Get("stricken") == "true",
+		GeneratedBy:  jwtCtx.Claims.RangerHandle(),
+		GeneratedAt:  time.Now(),
+	}
+	streetNames, err := concentricStreetNames(ctx, imsDB, event.ID)
+	if err != nil {
+		return template.Dossier{}, fmt.Errorf("[concentricStreetNames]: %w", err)
+	}
+	var incidentNumbers []int32
+	for _, r := range incidentRows {
+		incidentNumbers = append(incidentNumbers, r.Incident.Number)
+	}
+	entriesByIncident, err := incidentReportEntries(ctx, q, event.ID, generatedLTE, incidentNumbers)
+	if err != nil {
+		return template.Dossier{}, fmt.Errorf("[incidentReportEntries]: %w", err)
+	}
+
+	var incidents []imsjson.Incident
+	var fieldReportNumbers []int32
+	for _, r := range incidentRows {
+		var entries []imsjson.ReportEntry
+		for _, re := range entriesByIncident[r.Incident.Number] {
+			entries = append(entries, reportEntryToJSON(re))
+		}
+		incident, err := incidentToJSON(event, r, entries)
+		if err != nil {
+			return template.Dossier{}, fmt.Errorf("[incidentToJSON]: %w", err)
+		}
+		incidents = append(incidents, incident)
+		fieldReportNumbers = append(fieldReportNumbers, *incident.FieldReports...)
+	}
+	var fieldReports map[int32]imsjson.FieldReport
+	if eventPermissions&auth.EventReadAllFieldReports != 0 && len(fieldReportNumbers) > 0 {
+		fieldReports, err = fetchFieldReportsJSON(ctx, q, event, fieldReportNumbers, generatedLTE)
+		if err != nil {
+			return template.Dossier{}, fmt.Errorf("[fetchFieldReportsJSON]: %w", err)
+		}
+	}
+
+	for _, incident := range incidents {
+		di := template.DossierIncident{
+			Incident: incident,
+			Address:  dossierAddress(incident.Location, streetNames),
+		}
+		for _, frNumber := range *incident.FieldReports {
+			if fr, ok := fieldReports[frNumber]; ok {
+				di.FieldReports = append(di.FieldReports, fr)
+			}
+		}
+		dossier.Incidents = append(dossier.Incidents, di)
+	}
+	return dossier, nil
+}
+
+// dossierAddress formats a location's radial address, e.g. "3:45 & Esplanade".
+func dossierAddress(loc imsjson.Location, streetNames map[string]string) string {
+	var radial, street string
+	if loc.RadialHour != nil && *loc.RadialHour != "" {
+		minute, _ := strconv.Atoi(deref(loc.RadialMinute))
+		radial = fmt.Sprintf("%v:%02d", *loc.RadialHour, minute)
+	}
+	if loc.Concentric != nil && *loc.Concentric != "" {
+		street = cmp.Or(streetNames[*loc.Concentric], *loc.Concentric)
+	}
+	if radial != "" && street != "" {
+		return radial + " & " + street
+	}
+	return radial + street
+}
+
+func writeDossier(w http.ResponseWriter, req *http.Request, dossier template.Dossier, format, baseName string) {
+	// Render it all first, so that a failure can still be reported properly
+	var buf bytes.Buffer
+	if format == "pdf" {
+		if _, err := dossierPDF(dossier).WriteTo(&buf); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to write PDF", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": baseName + ".pdf",
+		}))
+	} else {
+		if err := template.IncidentDossier(dossier).Render(req.Context(), &buf); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to render dossier", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		slog.Error("Failed to write dossier", "error", err, "requestID", requestID(req))
+	}
+}
+
+// dossierPDF lays out the same content as the IncidentDossier template.
+func dossierPDF(d template.Dossier) *pdf.Document {
+	doc := pdf.New(d.Footer())
+	for i, incident := range d.Incidents {
+		if i > 0 {
+			doc.NewPage()
+		}
+		doc.Text(fmt.Sprintf("IMS #%d: %v", incident.Number, deref(incident.Summary)), pdf.Style{Size: 14, Bold: true})
+		doc.Space(6)
+		for _, field := range [][2]string{
+			{"Event", incident.Event},
+			{"State", template.StateName(incident.State)},
+			{"Priority", template.PriorityName(incident.Priority)},
+			{"Created", template.DossierTime(incident.Created)},
+			{"Last modified", template.DossierTime(incident.LastModified)},
+			{"Location", deref(incident.Location.Name)},
+			{"Address", incident.Address},
+			{"Location details", deref(incident.Location.Description)},
+			{"Incident types", joinStrings(incident.IncidentTypes)},
+			{"Rangers", joinStrings(incident.RangerHandles)},
+		} {
+			doc.Text(fmt.Sprintf("%-18v%v", field[0]+":", field[1]), pdf.Style{})
+		}
+		doc.Space(10)
+		doc.Text("Report entries", pdf.Style{Size: 12, Bold: true})
+		dossierPDFEntries(doc, d.Entries(incident.ReportEntries), 0)
+		if len(incident.FieldReports) > 0 {
+			doc.Space(10)
+			doc.Text("Field reports", pdf.Style{Size: 12, Bold: true})
+			for _, fr := range incident.FieldReports {
+				doc.Space(4)
+				doc.Text(fmt.Sprintf("FR #%d: %v", fr.Number, deref(fr.Summary)), pdf.Style{Bold: true, Indent: 18})
+				dossierPDFEntries(doc, d.Entries(fr.ReportEntries), 18)
+			}
+		}
+	}
+	return doc
+}
+
+func dossierPDFEntries(doc *pdf.Document, entries []imsjson.ReportEntry, indent float64) {
+	for _, e := range entries {
+		doc.Rule()
+		doc.Text(fmt.Sprintf("%v, %v", template.DossierTime(e.Created), e.Author),
+			pdf.Style{Size: 8, Indent: indent, Strike: e.Stricken})
+		doc.Text(e.Text, pdf.Style{Indent: indent, Italic: e.SystemEntry, Strike: e.Stricken})
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func joinStrings(s *[]string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ", ")
+}
@@ -115,7 +115,7 @@ func (action EditEvents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			handleErr(w, req, http.StatusBadRequest, "Event names must match the pattern "+allowedEventNames.String(), fmt.Errorf("invalid event name: '%s'", eventName))
 			return
 		}
-		id, err := imsdb.New(action.imsDB).CreateEvent(req.Context(), eventName)
+		id, err := createEvent(req.Context(), action.imsDB, newAuditor(req), eventName)
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Failed to create event", err)
 			return
@@ -124,3 +124,30 @@ func (action EditEvents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	http.Error(w, "Success", http.StatusNoContent)
 }
+
+func createEvent(ctx context.Context, imsDB *store.DB, audit auditor, eventName string) (int32, error) {
+	txn, err := imsDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("[Begin]: %w", err)
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+	id, err := dbTxn.CreateEvent(ctx, eventName)
+	if err != nil {
+		return 0, fmt.Errorf("[CreateEvent]: %w", err)
+	}
+	err = audit.record(ctx, dbTxn, auditChange{
+		action:     "create",
+		entityType: "event",
+		eventID:    int32(id),
+		entityID:   eventName,
+		after:      map[string]any{"name": eventName},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[record]: %w", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("[Commit]: %w", err)
+	}
+	return int32(id), nil
+}
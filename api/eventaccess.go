@@ -57,28 +57,44 @@ func getEventsAccess(ctx context.Context, imsDB *store.DB) (imsjson.EventsAccess
 
 	result := make(imsjson.EventsAccess)
 	for _, e := range storedEvents {
-		ea := imsjson.EventAccess{
-			Readers:   []imsjson.AccessRule{},
-			Writers:   []imsjson.AccessRule{},
-			Reporters: []imsjson.AccessRule{},
-		}
-		for _, accessRow := range accessRowByEventID[e.ID] {
-			access := accessRow
-			rule := imsjson.AccessRule{Expression: access.Expression, Validity: string(access.Validity)}
-			switch access.Mode {
-			case imsdb.EventAccessModeRead:
-				ea.Readers = append(ea.Readers, rule)
-			case imsdb.EventAccessModeWrite:
-				ea.Writers = append(ea.Writers, rule)
-			case imsdb.EventAccessModeReport:
-				ea.Reporters = append(ea.Reporters, rule)
-			}
-		}
-		result[e.Name] = ea
+		result[e.Name] = eventAccessToJSON(accessRowByEventID[e.ID])
 	}
 	return result, nil
 }
 
+func eventAccessToJSON(accessRows []imsdb.EventAccess) imsjson.EventAccess {
+	ea := imsjson.EventAccess{
+		Readers:   []imsjson.AccessRule{},
+		Writers:   []imsjson.AccessRule{},
+		Reporters: []imsjson.AccessRule{},
+	}
+	for _, access := range accessRows {
+		rule := imsjson.AccessRule{Expression: access.Expression, Validity: string(access.Validity)}
+		switch access.Mode {
+		case imsdb.EventAccessModeRead:
+			ea.Readers = append(ea.Readers, rule)
+		case imsdb.EventAccessModeWrite:
+			ea.Writers = append(ea.Writers, rule)
+		case imsdb.EventAccessModeReport:
+			ea.Reporters = append(ea.Reporters, rule)
+		}
+	}
+	return ea
+}
+
+// eventAccessInTxn reads an event's access rules within dbTxn's transaction.
+func eventAccessInTxn(ctx context.Context, dbTxn *imsdb.Queries, eventID int32) (imsjson.EventAccess, error) {
+	accessRows, err := dbTxn.EventAccess(ctx, eventID)
+	if err != nil {
+		return imsjson.EventAccess{}, fmt.Errorf("[EventAccess]: %w", err)
+	}
+	var access []imsdb.EventAccess
+	for _, ar := range accessRows {
+		access = append(access, ar.EventAccess)
+	}
+	return eventAccessToJSON(access), nil
+}
+
 type PostEventAccess struct {
 	imsDB     *store.DB
 	imsAdmins []string
@@ -100,15 +116,16 @@ func (action PostEventAccess) ServeHTTP(w http.ResponseWriter, req *http.Request
 	if !ok {
 		return
 	}
+	audit := newAuditor(req)
 	var errs []error
 	for eventName, access := range eventsAccess {
 		event, success := mustGetEvent(w, req, eventName, action.imsDB)
 		if !success {
 			return
 		}
-		errs = append(errs, action.maybeSetAccess(ctx, event, access.Readers, imsdb.EventAccessModeRead))
-		errs = append(errs, action.maybeSetAccess(ctx, event, access.Writers, imsdb.EventAccessModeWrite))
-		errs = append(errs, action.maybeSetAccess(ctx, event, access.Reporters, imsdb.EventAccessModeReport))
+		errs = append(errs, action.maybeSetAccess(ctx, audit, event, access.Readers, imsdb.EventAccessModeRead))
+		errs = append(errs, action.maybeSetAccess(ctx, audit, event, access.Writers, imsdb.EventAccessModeWrite))
+		errs = append(errs, action.maybeSetAccess(ctx, audit, event, access.Reporters, imsdb.EventAccessModeReport))
 	}
 	if err := errors.Join(errs...); err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to set event access", err)
@@ -117,7 +134,7 @@ func (action PostEventAccess) ServeHTTP(w http.ResponseWriter, req *http.Request
 	http.Error(w, "Successfully set event access", http.StatusNoContent)
 }
 
-func (action PostEventAccess) maybeSetAccess(ctx context.Context, event imsdb.Event, rules []imsjson.AccessRule, mode imsdb.EventAccessMode) error {
+func (action PostEventAccess) maybeSetAccess(ctx context.Context, audit auditor, event imsdb.Event, rules []imsjson.AccessRule, mode imsdb.EventAccessMode) error {
 	if rules == nil {
 		return nil
 	}
@@ -130,7 +147,14 @@ func (action PostEventAccess) maybeSetAccess(ctx context.Context, event imsdb.Ev
 		return fmt.Errorf("[BeginTx]: %w", err)
 	}
 	defer txn.Rollback()
-	err = imsdb.New(txn).ClearEventAccessForMode(ctx, imsdb.ClearEventAccessForModeParams{
+	dbTxn := imsdb.New(txn)
+	// Setting one mode's rules can take away others, so the whole event's access
+	// is recorded in the audit log.
+	before, err := eventAccessInTxn(ctx, dbTxn, event.ID)
+	if err != nil {
+		return fmt.Errorf("[eventAccessInTxn]: %w", err)
+	}
+	err = dbTxn.ClearEventAccessForMode(ctx, imsdb.ClearEventAccessForModeParams{
 		Event: event.ID,
 		Mode:  mode,
 	})
@@ -138,14 +162,14 @@ func (action PostEventAccess) maybeSetAccess(ctx context.Context, event imsdb.Ev
 		return fmt.Errorf("[ClearEventAccessForMode]: %w", err)
 	}
 	for _, rule := range rules {
-		err = imsdb.New(txn).ClearEventAccessForExpression(ctx, imsdb.ClearEventAccessForExpressionParams{
+		err = dbTxn.ClearEventAccessForExpression(ctx, imsdb.ClearEventAccessForExpressionParams{
 			Event:      event.ID,
 			Expression: rule.Expression,
 		})
 		if err != nil {
 			return fmt.Errorf("[ClearEventAccessForExpression]: %w", err)
 		}
-		_, err = imsdb.New(txn).AddEventAccess(ctx, imsdb.AddEventAccessParams{
+		_, err = dbTxn.AddEventAccess(ctx, imsdb.AddEventAccessParams{
 			Event:      event.ID,
 			Expression: rule.Expression,
 			Mode:       mode,
@@ -155,6 +179,21 @@ func (action PostEventAccess) maybeSetAccess(ctx context.Context, event imsdb.Ev
 			return fmt.Errorf("[AddEventAccess]: %w", err)
 		}
 	}
+	after, err := eventAccessInTxn(ctx, dbTxn, event.ID)
+	if err != nil {
+		return fmt.Errorf("[eventAccessInTxn]: %w", err)
+	}
+	err = audit.record(ctx, dbTxn, auditChange{
+		action:     "update",
+		entityType: "event_access",
+		eventID:    event.ID,
+		entityID:   event.Name,
+		before:     before,
+		after:      after,
+	})
+	if err != nil {
+		return fmt.Errorf("[record]: %w", err)
+	}
 	if err = txn.Commit(); err != nil {
 		return fmt.Errorf("[Commit]: %w", err)
 	}
@@ -1,15 +1,37 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"github.com/launchdarkly/eventsource"
+	"github.com/srabraham/ranger-ims-go/auth"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
 	"log/slog"
+	"net/http"
+	"slices"
 	"strconv"
-	"sync/atomic"
+	"sync"
+	"time"
 )
 
+// EventSourceChannel is the prefix for all the IMS EventSource channels.
+// Each subscriber gets its own channel, so that events can be filtered
+// according to that subscriber's permissions.
 const EventSourceChannel = "imsevents"
 
+// eventSourceTicketLifetime is how long a client has to use an EventSource
+// ticket. It only needs to be long enough to open the connection.
+const eventSourceTicketLifetime = 1 * time.Minute
+
+// sseEventRetention is the number of published events kept in the SSE_EVENT table
+// for replay. A client that falls further behind than this gets told to reload
+// everything.
+const sseEventRetention = 10000
+
 type IMSEventData struct {
 	// EventName an IMS Event Name, e.g. "2025"
 	EventName string `json:"event_name,omitzero"`
@@ -53,31 +75,109 @@ func (e IMSEvent) Data() string {
 	return string(b)
 }
 
+// subscriber is the Ranger on the other end of one EventSource connection.
+type subscriber struct {
+	handle    string
+	onsite    bool
+	positions []string
+	teams     []string
+}
+
 type EventSourcerer struct {
-	Server    *eventsource.Server
-	IdCounter atomic.Int64
+	Server *eventsource.Server
+
+	imsDB       *store.DB
+	imsAdmins   []string
+	broadcaster Broadcaster
+
+	// subscribers is keyed by EventSource channel name
+	subscribers   map[string]subscriber
+	subscribersMu sync.Mutex
 }
 
-func NewEventSourcerer() *EventSourcerer {
+func NewEventSourcerer(imsDB *store.DB, imsAdmins []string, broadcaster Broadcaster) *EventSourcerer {
 	es := &EventSourcerer{
-		Server:    eventsource.NewServer(),
-		IdCounter: atomic.Int64{},
+		Server:      eventsource.NewServer(),
+		imsDB:       imsDB,
+		imsAdmins:   imsAdmins,
+		broadcaster: broadcaster,
+		subscribers: make(map[string]subscriber),
 	}
-	es.Server.Register(EventSourceChannel, es)
 	es.Server.ReplayAll = true
+	go broadcaster.Listen(context.Background(), es.deliver)
 	return es
 }
 
+// GetEventSource serves the EventSource stream for a single authenticated subscriber.
+type GetEventSource struct {
+	es *EventSourcerer
+}
+
+func (action GetEventSource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	jwtCtx, ok := mustGetJwtCtx(w, req)
+	if !ok {
+		return
+	}
+	claims := jwtCtx.Claims
+	channel := EventSourceChannel + "-" + rand.Text()
+	action.es.subscribe(channel, subscriber{
+		handle:    claims.RangerHandle(),
+		onsite:    claims.RangerOnSite(),
+		positions: claims.RangerPositions(),
+		teams:     claims.RangerTeams(),
+	})
+	defer action.es.unsubscribe(channel)
+
+	// A browser's EventSource sends the Last-Event-ID header when it reconnects
+	// on its own, but a brand-new EventSource can't set that header, so we also
+	// accept the value as a query parameter.
+	if req.Header.Get("Last-Event-ID") == "" {
+		if lastEventID := req.URL.Query().Get("last_event_id"); lastEventID != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+	}
+	action.es.Server.Handler(channel).ServeHTTP(w, req)
+}
+
+type PostEventSourceTicket struct {
+	jwter auth.JWTer
+}
+
+type PostEventSourceTicketResponse struct {
+	Ticket string `json:"ticket"`
+}
+
+func (action PostEventSourceTicket) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	jwtCtx, ok := mustGetJwtCtx(w, req)
+	if !ok {
+		return
+	}
+	ticket := action.jwter.CreateEventSourceTicket(*jwtCtx.Claims, eventSourceTicketLifetime)
+	mustWriteJSON(w, PostEventSourceTicketResponse{Ticket: ticket})
+}
+
+func (es *EventSourcerer) subscribe(channel string, sub subscriber) {
+	es.subscribersMu.Lock()
+	defer es.subscribersMu.Unlock()
+	es.subscribers[channel] = sub
+	es.Server.Register(channel, es)
+}
+
+func (es *EventSourcerer) unsubscribe(channel string) {
+	es.subscribersMu.Lock()
+	defer es.subscribersMu.Unlock()
+	delete(es.subscribers, channel)
+	es.Server.Unregister(channel, true)
+}
+
 func (es *EventSourcerer) notifyFieldReportUpdate(eventName string, frNumber int32) {
 	if frNumber == 0 {
 		return
 	}
-	es.Server.Publish([]string{EventSourceChannel}, IMSEvent{
-		EventID: es.IdCounter.Add(1),
-		EventData: IMSEventData{
-			EventName:         eventName,
-			FieldReportNumber: frNumber,
-		},
+	es.publish(IMSEventData{
+		EventName:         eventName,
+		FieldReportNumber: frNumber,
 	})
 }
 
@@ -85,27 +185,233 @@ func (es *EventSourcerer) notifyIncidentUpdate(eventName string, incidentNumber
 	if incidentNumber == 0 {
 		return
 	}
-	es.Server.Publish([]string{EventSourceChannel}, IMSEvent{
-		EventID: es.IdCounter.Add(1),
-		EventData: IMSEventData{
-			EventName:      eventName,
-			IncidentNumber: incidentNumber,
-		},
+	es.publish(IMSEventData{
+		EventName:      eventName,
+		IncidentNumber: incidentNumber,
+	})
+}
+
+func (es *EventSourcerer) publish(data IMSEventData) {
+	ev, err := es.store(context.Background(), data)
+	if err != nil {
+		slog.Error("Failed to store EventSource event. Not sending event", "EventData", data, "error", err)
+		return
+	}
+	es.broadcaster.Broadcast(ev)
+}
+
+// deliver sends an update from the Broadcaster to this server's subscribers.
+func (es *EventSourcerer) deliver(ev IMSEvent) {
+	channels, err := es.authorizedChannels(context.Background(), ev.EventData, es.currentSubscribers())
+	if err != nil {
+		slog.Error("Failed to determine EventSource recipients. Not sending event", "EventData", ev.EventData, "error", err)
+		return
+	}
+	if len(channels) > 0 {
+		es.Server.Publish(channels, ev)
+	}
+}
+
+// store persists the event to the SSE_EVENT table, which is where its ID comes
+// from. This lets clients replay anything they missed, even across server restarts.
+func (es *EventSourcerer) store(ctx context.Context, data IMSEventData) (IMSEvent, error) {
+	eventRow, err := imsdb.New(es.imsDB).QueryEventID(ctx, data.EventName)
+	if err != nil {
+		return IMSEvent{}, fmt.Errorf("[QueryEventID]: %w", err)
+	}
+	id, err := imsdb.New(es.imsDB).CreateSSEEvent(ctx, imsdb.CreateSSEEventParams{
+		Created:           float64(time.Now().Unix()),
+		Event:             eventRow.Event.ID,
+		IncidentNumber:    sql.NullInt32{Int32: data.IncidentNumber, Valid: data.IncidentNumber != 0},
+		FieldReportNumber: sql.NullInt32{Int32: data.FieldReportNumber, Valid: data.FieldReportNumber != 0},
 	})
+	if err != nil {
+		return IMSEvent{}, fmt.Errorf("[CreateSSEEvent]: %w", err)
+	}
+	// No need to prune on every write
+	if id%100 == 0 {
+		if err = imsdb.New(es.imsDB).PruneSSEEvents(ctx, id-sseEventRetention); err != nil {
+			slog.Error("Failed to prune SSE_EVENT table", "error", err)
+		}
+	}
+	return IMSEvent{EventID: id, EventData: data}, nil
+}
+
+func (es *EventSourcerer) currentSubscribers() map[string]subscriber {
+	es.subscribersMu.Lock()
+	defer es.subscribersMu.Unlock()
+	subs := make(map[string]subscriber, len(es.subscribers))
+	for channel, sub := range es.subscribers {
+		subs[channel] = sub
+	}
+	return subs
 }
 
+// authorizedChannels returns the channels of all the subscribers who are
+// permitted to learn about the provided update.
+func (es *EventSourcerer) authorizedChannels(ctx context.Context, data IMSEventData, subs map[string]subscriber) ([]string, error) {
+	if len(subs) == 0 {
+		return nil, nil
+	}
+
+	eventRow, err := imsdb.New(es.imsDB).QueryEventID(ctx, data.EventName)
+	if err != nil {
+		return nil, fmt.Errorf("[QueryEventID]: %w", err)
+	}
+	eventID := eventRow.Event.ID
+	accessRows, err := imsdb.New(es.imsDB).EventAccess(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("[EventAccess]: %w", err)
+	}
+	accessByEvent := make(map[int32][]imsdb.EventAccess)
+	for _, ar := range accessRows {
+		accessByEvent[eventID] = append(accessByEvent[eventID], ar.EventAccess)
+	}
+
+	// Reporters may only hear about Field Reports that they've authored,
+	// so we need the authors of the Field Report in question.
+	var frAuthors []string
+	if data.FieldReportNumber != 0 {
+		entries, err := imsdb.New(es.imsDB).FieldReport_ReportEntries(ctx,
+			imsdb.FieldReport_ReportEntriesParams{
+				Event:             eventID,
+				FieldReportNumber: data.FieldReportNumber,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("[FieldReport_ReportEntries]: %w", err)
+		}
+		for _, entry := range entries {
+			frAuthors = append(frAuthors, entry.ReportEntry.Author)
+		}
+	}
+
+	var channels []string
+	for channel, sub := range subs {
+		permissions, _ := auth.ManyEventPermissions(
+			accessByEvent, es.imsAdmins, sub.handle, sub.onsite, sub.positions, sub.teams,
+		)
+		if permitted(data, permissions[eventID], sub.handle, frAuthors) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// permitted is whether a Ranger with eventPermissions may learn about the update.
+// frAuthors are the authors of the update's Field Report, if it's about one.
+func permitted(data IMSEventData, eventPermissions auth.EventPermissionMask, handle string, frAuthors []string) bool {
+	switch {
+	case data.IncidentNumber != 0:
+		return eventPermissions&auth.EventReadIncidents != 0
+	case data.FieldReportNumber != 0:
+		return eventPermissions&auth.EventReadAllFieldReports != 0 ||
+			(eventPermissions&auth.EventReadOwnFieldReports != 0 && slices.Contains(frAuthors, handle))
+	}
+	return false
+}
+
+// Replay is called when a client connects. The client always gets an InitialEvent.
+// If the client provided the ID of the last event it saw, it then gets every
+// later event that it's permitted to see.
 func (es *EventSourcerer) Replay(channel, id string) chan eventsource.Event {
-	if channel != EventSourceChannel {
+	es.subscribersMu.Lock()
+	sub, found := es.subscribers[channel]
+	es.subscribersMu.Unlock()
+	if !found {
 		return nil
 	}
-	out := make(chan eventsource.Event, 1)
-	out <- IMSEvent{
-		EventID: es.IdCounter.Load(),
-		EventData: IMSEventData{
-			InitialEvent: true,
-			Comment:      "The most recent SSE ID is provided in this message",
-		},
-	}
-	close(out)
+	out := make(chan eventsource.Event)
+	go func() {
+		defer close(out)
+		ctx := context.Background()
+		idRange, err := imsdb.New(es.imsDB).SSEEventIDRange(ctx)
+		if err != nil {
+			slog.Error("Failed to fetch SSE_EVENT ID range", "error", err)
+			return
+		}
+		minID, maxID := idRange.MinID, idRange.MaxID
+
+		lastSeen, err := strconv.ParseInt(id, 10, 64)
+		// The client's ID must be in the range of what we have stored. If it's too old,
+		// then it's missed events that are no longer available. If it's too new,
+		// then the SSE_EVENT table must have been reset.
+		canReplay := err == nil && lastSeen >= minID-1 && lastSeen <= maxID
+		if !canReplay {
+			// This tells the client the most recent ID, and that it should
+			// reload everything if it doesn't match what the client last saw.
+			out <- IMSEvent{
+				EventID: maxID,
+				EventData: IMSEventData{
+					InitialEvent: true,
+					Comment:      "The most recent SSE ID is provided in this message",
+				},
+			}
+			return
+		}
+		out <- IMSEvent{
+			EventID: lastSeen,
+			EventData: IMSEventData{
+				InitialEvent: true,
+				Comment:      "Replaying all events after the provided SSE ID",
+			},
+		}
+		rows, err := imsdb.New(es.imsDB).SSEEventsAfter(ctx, lastSeen)
+		if err != nil {
+			slog.Error("Failed to fetch SSE events for replay", "error", err)
+			return
+		}
+		// There could be thousands of events to replay, so the subscriber's permissions
+		// and the Field Report authors are each looked up just once, up front.
+		permissions, frAuthors, err := es.replayAuthorization(ctx, sub, lastSeen)
+		if err != nil {
+			slog.Error("Failed to authorize replayed SSE events", "error", err)
+			return
+		}
+		for _, row := range rows {
+			data := IMSEventData{
+				EventName:         row.EventName,
+				IncidentNumber:    row.SseEvent.IncidentNumber.Int32,
+				FieldReportNumber: row.SseEvent.FieldReportNumber.Int32,
+			}
+			authors := frAuthors[fieldReportKey{event: row.SseEvent.Event, number: data.FieldReportNumber}]
+			if permitted(data, permissions[row.SseEvent.Event], sub.handle, authors) {
+				out <- IMSEvent{EventID: row.SseEvent.ID, EventData: data}
+			}
+		}
+	}()
 	return out
 }
+
+type fieldReportKey struct {
+	event  int32
+	number int32
+}
+
+// replayAuthorization returns the subscriber's permissions for every event, and the
+// authors of every Field Report that's been updated since the event with ID lastSeen.
+func (es *EventSourcerer) replayAuthorization(ctx context.Context, sub subscriber, lastSeen int64) (
+	map[int32]auth.EventPermissionMask, map[fieldReportKey][]string, error,
+) {
+	accessRows, err := imsdb.New(es.imsDB).EventAccessAll(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[EventAccessAll]: %w", err)
+	}
+	accessByEvent := make(map[int32][]imsdb.EventAccess)
+	for _, ar := range accessRows {
+		accessByEvent[ar.EventAccess.Event] = append(accessByEvent[ar.EventAccess.Event], ar.EventAccess)
+	}
+	permissions, _ := auth.ManyEventPermissions(
+		accessByEvent, es.imsAdmins, sub.handle, sub.onsite, sub.positions, sub.teams,
+	)
+
+	authorRows, err := imsdb.New(es.imsDB).SSEEventFieldReportAuthorsAfter(ctx, lastSeen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[SSEEventFieldReportAuthorsAfter]: %w", err)
+	}
+	frAuthors := make(map[fieldReportKey][]string)
+	for _, row := range authorRows {
+		key := fieldReportKey{event: row.Event, number: row.FieldReportNumber.Int32}
+		frAuthors[key] = append(frAuthors[key], row.Author)
+	}
+	return permissions, frAuthors, nil
+}
@@ -0,0 +1,352 @@
+package api
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/auth"
+	"github.com/srabraham/ranger-ims-go/export"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportPageSize is how many incidents or field reports an export reads from
+// the database at a time.
+const exportPageSize = 500
+
+type ExportIncidents struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP streams an event's incidents as a table, one row per incident. It
+// takes the same filters as the incident list, plus:
+//
+//   - format: "csv" (the default) or "xlsx"
+//   - report_entries: "true" to add a column with the unstricken report entries
+//   - exclude_system_entries: "true" to leave the system's entries out of it
+//
+// The incidents are always in number order, and limit, cursor, and sort are
+// ignored.
+func (action ExportIncidents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, _, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	if !mustParseForm(w, req) {
+		return
+	}
+	format, ok := mustGetExportFormat(w, req)
+	if !ok {
+		return
+	}
+	withEntries := req.Form.Get("report_entries") == "true"
+	generatedLTE := req.Form.Get("exclude_system_entries") != "true" // false means to exclude
+	form := maps.Clone(req.Form)
+	for _, param := range []string{"limit", "cursor", "sort"} {
+		delete(form, param)
+	}
+	query, err := parseIncidentsQuery(event.ID, form)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+	ctx := req.Context()
+
+	streetNames, err := concentricStreetNames(ctx, action.imsDB, event.ID)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Streets", err)
+		return
+	}
+
+	header := []string{
+		"Number", "Created", "Last Modified", "State", "Priority", "Summary",
+		"Location Name", "Location Street", "Location Radial", "Location Description",
+		"Incident Types", "Rangers", "Field Reports",
+	}
+	if withEntries {
+		header = append(header, "Report Entries")
+	}
+
+	var tw export.TableWriter
+	params := query.params
+	params.Limit = exportPageSize
+	for {
+		rows, err := imsdb.New(action.imsDB).Incidents(ctx, params)
+		if err != nil {
+			exportFailed(w, req, tw, "Failed to fetch Incidents", err)
+			return
+		}
+		entriesByIncident := make(map[int32][]imsdb.ReportEntry)
+		if withEntries && len(rows) > 0 {
+			entries, err := imsdb.New(action.imsDB).Incidents_ReportEntries(ctx, imsdb.Incidents_ReportEntriesParams{
+				Event:             event.ID,
+				Generated:         generatedLTE,
+				MinIncidentNumber: sql.NullInt32{Int32: rows[0].Incident.Number, Valid: true},
+				MaxIncidentNumber: sql.NullInt32{Int32: rows[len(rows)-1].Incident.Number, Valid: true},
+			})
+			if err != nil {
+				exportFailed(w, req, tw, "Failed to fetch Incident Report Entries", err)
+				return
+			}
+			for _, e := range entries {
+				entriesByIncident[e.IncidentNumber] = append(entriesByIncident[e.IncidentNumber], e.ReportEntry)
+			}
+		}
+		if tw == nil {
+			if tw, ok = mustStartExport(w, req, format, event.Name+"-incidents", header); !ok {
+				return
+			}
+		}
+		for _, r := range rows {
+			incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(imsdb.IncidentRow(r))
+			if err != nil {
+				exportFailed(w, req, tw, "Failed to fetch Incident details", err)
+				return
+			}
+			i := r.Incident
+			row := []string{
+				strconv.Itoa(int(i.Number)),
+				exportTime(i.Created),
+				exportTime(i.LastModified),
+				string(i.State),
+				strconv.Itoa(int(i.Priority)),
+				i.Summary.String,
+				i.LocationName.String,
+				cmp.Or(streetNames[i.LocationConcentric.String], i.LocationConcentric.String),
+				exportRadial(i.LocationRadialHour, i.LocationRadialMinute),
+				i.LocationDescription.String,
+				strings.Join(incidentTypes, ", "),
+				strings.Join(rangerHandles, ", "),
+				joinInts(fieldReportNumbers),
+			}
+			if withEntries {
+				row = append(row, exportReportEntries(entriesByIncident[i.Number]))
+			}
+			if err = tw.WriteRow(row); err != nil {
+				exportFailed(w, req, tw, "Failed to write export", err)
+				return
+			}
+		}
+		if len(rows) < exportPageSize {
+			break
+		}
+		last := rows[len(rows)-1].Incident
+		params.AfterSortKey = sql.NullFloat64{Float64: query.sortKey(last), Valid: true}
+		params.AfterNumber = sql.NullInt32{Int32: last.Number * params.NumberDirection, Valid: true}
+	}
+	if err = tw.Close(); err != nil {
+		exportFailed(w, req, tw, "Failed to finish export", err)
+	}
+}
+
+type ExportFieldReports struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP streams an event's field reports as a table, one row per field
+// report. It takes the same parameters as ExportIncidents, though the only
+// filter that field reports have is modified_since. A requestor who may only
+// read their own field reports gets just those.
+func (action ExportFieldReports) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&(auth.EventReadAllFieldReports|auth.EventReadOwnFieldReports) == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to read Field Reports on this Event", nil)
+		return
+	}
+	// i.e. the user has EventReadOwnFieldReports, but not EventReadAllFieldReports
+	limitedAccess := eventPermissions&auth.EventReadAllFieldReports == 0
+
+	if !mustParseForm(w, req) {
+		return
+	}
+	format, ok := mustGetExportFormat(w, req)
+	if !ok {
+		return
+	}
+	withEntries := req.Form.Get("report_entries") == "true"
+	excludeSystemEntries := req.Form.Get("exclude_system_entries") == "true"
+	modifiedSince, err := parseTimeParam(req.Form, "modified_since")
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid modified_since", err)
+		return
+	}
+	ctx := req.Context()
+
+	header := []string{"Number", "Created", "Last Modified", "Summary", "Incident"}
+	if withEntries {
+		header = append(header, "Report Entries")
+	}
+
+	var tw export.TableWriter
+	params := imsdb.FieldReportsParams{
+		Event:         event.ID,
+		ModifiedSince: modifiedSince,
+		Limit:         exportPageSize,
+	}
+	for {
+		rows, err := imsdb.New(action.imsDB).FieldReports(ctx, params)
+		if err != nil {
+			exportFailed(w, req, tw, "Failed to fetch Field Reports", err)
+			return
+		}
+		entriesByFR := make(map[int32][]imsdb.ReportEntry)
+		if (withEntries || limitedAccess) && len(rows) > 0 {
+			// Get the system entries regardless, since they also show who has
+			// worked on a field report
+			entries, err := imsdb.New(action.imsDB).FieldReports_ReportEntries(ctx, imsdb.FieldReports_ReportEntriesParams{
+				Event:                event.ID,
+				Generated:            true,
+				MinFieldReportNumber: sql.NullInt32{Int32: rows[0].FieldReport.Number, Valid: true},
+				MaxFieldReportNumber: sql.NullInt32{Int32: rows[len(rows)-1].FieldReport.Number, Valid: true},
+			})
+			if err != nil {
+				exportFailed(w, req, tw, "Failed to fetch Field Report Report Entries", err)
+				return
+			}
+			for _, e := range entries {
+				entriesByFR[e.FieldReportNumber] = append(entriesByFR[e.FieldReportNumber], e.ReportEntry)
+			}
+		}
+		if tw == nil {
+			if tw, ok = mustStartExport(w, req, format, event.Name+"-field-reports", header); !ok {
+				return
+			}
+		}
+		for _, r := range rows {
+			fr := r.FieldReport
+			entries := entriesByFR[fr.Number]
+			if limitedAccess && !containsEntryAuthor(entries, jwtCtx.Claims.RangerHandle()) {
+				continue
+			}
+			row := []string{
+				strconv.Itoa(int(fr.Number)),
+				exportTime(fr.Created),
+				exportTime(fr.LastModified),
+				fr.Summary.String,
+				"",
+			}
+			if fr.IncidentNumber.Valid {
+				row[4] = strconv.Itoa(int(fr.IncidentNumber.Int32))
+			}
+			if withEntries {
+				if excludeSystemEntries {
+					entries = slices.DeleteFunc(entries, func(e imsdb.ReportEntry) bool { return e.Generated })
+				}
+				row = append(row, exportReportEntries(entries))
+			}
+			if err = tw.WriteRow(row); err != nil {
+				exportFailed(w, req, tw, "Failed to write export", err)
+				return
+			}
+		}
+		if len(rows) < exportPageSize {
+			break
+		}
+		params.AfterNumber = sql.NullInt32{Int32: rows[len(rows)-1].FieldReport.Number, Valid: true}
+	}
+	if err = tw.Close(); err != nil {
+		exportFailed(w, req, tw, "Failed to finish export", err)
+	}
+}
+
+func mustGetExportFormat(w http.ResponseWriter, req *http.Request) (export.Format, bool) {
+	format := export.Format(cmp.Or(req.Form.Get("format"), string(export.FormatCSV)))
+	if !format.Valid() {
+		handleErr(w, req, http.StatusBadRequest, "Invalid format", fmt.Errorf("unknown format %q", format))
+		return "", false
+	}
+	return format, true
+}
+
+// mustStartExport sends the response headers and the table's header row.
+// Nothing can be reported to the client as an HTTP error after this.
+func mustStartExport(
+	w http.ResponseWriter, req *http.Request, format export.Format, baseName string, header []string,
+) (export.TableWriter, bool) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": baseName + "." + string(format),
+	}))
+	tw, err := export.NewTableWriter(w, format, baseName)
+	if err != nil {
+		exportFailed(w, req, nil, "Failed to start export", err)
+		return nil, false
+	}
+	if err = tw.WriteRow(header); err != nil {
+		exportFailed(w, req, tw, "Failed to write export", err)
+		return nil, false
+	}
+	return tw, true
+}
+
+// exportFailed reports an error during an export. Once the table has been
+// started, the status has already gone out, so all that can be done is to
+// stop. The table is left unfinished, which for XLSX at least means that the
+// client ends up with a file that won't open rather than one that's missing rows.
+func exportFailed(w http.ResponseWriter, req *http.Request, tw export.TableWriter, errorForUser string, internalError error) {
+	if tw == nil {
+		handleErr(w, req, http.StatusInternalServerError, errorForUser, internalError)
+		return
+	}
+	slog.Error(errorForUser, "error", internalError, "path", req.URL.Path, "requestID", requestID(req))
+}
+
+// exportTime formats a time from the database in UTC.
+func exportTime(t float64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
+
+// exportRadial formats a location's radial address like a clock, e.g. "3:45".
+func exportRadial(hour, minute sql.NullInt16) string {
+	if !hour.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%d:%02d", hour.Int16, minute.Int16)
+}
+
+// exportReportEntries puts the unstricken report entries into one cell, with
+// a blank line between entries.
+func exportReportEntries(entries []imsdb.ReportEntry) string {
+	var texts []string
+	for _, re := range entries {
+		if re.Stricken {
+			continue
+		}
+		texts = append(texts, fmt.Sprintf("%v, %v:\n%v", exportTime(re.Created), re.Author, re.Text))
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+func joinInts(nums []int32) string {
+	strs := make([]string, 0, len(nums))
+	for _, n := range nums {
+		strs = append(strs, strconv.Itoa(int(n)))
+	}
+	return strings.Join(strs, ", ")
+}
+
+func containsEntryAuthor(entries []imsdb.ReportEntry, author string) bool {
+	for _, e := range entries {
+		if e.Author == author {
+			return true
+		}
+	}
+	return false
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/srabraham/ranger-ims-go/store"
 	"github.com/srabraham/ranger-ims-go/store/imsdb"
 	"log/slog"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -61,7 +63,16 @@ func (action GetFieldReports) ServeHTTP(w http.ResponseWriter, req *http.Request
 		})
 	}
 
-	storedFRs, err := imsdb.New(action.imsDB).FieldReports(req.Context(), event.ID)
+	modifiedSince, err := parseTimeParam(req.Form, "modified_since")
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid modified_since", err)
+		return
+	}
+	storedFRs, err := imsdb.New(action.imsDB).FieldReports(req.Context(), imsdb.FieldReportsParams{
+		Event:         event.ID,
+		ModifiedSince: modifiedSince,
+		Limit:         math.MaxInt32,
+	})
 	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Field Reports", err)
 		return
@@ -87,7 +98,9 @@ func (action GetFieldReports) ServeHTTP(w http.ResponseWriter, req *http.Request
 			Created:       time.Unix(int64(fr.FieldReport.Created), 0),
 			Summary:       stringOrNil(fr.FieldReport.Summary),
 			Incident:      fr.FieldReport.IncidentNumber.Int32,
+			LastModified:  time.Unix(int64(fr.FieldReport.LastModified), 0),
 			ReportEntries: entriesByFR[fr.FieldReport.Number],
+			Version:       fr.FieldReport.Version,
 		})
 	}
 
@@ -139,19 +152,7 @@ func (action GetFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request)
 		handleErr(w, req, http.StatusInternalServerError, "Failed to get FR report entries", err)
 		return
 	}
-	entries := make([]imsjson.ReportEntry, 0)
-	for _, rer := range reportEntryRows {
-		re := rer.ReportEntry
-		entries = append(entries, imsjson.ReportEntry{
-			ID:            re.ID,
-			Created:       time.Unix(int64(re.Created), 0),
-			Author:        re.Author,
-			SystemEntry:   re.Generated,
-			Text:          re.Text,
-			Stricken:      re.Stricken,
-			HasAttachment: re.AttachedFile.String != "",
-		})
-	}
+	entries := fieldReportEntriesToJSON(reportEntryRows)
 	if limitedAccess {
 		if !containsAuthor(entries, jwtCtx.Claims.RangerHandle()) {
 			handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to read this Field Report", nil)
@@ -167,18 +168,103 @@ func (action GetFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request)
 		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Field Report", err)
 		return
 	}
-	fr := frRow.FieldReport
 
-	response = imsjson.FieldReport{
+	response = fieldReportToJSON(event, frRow.FieldReport, entries)
+	w.Header().Set("ETag", versionETag(response.Version))
+	mustWriteJSON(w, response)
+}
+
+// fetchFieldReportJSON gets a field report in the form that it's served to clients.
+func fetchFieldReportJSON(ctx context.Context, imsDB *store.DB, event imsdb.Event, fieldReportNumber int32) (imsjson.FieldReport, error) {
+	reportEntryRows, err := imsdb.New(imsDB).FieldReport_ReportEntries(ctx,
+		imsdb.FieldReport_ReportEntriesParams{
+			Event:             event.ID,
+			FieldReportNumber: fieldReportNumber,
+		})
+	if err != nil {
+		return imsjson.FieldReport{}, fmt.Errorf("[FieldReport_ReportEntries]: %w", err)
+	}
+	frRow, err := imsdb.New(imsDB).FieldReport(ctx, imsdb.FieldReportParams{
+		Event:  event.ID,
+		Number: fieldReportNumber,
+	})
+	if err != nil {
+		return imsjson.FieldReport{}, fmt.Errorf("[FieldReport]: %w", err)
+	}
+	return fieldReportToJSON(event, frRow.FieldReport, fieldReportEntriesToJSON(reportEntryRows)), nil
+}
+
+// fetchFieldReportsJSON gets the numbered field reports with their report entries,
+// keyed by number. System entries are only included if generatedLTE is true.
+func fetchFieldReportsJSON(
+	ctx context.Context, q *imsdb.Queries, event imsdb.Event, numbers []int32, generatedLTE bool,
+) (map[int32]imsjson.FieldReport, error) {
+	fieldReports := make(map[int32]imsjson.FieldReport)
+	for batch := range slices.Chunk(numbers, reportEntriesBatchSize) {
+		frRows, err := q.FieldReportsByNumber(ctx, imsdb.FieldReportsByNumberParams{
+			Event:   event.ID,
+			Numbers: batch,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("[FieldReportsByNumber]: %w", err)
+		}
+		entryRows, err := q.FieldReportsByNumber_ReportEntries(ctx, imsdb.FieldReportsByNumber_ReportEntriesParams{
+			Event:              event.ID,
+			Generated:          generatedLTE,
+			FieldReportNumbers: batch,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("[FieldReportsByNumber_ReportEntries]: %w", err)
+		}
+		entriesByFR := make(map[int32][]imsjson.ReportEntry)
+		for _, row := range entryRows {
+			entriesByFR[row.FieldReportNumber] = append(entriesByFR[row.FieldReportNumber], reportEntryToJSON(row.ReportEntry))
+		}
+		for _, row := range frRows {
+			fieldReports[row.FieldReport.Number] = fieldReportToJSON(event, row.FieldReport, entriesByFR[row.FieldReport.Number])
+		}
+	}
+	return fieldReports, nil
+}
+
+func fieldReportEntriesToJSON(rows []imsdb.FieldReport_ReportEntriesRow) []imsjson.ReportEntry {
+	entries := make([]imsjson.ReportEntry, 0, len(rows))
+	for _, rer := range rows {
+		re := rer.ReportEntry
+		entries = append(entries, imsjson.ReportEntry{
+			ID:            re.ID,
+			Created:       time.Unix(int64(re.Created), 0),
+			Author:        re.Author,
+			SystemEntry:   re.Generated,
+			Text:          re.Text,
+			Stricken:      re.Stricken,
+			HasAttachment: re.AttachedFile.String != "",
+		})
+	}
+	return entries
+}
+
+func fieldReportToJSON(event imsdb.Event, fr imsdb.FieldReport, entries []imsjson.ReportEntry) imsjson.FieldReport {
+	return imsjson.FieldReport{
 		Event:         event.Name,
 		Number:        fr.Number,
 		Created:       time.Unix(int64(fr.Created), 0),
 		Summary:       stringOrNil(fr.Summary),
 		Incident:      fr.IncidentNumber.Int32,
-		ReportEntries: []imsjson.ReportEntry{},
+		LastModified:  time.Unix(int64(fr.LastModified), 0),
+		ReportEntries: entries,
+		Version:       fr.Version,
 	}
-	response.ReportEntries = entries
-	mustWriteJSON(w, response)
+}
+
+// writeFieldReportPreconditionFailed responds with 412 and the field report's current state.
+func writeFieldReportPreconditionFailed(w http.ResponseWriter, req *http.Request, imsDB *store.DB, event imsdb.Event, fieldReportNumber int32) {
+	current, err := fetchFieldReportJSON(req.Context(), imsDB, event, fieldReportNumber)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Field Report", err)
+		return
+	}
+	writePreconditionFailed(w, req, current.Version, current)
 }
 
 type EditFieldReport struct {
@@ -225,7 +311,28 @@ func (action EditFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request
 		return
 	}
 	storedFR := frr.FieldReport
+	if !parseIfMatch(req).allows(storedFR.Version) {
+		writeFieldReportPreconditionFailed(w, req, action.imsDB, event, fieldReportNumber)
+		return
+	}
+	// The version that the update below will be conditioned on
+	expectedVersion := storedFR.Version
+
+	requestFR, ok := mustReadBodyAs[imsjson.FieldReport](w, req)
+	if !ok {
+		return
+	}
 
+	txn, err := action.imsDB.Begin()
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+
+	// The incidents whose lists of field reports change
+	var movedBetween []sql.NullInt32
 	queryAction := req.FormValue("action")
 	if queryAction != "" {
 		previousIncident := storedFR.IncidentNumber
@@ -248,64 +355,113 @@ func (action EditFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request
 			handleErr(w, req, http.StatusBadRequest, "Invalid action", fmt.Errorf("provided bad action was %v", queryAction))
 			return
 		}
-		err = imsdb.New(action.imsDB).AttachFieldReportToIncident(ctx, imsdb.AttachFieldReportToIncidentParams{
-			IncidentNumber: newIncident,
-			Event:          event.ID,
-			Number:         fieldReportNumber,
+		now := float64(time.Now().Unix())
+		updated, err := dbTxn.AttachFieldReportToIncident(ctx, imsdb.AttachFieldReportToIncidentParams{
+			IncidentNumber:  newIncident,
+			LastModified:    now,
+			Event:           event.ID,
+			Number:          fieldReportNumber,
+			ExpectedVersion: sql.NullInt32{Int32: expectedVersion, Valid: true},
 		})
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Failed to attach Field Report to Incident", err)
 			return
 		}
-		err = addFRReportEntry(ctx, imsdb.New(action.imsDB), event.ID, fieldReportNumber, author, entryText, true)
+		if updated == 0 {
+			// Someone else changed the field report since it was read above
+			_ = txn.Rollback()
+			writeFieldReportPreconditionFailed(w, req, action.imsDB, event, fieldReportNumber)
+			return
+		}
+		err = recordFieldReportMove(ctx, dbTxn, event.ID, fieldReportNumber, previousIncident, newIncident, author, now)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to record Incident history", err)
+			return
+		}
+		storedFR.IncidentNumber = newIncident
+		expectedVersion++
+		movedBetween = []sql.NullInt32{previousIncident, newIncident}
+		for _, incident := range movedBetween {
+			if !incident.Valid {
+				continue
+			}
+			err = dbTxn.TouchIncident(ctx, imsdb.TouchIncidentParams{
+				LastModified: now,
+				Event:        event.ID,
+				Number:       incident.Int32,
+			})
+			if err != nil {
+				handleErr(w, req, http.StatusInternalServerError, "Failed to update Incident", err)
+				return
+			}
+		}
+		err = addFRReportEntry(ctx, dbTxn, event.ID, fieldReportNumber, author, entryText, true)
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Failed to attach Report Entry to Field Report", err)
 			return
 		}
-		defer action.eventSource.notifyFieldReportUpdate(event.Name, fieldReportNumber)
-		defer action.eventSource.notifyIncidentUpdate(event.Name, previousIncident.Int32)
-		defer action.eventSource.notifyIncidentUpdate(event.Name, newIncident.Int32)
+		err = newAuditor(req).record(ctx, dbTxn, auditChange{
+			action:     "update",
+			entityType: "field_report",
+			eventID:    event.ID,
+			entityID:   fmt.Sprint(fieldReportNumber),
+			before:     map[string]any{"incident": previousIncident.Int32},
+			after:      map[string]any{"incident": newIncident.Int32},
+		})
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to record Field Report change in audit log", err)
+			return
+		}
 		slog.Info("Attached Field Report to newIncident", "event", event.ID, "newIncident", newIncident.Int32, "previousIncident", previousIncident.Int32, "field report", fieldReportNumber)
 	}
 
-	requestFR, ok := mustReadBodyAs[imsjson.FieldReport](w, req)
-	if !ok {
-		return
-	}
 	// This is fine, as it may be that only an attach/detach was requested
 	if requestFR.Number == 0 {
 		slog.Debug("No field report number provided")
+		if err = txn.Commit(); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to commit transaction", err)
+			return
+		}
+		if movedBetween != nil {
+			defer action.eventSource.notifyFieldReportUpdate(event.Name, fieldReportNumber)
+		}
+		for _, incident := range movedBetween {
+			defer action.eventSource.notifyIncidentUpdate(event.Name, incident.Int32)
+		}
 		http.Error(w, "OK", http.StatusNoContent)
 		return
 	}
 
-	txn, err := action.imsDB.Begin()
-	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to start transaction", err)
-		return
-	}
-	defer txn.Rollback()
-	dbTxn := imsdb.New(txn)
+	before, after := make(map[string]any), make(map[string]any)
 
 	if requestFR.Summary != nil {
-		storedFR.Summary = sqlNullString(requestFR.Summary)
+		updated, err := dbTxn.UpdateFieldReport(ctx, imsdb.UpdateFieldReportParams{
+			Event:          storedFR.Event,
+			Number:         storedFR.Number,
+			Summary:        sqlNullString(requestFR.Summary),
+			IncidentNumber: storedFR.IncidentNumber,
+			LastModified:   float64(time.Now().Unix()),
+			Version:        expectedVersion,
+		})
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to update Field Report", err)
+			return
+		}
+		if updated == 0 {
+			// Someone else changed the field report since it was read above
+			_ = txn.Rollback()
+			writeFieldReportPreconditionFailed(w, req, action.imsDB, event, fieldReportNumber)
+			return
+		}
 		text := "Changed summary to: " + *requestFR.Summary
 		err = addFRReportEntry(ctx, dbTxn, event.ID, storedFR.Number, author, text, true)
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Error adding system Field Report Report Entry", err)
 			return
 		}
+		before["summary"], after["summary"] = stringOrNil(storedFR.Summary), stringOrNil(sqlNullString(requestFR.Summary))
 	}
-	err = dbTxn.UpdateFieldReport(ctx, imsdb.UpdateFieldReportParams{
-		Event:          storedFR.Event,
-		Number:         storedFR.Number,
-		Summary:        storedFR.Summary,
-		IncidentNumber: storedFR.IncidentNumber,
-	})
-	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to update Field Report", err)
-		return
-	}
+	var addedEntries []string
 	for _, entry := range requestFR.ReportEntries {
 		if entry.Text == "" {
 			continue
@@ -315,6 +471,24 @@ func (action EditFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request
 			handleErr(w, req, http.StatusInternalServerError, "Error adding Field Report Report Entry", err)
 			return
 		}
+		addedEntries = append(addedEntries, entry.Text)
+	}
+	if len(addedEntries) > 0 {
+		after["report_entries"] = addedEntries
+	}
+	if len(after) > 0 {
+		err = newAuditor(req).record(ctx, dbTxn, auditChange{
+			action:     "update",
+			entityType: "field_report",
+			eventID:    event.ID,
+			entityID:   fmt.Sprint(storedFR.Number),
+			before:     before,
+			after:      after,
+		})
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to record Field Report change in audit log", err)
+			return
+		}
 	}
 
 	if err = txn.Commit(); err != nil {
@@ -323,6 +497,9 @@ func (action EditFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request
 	}
 
 	defer action.eventSource.notifyFieldReportUpdate(event.Name, storedFR.Number)
+	for _, incident := range movedBetween {
+		defer action.eventSource.notifyIncidentUpdate(event.Name, incident.Int32)
+	}
 
 	http.Error(w, "Success", http.StatusNoContent)
 }
@@ -385,12 +562,6 @@ func (action NewFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request)
 	}
 
 	author := jwtCtx.Claims.RangerHandle()
-	numUntyped, err := imsdb.New(action.imsDB).MaxFieldReportNumber(ctx, event.ID)
-	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to find next Field Report number", err)
-		return
-	}
-	newFrNum := numUntyped.(int64) + 1
 
 	txn, err := action.imsDB.Begin()
 	if err != nil {
@@ -400,38 +571,64 @@ func (action NewFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request)
 	defer txn.Rollback()
 	dbTxn := imsdb.New(txn)
 
+	newFrNum, err := allocateFieldReportNumber(ctx, dbTxn, event.ID)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to find next Field Report number", err)
+		return
+	}
+
+	now := float64(time.Now().Unix())
 	err = dbTxn.CreateFieldReport(ctx, imsdb.CreateFieldReportParams{
 		Event:          event.ID,
-		Number:         int32(newFrNum),
-		Created:        float64(time.Now().Unix()),
+		Number:         newFrNum,
+		Created:        now,
 		Summary:        sqlNullString(fr.Summary),
 		IncidentNumber: sql.NullInt32{},
+		LastModified:   now,
 	})
 	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to create Field Report", err)
 		return
 	}
 
+	after := map[string]any{"summary": stringOrNil(sqlNullString(fr.Summary))}
+	var addedEntries []string
 	for _, entry := range fr.ReportEntries {
 		if entry.Text == "" {
 			continue
 		}
-		err = addFRReportEntry(ctx, dbTxn, event.ID, int32(newFrNum), author, entry.Text, false)
+		err = addFRReportEntry(ctx, dbTxn, event.ID, newFrNum, author, entry.Text, false)
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Error adding Report Entry", err)
 			return
 		}
+		addedEntries = append(addedEntries, entry.Text)
+	}
+	if len(addedEntries) > 0 {
+		after["report_entries"] = addedEntries
 	}
 
 	if fr.Summary != nil {
 		text := "Changed summary to: " + *fr.Summary
-		err = addFRReportEntry(ctx, dbTxn, event.ID, int32(newFrNum), author, text, true)
+		err = addFRReportEntry(ctx, dbTxn, event.ID, newFrNum, author, text, true)
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Error changing Field Report summary", err)
 			return
 		}
 	}
 
+	err = newAuditor(req).record(ctx, dbTxn, auditChange{
+		action:     "create",
+		entityType: "field_report",
+		eventID:    event.ID,
+		entityID:   fmt.Sprint(newFrNum),
+		after:      after,
+	})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to record Field Report in audit log", err)
+		return
+	}
+
 	if err = txn.Commit(); err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to commit transaction", err)
 		return
@@ -440,20 +637,24 @@ func (action NewFieldReport) ServeHTTP(w http.ResponseWriter, req *http.Request)
 	loc := fmt.Sprintf("/ims/api/events/%v/field_reports/%v", event.Name, newFrNum)
 	w.Header().Set("X-IMS-Field-Report-Number", fmt.Sprint(newFrNum))
 	w.Header().Set("Location", loc)
-	defer action.eventSource.notifyFieldReportUpdate(event.Name, int32(newFrNum))
+	defer action.eventSource.notifyFieldReportUpdate(event.Name, newFrNum)
 
 	http.Error(w, http.StatusText(http.StatusCreated), http.StatusCreated)
 }
 
 func addFRReportEntry(ctx context.Context, q *imsdb.Queries, eventID, frNum int32, author, text string, generated bool) error {
-	reID, err := q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{
-		Author:       author,
-		Text:         text,
-		Created:      float64(time.Now().Unix()),
-		Generated:    generated,
-		Stricken:     false,
-		AttachedFile: sql.NullString{},
+	return createFRReportEntry(ctx, q, eventID, frNum, imsdb.CreateReportEntryParams{
+		Author:    author,
+		Text:      text,
+		Generated: generated,
 	})
+}
+
+// createFRReportEntry adds entry to the field report. Its Created time is set to now.
+func createFRReportEntry(ctx context.Context, q *imsdb.Queries, eventID, frNum int32, entry imsdb.CreateReportEntryParams) error {
+	now := float64(time.Now().Unix())
+	entry.Created = now
+	reID, err := q.CreateReportEntry(ctx, entry)
 	if err != nil {
 		return fmt.Errorf("[CreateReportEntry]: %w", err)
 	}
@@ -465,6 +666,14 @@ func addFRReportEntry(ctx context.Context, q *imsdb.Queries, eventID, frNum int3
 	if err != nil {
 		return fmt.Errorf("[AttachReportEntryToFieldReport]: %w", err)
 	}
+	err = q.SetFieldReportLastModified(ctx, imsdb.SetFieldReportLastModifiedParams{
+		LastModified: now,
+		Event:        eventID,
+		Number:       frNum,
+	})
+	if err != nil {
+		return fmt.Errorf("[SetFieldReportLastModified]: %w", err)
+	}
 	return nil
 }
 
@@ -474,3 +683,20 @@ func sqlNullString(s *string) sql.NullString {
 	}
 	return sql.NullString{String: *s, Valid: true}
 }
+
+// allocateFieldReportNumber hands out the next field report number for an event.
+// The number is held by dbTxn's transaction, so concurrent callers wait their
+// turn rather than getting the same number.
+func allocateFieldReportNumber(ctx context.Context, dbTxn *imsdb.Queries, eventID int32) (int32, error) {
+	if err := dbTxn.CreateEventSequenceOrIgnore(ctx, eventID); err != nil {
+		return 0, fmt.Errorf("[CreateEventSequenceOrIgnore]: %w", err)
+	}
+	if err := dbTxn.IncrementFieldReportNumber(ctx, eventID); err != nil {
+		return 0, fmt.Errorf("[IncrementFieldReportNumber]: %w", err)
+	}
+	number, err := dbTxn.LastFieldReportNumber(ctx, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("[LastFieldReportNumber]: %w", err)
+	}
+	return number, nil
+}
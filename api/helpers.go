@@ -3,13 +3,17 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/srabraham/ranger-ims-go/auth"
 	"github.com/srabraham/ranger-ims-go/store"
 	"github.com/srabraham/ranger-ims-go/store/imsdb"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 func mustParseForm(w http.ResponseWriter, req *http.Request) (success bool) {
@@ -133,10 +137,63 @@ func mustGetGlobalPermissions(w http.ResponseWriter, req *http.Request, imsDB *s
 }
 
 func handleErr(w http.ResponseWriter, req *http.Request, statusCode int, errorForUser string, internalError error) {
-	slog.Error(errorForUser, "error", internalError, "statusCode", statusCode, "path", req.URL.Path)
+	slog.Error(errorForUser, "error", internalError, "statusCode", statusCode, "path", req.URL.Path, "requestID", requestID(req))
 	http.Error(w, errorForUser, statusCode)
 }
 
+// errVersionMismatch means that a record was changed by someone else since the
+// client last read it, so the client's conditional edit can't go ahead.
+var errVersionMismatch = errors.New("the record has been modified since it was read")
+
+// versionETag turns a record's VERSION into an ETag value.
+func versionETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersions holds the record versions from a request's If-Match header.
+// A nil ifMatchVersions means the request has no precondition.
+type ifMatchVersions []int32
+
+func (m ifMatchVersions) allows(version int32) bool {
+	return m == nil || slices.Contains(m, version)
+}
+
+// parseIfMatch reads the If-Match header, which should hold ETags from versionETag.
+// Anything that isn't one of those ETags can never match.
+func parseIfMatch(req *http.Request) ifMatchVersions {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	versions := ifMatchVersions{}
+	for _, etag := range strings.Split(header, ",") {
+		unquoted, ok := strings.CutPrefix(strings.TrimSpace(etag), `"`)
+		unquoted, ok2 := strings.CutSuffix(unquoted, `"`)
+		if !ok || !ok2 {
+			continue
+		}
+		if version, err := strconv.ParseInt(unquoted, 10, 32); err == nil {
+			versions = append(versions, int32(version))
+		}
+	}
+	return versions
+}
+
+// writePreconditionFailed tells the client that its conditional edit was rejected,
+// and gives it the current state of the record to work from.
+func writePreconditionFailed(w http.ResponseWriter, req *http.Request, version int32, current any) {
+	slog.Info("Rejected edit to a modified record", "path", req.URL.Path, "version", version)
+	marshalled, err := json.Marshal(current)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to marshal JSON", err)
+		return
+	}
+	w.Header().Set("ETag", versionETag(version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	_, _ = w.Write(marshalled)
+}
+
 func formatInt16(i sql.NullInt16) *string {
 	if i.Valid {
 		result := strconv.FormatInt(int64(i.Int16), 10)
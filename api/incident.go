@@ -40,67 +40,55 @@ func (action GetIncidents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	generatedLTE := req.Form.Get("exclude_system_entries") != "true" // false means to exclude
-
-	reportEntries, err := imsdb.New(action.imsDB).Incidents_ReportEntries(req.Context(),
-		imsdb.Incidents_ReportEntriesParams{
-			Event:     event.ID,
-			Generated: generatedLTE,
-		})
+	query, err := parseIncidentsQuery(event.ID, req.Form)
 	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident Report Entries", err)
+		handleErr(w, req, http.StatusBadRequest, "Invalid query", err)
 		return
 	}
 
-	entriesByIncident := make(map[int32][]imsjson.ReportEntry)
-	for _, row := range reportEntries {
-		re := row.ReportEntry
-		entriesByIncident[row.IncidentNumber] = append(entriesByIncident[row.IncidentNumber], reportEntryToJSON(re))
+	incidentsRows, err := imsdb.New(action.imsDB).Incidents(req.Context(), query.params)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incidents", err)
+		return
+	}
+	if query.limit > 0 && len(incidentsRows) > int(query.limit) {
+		incidentsRows = incidentsRows[:query.limit]
+		w.Header().Set("X-IMS-Next-Cursor", query.nextCursor(incidentsRows[len(incidentsRows)-1].Incident))
 	}
 
-	incidentsRows, err := imsdb.New(action.imsDB).Incidents(req.Context(), event.ID)
+	var entryRows map[int32][]imsdb.ReportEntry
+	if query.limit == 0 && !query.filtered() {
+		// This is every incident in the event, so there's no need to list them
+		entryRows, err = eventIncidentReportEntries(req.Context(), imsdb.New(action.imsDB), event.ID, generatedLTE)
+	} else {
+		numbers := make([]int32, 0, len(incidentsRows))
+		for _, r := range incidentsRows {
+			numbers = append(numbers, r.Incident.Number)
+		}
+		entryRows, err = incidentReportEntries(req.Context(), imsdb.New(action.imsDB), event.ID, generatedLTE, numbers)
+	}
 	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incidents", err)
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident Report Entries", err)
 		return
 	}
+	entriesByIncident := make(map[int32][]imsjson.ReportEntry)
+	for incidentNumber, entries := range entryRows {
+		for _, re := range entries {
+			entriesByIncident[incidentNumber] = append(entriesByIncident[incidentNumber], reportEntryToJSON(re))
+		}
+	}
 
 	for _, r := range incidentsRows {
 		// The conversion from IncidentsRow to IncidentRow works because the Incident and Incidents
 		// query row structs currently have the same fields in the same order. If that changes in the
 		// future, this won't compile, and we may need to duplicate the readExtraIncidentRowFields
 		// function.
-		incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(imsdb.IncidentRow(r))
+		incident, err := incidentToJSON(event, imsdb.IncidentRow(r), entriesByIncident[r.Incident.Number])
 		if err != nil {
 			handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident details", err)
 			return
 		}
-		lastModified := time.Unix(int64(r.Incident.Created), 0)
-		for _, re := range entriesByIncident[r.Incident.Number] {
-			if re.Created.After(lastModified) {
-				lastModified = re.Created
-			}
-		}
-		resp = append(resp, imsjson.Incident{
-			Event:        event.Name,
-			EventID:      event.ID,
-			Number:       r.Incident.Number,
-			Created:      time.Unix(int64(r.Incident.Created), 0),
-			LastModified: lastModified,
-			State:        string(r.Incident.State),
-			Priority:     r.Incident.Priority,
-			Summary:      stringOrNil(r.Incident.Summary),
-			Location: imsjson.Location{
-				Name:         stringOrNil(r.Incident.LocationName),
-				Concentric:   stringOrNil(r.Incident.LocationConcentric),
-				RadialHour:   formatInt16(r.Incident.LocationRadialHour),
-				RadialMinute: formatInt16(r.Incident.LocationRadialMinute),
-				Description:  stringOrNil(r.Incident.LocationDescription),
-				Type:         garett,
-			},
-			IncidentTypes: &incidentTypes,
-			FieldReports:  &fieldReportNumbers,
-			RangerHandles: &rangerHandles,
-			ReportEntries: entriesByIncident[r.Incident.Number],
-		})
+		resp = append(resp, incident)
 	}
 
 	mustWriteJSON(w, resp)
@@ -128,7 +116,7 @@ func (action GetIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	storedRow, reportEntries, err := fetchIncident(ctx, action.imsDB, event.ID, int32(incidentNumber))
+	result, err := fetchIncidentJSON(ctx, action.imsDB, event, int32(incidentNumber))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			handleErr(w, req, http.StatusNotFound, "No such incident", err)
@@ -138,47 +126,57 @@ func (action GetIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(result.Version))
+	mustWriteJSON(w, result)
+}
+
+// fetchIncidentJSON gets an incident in the form that it's served to clients.
+func fetchIncidentJSON(ctx context.Context, imsDB *store.DB, event imsdb.Event, incidentNumber int32) (imsjson.Incident, error) {
+	storedRow, reportEntries, err := fetchIncident(ctx, imsDB, event.ID, incidentNumber)
+	if err != nil {
+		return imsjson.Incident{}, fmt.Errorf("[fetchIncident]: %w", err)
+	}
+
 	resultEntries := make([]imsjson.ReportEntry, 0)
 	for _, re := range reportEntries {
 		resultEntries = append(resultEntries, reportEntryToJSON(re))
 	}
 
-	incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(storedRow)
+	incident, err := incidentToJSON(event, storedRow, resultEntries)
 	if err != nil {
-		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident details", err)
-		return
+		return imsjson.Incident{}, fmt.Errorf("[incidentToJSON]: %w", err)
 	}
+	return incident, nil
+}
 
-	lastModified := time.Unix(int64(storedRow.Incident.Created), 0)
-	for _, re := range resultEntries {
-		if re.Created.After(lastModified) {
-			lastModified = re.Created
-		}
+func incidentToJSON(event imsdb.Event, row imsdb.IncidentRow, entries []imsjson.ReportEntry) (imsjson.Incident, error) {
+	incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(row)
+	if err != nil {
+		return imsjson.Incident{}, fmt.Errorf("[readExtraIncidentRowFields]: %w", err)
 	}
-	result := imsjson.Incident{
+	return imsjson.Incident{
 		Event:        event.Name,
 		EventID:      event.ID,
-		Number:       storedRow.Incident.Number,
-		Created:      time.Unix(int64(storedRow.Incident.Created), 0),
-		LastModified: lastModified,
-		State:        string(storedRow.Incident.State),
-		Priority:     storedRow.Incident.Priority,
-		Summary:      stringOrNil(storedRow.Incident.Summary),
+		Number:       row.Incident.Number,
+		Created:      time.Unix(int64(row.Incident.Created), 0),
+		LastModified: time.Unix(int64(row.Incident.LastModified), 0),
+		State:        string(row.Incident.State),
+		Priority:     row.Incident.Priority,
+		Summary:      stringOrNil(row.Incident.Summary),
 		Location: imsjson.Location{
-			Name:         stringOrNil(storedRow.Incident.LocationName),
-			Concentric:   stringOrNil(storedRow.Incident.LocationConcentric),
-			RadialHour:   formatInt16(storedRow.Incident.LocationRadialHour),
-			RadialMinute: formatInt16(storedRow.Incident.LocationRadialMinute),
-			Description:  stringOrNil(storedRow.Incident.LocationDescription),
+			Name:         stringOrNil(row.Incident.LocationName),
+			Concentric:   stringOrNil(row.Incident.LocationConcentric),
+			RadialHour:   formatInt16(row.Incident.LocationRadialHour),
+			RadialMinute: formatInt16(row.Incident.LocationRadialMinute),
+			Description:  stringOrNil(row.Incident.LocationDescription),
 			Type:         garett,
 		},
 		IncidentTypes: &incidentTypes,
 		FieldReports:  &fieldReportNumbers,
 		RangerHandles: &rangerHandles,
-		ReportEntries: resultEntries,
-	}
-
-	mustWriteJSON(w, result)
+		ReportEntries: entries,
+		Version:       row.Incident.Version,
+	}, nil
 }
 
 func fetchIncident(ctx context.Context, imsDB *store.DB, eventID, incidentNumber int32) (
@@ -207,14 +205,18 @@ func fetchIncident(ctx context.Context, imsDB *store.DB, eventID, incidentNumber
 }
 
 func addIncidentReportEntry(ctx context.Context, q *imsdb.Queries, eventID, incidentNum int32, author, text string, generated bool) error {
-	reID, err := q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{
-		Author:       author,
-		Text:         text,
-		Created:      float64(time.Now().Unix()),
-		Generated:    generated,
-		Stricken:     false,
-		AttachedFile: sql.NullString{},
+	return createIncidentReportEntry(ctx, q, eventID, incidentNum, imsdb.CreateReportEntryParams{
+		Author:    author,
+		Text:      text,
+		Generated: generated,
 	})
+}
+
+// createIncidentReportEntry adds entry to the incident. Its Created time is set to now.
+func createIncidentReportEntry(ctx context.Context, q *imsdb.Queries, eventID, incidentNum int32, entry imsdb.CreateReportEntryParams) error {
+	now := float64(time.Now().Unix())
+	entry.Created = now
+	reID, err := q.CreateReportEntry(ctx, entry)
 	if err != nil {
 		return fmt.Errorf("[CreateReportEntry]: %w", err)
 	}
@@ -226,9 +228,62 @@ func addIncidentReportEntry(ctx context.Context, q *imsdb.Queries, eventID, inci
 	if err != nil {
 		return fmt.Errorf("[AttachReportEntryToIncident]: %w", err)
 	}
+	err = q.SetIncidentLastModified(ctx, imsdb.SetIncidentLastModifiedParams{
+		LastModified: now,
+		Event:        eventID,
+		Number:       incidentNum,
+	})
+	if err != nil {
+		return fmt.Errorf("[SetIncidentLastModified]: %w", err)
+	}
 	return nil
 }
 
+// reportEntriesBatchSize is how many incidents' report entries are fetched at once,
+// which keeps the number of query parameters well under the databases' limits.
+const reportEntriesBatchSize = 1000
+
+// incidentReportEntries gets the report entries of the numbered incidents, keyed by
+// incident number. System entries are only included if generatedLTE is true.
+func incidentReportEntries(
+	ctx context.Context, q *imsdb.Queries, eventID int32, generatedLTE bool, incidentNumbers []int32,
+) (map[int32][]imsdb.ReportEntry, error) {
+	entriesByIncident := make(map[int32][]imsdb.ReportEntry)
+	for batch := range slices.Chunk(incidentNumbers, reportEntriesBatchSize) {
+		rows, err := q.IncidentsByNumber_ReportEntries(ctx, imsdb.IncidentsByNumber_ReportEntriesParams{
+			Event:           eventID,
+			Generated:       generatedLTE,
+			IncidentNumbers: batch,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("[IncidentsByNumber_ReportEntries]: %w", err)
+		}
+		for _, row := range rows {
+			entriesByIncident[row.IncidentNumber] = append(entriesByIncident[row.IncidentNumber], row.ReportEntry)
+		}
+	}
+	return entriesByIncident, nil
+}
+
+// eventIncidentReportEntries gets the report entries of every incident in the event,
+// keyed by incident number.
+func eventIncidentReportEntries(
+	ctx context.Context, q *imsdb.Queries, eventID int32, generatedLTE bool,
+) (map[int32][]imsdb.ReportEntry, error) {
+	rows, err := q.Incidents_ReportEntries(ctx, imsdb.Incidents_ReportEntriesParams{
+		Event:     eventID,
+		Generated: generatedLTE,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[Incidents_ReportEntries]: %w", err)
+	}
+	entriesByIncident := make(map[int32][]imsdb.ReportEntry)
+	for _, row := range rows {
+		entriesByIncident[row.IncidentNumber] = append(entriesByIncident[row.IncidentNumber], row.ReportEntry)
+	}
+	return entriesByIncident, nil
+}
+
 type NewIncident struct {
 	imsDB     *store.DB
 	es        *EventSourcerer
@@ -252,29 +307,57 @@ func (action NewIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	author := jwtCtx.Claims.RangerHandle()
 
-	// First create the incident, to lock in the incident number reservation
-	numUntyped, _ := imsdb.New(action.imsDB).MaxIncidentNumber(ctx, event.ID)
 	newIncident.EventID = event.ID
 	newIncident.Event = event.Name
-	newIncident.Number = int32(numUntyped.(int64)) + 1
 
-	createTheIncident := imsdb.CreateIncidentParams{
-		Event:    newIncident.EventID,
-		Number:   newIncident.Number,
-		Created:  float64(time.Now().Unix()),
-		Priority: imsjson.IncidentPriorityNormal,
-		State:    imsdb.IncidentStateNew,
+	// Create the incident and fill it in from the request all at once, so that
+	// clients never see a half-made incident
+	txn, err := action.imsDB.Begin()
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
 	}
-	_, err := imsdb.New(action.imsDB).CreateIncident(ctx, createTheIncident)
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+
+	newIncident.Number, err = allocateIncidentNumber(ctx, dbTxn, event.ID)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to find next incident number", err)
+		return
+	}
+	now := float64(time.Now().Unix())
+	_, err = dbTxn.CreateIncident(ctx, imsdb.CreateIncidentParams{
+		Event:        newIncident.EventID,
+		Number:       newIncident.Number,
+		Created:      now,
+		Priority:     imsjson.IncidentPriorityNormal,
+		State:        imsdb.IncidentStateNew,
+		LastModified: now,
+	})
 	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to create incident", err)
 		return
 	}
-
-	if err = updateIncident(ctx, action.imsDB, action.es, newIncident, author); err != nil {
+	updatedFieldReports, movedFromIncidents, change, err := applyIncidentUpdate(ctx, dbTxn, newIncident, author, nil)
+	if errors.Is(err, errNoSuchFieldReport) {
+		handleErr(w, req, http.StatusBadRequest, "No such Field Report", err)
+		return
+	}
+	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to update incident", err)
 		return
 	}
+	// A new incident has no before, even though it was briefly a blank incident
+	change.action, change.before = "create", nil
+	if err = newAuditor(req).record(ctx, dbTxn, change); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to record incident in audit log", err)
+		return
+	}
+	if err = txn.Commit(); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+	notifyIncidentUpdate(action.es, newIncident, updatedFieldReports, movedFromIncidents)
 
 	w.Header().Set("X-IMS-Incident-Number", fmt.Sprint(newIncident.Number))
 	w.Header().Set("Location", "/ims/api/events/"+event.Name+"/incidents/"+fmt.Sprint(newIncident.Number))
@@ -310,21 +393,27 @@ func readExtraIncidentRowFields(row imsdb.IncidentRow) (incidentTypes, rangerHan
 	return incidentTypes, rangerHandles, fieldReportNumbers, nil
 }
 
-func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, newIncident imsjson.Incident, author string) error {
-	storedIncidentRow, err := imsdb.New(imsDB).Incident(ctx, imsdb.IncidentParams{
-		Event:  newIncident.EventID,
-		Number: newIncident.Number,
-	})
-	if err != nil {
-		return fmt.Errorf("[Incident]: %w", err)
+// allocateIncidentNumber hands out the next incident number for an event. The
+// number is held by dbTxn's transaction, so concurrent callers wait their turn
+// rather than getting the same number.
+func allocateIncidentNumber(ctx context.Context, dbTxn *imsdb.Queries, eventID int32) (int32, error) {
+	if err := dbTxn.CreateEventSequenceOrIgnore(ctx, eventID); err != nil {
+		return 0, fmt.Errorf("[CreateEventSequenceOrIgnore]: %w", err)
 	}
-	storedIncident := storedIncidentRow.Incident
-
-	incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(storedIncidentRow)
+	if err := dbTxn.IncrementIncidentNumber(ctx, eventID); err != nil {
+		return 0, fmt.Errorf("[IncrementIncidentNumber]: %w", err)
+	}
+	number, err := dbTxn.LastIncidentNumber(ctx, eventID)
 	if err != nil {
-		return fmt.Errorf("[readExtraIncidentRowFields]: %w", err)
+		return 0, fmt.Errorf("[LastIncidentNumber]: %w", err)
 	}
+	return number, nil
+}
 
+// updateIncident applies the non-nil fields of newIncident to the stored incident.
+// It returns errVersionMismatch if ifMatch doesn't allow the stored incident's
+// version, or if the incident was modified concurrently.
+func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, newIncident imsjson.Incident, audit auditor, ifMatch ifMatchVersions) error {
 	txn, err := imsDB.Begin()
 	if err != nil {
 		return fmt.Errorf("[Begin]: %w", err)
@@ -332,6 +421,56 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 	defer txn.Rollback()
 	dbTxn := imsdb.New(txn)
 
+	updatedFieldReports, movedFromIncidents, change, err := applyIncidentUpdate(ctx, dbTxn, newIncident, audit.actor, ifMatch)
+	if err != nil {
+		return fmt.Errorf("[applyIncidentUpdate]: %w", err)
+	}
+	if change.after != nil {
+		if err = audit.record(ctx, dbTxn, change); err != nil {
+			return fmt.Errorf("[record]: %w", err)
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("[Commit]: %w", err)
+	}
+	notifyIncidentUpdate(es, newIncident, updatedFieldReports, movedFromIncidents)
+	return nil
+}
+
+func notifyIncidentUpdate(es *EventSourcerer, incident imsjson.Incident, updatedFieldReports, movedFromIncidents []int32) {
+	es.notifyIncidentUpdate(incident.Event, incident.Number)
+	for _, fr := range updatedFieldReports {
+		es.notifyFieldReportUpdate(incident.Event, fr)
+	}
+	for _, other := range movedFromIncidents {
+		es.notifyIncidentUpdate(incident.Event, other)
+	}
+}
+
+// applyIncidentUpdate does the work of updateIncident within dbTxn's transaction.
+// It returns the numbers of any field reports that were attached or detached,
+// the other incidents that any of them were taken from, and the change for the
+// audit log.
+func applyIncidentUpdate(ctx context.Context, dbTxn *imsdb.Queries, newIncident imsjson.Incident, author string, ifMatch ifMatchVersions) (
+	updatedFieldReports, movedFromIncidents []int32, change auditChange, err error,
+) {
+	storedIncidentRow, err := dbTxn.Incident(ctx, imsdb.IncidentParams{
+		Event:  newIncident.EventID,
+		Number: newIncident.Number,
+	})
+	if err != nil {
+		return nil, nil, auditChange{}, fmt.Errorf("[Incident]: %w", err)
+	}
+	storedIncident := storedIncidentRow.Incident
+	if !ifMatch.allows(storedIncident.Version) {
+		return nil, nil, auditChange{}, errVersionMismatch
+	}
+
+	incidentTypes, rangerHandles, fieldReportNumbers, err := readExtraIncidentRowFields(storedIncidentRow)
+	if err != nil {
+		return nil, nil, auditChange{}, fmt.Errorf("[readExtraIncidentRowFields]: %w", err)
+	}
+
 	update := imsdb.UpdateIncidentParams{
 		Event:                storedIncident.Event,
 		Number:               storedIncident.Number,
@@ -344,50 +483,59 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 		LocationRadialHour:   storedIncident.LocationRadialHour,
 		LocationRadialMinute: storedIncident.LocationRadialMinute,
 		LocationDescription:  storedIncident.LocationDescription,
+		LastModified:         float64(time.Now().Unix()),
+		Version:              storedIncident.Version,
 	}
 
 	var logs []string
+	before, after := make(map[string]any), make(map[string]any)
 
 	if newIncident.Priority != 0 {
 		update.Priority = newIncident.Priority
 		logs = append(logs, fmt.Sprintf("Changed priority: %v", update.Priority))
+		before["priority"], after["priority"] = storedIncident.Priority, update.Priority
 	}
 	if imsdb.IncidentState(newIncident.State).Valid() {
 		update.State = imsdb.IncidentState(newIncident.State)
-		logs = append(logs, fmt.Sprintf("Changed state: %v", update.State))
+		logs = append(logs, changedStatePrefix+string(update.State))
+		before["state"], after["state"] = storedIncident.State, update.State
 	}
 	if newIncident.Summary != nil {
 		update.Summary = sqlNullString(newIncident.Summary)
 		logs = append(logs, fmt.Sprintf("Changed summary: %v", update.Summary.String))
+		before["summary"], after["summary"] = stringOrNil(storedIncident.Summary), stringOrNil(update.Summary)
 	}
 	if newIncident.Location.Name != nil {
 		update.LocationName = sqlNullString(newIncident.Location.Name)
 		logs = append(logs, fmt.Sprintf("Changed location name: %v", update.LocationName.String))
+		before["location_name"], after["location_name"] = stringOrNil(storedIncident.LocationName), stringOrNil(update.LocationName)
 	}
 	if newIncident.Location.Concentric != nil {
 		update.LocationConcentric = sqlNullString(newIncident.Location.Concentric)
 		logs = append(logs, fmt.Sprintf("Changed location concentric: %v", update.LocationConcentric.String))
+		before["location_concentric"], after["location_concentric"] = stringOrNil(storedIncident.LocationConcentric), stringOrNil(update.LocationConcentric)
 	}
 	if newIncident.Location.RadialHour != nil {
 		update.LocationRadialHour = parseInt16(newIncident.Location.RadialHour)
 		logs = append(logs, fmt.Sprintf("Changed location radial hour: %v", update.LocationRadialHour.Int16))
+		before["location_radial_hour"], after["location_radial_hour"] = formatInt16(storedIncident.LocationRadialHour), formatInt16(update.LocationRadialHour)
 	}
 	if newIncident.Location.RadialMinute != nil {
 		update.LocationRadialMinute = parseInt16(newIncident.Location.RadialMinute)
 		logs = append(logs, fmt.Sprintf("Changed location radial minute: %v", update.LocationRadialMinute.Int16))
+		before["location_radial_minute"], after["location_radial_minute"] = formatInt16(storedIncident.LocationRadialMinute), formatInt16(update.LocationRadialMinute)
 	}
 	if newIncident.Location.Description != nil {
 		update.LocationDescription = sqlNullString(newIncident.Location.Description)
 		logs = append(logs, fmt.Sprintf("Changed location description: %v", update.LocationDescription.String))
+		before["location_description"], after["location_description"] = stringOrNil(storedIncident.LocationDescription), stringOrNil(update.LocationDescription)
 	}
-	err = dbTxn.UpdateIncident(ctx, update)
-	if err != nil {
-		return fmt.Errorf("[UpdateIncident]: %w", err)
-	}
-
 	if newIncident.RangerHandles != nil {
 		add := sliceSubtract(*newIncident.RangerHandles, rangerHandles)
 		sub := sliceSubtract(rangerHandles, *newIncident.RangerHandles)
+		if len(add) > 0 || len(sub) > 0 {
+			before["ranger_handles"], after["ranger_handles"] = rangerHandles, *newIncident.RangerHandles
+		}
 		if len(add) > 0 {
 			logs = append(logs, fmt.Sprintf("Added Ranger: %v", strings.Join(add, ", ")))
 			for _, rh := range add {
@@ -397,7 +545,7 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 					RangerHandle:   rh,
 				})
 				if err != nil {
-					return fmt.Errorf("[AttachRangerHandleToIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[AttachRangerHandleToIncident]: %w", err)
 				}
 			}
 		}
@@ -410,7 +558,7 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 					RangerHandle:   rh,
 				})
 				if err != nil {
-					return fmt.Errorf("[DetachRangerHandleFromIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[DetachRangerHandleFromIncident]: %w", err)
 				}
 			}
 		}
@@ -419,6 +567,9 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 	if newIncident.IncidentTypes != nil {
 		add := sliceSubtract(*newIncident.IncidentTypes, incidentTypes)
 		sub := sliceSubtract(incidentTypes, *newIncident.IncidentTypes)
+		if len(add) > 0 || len(sub) > 0 {
+			before["incident_types"], after["incident_types"] = incidentTypes, *newIncident.IncidentTypes
+		}
 		if len(add) > 0 {
 			logs = append(logs, fmt.Sprintf("Added type: %v", strings.Join(add, ", ")))
 			for _, itype := range add {
@@ -428,7 +579,7 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 					Name:           itype,
 				})
 				if err != nil {
-					return fmt.Errorf("[AttachIncidentTypeToIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[AttachIncidentTypeToIncident]: %w", err)
 				}
 			}
 		}
@@ -441,73 +592,143 @@ func updateIncident(ctx context.Context, imsDB *store.DB, es *EventSourcerer, ne
 					Name:           rh,
 				})
 				if err != nil {
-					return fmt.Errorf("[DetachIncidentTypeFromIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[DetachIncidentTypeFromIncident]: %w", err)
 				}
 			}
 		}
 	}
-	var updatedFieldReports []int32
 	if newIncident.FieldReports != nil {
 		add := sliceSubtract(*newIncident.FieldReports, fieldReportNumbers)
 		sub := sliceSubtract(fieldReportNumbers, *newIncident.FieldReports)
+		if len(add) > 0 || len(sub) > 0 {
+			before["field_reports"], after["field_reports"] = fieldReportNumbers, *newIncident.FieldReports
+		}
 		updatedFieldReports = append(updatedFieldReports, add...)
 		updatedFieldReports = append(updatedFieldReports, sub...)
 
 		if len(add) > 0 {
 			logs = append(logs, fmt.Sprintf("Field Report added: %v", add))
 			for _, frNum := range add {
-				err = dbTxn.AttachFieldReportToIncident(ctx, imsdb.AttachFieldReportToIncidentParams{
-					Event:          newIncident.EventID,
-					Number:         frNum,
-					IncidentNumber: sql.NullInt32{Int32: newIncident.Number, Valid: true},
+				previousIncident, err := moveFieldReport(ctx, dbTxn, newIncident.EventID, frNum,
+					sql.NullInt32{Int32: newIncident.Number, Valid: true}, update.LastModified)
+				if err != nil {
+					return nil, nil, auditChange{}, fmt.Errorf("[moveFieldReport]: %w", err)
+				}
+				if !previousIncident.Valid || previousIncident.Int32 == newIncident.Number {
+					continue
+				}
+				// The incident the field report was taken from has changed too, so
+				// anyone still editing it from an older version must reload first
+				err = dbTxn.TouchIncident(ctx, imsdb.TouchIncidentParams{
+					LastModified: update.LastModified,
+					Event:        newIncident.EventID,
+					Number:       previousIncident.Int32,
 				})
 				if err != nil {
-					return fmt.Errorf("[AttachIncidentTypeToIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[TouchIncident]: %w", err)
+				}
+				// This incident's own history is recorded below, along with the rest
+				// of the update
+				err = recordFieldReportListChange(ctx, dbTxn, newIncident.EventID, previousIncident.Int32, frNum, true, author, update.LastModified)
+				if err != nil {
+					return nil, nil, auditChange{}, fmt.Errorf("[recordFieldReportListChange]: %w", err)
 				}
+				movedFromIncidents = append(movedFromIncidents, previousIncident.Int32)
 			}
 		}
 		if len(sub) > 0 {
 			logs = append(logs, fmt.Sprintf("Field Report removed: %v", sub))
 			for _, frNum := range sub {
-				err = dbTxn.AttachFieldReportToIncident(ctx, imsdb.AttachFieldReportToIncidentParams{
-					Event:          newIncident.EventID,
-					Number:         frNum,
-					IncidentNumber: sql.NullInt32{},
-				})
+				_, err = moveFieldReport(ctx, dbTxn, newIncident.EventID, frNum, sql.NullInt32{}, update.LastModified)
 				if err != nil {
-					return fmt.Errorf("[AttachFieldReportToIncident]: %w", err)
+					return nil, nil, auditChange{}, fmt.Errorf("[moveFieldReport]: %w", err)
 				}
 			}
 		}
 	}
 
 	if len(logs) > 0 {
+		// This bumps the incident's version, but only if no one else has already done so
+		updated, err := dbTxn.UpdateIncident(ctx, update)
+		if err != nil {
+			return nil, nil, auditChange{}, fmt.Errorf("[UpdateIncident]: %w", err)
+		}
+		if updated == 0 {
+			return nil, nil, auditChange{}, errVersionMismatch
+		}
+		err = recordIncidentChanges(ctx, dbTxn, newIncident.EventID, newIncident.Number, author, update.LastModified, before, after)
+		if err != nil {
+			return nil, nil, auditChange{}, fmt.Errorf("[recordIncidentChanges]: %w", err)
+		}
 		err = addIncidentReportEntry(ctx, dbTxn, newIncident.EventID, newIncident.Number, author, strings.Join(logs, "\n"), true)
 		if err != nil {
-			return fmt.Errorf("[addIncidentReportEntry]: %w", err)
+			return nil, nil, auditChange{}, fmt.Errorf("[addIncidentReportEntry]: %w", err)
 		}
 	}
 
+	var addedEntries []string
 	for _, entry := range newIncident.ReportEntries {
 		if entry.Text == "" {
 			continue
 		}
 		err = addIncidentReportEntry(ctx, dbTxn, newIncident.EventID, newIncident.Number, author, entry.Text, false)
 		if err != nil {
-			return fmt.Errorf("[addIncidentReportEntry]: %w", err)
+			return nil, nil, auditChange{}, fmt.Errorf("[addIncidentReportEntry]: %w", err)
 		}
+		addedEntries = append(addedEntries, entry.Text)
 	}
-
-	if err = txn.Commit(); err != nil {
-		return fmt.Errorf("[Commit]: %w", err)
+	if len(addedEntries) > 0 {
+		after["report_entries"] = addedEntries
 	}
 
-	es.notifyIncidentUpdate(newIncident.Event, newIncident.Number)
-	for _, fr := range updatedFieldReports {
-		es.notifyFieldReportUpdate(newIncident.Event, fr)
+	change = auditChange{
+		action:     "update",
+		entityType: "incident",
+		eventID:    newIncident.EventID,
+		entityID:   fmt.Sprint(newIncident.Number),
+	}
+	// Leave before and after nil if nothing changed
+	if len(after) > 0 {
+		change.before, change.after = before, after
 	}
+	return updatedFieldReports, movedFromIncidents, change, nil
+}
 
-	return nil
+// errNoSuchFieldReport means that an incident update named a field report
+// that doesn't exist.
+var errNoSuchFieldReport = errors.New("no such field report")
+
+// moveFieldReport attaches a field report to an incident, or detaches it if
+// incident is null. It returns the incident that the field report was on before.
+// The move is conditioned on the field report's version, so it returns
+// errVersionMismatch if the field report is changed by someone else meanwhile.
+func moveFieldReport(
+	ctx context.Context, dbTxn *imsdb.Queries, eventID, fieldReportNumber int32, incident sql.NullInt32, lastModified float64,
+) (previousIncident sql.NullInt32, err error) {
+	frRow, err := dbTxn.FieldReport(ctx, imsdb.FieldReportParams{
+		Event:  eventID,
+		Number: fieldReportNumber,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return previousIncident, fmt.Errorf("%w: %v", errNoSuchFieldReport, fieldReportNumber)
+	}
+	if err != nil {
+		return previousIncident, fmt.Errorf("[FieldReport]: %w", err)
+	}
+	updated, err := dbTxn.AttachFieldReportToIncident(ctx, imsdb.AttachFieldReportToIncidentParams{
+		IncidentNumber:  incident,
+		LastModified:    lastModified,
+		Event:           eventID,
+		Number:          fieldReportNumber,
+		ExpectedVersion: sql.NullInt32{Int32: frRow.FieldReport.Version, Valid: true},
+	})
+	if err != nil {
+		return previousIncident, fmt.Errorf("[AttachFieldReportToIncident]: %w", err)
+	}
+	if updated == 0 {
+		return previousIncident, errVersionMismatch
+	}
+	return frRow.FieldReport.IncidentNumber, nil
 }
 
 func sliceSubtract[T comparable](a, b []T) []T {
@@ -527,7 +748,7 @@ type EditIncident struct {
 }
 
 func (action EditIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	event, _, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
 	if !ok {
 		return
 	}
@@ -550,9 +771,21 @@ func (action EditIncident) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	newIncident.EventID = event.ID
 	newIncident.Number = int32(incidentNumber)
 
-	author := jwtCtx.Claims.RangerHandle()
-
-	if err = updateIncident(ctx, action.imsDB, action.es, newIncident, author); err != nil {
+	err = updateIncident(ctx, action.imsDB, action.es, newIncident, newAuditor(req), parseIfMatch(req))
+	if errors.Is(err, errNoSuchFieldReport) {
+		handleErr(w, req, http.StatusBadRequest, "No such Field Report", err)
+		return
+	}
+	if errors.Is(err, errVersionMismatch) {
+		current, err := fetchIncidentJSON(ctx, action.imsDB, event, newIncident.Number)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+			return
+		}
+		writePreconditionFailed(w, req, current.Version, current)
+		return
+	}
+	if err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Failed to update Incident", err)
 		return
 	}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/auth"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type GetIncidentHistory struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP returns the changes made to an incident's fields, oldest first.
+// Changes made before IMS kept this history only appear in the incident's
+// system report entries.
+func (action GetIncidentHistory) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, _, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	ctx := req.Context()
+
+	incidentNumber, err := strconv.ParseInt(req.PathValue("incidentNumber"), 10, 32)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Failed to parse incident number", err)
+		return
+	}
+	_, err = imsdb.New(action.imsDB).Incident(ctx, imsdb.IncidentParams{
+		Event:  event.ID,
+		Number: int32(incidentNumber),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			handleErr(w, req, http.StatusNotFound, "No such incident", err)
+			return
+		}
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident", err)
+		return
+	}
+
+	changeRows, err := imsdb.New(action.imsDB).IncidentChanges(ctx, imsdb.IncidentChangesParams{
+		Event:          event.ID,
+		IncidentNumber: int32(incidentNumber),
+	})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident history", err)
+		return
+	}
+	resp := make(imsjson.IncidentHistory, 0, len(changeRows))
+	for _, cr := range changeRows {
+		resp = append(resp, imsjson.IncidentChange{
+			Field:    cr.IncidentChange.Field,
+			OldValue: json.RawMessage(cr.IncidentChange.OldValue),
+			NewValue: json.RawMessage(cr.IncidentChange.NewValue),
+			Author:   cr.IncidentChange.Author,
+			Created:  time.Unix(int64(cr.IncidentChange.Created), 0),
+		})
+	}
+	mustWriteJSON(w, resp)
+}
+
+// recordIncidentChanges adds to an incident's history each field whose value
+// differs between before and after. Both are keyed by field name.
+func recordIncidentChanges(
+	ctx context.Context, q *imsdb.Queries, eventID, incidentNumber int32, author string, created float64, before, after map[string]any,
+) error {
+	for _, field := range slices.Sorted(maps.Keys(after)) {
+		oldValue, err := json.Marshal(before[field])
+		if err != nil {
+			return fmt.Errorf("[Marshal]: %w", err)
+		}
+		newValue, err := json.Marshal(after[field])
+		if err != nil {
+			return fmt.Errorf("[Marshal]: %w", err)
+		}
+		if bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		err = q.CreateIncidentChange(ctx, imsdb.CreateIncidentChangeParams{
+			Event:          eventID,
+			IncidentNumber: incidentNumber,
+			Created:        created,
+			Author:         author,
+			Field:          field,
+			OldValue:       string(oldValue),
+			NewValue:       string(newValue),
+		})
+		if err != nil {
+			return fmt.Errorf("[CreateIncidentChange]: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordFieldReportMove adds to the histories of the incidents that a field report
+// was just detached from and attached to. Either incident may be null.
+func recordFieldReportMove(
+	ctx context.Context, q *imsdb.Queries, eventID, fieldReportNumber int32, from, to sql.NullInt32, author string, created float64,
+) error {
+	if from == to {
+		return nil
+	}
+	if from.Valid {
+		err := recordFieldReportListChange(ctx, q, eventID, from.Int32, fieldReportNumber, true, author, created)
+		if err != nil {
+			return fmt.Errorf("[recordFieldReportListChange]: %w", err)
+		}
+	}
+	if to.Valid {
+		err := recordFieldReportListChange(ctx, q, eventID, to.Int32, fieldReportNumber, false, author, created)
+		if err != nil {
+			return fmt.Errorf("[recordFieldReportListChange]: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordFieldReportListChange adds to an incident's history that a field report
+// was just taken off it, if removed, or added to it otherwise.
+func recordFieldReportListChange(
+	ctx context.Context, q *imsdb.Queries, eventID, incidentNumber, fieldReportNumber int32, removed bool, author string, created float64,
+) error {
+	incidentRow, err := q.Incident(ctx, imsdb.IncidentParams{
+		Event:  eventID,
+		Number: incidentNumber,
+	})
+	if err != nil {
+		return fmt.Errorf("[Incident]: %w", err)
+	}
+	_, _, fieldReports, err := readExtraIncidentRowFields(incidentRow)
+	if err != nil {
+		return fmt.Errorf("[readExtraIncidentRowFields]: %w", err)
+	}
+	var previousFieldReports []int32
+	if removed {
+		previousFieldReports = append(slices.Clone(fieldReports), fieldReportNumber)
+		slices.Sort(previousFieldReports)
+	} else {
+		previousFieldReports = append([]int32{}, sliceSubtract(fieldReports, []int32{fieldReportNumber})...)
+	}
+	err = recordIncidentChanges(ctx, q, eventID, incidentNumber, author, created,
+		map[string]any{"field_reports": previousFieldReports},
+		map[string]any{"field_reports": fieldReports},
+	)
+	if err != nil {
+		return fmt.Errorf("[recordIncidentChanges]: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incidentSorts are the values of the "sort" query parameter for GetIncidents.
+// Any of them may be prefixed with "-" to sort in descending order.
+var incidentSorts = []string{"number", "created", "modified", "priority"}
+
+// incidentsQuery is the parsed form of GetIncidents' query parameters.
+type incidentsQuery struct {
+	params imsdb.IncidentsParams
+	sort   string
+	// limit is the page size, or 0 if the results shouldn't be paginated
+	limit int32
+}
+
+// parseIncidentsQuery turns GetIncidents' query parameters into the arguments
+// for the Incidents query. Everything is optional:
+//
+//   - state: comma-separated incident states, any of which may match
+//   - priority: an incident priority
+//   - incident_type: the name of an incident type the incident must have
+//   - ranger_handle: the handle of a Ranger who must be attached to the incident
+//   - created_after, created_before: RFC 3339 times
+//   - modified_since, modified_before: RFC 3339 times. A client can keep its list
+//     of incidents up to date by passing the latest last_modified it's seen as
+//     modified_since.
+//   - text: text to find in the summary, location, or an unstricken report entry
+//   - sort: one of incidentSorts, optionally prefixed with "-" (default "number")
+//   - limit: the maximum number of incidents to return
+//   - cursor: the X-IMS-Next-Cursor value from the previous page's response
+func parseIncidentsQuery(eventID int32, form url.Values) (incidentsQuery, error) {
+	q := incidentsQuery{
+		params: imsdb.IncidentsParams{
+			Event: eventID,
+			Limit: math.MaxInt32,
+		},
+		sort: "number",
+	}
+	p := &q.params
+
+	if states := strings.Join(form["state"], ","); states != "" {
+		for _, state := range strings.Split(states, ",") {
+			if !imsdb.IncidentState(state).Valid() {
+				return q, fmt.Errorf("invalid state %q", state)
+			}
+		}
+		p.States = sql.NullString{String: states, Valid: true}
+	}
+	if priority := form.Get("priority"); priority != "" {
+		num, err := strconv.ParseInt(priority, 10, 16)
+		if err != nil {
+			return q, fmt.Errorf("invalid priority %q", priority)
+		}
+		p.Priority = sql.NullInt16{Int16: int16(num), Valid: true}
+	}
+	if incidentType := form.Get("incident_type"); incidentType != "" {
+		p.IncidentType = sql.NullString{String: incidentType, Valid: true}
+	}
+	if rangerHandle := form.Get("ranger_handle"); rangerHandle != "" {
+		p.RangerHandle = sql.NullString{String: rangerHandle, Valid: true}
+	}
+	for param, dest := range map[string]*sql.NullFloat64{
+		"created_after":   &p.CreatedAfter,
+		"created_before":  &p.CreatedBefore,
+		"modified_since":  &p.ModifiedSince,
+		"modified_before": &p.ModifiedBefore,
+	} {
+		t, err := parseTimeParam(form, param)
+		if err != nil {
+			return q, fmt.Errorf("[parseTimeParam]: %w", err)
+		}
+		*dest = t
+	}
+	if text := form.Get("text"); text != "" {
+		p.Text = sql.NullString{String: "%" + escapeLike(text) + "%", Valid: true}
+	}
+
+	if sort := form.Get("sort"); sort != "" {
+		q.sort = sort
+	}
+	direction := int32(1)
+	column, descending := strings.CutPrefix(q.sort, "-")
+	if descending {
+		direction = -1
+	}
+	p.NumberDirection = direction
+	switch column {
+	case "number":
+		p.NumberWeight = direction
+	case "created":
+		p.CreatedWeight = float64(direction)
+	case "modified":
+		p.ModifiedWeight = float64(direction)
+	case "priority":
+		p.PriorityWeight = int8(direction)
+	default:
+		return q, fmt.Errorf("invalid sort %q, must be one of %v", q.sort, incidentSorts)
+	}
+
+	if limit := form.Get("limit"); limit != "" {
+		num, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil || num <= 0 || num >= math.MaxInt32 {
+			return q, fmt.Errorf("invalid limit %q", limit)
+		}
+		q.limit = int32(num)
+		// Get one more than was asked for, to find out if there's another page
+		p.Limit = q.limit + 1
+	}
+	if cursor := form.Get("cursor"); cursor != "" {
+		sort, sortKey, number, err := decodeIncidentsCursor(cursor)
+		if err != nil {
+			return q, fmt.Errorf("[decodeIncidentsCursor]: %w", err)
+		}
+		if sort != q.sort {
+			return q, fmt.Errorf("cursor is for sort %q, not %q", sort, q.sort)
+		}
+		p.AfterSortKey = sql.NullFloat64{Float64: sortKey, Valid: true}
+		p.AfterNumber = sql.NullInt32{Int32: number * direction, Valid: true}
+	}
+	return q, nil
+}
+
+// filtered is whether the query leaves out any of the event's incidents.
+func (q incidentsQuery) filtered() bool {
+	p := q.params
+	return p.States != nil || p.Priority.Valid || p.IncidentType.Valid || p.RangerHandle.Valid ||
+		p.CreatedAfter.Valid || p.CreatedBefore.Valid || p.ModifiedSince.Valid || p.ModifiedBefore.Valid ||
+		p.Text.Valid || p.AfterSortKey.Valid
+}
+
+// sortKey gives the value that the Incidents query sorts the incident on.
+func (q incidentsQuery) sortKey(incident imsdb.Incident) float64 {
+	return incident.Created*q.params.CreatedWeight +
+		incident.LastModified*q.params.ModifiedWeight +
+		float64(incident.Priority)*float64(q.params.PriorityWeight) +
+		float64(incident.Number)*float64(q.params.NumberWeight)
+}
+
+// nextCursor gives the cursor for the page that comes after lastIncident.
+func (q incidentsQuery) nextCursor(lastIncident imsdb.Incident) string {
+	raw := strings.Join([]string{
+		q.sort,
+		strconv.FormatFloat(q.sortKey(lastIncident), 'g', -1, 64),
+		strconv.FormatInt(int64(lastIncident.Number), 10),
+	}, " ")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeIncidentsCursor(cursor string) (sort string, sortKey float64, number int32, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("[DecodeString]: %w", err)
+	}
+	parts := strings.Split(string(raw), " ")
+	if len(parts) != 3 {
+		return "", 0, 0, errors.New("malformed cursor")
+	}
+	sortKey, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("[ParseFloat]: %w", err)
+	}
+	num, err := strconv.ParseInt(parts[2], 10, 32)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("[ParseInt]: %w", err)
+	}
+	return parts[0], sortKey, int32(num), nil
+}
+
+// parseTimeParam reads an RFC 3339 time from a query parameter, in the form that
+// the database stores times. It's null if the parameter isn't set.
+func parseTimeParam(form url.Values, param string) (sql.NullFloat64, error) {
+	val := form.Get(param)
+	if val == "" {
+		return sql.NullFloat64{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return sql.NullFloat64{}, fmt.Errorf("invalid %v %q", param, val)
+	}
+	return sql.NullFloat64{Float64: float64(t.UnixNano()) / float64(time.Second), Valid: true}, nil
+}
+
+// escapeLike escapes s for use in a "like ... escape '!'" pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// pngFile is enough of a PNG for its type to be detected
+var pngFile = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+func TestAttachments(t *testing.T) {
+	// Use a small size limit, so that it's easy to exceed
+	cfg := *shared.cfg
+	cfg.AttachmentsStore.MaxSize = 64
+	s := httptest.NewServer(api.AddToMux(nil, &cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "AttachmentEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.editAccess(imsjson.EventsAccess{
+		eventName: imsjson.EventAccess{
+			Writers:   []imsjson.AccessRule{{Expression: "person:" + userAdminHandle, Validity: "always"}},
+			Reporters: []imsjson.AccessRule{{Expression: "person:" + userAliceHandle, Validity: "always"}},
+		},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	access, resp := apisNonAdmin.getAuth(eventName)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, access.EventAccess[eventName].AttachFiles)
+
+	// Attach a file to an incident, which records it in a system entry
+	incident := apisAdmin.newIncidentSuccess(imsjson.Incident{Event: eventName, State: "new", Priority: imsjson.IncidentPriorityNormal})
+	incidentAttachments := serverURL.JoinPath("/ims/api/events/", eventName, "/incidents/", fmt.Sprint(incident), "/attachments").String()
+	resp = apisAdmin.attachFiles(incidentAttachments, map[string][]byte{"photo.png": pngFile})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	retrieved, resp := apisAdmin.getIncident(eventName, incident)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var attached *imsjson.ReportEntry
+	for _, re := range retrieved.ReportEntries {
+		if re.HasAttachment {
+			attached = &re
+		}
+	}
+	require.NotNil(t, attached)
+	require.True(t, attached.SystemEntry)
+	require.Equal(t, userAdminHandle, attached.Author)
+	require.Equal(t, `Attached file "photo.png" (image/png, 40 bytes)`, attached.Text)
+
+	data, resp := apisAdmin.getAttachment(incidentAttachments + fmt.Sprint("/", attached.ID))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, `attachment; filename=photo.png`, resp.Header.Get("Content-Disposition"))
+	require.Equal(t, pngFile, data)
+
+	// Only some files are allowed
+	resp = apisAdmin.attachFiles(incidentAttachments, map[string][]byte{"page.html": []byte("<html><script></script></html>")})
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp = apisAdmin.attachFiles(incidentAttachments, map[string][]byte{"big.png": append(pngFile, make([]byte, 64)...)})
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	resp = apisAdmin.attachFiles(incidentAttachments, nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// A reporter can't see or change incidents
+	_, resp = apisNonAdmin.getAttachment(incidentAttachments + fmt.Sprint("/", attached.ID))
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = apisNonAdmin.attachFiles(incidentAttachments, map[string][]byte{"photo.png": pngFile})
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// A reporter can attach files to their own field reports, but not others'
+	aliceFR := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("Alice's report")})
+	adminFR := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("Admin's report")})
+	aliceAttachments := serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports/", fmt.Sprint(aliceFR), "/attachments").String()
+	adminAttachments := serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports/", fmt.Sprint(adminFR), "/attachments").String()
+	resp = apisNonAdmin.attachFiles(aliceAttachments, map[string][]byte{"notes.txt": []byte("plain old text")})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisNonAdmin.attachFiles(adminAttachments, map[string][]byte{"notes.txt": []byte("plain old text")})
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	fieldReports, resp := apisNonAdmin.getFieldReports(eventName, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, fieldReports, 1)
+	var attachmentID int32
+	for _, re := range fieldReports[0].ReportEntries {
+		if re.HasAttachment {
+			attachmentID = re.ID
+		}
+	}
+	require.NotZero(t, attachmentID)
+	data, resp = apisNonAdmin.getAttachment(aliceAttachments + fmt.Sprint("/", attachmentID))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.Equal(t, "plain old text", string(data))
+	_, resp = apisAdmin.getAttachment(aliceAttachments + fmt.Sprint("/", attachmentID))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Attachments are only found through the report they're on
+	_, resp = apisAdmin.getAttachment(adminAttachments + fmt.Sprint("/", attachmentID))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "AuditEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+
+	records, resp := apisAdmin.getAudit(url.Values{"entity_type": {"event"}, "entity_id": {eventName}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, records, 1)
+	require.Equal(t, "create", records[0].Action)
+	require.Equal(t, userAdminHandle, records[0].Actor)
+	require.Equal(t, eventName, records[0].Event)
+	require.JSONEq(t, "null", string(records[0].Before))
+	require.JSONEq(t, fmt.Sprintf(`{"name": %q}`, eventName), string(records[0].After))
+
+	// The client's request ID is kept, so that the change can be traced back to it
+	resp = apisAdmin.imsPostWithHeader(
+		imsjson.EventsAccess{eventName: imsjson.EventAccess{
+			Writers: []imsjson.AccessRule{{Expression: "person:" + userAdminHandle, Validity: "always"}},
+		}},
+		serverURL.JoinPath("/ims/api/access").String(),
+		http.Header{"X-Request-Id": {"grant-writers-1"}},
+	)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "grant-writers-1", resp.Header.Get("X-Request-ID"))
+
+	records, resp = apisAdmin.getAudit(url.Values{"request_id": {"grant-writers-1"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, records, 1)
+	require.Equal(t, "event_access", records[0].EntityType)
+	require.Equal(t, eventName, records[0].EntityID)
+	var before, after imsjson.EventAccess
+	require.NoError(t, json.Unmarshal(records[0].Before, &before))
+	require.NoError(t, json.Unmarshal(records[0].After, &after))
+	require.Empty(t, before.Writers)
+	require.Equal(t, []imsjson.AccessRule{{Expression: "person:" + userAdminHandle, Validity: "always"}}, after.Writers)
+
+	// Incident changes record just the fields that changed
+	incidentNumber := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("Lost camera"),
+	})
+	resp = apisAdmin.updateIncident(eventName, incidentNumber, imsjson.Incident{
+		State:         "on_scene",
+		RangerHandles: &[]string{"Tool"},
+		ReportEntries: []imsjson.ReportEntry{{Text: "Found it"}},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	records, resp = apisAdmin.getAudit(url.Values{
+		"event":       {eventName},
+		"entity_type": {"incident"},
+		"entity_id":   {fmt.Sprint(incidentNumber)},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, records, 2)
+	require.Equal(t, "update", records[0].Action)
+	require.JSONEq(t, `{"state": "new", "ranger_handles": []}`, string(records[0].Before))
+	require.JSONEq(t, `{"state": "on_scene", "ranger_handles": ["Tool"], "report_entries": ["Found it"]}`, string(records[0].After))
+	require.Equal(t, "create", records[1].Action)
+	require.JSONEq(t, "null", string(records[1].Before))
+	require.Greater(t, records[0].ID, records[1].ID)
+
+	// Paging goes on from the last record seen
+	records, resp = apisAdmin.getAudit(url.Values{"event": {eventName}, "limit": {"1"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, records, 1)
+	olderRecords, resp := apisAdmin.getAudit(url.Values{"event": {eventName}, "before_id": {fmt.Sprint(records[0].ID)}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, olderRecords)
+	require.Less(t, olderRecords[0].ID, records[0].ID)
+
+	// Only real changes to incident types are recorded
+	typeName := "Audit Type"
+	resp = apisAdmin.editTypes(imsjson.EditIncidentTypesRequest{Add: []string{typeName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.editTypes(imsjson.EditIncidentTypesRequest{Add: []string{typeName}, Hide: []string{typeName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.editTypes(imsjson.EditIncidentTypesRequest{Hide: []string{typeName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	records, resp = apisAdmin.getAudit(url.Values{"entity_type": {"incident_type"}, "entity_id": {typeName}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, records, 2)
+	require.JSONEq(t, `{"hidden": false}`, string(records[0].Before))
+	require.JSONEq(t, `{"hidden": true}`, string(records[0].After))
+	require.Equal(t, "create", records[1].Action)
+
+	// Bad parameters
+	_, resp = apisAdmin.getAudit(url.Values{"since": {"yesterday"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp = apisAdmin.getAudit(url.Values{"limit": {"0"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Only admins may read the audit log
+	_, resp = apisNonAdmin.getAudit(nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -1,13 +1,22 @@
 package integration
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/srabraham/ranger-ims-go/api"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestPostAuthAPIAuthorization(t *testing.T) {
@@ -53,6 +62,209 @@ func TestPostAuthAPIAuthorization(t *testing.T) {
 	require.Empty(t, token)
 }
 
+func TestPostAuthThrottle(t *testing.T) {
+	cfg := *shared.cfg
+	cfg.LoginThrottle.FreeAttempts = 2
+	cfg.LoginThrottle.BaseDelay = time.Minute
+	s := httptest.NewServer(api.AddToMux(nil, &cfg, shared.imsDB, shared.userStore))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: serverURL, jwt: ""}
+
+	for range cfg.LoginThrottle.FreeAttempts {
+		statusCode, _, _ := apisNotAuthenticated.postAuth(api.PostAuthRequest{
+			Identification: userAliceHandle,
+			Password:       "not my password",
+		})
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+	}
+
+	// Now even the right password has to wait, by email too
+	resp := apisNotAuthenticated.imsPost(api.PostAuthRequest{
+		Identification: userAliceEmail,
+		Password:       userAlicePassword,
+	}, serverURL.JoinPath("/ims/api/auth").String())
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// Other Rangers can still log in
+	statusCode, _, token := apisNotAuthenticated.postAuth(api.PostAuthRequest{
+		Identification: userAdminHandle,
+		Password:       userAdminPassword,
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// Only admins can see who's blocked
+	lockouts, resp := ApiHelper{t: t, serverURL: serverURL, jwt: token}.getAuthLockouts()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, lockouts, 1)
+	require.Equal(t, "ranger", lockouts[0].Kind)
+	require.Equal(t, userAliceHandle, lockouts[0].Name)
+	require.Equal(t, int32(2), lockouts[0].Failures)
+	require.False(t, lockouts[0].LockedOut)
+	_, resp = ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}.getAuthLockouts()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestPostAuthRefresh(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, shared.userStore))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: serverURL, jwt: ""}
+
+	statusCode, _, login := apisNotAuthenticated.postAuthFull(api.PostAuthRequest{
+		Identification: userAliceHandle,
+		Password:       userAlicePassword,
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+	require.NotEmpty(t, login.RefreshToken)
+
+	// The refresh token gets a new access token that works, and a new refresh token
+	statusCode, refreshed := apisNotAuthenticated.postAuthRefresh(login.RefreshToken)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.NotEmpty(t, refreshed.Token)
+	require.NotEmpty(t, refreshed.RefreshToken)
+	require.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	authResp, resp := ApiHelper{t: t, serverURL: serverURL, jwt: refreshed.Token}.getAuth("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, authResp.Authenticated)
+	require.Equal(t, userAliceHandle, authResp.User)
+
+	// Using the first refresh token again is refused, and ends the session
+	statusCode, _ = apisNotAuthenticated.postAuthRefresh(login.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+	statusCode, _ = apisNotAuthenticated.postAuthRefresh(refreshed.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+
+	statusCode, _ = apisNotAuthenticated.postAuthRefresh("")
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+}
+
+func TestPostAuthLogout(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, shared.userStore))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: serverURL, jwt: ""}
+
+	_, _, login := apisNotAuthenticated.postAuthFull(api.PostAuthRequest{
+		Identification: userAliceHandle,
+		Password:       userAlicePassword,
+	})
+	other := jwtForRealTestUser(t)
+	apisAlice := ApiHelper{t: t, serverURL: serverURL, jwt: login.Token}
+	_, resp := apisAlice.getTypes(false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = apisAlice.postAuthLogout(login.RefreshToken)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// The token and its refresh token no longer work, but other sessions do
+	_, resp = apisAlice.getTypes(false)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	authResp, _ := apisAlice.getAuth("")
+	require.False(t, authResp.Authenticated)
+	statusCode, _ := apisNotAuthenticated.postAuthRefresh(login.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+	_, resp = ApiHelper{t: t, serverURL: serverURL, jwt: other}.getTypes(false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = apisNotAuthenticated.postAuthLogout("")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestPostAuthRevoke(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, shared.userStore))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: serverURL, jwt: ""}
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+
+	_, _, login := apisNotAuthenticated.postAuthFull(api.PostAuthRequest{
+		Identification: userAliceHandle,
+		Password:       userAlicePassword,
+	})
+	apisAlice := ApiHelper{t: t, serverURL: serverURL, jwt: login.Token}
+
+	// Only admins can revoke sessions
+	resp := apisAlice.postAuthRevoke(userAdminHandle)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = apisAdmin.postAuthRevoke(userAliceHandle)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, resp = apisAlice.getTypes(false)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	statusCode, _ := apisNotAuthenticated.postAuthRefresh(login.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, statusCode)
+	// The admin's own session is untouched
+	_, resp = apisAdmin.getTypes(false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Logging in again works
+	apisAlice.jwt = jwtForRealTestUser(t)
+	_, resp = apisAlice.getTypes(false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = apisAdmin.postAuthRevoke("")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestJWTSigningKey(t *testing.T) {
+	// With the shared secret, there are no public keys to give out
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, shared.userStore))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	jwks, resp := ApiHelper{t: t, serverURL: serverURL, jwt: ""}.getAuthJWKS()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, jwks.Keys)
+	secretSignedJWT := jwtForRealTestUser(t)
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt-signing-key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0o600))
+	cfg := *shared.cfg
+	cfg.Core.JWTSigningKey = keyFile
+
+	keyed := httptest.NewServer(api.AddToMux(nil, &cfg, shared.imsDB, shared.userStore))
+	defer keyed.Close()
+	keyedURL, err := url.Parse(keyed.URL)
+	require.NoError(t, err)
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: keyedURL, jwt: ""}
+	jwks, resp = apisNotAuthenticated.getAuthJWKS()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, jwks.Keys, 1)
+	jwk := jwks.Keys[0]
+	require.Equal(t, "OKP", jwk.Kty)
+	require.Equal(t, "EdDSA", jwk.Alg)
+
+	// Someone else can verify the token with nothing but the JWKS
+	statusCode, _, token := apisNotAuthenticated.postAuth(api.PostAuthRequest{
+		Identification: userAliceHandle,
+		Password:       userAlicePassword,
+	})
+	require.Equal(t, http.StatusOK, statusCode)
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+		require.Equal(t, jwk.Kid, token.Header["kid"])
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{jwk.Alg}))
+	require.NoError(t, err)
+	require.True(t, parsed.Valid)
+
+	_, resp = ApiHelper{t: t, serverURL: keyedURL, jwt: token}.getTypes(false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, resp = ApiHelper{t: t, serverURL: keyedURL, jwt: secretSignedJWT}.getTypes(false)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
 func TestGetAuthAPIAuthorization(t *testing.T) {
 	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, shared.userStore))
 	defer s.Close()
@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIncidentDossier(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "DossierEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+
+	incident1 := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:         eventName,
+		State:         "on_scene",
+		Priority:      imsjson.IncidentPriorityHigh,
+		Summary:       ptr("Lost <camera>"),
+		Location:      imsjson.Location{Name: ptr("Camp Lost"), RadialHour: ptr("3"), RadialMinute: ptr("5")},
+		RangerHandles: &[]string{"Tool"},
+		ReportEntries: []imsjson.ReportEntry{{Text: "Found the camera"}, {Text: "Wrong camera"}},
+	})
+	fr := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{
+		Summary:       ptr("Camera report"),
+		ReportEntries: []imsjson.ReportEntry{{Text: "Saw a camera"}},
+	})
+	resp = apisAdmin.updateIncident(eventName, incident1, imsjson.Incident{FieldReports: &[]int32{fr}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	incident, _ := apisAdmin.getIncident(eventName, incident1)
+	for _, re := range incident.ReportEntries {
+		if re.Text == "Wrong camera" {
+			resp = apisAdmin.imsPost(imsjson.ReportEntry{Stricken: true},
+				serverURL.JoinPath("/ims/api/events", eventName, "incidents", fmt.Sprint(incident1), "report_entries", fmt.Sprint(re.ID)).String())
+			require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		}
+	}
+	incident2 := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("Bike theft"),
+	})
+
+	dossierPath := serverURL.JoinPath("/ims/api/events", eventName, "incidents", fmt.Sprint(incident1), "dossier")
+	body, resp := apisAdmin.imsGetBytes(dossierPath.String())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	html := string(body)
+	require.Contains(t, html, fmt.Sprintf("IMS #%d: Lost &lt;camera&gt;", incident1))
+	require.Contains(t, html, "3:05")
+	require.Contains(t, html, "Found the camera")
+	require.NotContains(t, html, "Wrong camera")
+	require.Contains(t, html, "Camera report")
+	require.Contains(t, html, "Saw a camera")
+	require.Contains(t, html, "Generated by "+userAdminHandle)
+
+	// Stricken entries can be included, struck through
+	body, resp = apisAdmin.imsGetBytes(dossierPath.String() + "?stricken=true&exclude_system_entries=true")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	html = string(body)
+	require.Contains(t, html, "Wrong camera")
+	require.Contains(t, html, `class="entry stricken"`)
+	require.NotContains(t, html, "Changed state")
+
+	body, resp = apisAdmin.imsGetBytes(dossierPath.String() + "?format=pdf")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	require.Contains(t, resp.Header.Get("Content-Disposition"), fmt.Sprintf("DossierEvent-IMS-%d.pdf", incident1))
+	require.True(t, bytes.HasPrefix(body, []byte("%PDF-")))
+
+	// A set of incidents, filtered like the incident list
+	setPath := serverURL.JoinPath("/ims/api/events", eventName, "incidents", "dossier")
+	body, resp = apisAdmin.imsGetBytes(setPath.String())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), "Lost &lt;camera&gt;")
+	require.Contains(t, string(body), "Bike theft")
+	body, resp = apisAdmin.imsGetBytes(setPath.String() + "?priority=3")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotContains(t, string(body), "Lost &lt;camera&gt;")
+	require.Contains(t, string(body), fmt.Sprintf("IMS #%d: Bike theft", incident2))
+	body, resp = apisAdmin.imsGetBytes(setPath.String() + "?format=pdf&sort=-number")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, bytes.HasPrefix(body, []byte("%PDF-")))
+
+	_, resp = apisAdmin.imsGetBytes(setPath.String() + "?format=docx")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp = apisAdmin.imsGetBytes(serverURL.JoinPath("/ims/api/events", eventName, "incidents", "9999", "dossier").String())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	_, resp = apisNonAdmin.imsGetBytes(dossierPath.String())
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_, resp = apisNonAdmin.imsGetBytes(setPath.String())
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
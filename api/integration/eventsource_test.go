@@ -0,0 +1,193 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/srabraham/ranger-ims-go/api"
+	"github.com/srabraham/ranger-ims-go/conf"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEventSourceAuthentication(t *testing.T) {
+	serverURL := newEventSourceServer(t, shared.cfg)
+	ctx := t.Context()
+
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+	apisNotAuthenticated := ApiHelper{t: t, serverURL: serverURL, jwt: ""}
+
+	// No token at all
+	_, resp := apisNotAuthenticated.subscribe(ctx, "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	// A bogus ticket
+	_, resp = apisNotAuthenticated.subscribe(ctx, "not-a-real-ticket")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	// Tickets are only given to authenticated users
+	_, resp = apisNotAuthenticated.postEventSourceTicket()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	// A ticket can't be used as an access token for other endpoints
+	ticket, resp := apisNonAdmin.postEventSourceTicket()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, resp = ApiHelper{t: t, serverURL: serverURL, jwt: ticket}.getEvents()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A valid ticket gets a stream, starting with the InitialEvent
+	messages, resp := apisNotAuthenticated.subscribe(ctx, ticket)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, messages).Event)
+
+	// A valid Authorization header also works
+	messages, resp = apisNonAdmin.subscribe(ctx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, messages).Event)
+}
+
+func TestEventSourceAuthorization(t *testing.T) {
+	serverURL := newEventSourceServer(t, shared.cfg)
+	ctx := t.Context()
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	// Alice can write to this event. The admin has no access to it.
+	eventName := "EventSourceEvent-7361"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.addWriter(eventName, userAliceHandle)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	aliceMessages, resp := apisNonAdmin.subscribe(ctx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, aliceMessages).Event)
+	adminMessages, resp := apisAdmin.subscribe(ctx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, adminMessages).Event)
+
+	num := apisNonAdmin.newIncidentSuccess(imsjson.Incident{Event: eventName})
+
+	msg := receive(t, aliceMessages)
+	require.Equal(t, "Incident", msg.Event)
+	var data api.IMSEventData
+	require.NoError(t, json.Unmarshal([]byte(msg.Data), &data))
+	require.Equal(t, api.IMSEventData{EventName: eventName, IncidentNumber: num}, data)
+
+	// The admin has no access to the event, so they hear nothing about it
+	select {
+	case msg = <-adminMessages:
+		t.Fatalf("admin should not have received %v", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestEventSourceReplay(t *testing.T) {
+	serverURL := newEventSourceServer(t, shared.cfg)
+	ctx := t.Context()
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "EventSourceEvent-2950"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.addWriter(eventName, userAliceHandle)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Find out the latest event ID, then disconnect
+	firstCtx, firstCancel := context.WithCancel(ctx)
+	messages, resp := apisNonAdmin.subscribe(firstCtx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	initial := receive(t, messages)
+	require.Equal(t, "InitialEvent", initial.Event)
+	firstCancel()
+
+	// These happen while Alice isn't connected
+	num1 := apisNonAdmin.newIncidentSuccess(imsjson.Incident{Event: eventName})
+	num2 := apisNonAdmin.newIncidentSuccess(imsjson.Incident{Event: eventName})
+	frNum := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Event: eventName, Summary: ptr("replayed")})
+
+	// Alice reconnects and gets everything she missed
+	messages, resp = apisNonAdmin.subscribeAfter(ctx, initial.ID)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	msg := receive(t, messages)
+	require.Equal(t, "InitialEvent", msg.Event)
+	require.Equal(t, initial.ID, msg.ID)
+	for _, num := range []int32{num1, num2} {
+		msg = receive(t, messages)
+		require.Equal(t, "Incident", msg.Event)
+		var data api.IMSEventData
+		require.NoError(t, json.Unmarshal([]byte(msg.Data), &data))
+		require.Equal(t, api.IMSEventData{EventName: eventName, IncidentNumber: num}, data)
+	}
+	msg = receive(t, messages)
+	require.Equal(t, "FieldReport", msg.Event)
+	var frData api.IMSEventData
+	require.NoError(t, json.Unmarshal([]byte(msg.Data), &frData))
+	require.Equal(t, api.IMSEventData{EventName: eventName, FieldReportNumber: frNum}, frData)
+
+	// The admin can't read the event, so the replay tells them nothing about it
+	adminMessages, resp := apisAdmin.subscribeAfter(ctx, initial.ID)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, adminMessages).Event)
+	select {
+	case msg = <-adminMessages:
+		t.Fatalf("admin should not have received %v", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// An ID that the server has never issued means the client needs to reload everything
+	messages, resp = apisNonAdmin.subscribeAfter(ctx, "999999999")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	msg = receive(t, messages)
+	require.Equal(t, "InitialEvent", msg.Event)
+	require.NotEqual(t, "999999999", msg.ID)
+}
+
+func TestEventSourceAcrossServers(t *testing.T) {
+	cfg := *shared.cfg
+	cfg.Core.Broadcast = conf.BroadcastTypeDBPoll
+	// Two IMS servers sharing one database, as though behind a load balancer
+	serverURL1 := newEventSourceServer(t, &cfg)
+	serverURL2 := newEventSourceServer(t, &cfg)
+	ctx := t.Context()
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL1, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin1 := ApiHelper{t: t, serverURL: serverURL1, jwt: jwtForRealTestUser(t)}
+	apisNonAdmin2 := ApiHelper{t: t, serverURL: serverURL2, jwt: jwtForRealTestUser(t)}
+
+	eventName := "EventSourceEvent-4182"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.addWriter(eventName, userAliceHandle)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	messages1, resp := apisNonAdmin1.subscribe(ctx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, messages1).Event)
+	messages2, resp := apisNonAdmin2.subscribe(ctx, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "InitialEvent", receive(t, messages2).Event)
+
+	// An update made on the first server reaches the clients of both servers
+	num := apisNonAdmin1.newIncidentSuccess(imsjson.Incident{Event: eventName})
+	for _, messages := range []<-chan sseMessage{messages1, messages2} {
+		msg := receive(t, messages)
+		require.Equal(t, "Incident", msg.Event)
+		var data api.IMSEventData
+		require.NoError(t, json.Unmarshal([]byte(msg.Data), &data))
+		require.Equal(t, api.IMSEventData{EventName: eventName, IncidentNumber: num}, data)
+	}
+}
+
+func receive(t *testing.T, messages <-chan sseMessage) sseMessage {
+	select {
+	case msg, ok := <-messages:
+		require.True(t, ok, "EventSource stream closed")
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for EventSource message")
+		return sseMessage{}
+	}
+}
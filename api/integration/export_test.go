@@ -0,0 +1,213 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExportIncidents(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "ExportEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+
+	incident1 := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("=1+1"),
+		Location: imsjson.Location{
+			Name:         ptr("Camp Lost"),
+			RadialHour:   ptr("3"),
+			RadialMinute: ptr("5"),
+		},
+		RangerHandles: &[]string{"Tool", "Bucket"},
+		ReportEntries: []imsjson.ReportEntry{{Text: "Found the camera"}},
+	})
+	fr := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("Camera report")})
+	resp = apisAdmin.updateIncident(eventName, incident1, imsjson.Incident{FieldReports: &[]int32{fr}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	incident2 := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "on_scene",
+		Priority: imsjson.IncidentPriorityHigh,
+	})
+
+	body, resp := apisAdmin.exportTable(eventName, "incidents", url.Values{"report_entries": {"true"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.Contains(t, resp.Header.Get("Content-Disposition"), `filename=ExportEvent-incidents.csv`)
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	header := rows[0]
+	col := func(row []string, name string) string {
+		for i, h := range header {
+			if h == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("no column %q", name)
+		return ""
+	}
+	require.Equal(t, strconv.Itoa(int(incident1)), col(rows[1], "Number"))
+	// A summary that looks like a formula stays text
+	require.Equal(t, "'=1+1", col(rows[1], "Summary"))
+	require.Equal(t, "Camp Lost", col(rows[1], "Location Name"))
+	require.Equal(t, "3:05", col(rows[1], "Location Radial"))
+	require.ElementsMatch(t, []string{"Tool", "Bucket"}, splitList(col(rows[1], "Rangers")))
+	require.Equal(t, strconv.Itoa(int(fr)), col(rows[1], "Field Reports"))
+	require.Contains(t, col(rows[1], "Report Entries"), "Found the camera")
+	require.Contains(t, col(rows[1], "Report Entries"), "Added Ranger: ")
+	require.Equal(t, strconv.Itoa(int(incident2)), col(rows[2], "Number"))
+	require.Equal(t, "on_scene", col(rows[2], "State"))
+
+	// The same filters as the incident list, and no report entries by default
+	body, resp = apisAdmin.exportTable(eventName, "incidents", url.Values{
+		"priority": {"5"},
+		"limit":    {"1"},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err = csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.NotContains(t, rows[0], "Report Entries")
+	require.Equal(t, strconv.Itoa(int(incident2)), rows[1][0])
+
+	body, resp = apisAdmin.exportTable(eventName, "incidents", url.Values{"format": {"xlsx"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Disposition"), `filename=ExportEvent-incidents.xlsx`)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 5)
+
+	_, resp = apisAdmin.exportTable(eventName, "incidents", url.Values{"format": {"pdf"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp = apisAdmin.exportTable(eventName, "incidents", url.Values{"state": {"bogus"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp = apisNonAdmin.exportTable(eventName, "incidents", nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestExportIncidentsPaging(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+
+	eventName := "ExportEvent-Paging"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+
+	// More incidents than fit on a page of the export
+	q := imsdb.New(shared.imsDB)
+	eventRow, err := q.QueryEventID(ctx, eventName)
+	require.NoError(t, err)
+	const incidentCount = 1203
+	now := float64(time.Now().Unix())
+	for num := int32(1); num <= incidentCount; num++ {
+		_, err = q.CreateIncident(ctx, imsdb.CreateIncidentParams{
+			Event:        eventRow.Event.ID,
+			Number:       num,
+			Created:      now,
+			Priority:     imsjson.IncidentPriorityNormal,
+			State:        imsdb.IncidentStateNew,
+			LastModified: now,
+		})
+		require.NoError(t, err)
+	}
+
+	body, resp := apisAdmin.exportTable(eventName, "incidents", url.Values{"sort": {"-created"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, incidentCount+1)
+	for i, row := range rows[1:] {
+		require.Equal(t, strconv.Itoa(i+1), row[0])
+	}
+}
+
+func TestExportFieldReports(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "ExportEvent-FieldReports"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+	resp = apisAdmin.editAccess(imsjson.EventsAccess{eventName: imsjson.EventAccess{
+		Writers:   []imsjson.AccessRule{{Expression: "person:" + userAdminHandle, Validity: "always"}},
+		Reporters: []imsjson.AccessRule{{Expression: "person:" + userAliceHandle, Validity: "always"}},
+	}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	adminFR := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{
+		Summary:       ptr("Admin's report"),
+		ReportEntries: []imsjson.ReportEntry{{Text: "Seen by admin"}},
+	})
+	aliceFR := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{
+		Summary:       ptr("Alice's report"),
+		ReportEntries: []imsjson.ReportEntry{{Text: "Seen by Alice"}},
+	})
+
+	body, resp := apisAdmin.exportTable(eventName, "field_reports", url.Values{
+		"report_entries":         {"true"},
+		"exclude_system_entries": {"true"},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []string{"Number", "Created", "Last Modified", "Summary", "Incident", "Report Entries"}, rows[0])
+	require.Len(t, rows, 3)
+	require.Equal(t, strconv.Itoa(int(adminFR)), rows[1][0])
+	require.Equal(t, "Admin's report", rows[1][3])
+	require.Empty(t, rows[1][4])
+	require.Contains(t, rows[1][5], "Seen by admin")
+	require.NotContains(t, rows[1][5], "Changed summary")
+
+	// A reporter only gets their own field reports
+	body, resp = apisNonAdmin.exportTable(eventName, "field_reports", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err = csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, strconv.Itoa(int(aliceFR)), rows[1][0])
+
+	_, resp = apisNonAdmin.exportTable(eventName, "incidents", nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func splitList(cell string) []string {
+	r := csv.NewReader(bytes.NewReader([]byte(cell)))
+	r.TrimLeadingSpace = true
+	fields, _ := r.Read()
+	return fields
+}
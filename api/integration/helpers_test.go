@@ -1,17 +1,23 @@
 package integration
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/srabraham/ranger-ims-go/api"
+	"github.com/srabraham/ranger-ims-go/auth"
+	"github.com/srabraham/ranger-ims-go/conf"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/stretchr/testify/require"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,16 +29,47 @@ type ApiHelper struct {
 }
 
 func (a ApiHelper) postAuth(req api.PostAuthRequest) (statusCode int, body, validJWT string) {
-	response := &api.PostAuthResponse{}
+	statusCode, body, response := a.postAuthFull(req)
+	return statusCode, body, response.Token
+}
+
+func (a ApiHelper) postAuthFull(req api.PostAuthRequest) (statusCode int, body string, response api.PostAuthResponse) {
 	resp := a.imsPost(req, a.serverURL.JoinPath("/ims/api/auth").String())
 	defer resp.Body.Close()
 	b, err := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return resp.StatusCode, string(b), ""
+		return resp.StatusCode, string(b), response
 	}
 	err = json.Unmarshal(b, &response)
 	require.NoError(a.t, err)
-	return resp.StatusCode, string(b), response.Token
+	return resp.StatusCode, string(b), response
+}
+
+func (a ApiHelper) postAuthRefresh(refreshToken string) (statusCode int, response api.PostAuthResponse) {
+	resp := a.imsPost(api.PostAuthRefreshRequest{RefreshToken: refreshToken}, a.serverURL.JoinPath("/ims/api/auth/refresh").String())
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(a.t, json.NewDecoder(resp.Body).Decode(&response))
+	}
+	return resp.StatusCode, response
+}
+
+func (a ApiHelper) postAuthLogout(refreshToken string) *http.Response {
+	return a.imsPost(api.PostAuthLogoutRequest{RefreshToken: refreshToken}, a.serverURL.JoinPath("/ims/api/auth/logout").String())
+}
+
+func (a ApiHelper) postAuthRevoke(handle string) *http.Response {
+	return a.imsPost(api.PostAuthRevokeRequest{Handle: handle}, a.serverURL.JoinPath("/ims/api/auth/revoke").String())
+}
+
+func (a ApiHelper) getAuthLockouts() ([]api.LoginLockout, *http.Response) {
+	bod, resp := a.imsGet(a.serverURL.JoinPath("/ims/api/auth/lockouts").String(), &[]api.LoginLockout{})
+	return *bod.(*[]api.LoginLockout), resp
+}
+
+func (a ApiHelper) getAuthJWKS() (auth.JWKS, *http.Response) {
+	bod, resp := a.imsGet(a.serverURL.JoinPath("/ims/api/auth/jwks.json").String(), &auth.JWKS{})
+	return *bod.(*auth.JWKS), resp
 }
 
 func (a ApiHelper) getAuth(eventName string) (api.GetAuthResponse, *http.Response) {
@@ -82,12 +119,78 @@ func (a ApiHelper) updateIncident(eventName string, incident int32, req imsjson.
 	return a.imsPost(req, a.serverURL.JoinPath("/ims/api/events/", eventName, "/incidents/", fmt.Sprint(incident)).String())
 }
 
+func (a ApiHelper) updateIncidentIfMatch(eventName string, incident int32, req imsjson.Incident, ifMatch string) *http.Response {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/incidents/", fmt.Sprint(incident)).String()
+	return a.imsPostWithHeader(req, path, http.Header{"If-Match": {ifMatch}})
+}
+
+func (a ApiHelper) getIncidentHistory(eventName string, incident int32) (imsjson.IncidentHistory, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/incidents/", fmt.Sprint(incident), "/history")
+	bod, resp := a.imsGet(path.String(), &imsjson.IncidentHistory{})
+	return *bod.(*imsjson.IncidentHistory), resp
+}
+
 func (a ApiHelper) getIncidents(eventName string) (imsjson.Incidents, *http.Response) {
 	path := a.serverURL.JoinPath(fmt.Sprint("/ims/api/events/", eventName, "/incidents")).String()
 	bod, resp := a.imsGet(path, &imsjson.Incidents{})
 	return *bod.(*imsjson.Incidents), resp
 }
 
+func (a ApiHelper) getIncidentsWithQuery(eventName string, query url.Values) (imsjson.Incidents, *http.Response) {
+	path := a.serverURL.JoinPath(fmt.Sprint("/ims/api/events/", eventName, "/incidents"))
+	path.RawQuery = query.Encode()
+	bod, resp := a.imsGet(path.String(), &imsjson.Incidents{})
+	return *bod.(*imsjson.Incidents), resp
+}
+
+func (a ApiHelper) newFieldReportSuccess(eventName string, fr imsjson.FieldReport) (fieldReportNumber int32) {
+	resp := a.imsPost(fr, a.serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports").String())
+	require.Equal(a.t, http.StatusCreated, resp.StatusCode)
+	num, err := strconv.ParseInt(resp.Header.Get("X-IMS-Field-Report-Number"), 10, 32)
+	require.NoError(a.t, err)
+	return int32(num)
+}
+
+func (a ApiHelper) getFieldReport(eventName string, fieldReport int32) (imsjson.FieldReport, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports/", fmt.Sprint(fieldReport)).String()
+	bod, resp := a.imsGet(path, &imsjson.FieldReport{})
+	return *bod.(*imsjson.FieldReport), resp
+}
+
+func (a ApiHelper) attachFieldReportIfMatch(eventName string, fieldReport, incident int32, ifMatch string) *http.Response {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports/", fmt.Sprint(fieldReport))
+	path.RawQuery = url.Values{"action": {"attach"}, "incident": {fmt.Sprint(incident)}}.Encode()
+	return a.imsPostWithHeader(imsjson.FieldReport{}, path.String(), http.Header{"If-Match": {ifMatch}})
+}
+
+func (a ApiHelper) getFieldReports(eventName string, query url.Values) (imsjson.FieldReports, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports")
+	path.RawQuery = query.Encode()
+	bod, resp := a.imsGet(path.String(), &imsjson.FieldReports{})
+	return *bod.(*imsjson.FieldReports), resp
+}
+
+func (a ApiHelper) search(eventName string, query url.Values) (imsjson.SearchResults, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/search")
+	path.RawQuery = query.Encode()
+	bod, resp := a.imsGet(path.String(), &imsjson.SearchResults{})
+	return *bod.(*imsjson.SearchResults), resp
+}
+
+func (a ApiHelper) getAudit(query url.Values) (imsjson.AuditRecords, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/audit")
+	path.RawQuery = query.Encode()
+	bod, resp := a.imsGet(path.String(), &imsjson.AuditRecords{})
+	return *bod.(*imsjson.AuditRecords), resp
+}
+
+func (a ApiHelper) getMetrics(eventName string, query url.Values) (imsjson.IncidentMetrics, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, "/metrics")
+	path.RawQuery = query.Encode()
+	bod, resp := a.imsGet(path.String(), &imsjson.IncidentMetrics{})
+	return *bod.(*imsjson.IncidentMetrics), resp
+}
+
 func (a ApiHelper) editEvent(req imsjson.EditEventsRequest) *http.Response {
 	return a.imsPost(req, a.serverURL.JoinPath("/ims/api/events").String())
 }
@@ -117,7 +220,101 @@ func (a ApiHelper) getAccess() (imsjson.EventsAccess, *http.Response) {
 	return *bod.(*imsjson.EventsAccess), resp
 }
 
+func (a ApiHelper) postEventSourceTicket() (string, *http.Response) {
+	resp := a.imsPost(nil, a.serverURL.JoinPath("/ims/api/eventsource/ticket").String())
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", resp
+	}
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(a.t, err)
+	ticket := api.PostEventSourceTicketResponse{}
+	require.NoError(a.t, json.Unmarshal(b, &ticket))
+	return ticket.Ticket, resp
+}
+
+// newEventSourceServer starts an IMS server with cfg, for tests that open
+// EventSource streams. It's closed when the test ends. Streams should be opened
+// with t.Context(), which is cancelled before the server is closed, since the
+// server can't close while streams are still open.
+func newEventSourceServer(t *testing.T, cfg *conf.IMSConfig) *url.URL {
+	t.Helper()
+	s := httptest.NewServer(api.AddToMux(nil, cfg, shared.imsDB, nil))
+	t.Cleanup(s.Close)
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	return serverURL
+}
+
+// sseMessage is a single message read off of an EventSource stream.
+type sseMessage struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// subscribe opens an EventSource stream using the provided ticket. If the
+// response status is OK, messages from the stream are sent on the returned channel
+// until the context is canceled.
+func (a ApiHelper) subscribe(ctx context.Context, ticket string) (<-chan sseMessage, *http.Response) {
+	query := url.Values{}
+	if ticket != "" {
+		query.Set("ticket", ticket)
+	}
+	return a.subscribeWithQuery(ctx, query)
+}
+
+// subscribeAfter opens an EventSource stream that replays everything after lastEventID.
+func (a ApiHelper) subscribeAfter(ctx context.Context, lastEventID string) (<-chan sseMessage, *http.Response) {
+	return a.subscribeWithQuery(ctx, url.Values{"last_event_id": {lastEventID}})
+}
+
+func (a ApiHelper) subscribeWithQuery(ctx context.Context, query url.Values) (<-chan sseMessage, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/eventsource")
+	path.RawQuery = query.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", path.String(), nil)
+	require.NoError(a.t, err)
+	if a.jwt != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.jwt)
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	require.NoError(a.t, err)
+	messages := make(chan sseMessage, 100)
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		close(messages)
+		return messages, resp
+	}
+	go func() {
+		defer resp.Body.Close()
+		defer close(messages)
+		scanner := bufio.NewScanner(resp.Body)
+		var msg sseMessage
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				msg.ID = value
+			case "event":
+				msg.Event = value
+			case "data":
+				msg.Data = value
+			case "":
+				if msg != (sseMessage{}) {
+					messages <- msg
+				}
+				msg = sseMessage{}
+			}
+		}
+	}()
+	return messages, resp
+}
+
 func (a ApiHelper) imsPost(body any, path string) *http.Response {
+	return a.imsPostWithHeader(body, path, nil)
+}
+
+func (a ApiHelper) imsPostWithHeader(body any, path string, header http.Header) *http.Response {
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
 	}
@@ -125,6 +322,9 @@ func (a ApiHelper) imsPost(body any, path string) *http.Response {
 	require.NoError(a.t, err)
 	httpPost, err := http.NewRequest("POST", path, bytes.NewReader(postBody))
 	require.NoError(a.t, err)
+	for k, v := range header {
+		httpPost.Header[k] = v
+	}
 	if a.jwt != "" {
 		httpPost.Header.Set("Authorization", "Bearer "+a.jwt)
 	}
@@ -134,6 +334,52 @@ func (a ApiHelper) imsPost(body any, path string) *http.Response {
 	// require.Equal(a.t, http.StatusNoContent, resp.StatusCode)
 }
 
+// attachFiles uploads files, keyed by file name, as a browser would from a form.
+func (a ApiHelper) attachFiles(path string, files map[string][]byte) *http.Response {
+	var body bytes.Buffer
+	mpw := multipart.NewWriter(&body)
+	for name, data := range files {
+		fw, err := mpw.CreateFormFile("files", name)
+		require.NoError(a.t, err)
+		_, err = fw.Write(data)
+		require.NoError(a.t, err)
+	}
+	require.NoError(a.t, mpw.Close())
+	httpPost, err := http.NewRequest("POST", path, &body)
+	require.NoError(a.t, err)
+	httpPost.Header.Set("Content-Type", mpw.FormDataContentType())
+	if a.jwt != "" {
+		httpPost.Header.Set("Authorization", "Bearer "+a.jwt)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(httpPost)
+	require.NoError(a.t, err)
+	return resp
+}
+
+func (a ApiHelper) getAttachment(path string) ([]byte, *http.Response) {
+	return a.imsGetBytes(path)
+}
+
+func (a ApiHelper) exportTable(eventName, kind string, query url.Values) ([]byte, *http.Response) {
+	path := a.serverURL.JoinPath("/ims/api/events/", eventName, kind, "export")
+	path.RawQuery = query.Encode()
+	return a.imsGetBytes(path.String())
+}
+
+func (a ApiHelper) imsGetBytes(path string) ([]byte, *http.Response) {
+	httpReq, err := http.NewRequest("GET", path, nil)
+	require.NoError(a.t, err)
+	if a.jwt != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.jwt)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(httpReq)
+	require.NoError(a.t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(a.t, err)
+	return b, resp
+}
+
 func (a ApiHelper) imsGet(path string, resp any) (any, *http.Response) {
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
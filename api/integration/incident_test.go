@@ -1,12 +1,16 @@
 package integration
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/srabraham/ranger-ims-go/api"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -244,6 +248,371 @@ func TestCreateAndUpdateIncident(t *testing.T) {
 	requireEqualIncident(t, expected, retrievedIncidentAfterUpdate)
 }
 
+func TestUpdateIncidentIfMatch(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-IfMatch"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	num := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	retrieved, resp := apisNonAdmin.getIncident(eventName, num)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, fmt.Sprintf(`"%d"`, retrieved.Version), resp.Header.Get("ETag"))
+	staleETag := resp.Header.Get("ETag")
+
+	// An edit conditioned on the current version succeeds and bumps the version
+	resp = apisNonAdmin.updateIncidentIfMatch(eventName, num, imsjson.Incident{
+		Event:   eventName,
+		Number:  num,
+		Summary: ptr("first edit"),
+	}, staleETag)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	afterEdit, resp := apisNonAdmin.getIncident(eventName, num)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, retrieved.Version+1, afterEdit.Version)
+
+	// An edit conditioned on the old version is refused, and the response
+	// has the current incident
+	resp = apisNonAdmin.updateIncidentIfMatch(eventName, num, imsjson.Incident{
+		Event:   eventName,
+		Number:  num,
+		Summary: ptr("lost update"),
+	}, staleETag)
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	require.Equal(t, fmt.Sprintf(`"%d"`, afterEdit.Version), resp.Header.Get("ETag"))
+	var current imsjson.Incident
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&current))
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "first edit", *current.Summary)
+	require.Equal(t, afterEdit.Version, current.Version)
+
+	afterRefusal, resp := apisNonAdmin.getIncident(eventName, num)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "first edit", *afterRefusal.Summary)
+}
+
+func TestAttachFieldReportIfMatch(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-AttachIfMatch"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	incident1 := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	incident2 := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	frNum := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Event: eventName})
+	_, resp = apisNonAdmin.getFieldReport(eventName, frNum)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	staleETag := resp.Header.Get("ETag")
+	beforeAttach, _ := apisNonAdmin.getIncident(eventName, incident1)
+
+	resp = apisNonAdmin.attachFieldReportIfMatch(eventName, frNum, incident1, staleETag)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// The incident's list of field reports changed, so it has a new version
+	afterAttach, resp := apisNonAdmin.getIncident(eventName, incident1)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []int32{frNum}, *afterAttach.FieldReports)
+	require.Greater(t, afterAttach.Version, beforeAttach.Version)
+
+	// A second attach based on the same version is refused, and changes nothing
+	resp = apisNonAdmin.attachFieldReportIfMatch(eventName, frNum, incident2, staleETag)
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	fr, _ := apisNonAdmin.getFieldReport(eventName, frNum)
+	require.Equal(t, incident1, fr.Incident)
+	notAttached, _ := apisNonAdmin.getIncident(eventName, incident2)
+	require.Empty(t, *notAttached.FieldReports)
+}
+
+func TestMoveFieldReportBetweenIncidents(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-MoveFieldReport"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	incidentA := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	incidentB := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	frNum := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Event: eventName})
+	resp = apisNonAdmin.updateIncident(eventName, incidentB, imsjson.Incident{
+		Event:        eventName,
+		Number:       incidentB,
+		FieldReports: &[]int32{frNum},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	_, resp = apisNonAdmin.getIncident(eventName, incidentB)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	staleETag := resp.Header.Get("ETag")
+
+	// Moving the field report to A changes B too
+	resp = apisNonAdmin.updateIncident(eventName, incidentA, imsjson.Incident{
+		Event:        eventName,
+		Number:       incidentA,
+		FieldReports: &[]int32{frNum},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	incB, _ := apisNonAdmin.getIncident(eventName, incidentB)
+	require.Empty(t, *incB.FieldReports)
+	require.NotEqual(t, staleETag, fmt.Sprintf(`"%d"`, incB.Version))
+
+	// So an edit of B from before the move can't quietly move it back
+	resp = apisNonAdmin.updateIncidentIfMatch(eventName, incidentB, imsjson.Incident{
+		Event:        eventName,
+		Number:       incidentB,
+		FieldReports: &[]int32{frNum},
+	}, staleETag)
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+	fr, _ := apisNonAdmin.getFieldReport(eventName, frNum)
+	require.Equal(t, incidentA, fr.Incident)
+
+	// A field report that doesn't exist can't be added
+	resp = apisNonAdmin.updateIncident(eventName, incidentA, imsjson.Incident{
+		Event:        eventName,
+		Number:       incidentA,
+		FieldReports: &[]int32{frNum, 99999},
+	})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	incA, _ := apisNonAdmin.getIncident(eventName, incidentA)
+	require.Equal(t, []int32{frNum}, *incA.FieldReports)
+}
+
+func TestCreateIncidentsConcurrently(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-Concurrent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	const creates = 10
+	statuses := make(chan int, creates)
+	var wg sync.WaitGroup
+	for range creates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := apisNonAdmin.newIncident(sampleIncident1(eventName))
+			_ = resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		require.Equal(t, http.StatusCreated, status)
+	}
+
+	// Every incident got its own number, with no gaps
+	incidents, resp := apisNonAdmin.getIncidents(eventName)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var numbers []int32
+	for _, incident := range incidents {
+		numbers = append(numbers, incident.Number)
+		require.Equal(t, "my summary!", *incident.Summary)
+	}
+	slices.Sort(numbers)
+	require.Equal(t, []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, numbers)
+}
+
+func TestGetIncidentsFilterSortAndPaginate(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-Query"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	newIncident := func(state string, priority int8, summary string, types []string, handles []string) int32 {
+		return apisNonAdmin.newIncidentSuccess(imsjson.Incident{
+			Event:         eventName,
+			State:         state,
+			Priority:      priority,
+			Summary:       ptr(summary),
+			IncidentTypes: &types,
+			RangerHandles: &handles,
+		})
+	}
+	one := newIncident("new", 3, "Lost 50% of a bike", []string{"Admin"}, []string{"SomeOne"})
+	two := newIncident("dispatched", 1, "Art car fire", []string{"Junk"}, []string{"SomeTwo"})
+	three := newIncident("closed", 5, "Found bike", []string{"Admin", "Junk"}, []string{"SomeOne", "SomeTwo"})
+	four := newIncident("on_scene", 3, "Noise complaint", nil, nil)
+
+	numbers := func(query url.Values) []int32 {
+		incidents, resp := apisNonAdmin.getIncidentsWithQuery(eventName, query)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		result := []int32{}
+		for _, incident := range incidents {
+			result = append(result, incident.Number)
+		}
+		return result
+	}
+
+	require.Equal(t, []int32{one, two, three, four}, numbers(nil))
+	require.Equal(t, []int32{one, two, four}, numbers(url.Values{"state": {"new,dispatched,on_scene"}}))
+	require.Equal(t, []int32{one, four}, numbers(url.Values{"state": {"new", "on_scene"}}))
+	require.Equal(t, []int32{one, four}, numbers(url.Values{"priority": {"3"}}))
+	require.Equal(t, []int32{two, three}, numbers(url.Values{"incident_type": {"Junk"}}))
+	require.Equal(t, []int32{one, three}, numbers(url.Values{"ranger_handle": {"SomeOne"}}))
+	require.Equal(t, []int32{one, three}, numbers(url.Values{"text": {"BIKE"}}))
+	require.Equal(t, []int32{one}, numbers(url.Values{"text": {"50%"}}))
+	// "on_scene" is in a system report entry
+	require.Equal(t, []int32{four}, numbers(url.Values{"text": {"_"}}))
+	require.Equal(t, []int32{three}, numbers(url.Values{"text": {"bike"}, "incident_type": {"Junk"}}))
+	require.Equal(t, []int32{four, three, two, one}, numbers(url.Values{"sort": {"-number"}}))
+	require.Equal(t, []int32{two, one, four, three}, numbers(url.Values{"sort": {"priority"}}))
+	require.Equal(t, []int32{three, four, one, two}, numbers(url.Values{"sort": {"-priority"}}))
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	require.Equal(t, []int32{}, numbers(url.Values{"created_after": {future}}))
+	require.Equal(t, []int32{one, two, three, four}, numbers(url.Values{"created_after": {past}, "created_before": {future}}))
+	require.Equal(t, []int32{}, numbers(url.Values{"modified_before": {past}}))
+	require.Equal(t, []int32{one, two, three, four}, numbers(url.Values{"modified_since": {past}}))
+
+	// A filtered list still has each of its incidents' report entries
+	filtered, resp := apisNonAdmin.getIncidentsWithQuery(eventName, url.Values{"priority": {"3"}, "sort": {"-number"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, filtered, 2)
+	for _, incident := range filtered {
+		require.NotEmpty(t, incident.ReportEntries)
+	}
+
+	// Page through the incidents, sorted by descending priority
+	var paged []int32
+	query := url.Values{"sort": {"-priority"}, "limit": {"3"}}
+	for {
+		incidents, resp := apisNonAdmin.getIncidentsWithQuery(eventName, query)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.LessOrEqual(t, len(incidents), 3)
+		for _, incident := range incidents {
+			paged = append(paged, incident.Number)
+			require.NotEmpty(t, incident.ReportEntries)
+		}
+		cursor := resp.Header.Get("X-IMS-Next-Cursor")
+		if cursor == "" {
+			break
+		}
+		query.Set("cursor", cursor)
+	}
+	require.Equal(t, []int32{three, four, one, two}, paged)
+
+	// Bad parameters are rejected
+	for _, query := range []url.Values{
+		{"state": {"nonsense"}},
+		{"priority": {"high"}},
+		{"created_after": {"yesterday"}},
+		{"sort": {"summary"}},
+		{"limit": {"0"}},
+		{"cursor": {"garbage"}},
+		{"sort": {"number"}, "cursor": {query.Get("cursor")}},
+	} {
+		_, resp := apisNonAdmin.getIncidentsWithQuery(eventName, query)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestGetModifiedSince(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentEvent-ModifiedSince"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAliceHandle)
+
+	incident1 := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	incident2 := apisNonAdmin.newIncidentSuccess(sampleIncident1(eventName))
+	fr1 := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("one")})
+	fr2 := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("two")})
+
+	// Modification times are stored to the second
+	time.Sleep(time.Second)
+	since := url.Values{"modified_since": {time.Now().Truncate(time.Second).Format(time.RFC3339)}}
+	incidents, resp := apisNonAdmin.getIncidentsWithQuery(eventName, since)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, incidents)
+	fieldReports, resp := apisNonAdmin.getFieldReports(eventName, since)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, fieldReports)
+
+	// Adding a report entry modifies only that incident
+	resp = apisNonAdmin.updateIncident(eventName, incident2, imsjson.Incident{
+		Event:         eventName,
+		Number:        incident2,
+		ReportEntries: []imsjson.ReportEntry{{Text: "more news"}},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	incidents, resp = apisNonAdmin.getIncidentsWithQuery(eventName, since)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, incidents, 1)
+	require.Equal(t, incident2, incidents[0].Number)
+	require.WithinDuration(t, time.Now(), incidents[0].LastModified, 5*time.Second)
+
+	// Attaching a field report modifies the field report and the incident
+	resp = apisNonAdmin.updateIncident(eventName, incident1, imsjson.Incident{
+		Event:        eventName,
+		Number:       incident1,
+		FieldReports: &[]int32{fr2},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	incidents, resp = apisNonAdmin.getIncidentsWithQuery(eventName, since)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, incidents, 2)
+	fieldReports, resp = apisNonAdmin.getFieldReports(eventName, since)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, fieldReports, 1)
+	require.Equal(t, fr2, fieldReports[0].Number)
+
+	// The unmodified field report is still there without modified_since
+	fieldReports, resp = apisNonAdmin.getFieldReports(eventName, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, fieldReports, 2)
+	require.Equal(t, fr1, fieldReports[0].Number)
+
+	_, resp = apisNonAdmin.getFieldReports(eventName, url.Values{"modified_since": {"yesterday"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 // requireEqualIncident is a hacky way of checking two incident responses are the same.
 // It does not consider ReportEntries.
 func requireEqualIncident(t *testing.T, before imsjson.Incident, after imsjson.Incident) {
@@ -285,3 +654,82 @@ func requireEqualIncident(t *testing.T, before imsjson.Incident, after imsjson.I
 func ptr[T any](s T) *T {
 	return &s
 }
+
+func TestIncidentHistory(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "IncidentHistoryEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+
+	incidentNumber := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("Lost child"),
+	})
+	resp = apisAdmin.updateIncident(eventName, incidentNumber, imsjson.Incident{
+		State:         "on_scene",
+		RangerHandles: &[]string{"Tool"},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	// Setting a field to the value it already has isn't a change
+	resp = apisAdmin.updateIncident(eventName, incidentNumber, imsjson.Incident{
+		State:   "closed",
+		Summary: ptr("Lost child"),
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Attaching a field report from the field report's side changes the incident too
+	fieldReportNumber := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{Summary: ptr("Found child")})
+	attachURL := serverURL.JoinPath("/ims/api/events/", eventName, "/field_reports/", fmt.Sprint(fieldReportNumber))
+	attachURL.RawQuery = url.Values{"action": {"attach"}, "incident": {fmt.Sprint(incidentNumber)}}.Encode()
+	resp = apisAdmin.imsPost(imsjson.FieldReport{}, attachURL.String())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	history, resp := apisAdmin.getIncidentHistory(eventName, incidentNumber)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	type change struct{ field, oldValue, newValue string }
+	var changes []change
+	for _, c := range history {
+		require.Equal(t, userAdminHandle, c.Author)
+		require.WithinDuration(t, time.Now(), c.Created, 5*time.Minute)
+		changes = append(changes, change{c.Field, string(c.OldValue), string(c.NewValue)})
+	}
+	require.Equal(t, []change{
+		{"summary", `null`, `"Lost child"`},
+		{"ranger_handles", `[]`, `["Tool"]`},
+		{"state", `"new"`, `"on_scene"`},
+		{"state", `"on_scene"`, `"closed"`},
+		{"field_reports", `[]`, fmt.Sprintf("[%d]", fieldReportNumber)},
+	}, changes)
+
+	// Moving the field report by editing another incident is in both histories
+	otherIncident := apisAdmin.newIncidentSuccess(imsjson.Incident{Event: eventName})
+	resp = apisAdmin.updateIncident(eventName, otherIncident, imsjson.Incident{
+		FieldReports: &[]int32{fieldReportNumber},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	history, resp = apisAdmin.getIncidentHistory(eventName, incidentNumber)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	last := history[len(history)-1]
+	require.Equal(t, change{"field_reports", fmt.Sprintf("[%d]", fieldReportNumber), `[]`},
+		change{last.Field, string(last.OldValue), string(last.NewValue)})
+	history, resp = apisAdmin.getIncidentHistory(eventName, otherIncident)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	last = history[len(history)-1]
+	require.Equal(t, change{"field_reports", `[]`, fmt.Sprintf("[%d]", fieldReportNumber)},
+		change{last.Field, string(last.OldValue), string(last.NewValue)})
+
+	_, resp = apisAdmin.getIncidentHistory(eventName, 99999)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	_, resp = apisNonAdmin.getIncidentHistory(eventName, incidentNumber)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
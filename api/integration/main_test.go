@@ -12,6 +12,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -20,6 +21,8 @@ import (
 // mainTestInternal contains fields to be used only within main_test.go.
 var mainTestInternal struct {
 	imsDBContainer testcontainers.Container
+	sqliteDir      string
+	attachmentsDir string
 }
 
 // shared contains fields that may be used by any test in the integration package.
@@ -44,6 +47,9 @@ const (
 // TestMain does the common setup and teardown for all tests in this package.
 // It's slow to start up a MariaDB container, so we want to only have to do
 // that once for the whole suite of test files.
+//
+// Set IMS_TEST_STORE=sqlite to run the suite against SQLite instead, which
+// doesn't need Docker.
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 	defer func() {
@@ -93,6 +99,37 @@ func setup(ctx context.Context) {
 		panic(err)
 	}
 	shared.userStore = userStore
+	mainTestInternal.attachmentsDir, err = os.MkdirTemp("", "ims-attachments")
+	if err != nil {
+		panic(err)
+	}
+	shared.cfg.AttachmentsStore.Type = conf.AttachmentsStoreTypeLocal
+	shared.cfg.AttachmentsStore.Local.Dir = mainTestInternal.attachmentsDir
+	if os.Getenv("IMS_TEST_STORE") == string(conf.StoreTypeSQLite) {
+		setupSQLite(ctx)
+		return
+	}
+	setupMariaDB(ctx)
+}
+
+func setupSQLite(ctx context.Context) {
+	var err error
+	mainTestInternal.sqliteDir, err = os.MkdirTemp("", "ims-integration")
+	if err != nil {
+		panic(err)
+	}
+	shared.cfg.Store.Type = conf.StoreTypeSQLite
+	shared.cfg.Store.SQLite.Path = filepath.Join(mainTestInternal.sqliteDir, "ims.sqlite")
+	shared.imsDB, err = store.OpenSQLite(ctx, shared.cfg.Store.SQLite.Path)
+	if err != nil {
+		panic(err)
+	}
+	if err = store.Migrate(ctx, shared.imsDB); err != nil {
+		panic(err)
+	}
+}
+
+func setupMariaDB(ctx context.Context) {
 	req := testcontainers.ContainerRequest{
 		Image:        "mariadb:10.5.27",
 		ExposedPorts: []string{"3306/tcp"},
@@ -104,6 +141,7 @@ func setup(ctx context.Context) {
 			"MARIADB_PASSWORD":             shared.cfg.Store.MySQL.Password,
 		},
 	}
+	var err error
 	mainTestInternal.imsDBContainer, err = testcontainers.GenericContainer(ctx,
 		testcontainers.GenericContainerRequest{
 			ContainerRequest: req,
@@ -122,15 +160,18 @@ func setup(ctx context.Context) {
 	port, _ := strconv.Atoi(strings.TrimPrefix(endpoint, "localhost:"))
 	shared.cfg.Store.MySQL.HostPort = int32(port)
 	shared.imsDB = &store.DB{DB: store.MariaDB(shared.cfg)}
-	script := "BEGIN NOT ATOMIC\n" + store.CurrentSchema + "\nEND"
-	_, err = shared.imsDB.ExecContext(ctx, script)
-	if err != nil {
+	if err = store.Migrate(ctx, shared.imsDB); err != nil {
 		panic(err)
 	}
 }
 
 func shutdown(ctx context.Context) {
 	_ = shared.imsDB.Close()
+	_ = os.RemoveAll(mainTestInternal.attachmentsDir)
+	if mainTestInternal.sqliteDir != "" {
+		_ = os.RemoveAll(mainTestInternal.sqliteDir)
+		return
+	}
 	err := mainTestInternal.imsDBContainer.Terminate(ctx)
 	if err != nil {
 		// log and continue
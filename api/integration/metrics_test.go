@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"context"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIncidentMetrics(t *testing.T) {
+	ctx := context.Background()
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "MetricsEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	apisAdmin.addWriter(eventName, userAdminHandle)
+	typeName := "Metrics Type"
+	resp = apisAdmin.editTypes(imsjson.EditIncidentTypesRequest{Add: []string{typeName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// One incident goes all the way through to closed
+	closedIncident := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:         eventName,
+		State:         "new",
+		Priority:      imsjson.IncidentPriorityNormal,
+		IncidentTypes: &[]string{typeName},
+	})
+	for _, state := range []string{"dispatched", "on_scene", "closed"} {
+		resp = apisAdmin.updateIncident(eventName, closedIncident, imsjson.Incident{State: state})
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	// and another is still open
+	_ = apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityHigh,
+	})
+	// This one was handled before IMS kept incident history, so its changes of
+	// state are only in its system report entries.
+	q := imsdb.New(shared.imsDB)
+	eventRow, err := q.QueryEventID(ctx, eventName)
+	require.NoError(t, err)
+	eventID := eventRow.Event.ID
+	created := time.Now().Add(-time.Hour).Unix()
+	const legacyIncident = 1000
+	_, err = q.CreateIncident(ctx, imsdb.CreateIncidentParams{
+		Event:        eventID,
+		Number:       legacyIncident,
+		Created:      float64(created),
+		Priority:     imsjson.IncidentPriorityNormal,
+		State:        imsdb.IncidentStateClosed,
+		LastModified: float64(created + 1800),
+	})
+	require.NoError(t, err)
+	for offset, text := range map[int64]string{
+		600:  "Changed state: dispatched",
+		1800: "Changed priority: 3\nChanged state: closed",
+		1900: "Changed summary: Changed state: on_hold",
+	} {
+		reID, err := q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{
+			Author:    userAdminHandle,
+			Text:      text,
+			Created:   float64(created + offset),
+			Generated: true,
+		})
+		require.NoError(t, err)
+		err = q.AttachReportEntryToIncident(ctx, imsdb.AttachReportEntryToIncidentParams{
+			Event:          eventID,
+			IncidentNumber: legacyIncident,
+			ReportEntry:    int32(reID),
+		})
+		require.NoError(t, err)
+	}
+
+	metrics, resp := apisAdmin.getMetrics(eventName, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, metrics.Overall.Incidents)
+	require.Equal(t, 2, metrics.Overall.TimeToDispatch.Count)
+	require.Equal(t, 600.0, metrics.Overall.TimeToDispatch.MaxSeconds)
+	require.Equal(t, 1, metrics.Overall.TimeToOnScene.Count)
+	require.Equal(t, 2, metrics.Overall.TimeToClose.Count)
+	require.Equal(t, 1800.0, metrics.Overall.TimeToClose.MaxSeconds)
+	require.Equal(t, 900.0, metrics.Overall.TimeToClose.MeanSeconds)
+
+	require.Equal(t, 2, metrics.ByPriority[imsjson.IncidentPriorityNormal].Incidents)
+	require.Equal(t, 1, metrics.ByPriority[imsjson.IncidentPriorityHigh].Incidents)
+	require.Equal(t, 0, metrics.ByPriority[imsjson.IncidentPriorityHigh].TimeToClose.Count)
+	require.Equal(t, 1, metrics.ByIncidentType[typeName].Incidents)
+	require.Equal(t, 1, metrics.ByIncidentType[typeName].TimeToOnScene.Count)
+
+	require.NotEmpty(t, metrics.Hourly)
+	totalCreated := 0
+	for _, h := range metrics.Hourly {
+		totalCreated += h.Created
+	}
+	require.Equal(t, 3, totalCreated)
+	require.Equal(t, 1, metrics.Hourly[len(metrics.Hourly)-1].Open)
+
+	// The incident list's filters narrow down the incidents counted
+	metrics, resp = apisAdmin.getMetrics(eventName, url.Values{"priority": {"5"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, metrics.Overall.Incidents)
+	// but the list's paging doesn't
+	metrics, resp = apisAdmin.getMetrics(eventName, url.Values{"limit": {"1"}, "sort": {"-number"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, metrics.Overall.Incidents)
+	_, resp = apisAdmin.getMetrics(eventName, url.Values{"state": {"bogus"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, resp = apisNonAdmin.getMetrics(eventName, nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/api"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	s := httptest.NewServer(api.AddToMux(nil, shared.cfg, shared.imsDB, nil))
+	defer s.Close()
+	serverURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	apisAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForTestAdminRanger(t)}
+	apisNonAdmin := ApiHelper{t: t, serverURL: serverURL, jwt: jwtForRealTestUser(t)}
+
+	eventName := "SearchEvent"
+	resp := apisAdmin.editEvent(imsjson.EditEventsRequest{Add: []string{eventName}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apisAdmin.editAccess(imsjson.EventsAccess{
+		eventName: imsjson.EventAccess{
+			Writers:   []imsjson.AccessRule{{Expression: "person:" + userAdminHandle, Validity: "always"}},
+			Reporters: []imsjson.AccessRule{{Expression: "person:" + userAliceHandle, Validity: "always"}},
+		},
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	results, resp := apisAdmin.search(eventName, url.Values{"q": {"bike"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, results)
+
+	incident := apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("Stolen blue bike"),
+		Location: imsjson.Location{Name: ptr("Esplanade Camp"), Type: "garett"},
+		ReportEntries: []imsjson.ReportEntry{
+			{Text: "Reporting party says the bike has <b>sparkly</b> streamers"},
+		},
+	})
+	adminFR := apisAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{
+		Summary: ptr("Unrelated noise complaint"),
+		ReportEntries: []imsjson.ReportEntry{
+			{Text: "Someone saw a blue bike near the Esplanade"},
+		},
+	})
+	aliceFR := apisNonAdmin.newFieldReportSuccess(eventName, imsjson.FieldReport{
+		Summary: ptr("Bike found"),
+	})
+	_ = apisAdmin.newIncidentSuccess(imsjson.Incident{
+		Event:    eventName,
+		State:    "new",
+		Priority: imsjson.IncidentPriorityNormal,
+		Summary:  ptr("Lost keys"),
+	})
+
+	// The writer sees everything that matches, with the matches marked up
+	results, resp = apisAdmin.search(eventName, url.Values{"q": {"sparkly bike!"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	found := make(map[string]imsjson.SearchResult)
+	for _, result := range results {
+		found[fmt.Sprint(result.Type, result.Number)] = result
+	}
+	require.Len(t, found, 3)
+	require.Contains(t, found, fmt.Sprint("incident", incident))
+	require.Contains(t, found, fmt.Sprint("field_report", adminFR))
+	require.Contains(t, found, fmt.Sprint("field_report", aliceFR))
+	require.GreaterOrEqual(t, results[0].Score, results[1].Score)
+	require.GreaterOrEqual(t, results[1].Score, results[2].Score)
+
+	incidentResult := found[fmt.Sprint("incident", incident)]
+	require.Equal(t, "Stolen blue bike", *incidentResult.Summary)
+	require.Contains(t, incidentResult.Snippets, "Stolen blue <mark>bike</mark> | Esplanade Camp")
+	require.Contains(t, incidentResult.Snippets,
+		"Reporting party says the <mark>bike</mark> has &lt;b&gt;<mark>sparkly</mark>&lt;/b&gt; streamers")
+
+	results, resp = apisAdmin.search(eventName, url.Values{"q": {"sparkly bike"}, "limit": {"1"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, results, 1)
+
+	// A reporter only sees their own field reports
+	results, resp = apisNonAdmin.search(eventName, url.Values{"q": {"bike"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, results, 1)
+	require.Equal(t, "field_report", results[0].Type)
+	require.Equal(t, aliceFR, results[0].Number)
+	require.Equal(t, []string{"<mark>Bike</mark> found"}, results[0].Snippets)
+
+	_, resp = apisAdmin.search(eventName, url.Values{"q": {"  !? "}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp = apisAdmin.search(eventName, url.Values{"q": {"bike"}, "limit": {"0"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
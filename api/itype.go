@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"github.com/srabraham/ranger-ims-go/auth"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/srabraham/ranger-ims-go/store"
@@ -66,35 +68,88 @@ func (action EditIncidentTypes) ServeHTTP(w http.ResponseWriter, req *http.Reque
 	if !ok {
 		return
 	}
+	if err := editIncidentTypes(ctx, action.imsDB, newAuditor(req), typesReq); err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to edit incident types", err)
+		return
+	}
+	http.Error(w, "Success", http.StatusNoContent)
+}
+
+// editIncidentTypes adds, hides, and shows incident types. Only what actually
+// changes is recorded in the audit log.
+func editIncidentTypes(ctx context.Context, imsDB *store.DB, audit auditor, typesReq imsjson.EditIncidentTypesRequest) error {
+	txn, err := imsDB.Begin()
+	if err != nil {
+		return fmt.Errorf("[Begin]: %w", err)
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+
+	typeRows, err := dbTxn.IncidentTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("[IncidentTypes]: %w", err)
+	}
+	hidden := make(map[string]bool)
+	for _, tr := range typeRows {
+		hidden[tr.IncidentType.Name] = tr.IncidentType.Hidden
+	}
+
 	for _, it := range typesReq.Add {
-		err := imsdb.New(action.imsDB).CreateIncidentTypeOrIgnore(ctx, imsdb.CreateIncidentTypeOrIgnoreParams{
+		if _, exists := hidden[it]; exists {
+			continue
+		}
+		err = dbTxn.CreateIncidentTypeOrIgnore(ctx, imsdb.CreateIncidentTypeOrIgnoreParams{
 			Name:   it,
 			Hidden: false,
 		})
 		if err != nil {
-			handleErr(w, req, http.StatusInternalServerError, "Failed to create incident type", nil)
-			return
+			return fmt.Errorf("[CreateIncidentTypeOrIgnore]: %w", err)
 		}
-	}
-	for _, it := range typesReq.Hide {
-		err := imsdb.New(action.imsDB).HideShowIncidentType(ctx, imsdb.HideShowIncidentTypeParams{
-			Name:   it,
-			Hidden: true,
+		hidden[it] = false
+		err = audit.record(ctx, dbTxn, auditChange{
+			action:     "create",
+			entityType: "incident_type",
+			entityID:   it,
+			after:      map[string]any{"hidden": false},
 		})
 		if err != nil {
-			handleErr(w, req, http.StatusInternalServerError, "Failed to hide incident type", nil)
-			return
+			return fmt.Errorf("[record]: %w", err)
 		}
 	}
-	for _, it := range typesReq.Show {
-		err := imsdb.New(action.imsDB).HideShowIncidentType(ctx, imsdb.HideShowIncidentTypeParams{
-			Name:   it,
-			Hidden: false,
-		})
-		if err != nil {
-			handleErr(w, req, http.StatusInternalServerError, "Failed to unhide incident type", nil)
-			return
+	for _, change := range []struct {
+		names  []string
+		hidden bool
+	}{
+		{typesReq.Hide, true},
+		{typesReq.Show, false},
+	} {
+		for _, it := range change.names {
+			wasHidden, exists := hidden[it]
+			if !exists || wasHidden == change.hidden {
+				continue
+			}
+			err = dbTxn.HideShowIncidentType(ctx, imsdb.HideShowIncidentTypeParams{
+				Name:   it,
+				Hidden: change.hidden,
+			})
+			if err != nil {
+				return fmt.Errorf("[HideShowIncidentType]: %w", err)
+			}
+			hidden[it] = change.hidden
+			err = audit.record(ctx, dbTxn, auditChange{
+				action:     "update",
+				entityType: "incident_type",
+				entityID:   it,
+				before:     map[string]any{"hidden": wasHidden},
+				after:      map[string]any{"hidden": change.hidden},
+			})
+			if err != nil {
+				return fmt.Errorf("[record]: %w", err)
+			}
 		}
 	}
-	http.Error(w, "Success", http.StatusNoContent)
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("[Commit]: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/auth"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// changedStatePrefix starts the line of a system report entry that records a
+// change of an incident's state.
+const changedStatePrefix = "Changed state: "
+
+// maxMetricsHours caps the length of the hourly series, in case an incident
+// has a wildly wrong time.
+const maxMetricsHours = 366 * 24
+
+type GetIncidentMetrics struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP computes response times and incident counts for an event. It takes
+// the same filters as the incident list, e.g. "priority" or "created_after",
+// to narrow down the incidents that are counted.
+func (action GetIncidentMetrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, _, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	if eventPermissions&auth.EventReadIncidents == 0 {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have EventReadIncidents permission on this Event", nil)
+		return
+	}
+	if !mustParseForm(w, req) {
+		return
+	}
+	ctx := req.Context()
+	// The statistics are always for every matching incident, never just a page
+	form := maps.Clone(req.Form)
+	for _, param := range []string{"limit", "cursor", "sort"} {
+		delete(form, param)
+	}
+	query, err := parseIncidentsQuery(event.ID, form)
+	if err != nil {
+		handleErr(w, req, http.StatusBadRequest, "Invalid query", err)
+		return
+	}
+
+	incidentsRows, err := imsdb.New(action.imsDB).Incidents(ctx, query.params)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incidents", err)
+		return
+	}
+	transitions, err := incidentStateTransitions(ctx, action.imsDB, event.ID)
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Incident state changes", err)
+		return
+	}
+	var timelines []incidentTimeline
+	for _, r := range incidentsRows {
+		incidentTypes, err := unmarshalByteSlice[[]string](r.IncidentTypes)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to read Incident types", err)
+			return
+		}
+		timelines = append(timelines, incidentTimeline{
+			created:       r.Incident.Created,
+			priority:      r.Incident.Priority,
+			incidentTypes: incidentTypes,
+			state:         r.Incident.State,
+			transitions:   transitions[r.Incident.Number],
+		})
+	}
+	mustWriteJSON(w, incidentMetrics(timelines))
+}
+
+type stateTransition struct {
+	at    float64
+	state imsdb.IncidentState
+}
+
+// incidentTimeline is what the metrics need to know about an incident.
+type incidentTimeline struct {
+	created       float64
+	priority      int8
+	incidentTypes []string
+	// state is the incident's current state
+	state imsdb.IncidentState
+	// transitions are the changes of state, oldest first
+	transitions []stateTransition
+}
+
+// incidentStateTransitions gets the changes of state of the event's incidents,
+// keyed by incident number. These come from the incidents' history, plus the
+// system report entries of changes made before that history was kept.
+func incidentStateTransitions(ctx context.Context, imsDB *store.DB, eventID int32) (map[int32][]stateTransition, error) {
+	changeRows, err := imsdb.New(imsDB).IncidentStateChanges(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("[IncidentStateChanges]: %w", err)
+	}
+	fromHistory := make(map[int32][]stateTransition)
+	for _, cr := range changeRows {
+		var state imsdb.IncidentState
+		if err = json.Unmarshal([]byte(cr.NewValue), &state); err != nil {
+			return nil, fmt.Errorf("[Unmarshal]: %w", err)
+		}
+		fromHistory[cr.IncidentNumber] = append(fromHistory[cr.IncidentNumber], stateTransition{at: cr.Created, state: state})
+	}
+
+	entryRows, err := imsdb.New(imsDB).IncidentStateReportEntries(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("[IncidentStateReportEntries]: %w", err)
+	}
+	result := make(map[int32][]stateTransition)
+	for _, er := range entryRows {
+		// Once the history was kept, these entries just repeat it
+		if history := fromHistory[er.IncidentNumber]; len(history) > 0 && er.Created >= history[0].at {
+			continue
+		}
+		for _, line := range strings.Split(er.Text, "\n") {
+			state, ok := strings.CutPrefix(line, changedStatePrefix)
+			if ok && imsdb.IncidentState(state).Valid() {
+				result[er.IncidentNumber] = append(result[er.IncidentNumber], stateTransition{at: er.Created, state: imsdb.IncidentState(state)})
+			}
+		}
+	}
+	for num, history := range fromHistory {
+		result[num] = append(result[num], history...)
+	}
+	return result, nil
+}
+
+func incidentMetrics(timelines []incidentTimeline) imsjson.IncidentMetrics {
+	overall := &responseTimeStats{}
+	byPriority := make(map[int8]*responseTimeStats)
+	byType := make(map[string]*responseTimeStats)
+	for _, tl := range timelines {
+		groups := []*responseTimeStats{overall}
+		if byPriority[tl.priority] == nil {
+			byPriority[tl.priority] = &responseTimeStats{}
+		}
+		groups = append(groups, byPriority[tl.priority])
+		for _, it := range tl.incidentTypes {
+			if byType[it] == nil {
+				byType[it] = &responseTimeStats{}
+			}
+			groups = append(groups, byType[it])
+		}
+		for _, g := range groups {
+			g.add(tl)
+		}
+	}
+
+	result := imsjson.IncidentMetrics{
+		Overall:        overall.toJSON(),
+		ByPriority:     make(map[int8]imsjson.ResponseTimes),
+		ByIncidentType: make(map[string]imsjson.ResponseTimes),
+		Hourly:         hourlyIncidents(timelines),
+	}
+	for priority, stats := range byPriority {
+		result.ByPriority[priority] = stats.toJSON()
+	}
+	for it, stats := range byType {
+		result.ByIncidentType[it] = stats.toJSON()
+	}
+	return result
+}
+
+// responseTimeStats collects response times in seconds.
+type responseTimeStats struct {
+	incidents                      int
+	toDispatch, toOnScene, toClose []float64
+}
+
+func (s *responseTimeStats) add(tl incidentTimeline) {
+	s.incidents++
+	dispatched, onScene, closed := math.NaN(), math.NaN(), math.NaN()
+	for _, t := range tl.transitions {
+		switch t.state {
+		case imsdb.IncidentStateDispatched:
+			if math.IsNaN(dispatched) {
+				dispatched = t.at
+			}
+		case imsdb.IncidentStateOnScene:
+			if math.IsNaN(dispatched) {
+				dispatched = t.at
+			}
+			if math.IsNaN(onScene) {
+				onScene = t.at
+			}
+		case imsdb.IncidentStateClosed:
+			closed = t.at
+		}
+	}
+	if !math.IsNaN(dispatched) {
+		s.toDispatch = append(s.toDispatch, max(0, dispatched-tl.created))
+	}
+	if !math.IsNaN(onScene) {
+		s.toOnScene = append(s.toOnScene, max(0, onScene-tl.created))
+	}
+	if tl.state == imsdb.IncidentStateClosed && !math.IsNaN(closed) {
+		s.toClose = append(s.toClose, max(0, closed-tl.created))
+	}
+}
+
+func (s *responseTimeStats) toJSON() imsjson.ResponseTimes {
+	return imsjson.ResponseTimes{
+		Incidents:      s.incidents,
+		TimeToDispatch: durationStats(s.toDispatch),
+		TimeToOnScene:  durationStats(s.toOnScene),
+		TimeToClose:    durationStats(s.toClose),
+	}
+}
+
+func durationStats(seconds []float64) imsjson.DurationStats {
+	if len(seconds) == 0 {
+		return imsjson.DurationStats{}
+	}
+	sorted := slices.Sorted(slices.Values(seconds))
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+	}
+	// These are nearest-rank percentiles
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p * float64(len(sorted))))
+		return sorted[max(0, rank-1)]
+	}
+	return imsjson.DurationStats{
+		Count:         len(sorted),
+		MeanSeconds:   sum / float64(len(sorted)),
+		MedianSeconds: percentile(0.5),
+		P90Seconds:    percentile(0.9),
+		MaxSeconds:    sorted[len(sorted)-1],
+	}
+}
+
+// hourlyIncidents counts, for each hour, the incidents created in that hour
+// and those open at its end. An incident is open from its creation until it's
+// closed, and again whenever it's reopened.
+func hourlyIncidents(timelines []incidentTimeline) []imsjson.HourlyIncidents {
+	type openDelta struct {
+		at    float64
+		delta int
+	}
+	var deltas []openDelta
+	var created []float64
+	for _, tl := range timelines {
+		created = append(created, tl.created)
+		deltas = append(deltas, openDelta{tl.created, 1})
+		isClosed := false
+		for _, t := range tl.transitions {
+			switch {
+			case t.state == imsdb.IncidentStateClosed && !isClosed:
+				deltas = append(deltas, openDelta{t.at, -1})
+				isClosed = true
+			case t.state != imsdb.IncidentStateClosed && isClosed:
+				deltas = append(deltas, openDelta{t.at, 1})
+				isClosed = false
+			}
+		}
+	}
+	if len(deltas) == 0 {
+		return []imsjson.HourlyIncidents{}
+	}
+	slices.SortFunc(deltas, func(a, b openDelta) int { return cmp.Compare(a.at, b.at) })
+	slices.Sort(created)
+
+	first := time.Unix(int64(created[0]), 0).UTC().Truncate(time.Hour)
+	last := time.Unix(int64(deltas[len(deltas)-1].at), 0).UTC().Truncate(time.Hour)
+	if last.Sub(first) > maxMetricsHours*time.Hour {
+		first = last.Add(-maxMetricsHours * time.Hour)
+	}
+
+	var result []imsjson.HourlyIncidents
+	open, di, ci := 0, 0, 0
+	for hour := first; !hour.After(last); hour = hour.Add(time.Hour) {
+		end := float64(hour.Add(time.Hour).Unix())
+		h := imsjson.HourlyIncidents{Hour: hour}
+		for ; ci < len(created) && created[ci] < end; ci++ {
+			if created[ci] >= float64(hour.Unix()) {
+				h.Created++
+			}
+		}
+		for ; di < len(deltas) && deltas[di].at < end; di++ {
+			open += deltas[di].delta
+		}
+		h.Open = open
+		result = append(result, h)
+	}
+	return result
+}
@@ -2,12 +2,16 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"github.com/srabraham/ranger-ims-go/auth"
 	"github.com/srabraham/ranger-ims-go/conf"
 	"github.com/srabraham/ranger-ims-go/directory"
 	"github.com/srabraham/ranger-ims-go/store"
+	"log"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"runtime/debug"
 	"time"
 )
@@ -17,14 +21,19 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 		mux = http.NewServeMux()
 	}
 
-	jwter := auth.JWTer{SecretKey: cfg.Core.JWTSecret}
-	es := NewEventSourcerer()
+	jwter := mustNewJWTer(cfg.Core)
+	refreshTokens := auth.RefreshTokens{ImsDB: db, Lifetime: cfg.Core.RefreshTokenLifetime}
+	revocations := auth.Revocations{ImsDB: db}
+	loginThrottle := auth.NewLoginThrottle(cfg.LoginThrottle, db)
+	es := NewEventSourcerer(db, cfg.Core.Admins, NewBroadcaster(cfg.Core.Broadcast, db))
+	attachments := newAttachments(cfg.AttachmentsStore)
 
 	mux.Handle("GET /ims/api/access",
 		Adapt(
 			GetEventAccesses{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -32,8 +41,19 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/access",
 		Adapt(
 			PostEventAccess{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/audit",
+		Adapt(
+			GetAudit{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -41,11 +61,15 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/auth",
 		Adapt(
 			PostAuth{
-				imsDB:       db,
-				userStore:   userStore,
-				jwtSecret:   cfg.Core.JWTSecret,
-				jwtDuration: cfg.Core.TokenLifetime,
+				imsDB:          db,
+				userStore:      userStore,
+				jwter:          jwter,
+				jwtDuration:    cfg.Core.TokenLifetime,
+				refreshTokens:  refreshTokens,
+				throttle:       loginThrottle,
+				clientIPHeader: cfg.LoginThrottle.ClientIPHeader,
 			},
+			AssignRequestID(),
 			RecoverOnPanic(),
 			LogBeforeAfter(),
 			// This endpoint does not require authentication, nor
@@ -54,16 +78,73 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 		),
 	)
 
+	mux.Handle("POST /ims/api/auth/refresh",
+		Adapt(
+			PostAuthRefresh{
+				userStore:     userStore,
+				jwter:         jwter,
+				jwtDuration:   cfg.Core.TokenLifetime,
+				refreshTokens: refreshTokens,
+			},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			LogBeforeAfter(),
+			// Like POST /ims/api/auth, this takes no Authorization header.
+			// The refresh token in the body is the credential.
+		),
+	)
+
+	mux.Handle("POST /ims/api/auth/logout",
+		Adapt(
+			PostAuthLogout{refreshTokens: refreshTokens, revocations: revocations},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("POST /ims/api/auth/revoke",
+		Adapt(
+			PostAuthRevoke{imsDB: db, imsAdmins: cfg.Core.Admins, jwtDuration: cfg.Core.TokenLifetime},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/auth/lockouts",
+		Adapt(
+			GetAuthLockouts{imsDB: db, imsAdmins: cfg.Core.Admins, throttle: loginThrottle},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/auth/jwks.json",
+		Adapt(
+			GetAuthJWKS{jwter: jwter},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			// This endpoint does not require authentication, by design
+			LogBeforeAfter(),
+		),
+	)
+
 	mux.Handle("GET /ims/api/auth",
 		Adapt(
 			GetAuth{
-				imsDB:     db,
-				jwtSecret: cfg.Core.JWTSecret,
-				admins:    cfg.Core.Admins,
+				imsDB:       db,
+				admins:      cfg.Core.Admins,
+				attachments: attachments,
 			},
+			AssignRequestID(),
 			RecoverOnPanic(),
 			// This endpoint does not require authentication or authorization, by design
-			OptionalAuthN(jwter),
+			OptionalAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -71,8 +152,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/events/{eventName}/incidents",
 		Adapt(
 			GetIncidents{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -80,8 +162,29 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/incidents",
 		Adapt(
 			NewIncident{imsDB: db, es: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/incidents/export",
+		Adapt(
+			ExportIncidents{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/incidents/dossier",
+		Adapt(
+			GetIncidentsDossier{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -89,8 +192,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/events/{eventName}/incidents/{incidentNumber}",
 		Adapt(
 			GetIncident{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -98,8 +202,29 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/incidents/{incidentNumber}",
 		Adapt(
 			EditIncident{imsDB: db, es: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/incidents/{incidentNumber}/history",
+		Adapt(
+			GetIncidentHistory{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/incidents/{incidentNumber}/dossier",
+		Adapt(
+			GetIncidentDossier{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -107,8 +232,29 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/incidents/{incidentNumber}/report_entries/{reportEntryId}",
 		Adapt(
 			EditIncidentReportEntry{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("POST /ims/api/events/{eventName}/incidents/{incidentNumber}/attachments",
+		Adapt(
+			AttachToIncident{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins, attachments: attachments},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/incidents/{incidentNumber}/attachments/{attachmentNumber}",
+		Adapt(
+			GetIncidentAttachment{imsDB: db, imsAdmins: cfg.Core.Admins, attachments: attachments},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -116,8 +262,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/events/{eventName}/field_reports",
 		Adapt(
 			GetFieldReports{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -125,8 +272,19 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/field_reports",
 		Adapt(
 			NewFieldReport{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/field_reports/export",
+		Adapt(
+			ExportFieldReports{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -134,8 +292,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/events/{eventName}/field_reports/{fieldReportNumber}",
 		Adapt(
 			GetFieldReport{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -143,8 +302,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/field_reports/{fieldReportNumber}",
 		Adapt(
 			EditFieldReport{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -152,8 +312,49 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events/{eventName}/field_reports/{fieldReportNumber}/report_entries/{reportEntryId}",
 		Adapt(
 			EditFieldReportReportEntry{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("POST /ims/api/events/{eventName}/field_reports/{fieldReportNumber}/attachments",
+		Adapt(
+			AttachToFieldReport{imsDB: db, eventSource: es, imsAdmins: cfg.Core.Admins, attachments: attachments},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/field_reports/{fieldReportNumber}/attachments/{attachmentNumber}",
+		Adapt(
+			GetFieldReportAttachment{imsDB: db, imsAdmins: cfg.Core.Admins, attachments: attachments},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/metrics",
+		Adapt(
+			GetIncidentMetrics{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("GET /ims/api/events/{eventName}/search",
+		Adapt(
+			GetSearch{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -161,8 +362,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/events",
 		Adapt(
 			GetEvents{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -170,8 +372,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/events",
 		Adapt(
 			EditEvents{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -179,8 +382,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/streets",
 		Adapt(
 			GetStreets{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -188,8 +392,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/streets",
 		Adapt(
 			EditStreets{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -197,8 +402,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/incident_types",
 		Adapt(
 			GetIncidentTypes{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -206,8 +412,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("POST /ims/api/incident_types",
 		Adapt(
 			EditIncidentTypes{imsDB: db, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -215,16 +422,31 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig, db *store.DB, userStore *
 	mux.Handle("GET /ims/api/personnel",
 		Adapt(
 			GetPersonnel{imsDB: db, userStore: userStore, imsAdmins: cfg.Core.Admins},
+			AssignRequestID(),
 			RecoverOnPanic(),
-			RequireAuthN(jwter),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
 
 	mux.Handle("GET /ims/api/eventsource",
 		Adapt(
-			es.Server.Handler(EventSourceChannel),
+			GetEventSource{es: es},
+			AssignRequestID(),
 			RecoverOnPanic(),
+			// Browsers' EventSource can't set an Authorization header,
+			// so this also accepts a ticket in the query string.
+			RequireAuthNOrEventSourceTicket(jwter, revocations),
+			LogBeforeAfter(),
+		),
+	)
+
+	mux.Handle("POST /ims/api/eventsource/ticket",
+		Adapt(
+			PostEventSourceTicket{jwter: jwter},
+			AssignRequestID(),
+			RecoverOnPanic(),
+			RequireAuthN(jwter, revocations),
 			LogBeforeAfter(),
 		),
 	)
@@ -263,6 +485,7 @@ func LogBeforeAfter() Adapter {
 			}
 			slog.Debug("Done serving request",
 				"duration", time.Since(start).Round(100*time.Microsecond),
+				"requestID", requestID(r),
 				"method", r.Method,
 				"path", r.URL.Path,
 				"user", username,
@@ -271,6 +494,32 @@ func LogBeforeAfter() Adapter {
 	}
 }
 
+// AssignRequestID gives each request an ID, which is returned in the
+// X-Request-ID response header and recorded in the logs and the audit log.
+// An X-Request-ID set by the client or by a proxy in front of IMS is kept,
+// so long as it's short and plain enough to be stored.
+func AssignRequestID() Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if !validRequestID.MatchString(id) {
+				id = rand.Text()
+			}
+			w.Header().Set("X-Request-ID", id)
+			ctx := context.WithValue(r.Context(), RequestIDContextKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+var validRequestID = regexp.MustCompile(`^[\w.:-]{1,64}$`)
+
+// requestID gets the ID that AssignRequestID gave the request.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDContextKey).(string)
+	return id
+}
+
 func RecoverOnPanic() Adapter {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -290,6 +539,24 @@ type ContextKey string
 
 const JWTContextKey ContextKey = "JWTContext"
 
+const RequestIDContextKey ContextKey = "RequestID"
+
+// mustNewJWTer makes the JWTer, with the signing keys if they're configured.
+// It panics if they can't be loaded, since then the server is of no use.
+func mustNewJWTer(cfg conf.ConfigCore) auth.JWTer {
+	jwter := auth.JWTer{SecretKey: cfg.JWTSecret}
+	if cfg.JWTSigningKey == "" {
+		return jwter
+	}
+	keys, err := auth.LoadKeySet(cfg.JWTSigningKey, cfg.JWTVerificationKeys)
+	if err != nil {
+		log.Panicf("Failed to load JWT keys: %v", err)
+	}
+	slog.Info("Signing JWTs with key", "kid", keys.SigningKeyID(), "keys", len(keys.JWKS().Keys))
+	jwter.Keys = keys
+	return jwter
+}
+
 type JWTContext struct {
 	Claims *auth.IMSClaims
 	Error  error
@@ -302,11 +569,16 @@ type PermissionsContext struct {
 	GlobalPermissions auth.GlobalPermissionMask
 }
 
-func OptionalAuthN(j auth.JWTer) Adapter {
+func OptionalAuthN(j auth.JWTer, revocations auth.Revocations) Adapter {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
 			claims, err := j.AuthenticateJWT(header)
+			if err == nil {
+				if err = revocations.Check(r.Context(), *claims); err != nil {
+					claims = nil
+				}
+			}
 			ctx := context.WithValue(r.Context(), JWTContextKey, JWTContext{
 				Claims: claims,
 				Error:  err,
@@ -316,7 +588,7 @@ func OptionalAuthN(j auth.JWTer) Adapter {
 	}
 }
 
-func RequireAuthN(j auth.JWTer) Adapter {
+func RequireAuthN(j auth.JWTer, revocations auth.Revocations) Adapter {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
@@ -331,6 +603,37 @@ func RequireAuthN(j auth.JWTer) Adapter {
 				http.Error(w, "Invalid Authorization token", http.StatusUnauthorized)
 				return
 			}
+			if !checkNotRevoked(w, r, revocations, *claims) {
+				return
+			}
+			jwtCtx := context.WithValue(r.Context(), JWTContextKey, JWTContext{
+				Claims: claims,
+				Error:  err,
+			})
+			next.ServeHTTP(w, r.WithContext(jwtCtx))
+		})
+	}
+}
+
+// RequireAuthNOrEventSourceTicket is like RequireAuthN, but it also accepts
+// an EventSource ticket in the "ticket" query parameter.
+func RequireAuthNOrEventSourceTicket(j auth.JWTer, revocations auth.Revocations) Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" {
+				RequireAuthN(j, revocations)(next).ServeHTTP(w, r)
+				return
+			}
+			claims, err := j.AuthenticateEventSourceTicket(ticket)
+			if err != nil || claims == nil {
+				slog.Error("Failed to authenticate EventSource ticket", "error", err)
+				http.Error(w, "Invalid EventSource ticket", http.StatusUnauthorized)
+				return
+			}
+			if !checkNotRevoked(w, r, revocations, *claims) {
+				return
+			}
 			jwtCtx := context.WithValue(r.Context(), JWTContextKey, JWTContext{
 				Claims: claims,
 				Error:  err,
@@ -340,6 +643,22 @@ func RequireAuthN(j auth.JWTer) Adapter {
 	}
 }
 
+// checkNotRevoked writes an error response if the token has been revoked.
+func checkNotRevoked(w http.ResponseWriter, r *http.Request, revocations auth.Revocations, claims auth.IMSClaims) bool {
+	err := revocations.Check(r.Context(), claims)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		slog.Error("Revoked JWT", "user", claims.RangerHandle())
+		http.Error(w, "Invalid Authorization token", http.StatusUnauthorized)
+		return false
+	}
+	if err != nil {
+		slog.Error("Failed to check for JWT revocation", "error", err)
+		http.Error(w, "Failed to check Authorization token", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 func Adapt(handler http.Handler, adapters ...Adapter) http.Handler {
 	for i := range adapters {
 		adapter := adapters[len(adapters)-1-i] // range in reverse
@@ -76,6 +76,17 @@ func (action EditFieldReportReportEntry) ServeHTTP(w http.ResponseWriter, req *h
 		handleErr(w, req, http.StatusInternalServerError, "Error adding report entry", err)
 		return
 	}
+	err = newAuditor(req).record(ctx, dbTxn, auditChange{
+		action:     "update",
+		entityType: "report_entry",
+		eventID:    event.ID,
+		entityID:   fmt.Sprint(reportEntryId),
+		after:      map[string]any{"stricken": re.Stricken},
+	})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Error recording report entry change in audit log", err)
+		return
+	}
 	if err = txn.Commit(); err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Error committing transaction", err)
 		return
@@ -152,6 +163,17 @@ func (action EditIncidentReportEntry) ServeHTTP(w http.ResponseWriter, req *http
 		handleErr(w, req, http.StatusInternalServerError, "Error adding report entry", err)
 		return
 	}
+	err = newAuditor(req).record(ctx, dbTxn, auditChange{
+		action:     "update",
+		entityType: "report_entry",
+		eventID:    event.ID,
+		entityID:   fmt.Sprint(reportEntryId),
+		after:      map[string]any{"stricken": re.Stricken},
+	})
+	if err != nil {
+		handleErr(w, req, http.StatusInternalServerError, "Error recording report entry change in audit log", err)
+		return
+	}
 	if err = txn.Commit(); err != nil {
 		handleErr(w, req, http.StatusInternalServerError, "Error committing transaction", err)
 		return
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"cmp"
+	"database/sql"
+	"github.com/srabraham/ranger-ims-go/auth"
+	imsjson "github.com/srabraham/ranger-ims-go/json"
+	"github.com/srabraham/ranger-ims-go/store"
+	"html"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+	// maxSearchHits caps how many matching pieces of text each search query reads
+	maxSearchHits = 2000
+	// maxSearchTerms caps how many words of the search are used
+	maxSearchTerms = 20
+	// maxSnippets is the most snippets returned for any one result
+	maxSnippets = 3
+	// snippetContext is about how many bytes of text to show on each side of a match
+	snippetContext = 60
+)
+
+type GetSearch struct {
+	imsDB     *store.DB
+	imsAdmins []string
+}
+
+// ServeHTTP runs a full-text search over an event's incidents and field reports,
+// including their report entries. The "q" query parameter is the search, and
+// any of its words may match. The optional "limit" parameter caps the number of
+// results, which come back best match first.
+//
+// The results only include what the requestor may read. In particular, someone
+// who may only read their own field reports only gets back field reports they
+// wrote an entry on.
+func (action GetSearch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, jwtCtx, eventPermissions, ok := mustGetEventPermissions(w, req, action.imsDB, action.imsAdmins)
+	if !ok {
+		return
+	}
+	readIncidents := eventPermissions&auth.EventReadIncidents != 0
+	readFieldReports := eventPermissions&(auth.EventReadAllFieldReports|auth.EventReadOwnFieldReports) != 0
+	if !readIncidents && !readFieldReports {
+		handleErr(w, req, http.StatusForbidden, "The requestor does not have permission to search this Event", nil)
+		return
+	}
+	if ok = mustParseForm(w, req); !ok {
+		return
+	}
+	terms := searchTerms(req.Form.Get("q"))
+	if len(terms) == 0 {
+		handleErr(w, req, http.StatusBadRequest, "The q parameter must contain at least one word to search for", nil)
+		return
+	}
+	limit := defaultSearchLimit
+	if limitParam := req.Form.Get("limit"); limitParam != "" {
+		num, err := strconv.Atoi(limitParam)
+		if err != nil || num <= 0 || num > maxSearchLimit {
+			handleErr(w, req, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = num
+	}
+
+	resp := make(imsjson.SearchResults, 0)
+	if readIncidents {
+		hits, err := action.imsDB.SearchIncidents(req.Context(), event.ID, terms, maxSearchHits)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to search Incidents", err)
+			return
+		}
+		resp = append(resp, searchResults("incident", hits, terms)...)
+	}
+	if readFieldReports {
+		// i.e. the user has EventReadOwnFieldReports, but not EventReadAllFieldReports
+		var author sql.NullString
+		if eventPermissions&auth.EventReadAllFieldReports == 0 {
+			author = sql.NullString{String: jwtCtx.Claims.RangerHandle(), Valid: true}
+		}
+		hits, err := action.imsDB.SearchFieldReports(req.Context(), event.ID, terms, author, maxSearchHits)
+		if err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to search Field Reports", err)
+			return
+		}
+		resp = append(resp, searchResults("field_report", hits, terms)...)
+	}
+
+	slices.SortStableFunc(resp, func(a, b imsjson.SearchResult) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			strings.Compare(a.Type, b.Type),
+			cmp.Compare(a.Number, b.Number),
+		)
+	})
+	if len(resp) > limit {
+		resp = resp[:limit]
+	}
+	mustWriteJSON(w, resp)
+}
+
+// searchTerms splits a search into lowercase words, without duplicates.
+func searchTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(q), isNotWordRune) {
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// searchResults combines the hits into one result per record. A record's score
+// is the sum of its hits' scores, and its snippets come from its best hits.
+func searchResults(recordType string, hits []store.SearchHit, terms []string) []imsjson.SearchResult {
+	var results []imsjson.SearchResult
+	indexes := make(map[int32]int)
+	for _, hit := range hits {
+		i, ok := indexes[hit.Number]
+		if !ok {
+			i = len(results)
+			indexes[hit.Number] = i
+			results = append(results, imsjson.SearchResult{
+				Type:     recordType,
+				Number:   hit.Number,
+				Summary:  stringOrNil(hit.Summary),
+				Snippets: []string{},
+			})
+		}
+		results[i].Score += hit.Score
+		if len(results[i].Snippets) < maxSnippets {
+			results[i].Snippets = append(results[i].Snippets, snippet(hit.Text, terms))
+		}
+	}
+	return results
+}
+
+// snippet excerpts the text around the first of the terms found in it. The
+// result is HTML, with the terms in <mark> elements and everything else escaped.
+func snippet(text string, terms []string) string {
+	type span struct{ start, end int }
+	var matches []span
+	wordStart := -1
+	for i, r := range text + " " {
+		switch {
+		case !isNotWordRune(r) && wordStart < 0:
+			wordStart = i
+		case isNotWordRune(r) && wordStart >= 0:
+			if slices.Contains(terms, strings.ToLower(text[wordStart:i])) {
+				matches = append(matches, span{wordStart, i})
+			}
+			wordStart = -1
+		}
+	}
+
+	// Center the excerpt on the first match, or start at the beginning if the
+	// database matched something this didn't, e.g. a word without its accents.
+	start, end := 0, min(len(text), 2*snippetContext)
+	if len(matches) > 0 {
+		start = max(0, matches[0].start-snippetContext)
+		end = min(len(text), matches[0].end+snippetContext)
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		sb.WriteString(html.EscapeString(text[pos:m.start]))
+		sb.WriteString("<mark>")
+		sb.WriteString(html.EscapeString(text[m.start:m.end]))
+		sb.WriteString("</mark>")
+		pos = m.end
+	}
+	sb.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
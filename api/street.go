@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"github.com/srabraham/ranger-ims-go/auth"
 	imsjson "github.com/srabraham/ranger-ims-go/json"
 	"github.com/srabraham/ranger-ims-go/store"
@@ -81,33 +83,74 @@ func (action EditStreets) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if !ok {
 		return
 	}
+	audit := newAuditor(req)
 	for eventName, newEventStreets := range eventsStreets {
 		event, ok := mustGetEvent(w, req, eventName, action.imsDB)
 		if !ok {
 			return
 		}
-		currentStreets, err := imsdb.New(action.imsDB).ConcentricStreets(req.Context(), event.ID)
-		if err != nil {
-			handleErr(w, req, http.StatusInternalServerError, "Failed to fetch Streets", err)
+		if err := addStreets(ctx, action.imsDB, audit, event, newEventStreets); err != nil {
+			handleErr(w, req, http.StatusInternalServerError, "Failed to create Streets", err)
 			return
 		}
-		currentStreetIDs := make(map[string]bool)
-		for _, street := range currentStreets {
-			currentStreetIDs[street.ConcentricStreet.ID] = true
+	}
+	http.Error(w, "Success", http.StatusNoContent)
+}
+
+// addStreets creates those of the streets that the event doesn't already have.
+func addStreets(ctx context.Context, imsDB *store.DB, audit auditor, event imsdb.Event, streets imsjson.EventStreets) error {
+	txn, err := imsDB.Begin()
+	if err != nil {
+		return fmt.Errorf("[Begin]: %w", err)
+	}
+	defer txn.Rollback()
+	dbTxn := imsdb.New(txn)
+	currentStreets, err := dbTxn.ConcentricStreets(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("[ConcentricStreets]: %w", err)
+	}
+	currentStreetIDs := make(map[string]bool)
+	for _, street := range currentStreets {
+		currentStreetIDs[street.ConcentricStreet.ID] = true
+	}
+	for streetID, streetName := range streets {
+		if currentStreetIDs[streetID] {
+			continue
+		}
+		err = dbTxn.CreateConcentricStreet(ctx, imsdb.CreateConcentricStreetParams{
+			Event: event.ID,
+			ID:    streetID,
+			Name:  streetName,
+		})
+		if err != nil {
+			return fmt.Errorf("[CreateConcentricStreet]: %w", err)
 		}
-		for streetID, streetName := range newEventStreets {
-			if !currentStreetIDs[streetID] {
-				err = imsdb.New(action.imsDB).CreateConcentricStreet(ctx, imsdb.CreateConcentricStreetParams{
-					Event: event.ID,
-					ID:    streetID,
-					Name:  streetName,
-				})
-				if err != nil {
-					handleErr(w, req, http.StatusInternalServerError, "Failed to create Street", err)
-					return
-				}
-			}
+		err = audit.record(ctx, dbTxn, auditChange{
+			action:     "create",
+			entityType: "street",
+			eventID:    event.ID,
+			entityID:   streetID,
+			after:      map[string]any{"name": streetName},
+		})
+		if err != nil {
+			return fmt.Errorf("[record]: %w", err)
 		}
 	}
-	http.Error(w, "Success", http.StatusNoContent)
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("[Commit]: %w", err)
+	}
+	return nil
+}
+
+// concentricStreetNames maps the IDs of an event's concentric streets to their names.
+func concentricStreetNames(ctx context.Context, imsDB *store.DB, eventID int32) (map[string]string, error) {
+	streets, err := imsdb.New(imsDB).ConcentricStreets(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("[ConcentricStreets]: %w", err)
+	}
+	names := make(map[string]string)
+	for _, s := range streets {
+		names[s.ConcentricStreet.ID] = s.ConcentricStreet.Name
+	}
+	return names, nil
 }
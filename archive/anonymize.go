@@ -0,0 +1,215 @@
+package archive
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[\p{L}\p{N}._%+-]+@[\p{L}\p{N}-]+(\.[\p{L}\p{N}-]+)+`)
+	// phonePattern finds runs of digits and phone punctuation. Only those with
+	// enough digits to be a phone number are scrubbed, so that times and dates
+	// are left alone.
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d ().-]{6,}\d`)
+)
+
+const minPhoneDigits = 10
+
+// Anonymizer takes people out of an archive, so that it can be shared. It
+//
+//   - replaces Ranger handles and report entry authors with pseudonyms,
+//     including where the handles appear in text
+//   - scrubs email addresses, phone numbers, and any other given patterns
+//     from summaries, locations, and report entries
+//   - drops stricken report entries, the names of attached files, and the
+//     event's access rules
+//
+// A pseudonym is derived from the handle and the key, so it's the same in
+// every archive made with the same key. Handles are matched in text without
+// regard to case, so a handle that's also a common word gets replaced
+// wherever that word appears.
+type Anonymizer struct {
+	key      []byte
+	patterns []*regexp.Regexp
+	// handles matches any of the handles in the archive
+	handles *regexp.Regexp
+}
+
+// NewAnonymizer returns an Anonymizer that derives pseudonyms from key, and
+// that also scrubs the given regular expressions from text.
+func NewAnonymizer(key string, scrubPatterns []string) (*Anonymizer, error) {
+	if key == "" {
+		return nil, fmt.Errorf("a pseudonym key is required")
+	}
+	a := &Anonymizer{key: []byte(key)}
+	for _, p := range scrubPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("[Compile]: %w", err)
+		}
+		a.patterns = append(a.patterns, re)
+	}
+	return a, nil
+}
+
+// Pseudonym is the stand-in for a handle.
+func (a *Anonymizer) Pseudonym(handle string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(strings.ToLower(handle)))
+	return "ranger-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+func (a *Anonymizer) anonymize(records []Record) []Record {
+	a.learnHandles(records)
+	var result []Record
+	for _, r := range records {
+		switch {
+		case r.Header != nil:
+			h := *r.Header
+			h.Anonymized = true
+			r.Header = &h
+		case r.Access != nil:
+			continue
+		case r.Incident != nil:
+			i := *r.Incident
+			i.Summary = a.scrubPtr(i.Summary)
+			i.LocationName = a.scrubPtr(i.LocationName)
+			i.LocationDescription = a.scrubPtr(i.LocationDescription)
+			var rangers []string
+			for _, h := range i.RangerHandles {
+				rangers = append(rangers, a.Pseudonym(h))
+			}
+			i.RangerHandles = rangers
+			i.ReportEntries = a.entries(i.ReportEntries)
+			r.Incident = &i
+		case r.FieldReport != nil:
+			fr := *r.FieldReport
+			fr.Summary = a.scrubPtr(fr.Summary)
+			fr.ReportEntries = a.entries(fr.ReportEntries)
+			r.FieldReport = &fr
+		case r.IncidentChange != nil:
+			c := *r.IncidentChange
+			c.Author = a.Pseudonym(c.Author)
+			c.OldValue = a.scrub(c.OldValue)
+			c.NewValue = a.scrub(c.NewValue)
+			r.IncidentChange = &c
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// learnHandles gathers every handle in the records, so that they can be found
+// in text. Longer handles come first in the pattern, so that a handle that
+// contains another is replaced whole.
+func (a *Anonymizer) learnHandles(records []Record) {
+	seen := make(map[string]bool)
+	learn := func(handle string) {
+		if strings.TrimSpace(handle) != "" {
+			seen[strings.ToLower(handle)] = true
+		}
+	}
+	for _, r := range records {
+		switch {
+		case r.Incident != nil:
+			for _, h := range r.Incident.RangerHandles {
+				learn(h)
+			}
+			for _, e := range r.Incident.ReportEntries {
+				learn(e.Author)
+			}
+		case r.FieldReport != nil:
+			for _, e := range r.FieldReport.ReportEntries {
+				learn(e.Author)
+			}
+		case r.IncidentChange != nil:
+			learn(r.IncidentChange.Author)
+		}
+	}
+	if len(seen) == 0 {
+		return
+	}
+	var handles []string
+	for h := range seen {
+		handles = append(handles, h)
+	}
+	slices.SortFunc(handles, func(x, y string) int {
+		if len(x) != len(y) {
+			return len(y) - len(x)
+		}
+		return strings.Compare(x, y)
+	})
+	var alternatives []string
+	for _, h := range handles {
+		alternatives = append(alternatives, wordBoundary(h, true)+regexp.QuoteMeta(h)+wordBoundary(h, false))
+	}
+	a.handles = regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+}
+
+// wordBoundary is `\b` if the handle starts (or ends) with a word character,
+// so that handles only match whole words.
+func wordBoundary(handle string, start bool) string {
+	var r rune
+	if start {
+		r, _ = utf8.DecodeRuneInString(handle)
+	} else {
+		r, _ = utf8.DecodeLastRuneInString(handle)
+	}
+	if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return `\b`
+	}
+	return ""
+}
+
+func (a *Anonymizer) entries(entries []ReportEntry) []ReportEntry {
+	var result []ReportEntry
+	for _, e := range entries {
+		if e.Stricken {
+			continue
+		}
+		e.Author = a.Pseudonym(e.Author)
+		e.Text = a.scrub(e.Text)
+		e.AttachedFile = nil
+		e.AttachedFileName = nil
+		result = append(result, e)
+	}
+	return result
+}
+
+func (a *Anonymizer) scrubPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	scrubbed := a.scrub(*s)
+	return &scrubbed
+}
+
+func (a *Anonymizer) scrub(s string) string {
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	s = phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minPhoneDigits {
+			return m
+		}
+		return "[phone]"
+	})
+	for _, p := range a.patterns {
+		s = p.ReplaceAllString(s, "[redacted]")
+	}
+	if a.handles != nil {
+		s = a.handles.ReplaceAllStringFunc(s, a.Pseudonym)
+	}
+	return s
+}
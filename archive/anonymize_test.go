@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	anon, err := NewAnonymizer("secret", []string{`BRC-\d+`})
+	require.NoError(t, err)
+	tool := anon.Pseudonym("Tool")
+	hardware := anon.Pseudonym("Hardware")
+	require.Equal(t, tool, anon.Pseudonym("tool"))
+	require.NotEqual(t, tool, hardware)
+	require.Regexp(t, `^ranger-[0-9a-f]{10}$`, tool)
+
+	summary := "Tool lost a camera, call (415) 555-0100"
+	records := []Record{
+		{Header: &Header{Format: FormatName, Version: FormatVersion, Event: "2024"}},
+		{Access: &Access{Expression: "person:Tool", Mode: "write", Validity: "always"}},
+		{Incident: &Incident{
+			Number:        1,
+			Summary:       &summary,
+			RangerHandles: []string{"Tool"},
+			ReportEntries: []ReportEntry{
+				{ID: 1, Author: "Hardware", Text: "Spoke to TOOL at tool@example.com about BRC-1234 on 2024-08-30 at 14:05"},
+				{ID: 2, Author: "Hardware", Text: "Wrong camera", Stricken: true},
+				{ID: 3, Author: "Hardware", Text: "Toolbox found", AttachedFile: &summary},
+			},
+		}},
+		{FieldReport: &FieldReport{Number: 1, ReportEntries: []ReportEntry{{ID: 4, Author: "Tool", Text: "+1 415 555 0100"}}}},
+		{IncidentChange: &IncidentChange{IncidentNumber: 1, Author: "Hardware", Field: "ranger_handles", OldValue: `[]`, NewValue: `["Tool"]`}},
+	}
+	result := anon.anonymize(records)
+
+	require.Len(t, result, 4)
+	require.True(t, result[0].Header.Anonymized)
+	require.False(t, records[0].Header.Anonymized)
+
+	incident := result[1].Incident
+	require.Equal(t, tool+" lost a camera, call [phone]", *incident.Summary)
+	require.Equal(t, []string{tool}, incident.RangerHandles)
+	require.Len(t, incident.ReportEntries, 2)
+	require.Equal(t, hardware, incident.ReportEntries[0].Author)
+	require.Equal(t, "Spoke to "+tool+" at [email] about [redacted] on 2024-08-30 at 14:05", incident.ReportEntries[0].Text)
+	// Handles only match whole words
+	require.Equal(t, "Toolbox found", incident.ReportEntries[1].Text)
+	require.Nil(t, incident.ReportEntries[1].AttachedFile)
+
+	require.Equal(t, tool, result[2].FieldReport.ReportEntries[0].Author)
+	require.Equal(t, "[phone]", result[2].FieldReport.ReportEntries[0].Text)
+
+	change := result[3].IncidentChange
+	require.Equal(t, hardware, change.Author)
+	require.Equal(t, `["`+tool+`"]`, change.NewValue)
+
+	// The originals are untouched
+	require.Equal(t, "Tool", records[2].Incident.RangerHandles[0])
+
+	// Another key gives other pseudonyms
+	other, err := NewAnonymizer("other", nil)
+	require.NoError(t, err)
+	require.NotEqual(t, tool, other.Pseudonym("Tool"))
+
+	_, err = NewAnonymizer("", nil)
+	require.Error(t, err)
+	_, err = NewAnonymizer("secret", []string{"("})
+	require.Error(t, err)
+}
@@ -0,0 +1,382 @@
+// Package archive moves an event's data between IMS databases. An archive is
+// a JSON-lines file: a header line, then one line per record, each with just
+// one of Record's fields set.
+//
+// Report entries are numbered by the database, so they're renumbered on import.
+// The files attached to report entries aren't in the archive, just their keys.
+package archive
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// FormatName identifies an IMS event archive.
+	FormatName = "ranger-ims-event-archive"
+	// FormatVersion is the version of the archive format that this build
+	// writes. It reads this version and any earlier one.
+	FormatVersion = 1
+)
+
+// Record is one line of an archive.
+type Record struct {
+	Header         *Header         `json:"header,omitempty"`
+	Street         *Street         `json:"street,omitempty"`
+	Access         *Access         `json:"access,omitempty"`
+	IncidentType   *IncidentType   `json:"incident_type,omitempty"`
+	Incident       *Incident       `json:"incident,omitempty"`
+	FieldReport    *FieldReport    `json:"field_report,omitempty"`
+	IncidentChange *IncidentChange `json:"incident_change,omitempty"`
+}
+
+// Header is the first line of an archive.
+type Header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// SchemaVersion is the schema version of the database that the archive
+	// was exported from.
+	SchemaVersion int16     `json:"schema_version"`
+	Event         string    `json:"event"`
+	Exported      time.Time `json:"exported"`
+	// Anonymized is set when people's names and contact details have been
+	// taken out. See Anonymizer.
+	Anonymized bool `json:"anonymized,omitzero"`
+}
+
+type Street struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Access struct {
+	Expression string `json:"expression"`
+	Mode       string `json:"mode"`
+	Validity   string `json:"validity"`
+}
+
+// IncidentType is one of the incident types that the event's incidents use.
+// Types are global, so they're matched up by name on import.
+type IncidentType struct {
+	Name   string `json:"name"`
+	Hidden bool   `json:"hidden"`
+}
+
+// Times are kept as the database has them, in seconds since the epoch, so that
+// they survive the trip exactly.
+
+type Incident struct {
+	Number               int32         `json:"number"`
+	Created              float64       `json:"created"`
+	LastModified         float64       `json:"last_modified"`
+	Version              int32         `json:"version"`
+	Priority             int8          `json:"priority"`
+	State                string        `json:"state"`
+	Summary              *string       `json:"summary"`
+	LocationName         *string       `json:"location_name"`
+	LocationConcentric   *string       `json:"location_concentric"`
+	LocationRadialHour   *int16        `json:"location_radial_hour"`
+	LocationRadialMinute *int16        `json:"location_radial_minute"`
+	LocationDescription  *string       `json:"location_description"`
+	IncidentTypes        []string      `json:"incident_types"`
+	RangerHandles        []string      `json:"ranger_handles"`
+	ReportEntries        []ReportEntry `json:"report_entries"`
+}
+
+type FieldReport struct {
+	Number         int32         `json:"number"`
+	Created        float64       `json:"created"`
+	LastModified   float64       `json:"last_modified"`
+	Version        int32         `json:"version"`
+	Summary        *string       `json:"summary"`
+	IncidentNumber *int32        `json:"incident_number"`
+	ReportEntries  []ReportEntry `json:"report_entries"`
+}
+
+type ReportEntry struct {
+	// ID is the entry's ID in the database it was exported from. An entry
+	// that's in more than one place has the same ID in each.
+	ID           int32   `json:"id"`
+	Author       string  `json:"author"`
+	Text         string  `json:"text"`
+	Created      float64 `json:"created"`
+	Generated    bool    `json:"generated"`
+	Stricken     bool    `json:"stricken"`
+	AttachedFile *string `json:"attached_file"`
+	// AttachedFileName is the name AttachedFile was uploaded with. Older
+	// archives don't have it.
+	AttachedFileName *string `json:"attached_file_name,omitempty"`
+}
+
+type IncidentChange struct {
+	IncidentNumber int32   `json:"incident_number"`
+	Created        float64 `json:"created"`
+	Author         string  `json:"author"`
+	Field          string  `json:"field"`
+	OldValue       string  `json:"old_value"`
+	NewValue       string  `json:"new_value"`
+}
+
+// Summary counts what went into or came out of an archive.
+type Summary struct {
+	Event         string
+	Incidents     int
+	FieldReports  int
+	ReportEntries int
+}
+
+// Export writes an archive of the named event to w. It reads everything in one
+// transaction, so the archive is consistent even while the server is running.
+// With an Anonymizer, the archive leaves out what identifies people.
+func Export(ctx context.Context, imsDB *store.DB, eventName string, w io.Writer, anon *Anonymizer) (Summary, error) {
+	summary := Summary{Event: eventName}
+	records, err := readEvent(ctx, imsDB, eventName)
+	if err != nil {
+		return summary, err
+	}
+	if anon != nil {
+		records = anon.anonymize(records)
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err = enc.Encode(r); err != nil {
+			return summary, fmt.Errorf("[Encode]: %w", err)
+		}
+		switch {
+		case r.Incident != nil:
+			summary.Incidents++
+			summary.ReportEntries += len(r.Incident.ReportEntries)
+		case r.FieldReport != nil:
+			summary.FieldReports++
+			summary.ReportEntries += len(r.FieldReport.ReportEntries)
+		}
+	}
+	if err = bw.Flush(); err != nil {
+		return summary, fmt.Errorf("[Flush]: %w", err)
+	}
+	return summary, nil
+}
+
+// readEvent fetches all of an event's records, in the order they go in an archive.
+func readEvent(ctx context.Context, imsDB *store.DB, eventName string) ([]Record, error) {
+	schemaVersion, err := store.SchemaVersion(ctx, imsDB)
+	if err != nil {
+		return nil, fmt.Errorf("[SchemaVersion]: %w", err)
+	}
+	txn, err := imsDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+
+	eventRow, err := q.QueryEventID(ctx, eventName)
+	if err != nil {
+		return nil, fmt.Errorf("[QueryEventID]: %w", err)
+	}
+	event := eventRow.Event.ID
+
+	records := []Record{{Header: &Header{
+		Format:        FormatName,
+		Version:       FormatVersion,
+		SchemaVersion: schemaVersion,
+		Event:         eventName,
+		Exported:      time.Now().UTC(),
+	}}}
+
+	streets, err := q.ConcentricStreets(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[ConcentricStreets]: %w", err)
+	}
+	slices.SortFunc(streets, func(a, b imsdb.ConcentricStreetsRow) int {
+		return cmp.Compare(a.ConcentricStreet.ID, b.ConcentricStreet.ID)
+	})
+	for _, s := range streets {
+		records = append(records, Record{Street: &Street{ID: s.ConcentricStreet.ID, Name: s.ConcentricStreet.Name}})
+	}
+
+	accesses, err := q.EventAccess(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[EventAccess]: %w", err)
+	}
+	for _, a := range accesses {
+		records = append(records, Record{Access: &Access{
+			Expression: a.EventAccess.Expression,
+			Mode:       string(a.EventAccess.Mode),
+			Validity:   string(a.EventAccess.Validity),
+		}})
+	}
+
+	typeRows, err := q.ArchiveIncidentTypes(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[ArchiveIncidentTypes]: %w", err)
+	}
+	incidentTypes := make(map[int32][]string)
+	typesWritten := make(map[string]bool)
+	for _, r := range typeRows {
+		incidentTypes[r.IncidentNumber] = append(incidentTypes[r.IncidentNumber], r.IncidentType.Name)
+		if typesWritten[r.IncidentType.Name] {
+			continue
+		}
+		typesWritten[r.IncidentType.Name] = true
+		records = append(records, Record{IncidentType: &IncidentType{Name: r.IncidentType.Name, Hidden: r.IncidentType.Hidden}})
+	}
+
+	rangerRows, err := q.ArchiveIncidentRangers(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[ArchiveIncidentRangers]: %w", err)
+	}
+	rangers := make(map[int32][]string)
+	for _, r := range rangerRows {
+		rangers[r.IncidentNumber] = append(rangers[r.IncidentNumber], r.RangerHandle)
+	}
+	incidentEntryRows, err := q.Incidents_ReportEntries(ctx, imsdb.Incidents_ReportEntriesParams{
+		Event:     event,
+		Generated: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[Incidents_ReportEntries]: %w", err)
+	}
+	incidentEntries := make(map[int32][]ReportEntry)
+	for _, r := range incidentEntryRows {
+		incidentEntries[r.IncidentNumber] = append(incidentEntries[r.IncidentNumber], reportEntry(r.ReportEntry))
+	}
+	incidents, err := q.ArchiveIncidents(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[ArchiveIncidents]: %w", err)
+	}
+	for _, r := range incidents {
+		i := r.Incident
+		records = append(records, Record{Incident: &Incident{
+			Number:               i.Number,
+			Created:              i.Created,
+			LastModified:         i.LastModified,
+			Version:              i.Version,
+			Priority:             i.Priority,
+			State:                string(i.State),
+			Summary:              fromNullString(i.Summary),
+			LocationName:         fromNullString(i.LocationName),
+			LocationConcentric:   fromNullString(i.LocationConcentric),
+			LocationRadialHour:   fromNullInt16(i.LocationRadialHour),
+			LocationRadialMinute: fromNullInt16(i.LocationRadialMinute),
+			LocationDescription:  fromNullString(i.LocationDescription),
+			IncidentTypes:        incidentTypes[i.Number],
+			RangerHandles:        rangers[i.Number],
+			ReportEntries:        sortedEntries(incidentEntries[i.Number]),
+		}})
+	}
+
+	frEntryRows, err := q.FieldReports_ReportEntries(ctx, imsdb.FieldReports_ReportEntriesParams{
+		Event:     event,
+		Generated: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[FieldReports_ReportEntries]: %w", err)
+	}
+	frEntries := make(map[int32][]ReportEntry)
+	for _, r := range frEntryRows {
+		frEntries[r.FieldReportNumber] = append(frEntries[r.FieldReportNumber], reportEntry(r.ReportEntry))
+	}
+	fieldReports, err := q.FieldReports(ctx, imsdb.FieldReportsParams{
+		Event: event,
+		Limit: math.MaxInt32,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[FieldReports]: %w", err)
+	}
+	for _, r := range fieldReports {
+		fr := r.FieldReport
+		var incidentNumber *int32
+		if fr.IncidentNumber.Valid {
+			incidentNumber = &fr.IncidentNumber.Int32
+		}
+		records = append(records, Record{FieldReport: &FieldReport{
+			Number:         fr.Number,
+			Created:        fr.Created,
+			LastModified:   fr.LastModified,
+			Version:        fr.Version,
+			Summary:        fromNullString(fr.Summary),
+			IncidentNumber: incidentNumber,
+			ReportEntries:  sortedEntries(frEntries[fr.Number]),
+		}})
+	}
+
+	changes, err := q.ArchiveIncidentChanges(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("[ArchiveIncidentChanges]: %w", err)
+	}
+	for _, r := range changes {
+		c := r.IncidentChange
+		records = append(records, Record{IncidentChange: &IncidentChange{
+			IncidentNumber: c.IncidentNumber,
+			Created:        c.Created,
+			Author:         c.Author,
+			Field:          c.Field,
+			OldValue:       c.OldValue,
+			NewValue:       c.NewValue,
+		}})
+	}
+	return records, nil
+}
+
+func reportEntry(re imsdb.ReportEntry) ReportEntry {
+	return ReportEntry{
+		ID:               re.ID,
+		Author:           re.Author,
+		Text:             re.Text,
+		Created:          re.Created,
+		Generated:        re.Generated,
+		Stricken:         re.Stricken,
+		AttachedFile:     fromNullString(re.AttachedFile),
+		AttachedFileName: fromNullString(re.AttachedFileName),
+	}
+}
+
+// sortedEntries puts report entries in the order they were written.
+func sortedEntries(entries []ReportEntry) []ReportEntry {
+	slices.SortFunc(entries, func(a, b ReportEntry) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return entries
+}
+
+func fromNullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func fromNullInt16(i sql.NullInt16) *int16 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int16
+}
+
+func toNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func toNullInt16(i *int16) sql.NullInt16 {
+	if i == nil {
+		return sql.NullInt16{}
+	}
+	return sql.NullInt16{Int16: *i, Valid: true}
+}
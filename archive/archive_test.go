@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	q := imsdb.New(db)
+
+	id, err := q.CreateEvent(ctx, "2024")
+	require.NoError(t, err)
+	event := int32(id)
+	require.NoError(t, q.CreateConcentricStreet(ctx, imsdb.CreateConcentricStreetParams{Event: event, ID: "A", Name: "Atwood"}))
+	_, err = q.AddEventAccess(ctx, imsdb.AddEventAccessParams{
+		Event: event, Expression: "person:Tool", Mode: imsdb.EventAccessModeWrite, Validity: imsdb.EventAccessValidityOnsite,
+	})
+	require.NoError(t, err)
+	require.NoError(t, q.CreateIncidentTypeOrIgnore(ctx, imsdb.CreateIncidentTypeOrIgnoreParams{Name: "Lost", Hidden: true}))
+	require.NoError(t, q.ImportIncident(ctx, imsdb.ImportIncidentParams{
+		Event: event, Number: 7, Created: 1000.5, Priority: 3, State: imsdb.IncidentStateOnScene,
+		Summary:            sql.NullString{String: "Lost camera", Valid: true},
+		LocationConcentric: sql.NullString{String: "A", Valid: true},
+		LocationRadialHour: sql.NullInt16{Int16: 3, Valid: true},
+		Version:            4, LastModified: 2000.25,
+	}))
+	require.NoError(t, q.AttachIncidentTypeToIncident(ctx, imsdb.AttachIncidentTypeToIncidentParams{Event: event, IncidentNumber: 7, Name: "Lost"}))
+	require.NoError(t, q.AttachRangerHandleToIncident(ctx, imsdb.AttachRangerHandleToIncidentParams{Event: event, IncidentNumber: 7, RangerHandle: "Tool"}))
+	entry1, err := q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{Author: "Tool", Text: "first", Created: 1001})
+	require.NoError(t, err)
+	entry2, err := q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{
+		Author: "Tool", Text: "photo", Created: 1002, Stricken: true,
+		AttachedFile:     sql.NullString{String: "1/ABC", Valid: true},
+		AttachedFileName: sql.NullString{String: "photo.jpg", Valid: true},
+	})
+	require.NoError(t, err)
+	for _, e := range []int64{entry1, entry2} {
+		require.NoError(t, q.AttachReportEntryToIncident(ctx, imsdb.AttachReportEntryToIncidentParams{Event: event, IncidentNumber: 7, ReportEntry: int32(e)}))
+	}
+	require.NoError(t, q.ImportFieldReport(ctx, imsdb.ImportFieldReportParams{
+		Event: event, Number: 2, Created: 900, IncidentNumber: sql.NullInt32{Int32: 7, Valid: true}, Version: 1, LastModified: 950,
+	}))
+	// The same entry on the field report, which import mustn't duplicate
+	require.NoError(t, q.AttachReportEntryToFieldReport(ctx, imsdb.AttachReportEntryToFieldReportParams{Event: event, FieldReportNumber: 2, ReportEntry: int32(entry1)}))
+	require.NoError(t, q.CreateIncidentChange(ctx, imsdb.CreateIncidentChangeParams{
+		Event: event, IncidentNumber: 7, Created: 1500, Author: "Tool", Field: "state", OldValue: `"new"`, NewValue: `"on_scene"`,
+	}))
+
+	var archived bytes.Buffer
+	summary, err := Export(ctx, db, "2024", &archived, nil)
+	require.NoError(t, err)
+	require.Equal(t, Summary{Event: "2024", Incidents: 1, FieldReports: 1, ReportEntries: 3}, summary)
+	lines := strings.Split(strings.TrimSpace(archived.String()), "\n")
+	require.Len(t, lines, 7)
+	var header Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	require.Equal(t, FormatName, header.Header.Format)
+	require.Equal(t, store.ExpectedSchemaVersion(), header.Header.SchemaVersion)
+
+	summary, err = Import(ctx, db, bytes.NewReader(archived.Bytes()), "2024-staging")
+	require.NoError(t, err)
+	require.Equal(t, Summary{Event: "2024-staging", Incidents: 1, FieldReports: 1, ReportEntries: 2}, summary)
+
+	// Exporting the copy gives the same archive, apart from the header and the entry IDs
+	var copied bytes.Buffer
+	_, err = Export(ctx, db, "2024-staging", &copied, nil)
+	require.NoError(t, err)
+	copiedLines := strings.Split(strings.TrimSpace(copied.String()), "\n")
+	require.Len(t, copiedLines, len(lines))
+	var original, imported Record
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &original))
+	require.NoError(t, json.Unmarshal([]byte(copiedLines[4]), &imported))
+	require.NotEqual(t, original.Incident.ReportEntries[0].ID, imported.Incident.ReportEntries[0].ID)
+	sharedEntryID := imported.Incident.ReportEntries[0].ID
+	for i := range original.Incident.ReportEntries {
+		imported.Incident.ReportEntries[i].ID = original.Incident.ReportEntries[i].ID
+	}
+	require.Equal(t, original, imported)
+	require.Equal(t, lines[1:4], copiedLines[1:4])
+	require.Equal(t, lines[6], copiedLines[6])
+	require.NoError(t, json.Unmarshal([]byte(copiedLines[5]), &imported))
+	require.Equal(t, sharedEntryID, imported.FieldReport.ReportEntries[0].ID)
+
+	// New numbers carry on from the archived ones
+	require.NoError(t, q.IncrementIncidentNumber(ctx, eventID(t, q, "2024-staging")))
+	last, err := q.LastIncidentNumber(ctx, eventID(t, q, "2024-staging"))
+	require.NoError(t, err)
+	require.Equal(t, int32(8), last)
+
+	// The name must be new, and nothing is left behind by a failed import
+	_, err = Import(ctx, db, bytes.NewReader(archived.Bytes()), "")
+	require.ErrorContains(t, err, `event "2024" already exists`)
+	broken := strings.Join(lines[:5], "\n") + "\n{\"field_report\":{\"number\":\"x\"}}\n"
+	_, err = Import(ctx, db, strings.NewReader(broken), "2024-broken")
+	require.Error(t, err)
+	_, err = q.QueryEventID(ctx, "2024-broken")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = Import(ctx, db, strings.NewReader(`{"header":{"format":"`+FormatName+`","version":99}}`), "")
+	require.ErrorContains(t, err, "version 99")
+}
+
+func eventID(t *testing.T, q *imsdb.Queries, name string) int32 {
+	t.Helper()
+	row, err := q.QueryEventID(t.Context(), name)
+	require.NoError(t, err)
+	return row.Event.ID
+}
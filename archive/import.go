@@ -0,0 +1,260 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"io"
+)
+
+// Import reads an archive from r into a new event. The event is named
+// eventName, or if that's empty, whatever it was called in the archive. There
+// mustn't already be an event by that name. It all happens in one transaction,
+// so a failed import leaves nothing behind.
+func Import(ctx context.Context, imsDB *store.DB, r io.Reader, eventName string) (Summary, error) {
+	dec := json.NewDecoder(r)
+	var first Record
+	if err := dec.Decode(&first); err != nil {
+		return Summary{}, fmt.Errorf("[Decode]: failed to read archive header: %w", err)
+	}
+	header := first.Header
+	if header == nil || header.Format != FormatName {
+		return Summary{}, errors.New("not an IMS event archive")
+	}
+	if header.Version < 1 || header.Version > FormatVersion {
+		return Summary{}, fmt.Errorf("archive format version %v isn't supported by this build, which reads up to version %v",
+			header.Version, FormatVersion)
+	}
+	if eventName == "" {
+		eventName = header.Event
+	}
+	summary := Summary{Event: eventName}
+
+	txn, err := imsDB.BeginTx(ctx, nil)
+	if err != nil {
+		return summary, fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+
+	_, err = q.QueryEventID(ctx, eventName)
+	if err == nil {
+		return summary, fmt.Errorf("event %q already exists", eventName)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return summary, fmt.Errorf("[QueryEventID]: %w", err)
+	}
+	eventID, err := q.CreateEvent(ctx, eventName)
+	if err != nil {
+		return summary, fmt.Errorf("[CreateEvent]: %w", err)
+	}
+	imp := importer{q: q, event: int32(eventID), entryIDs: make(map[int32]int32), summary: &summary}
+
+	for line := 2; ; line++ {
+		var rec Record
+		err = dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("[Decode]: record %v: %w", line, err)
+		}
+		if err = imp.record(ctx, rec); err != nil {
+			return summary, fmt.Errorf("record %v: %w", line, err)
+		}
+	}
+
+	// Carry on numbering where the archived event left off
+	if err = q.CreateEventSequenceOrIgnore(ctx, imp.event); err != nil {
+		return summary, fmt.Errorf("[CreateEventSequenceOrIgnore]: %w", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return summary, fmt.Errorf("[Commit]: %w", err)
+	}
+	return summary, nil
+}
+
+type importer struct {
+	q     *imsdb.Queries
+	event int32
+	// entryIDs maps report entry IDs in the archive to their new IDs
+	entryIDs map[int32]int32
+	summary  *Summary
+}
+
+func (imp importer) record(ctx context.Context, rec Record) error {
+	q := imp.q
+	switch {
+	case rec.Street != nil:
+		if err := q.CreateConcentricStreet(ctx, imsdb.CreateConcentricStreetParams{
+			Event: imp.event,
+			ID:    rec.Street.ID,
+			Name:  rec.Street.Name,
+		}); err != nil {
+			return fmt.Errorf("[CreateConcentricStreet]: %w", err)
+		}
+	case rec.Access != nil:
+		mode := imsdb.EventAccessMode(rec.Access.Mode)
+		validity := imsdb.EventAccessValidity(rec.Access.Validity)
+		if !mode.Valid() || !validity.Valid() {
+			return fmt.Errorf("invalid access mode %q or validity %q", rec.Access.Mode, rec.Access.Validity)
+		}
+		if _, err := q.AddEventAccess(ctx, imsdb.AddEventAccessParams{
+			Event:      imp.event,
+			Expression: rec.Access.Expression,
+			Mode:       mode,
+			Validity:   validity,
+		}); err != nil {
+			return fmt.Errorf("[AddEventAccess]: %w", err)
+		}
+	case rec.IncidentType != nil:
+		// A type that's already in the database keeps its own hidden flag
+		if err := q.CreateIncidentTypeOrIgnore(ctx, imsdb.CreateIncidentTypeOrIgnoreParams{
+			Name:   rec.IncidentType.Name,
+			Hidden: rec.IncidentType.Hidden,
+		}); err != nil {
+			return fmt.Errorf("[CreateIncidentTypeOrIgnore]: %w", err)
+		}
+	case rec.Incident != nil:
+		return imp.incident(ctx, *rec.Incident)
+	case rec.FieldReport != nil:
+		return imp.fieldReport(ctx, *rec.FieldReport)
+	case rec.IncidentChange != nil:
+		c := rec.IncidentChange
+		if err := q.CreateIncidentChange(ctx, imsdb.CreateIncidentChangeParams{
+			Event:          imp.event,
+			IncidentNumber: c.IncidentNumber,
+			Created:        c.Created,
+			Author:         c.Author,
+			Field:          c.Field,
+			OldValue:       c.OldValue,
+			NewValue:       c.NewValue,
+		}); err != nil {
+			return fmt.Errorf("[CreateIncidentChange]: %w", err)
+		}
+	case rec.Header != nil:
+		return errors.New("unexpected second header")
+	default:
+		return errors.New("empty or unknown record")
+	}
+	return nil
+}
+
+func (imp importer) incident(ctx context.Context, i Incident) error {
+	q := imp.q
+	state := imsdb.IncidentState(i.State)
+	if !state.Valid() {
+		return fmt.Errorf("incident %v has invalid state %q", i.Number, i.State)
+	}
+	if err := q.ImportIncident(ctx, imsdb.ImportIncidentParams{
+		Event:                imp.event,
+		Number:               i.Number,
+		Created:              i.Created,
+		Priority:             i.Priority,
+		State:                state,
+		Summary:              toNullString(i.Summary),
+		LocationName:         toNullString(i.LocationName),
+		LocationConcentric:   toNullString(i.LocationConcentric),
+		LocationRadialHour:   toNullInt16(i.LocationRadialHour),
+		LocationRadialMinute: toNullInt16(i.LocationRadialMinute),
+		LocationDescription:  toNullString(i.LocationDescription),
+		Version:              i.Version,
+		LastModified:         i.LastModified,
+	}); err != nil {
+		return fmt.Errorf("[ImportIncident]: %w", err)
+	}
+	for _, name := range i.IncidentTypes {
+		if err := q.AttachIncidentTypeToIncident(ctx, imsdb.AttachIncidentTypeToIncidentParams{
+			Event:          imp.event,
+			IncidentNumber: i.Number,
+			Name:           name,
+		}); err != nil {
+			return fmt.Errorf("[AttachIncidentTypeToIncident]: incident type %q: %w", name, err)
+		}
+	}
+	for _, handle := range i.RangerHandles {
+		if err := q.AttachRangerHandleToIncident(ctx, imsdb.AttachRangerHandleToIncidentParams{
+			Event:          imp.event,
+			IncidentNumber: i.Number,
+			RangerHandle:   handle,
+		}); err != nil {
+			return fmt.Errorf("[AttachRangerHandleToIncident]: %w", err)
+		}
+	}
+	for _, re := range i.ReportEntries {
+		id, err := imp.reportEntry(ctx, re)
+		if err != nil {
+			return err
+		}
+		if err = q.AttachReportEntryToIncident(ctx, imsdb.AttachReportEntryToIncidentParams{
+			Event:          imp.event,
+			IncidentNumber: i.Number,
+			ReportEntry:    id,
+		}); err != nil {
+			return fmt.Errorf("[AttachReportEntryToIncident]: %w", err)
+		}
+	}
+	imp.summary.Incidents++
+	return nil
+}
+
+func (imp importer) fieldReport(ctx context.Context, fr FieldReport) error {
+	q := imp.q
+	var incidentNumber sql.NullInt32
+	if fr.IncidentNumber != nil {
+		incidentNumber = sql.NullInt32{Int32: *fr.IncidentNumber, Valid: true}
+	}
+	if err := q.ImportFieldReport(ctx, imsdb.ImportFieldReportParams{
+		Event:          imp.event,
+		Number:         fr.Number,
+		Created:        fr.Created,
+		Summary:        toNullString(fr.Summary),
+		IncidentNumber: incidentNumber,
+		Version:        fr.Version,
+		LastModified:   fr.LastModified,
+	}); err != nil {
+		return fmt.Errorf("[ImportFieldReport]: %w", err)
+	}
+	for _, re := range fr.ReportEntries {
+		id, err := imp.reportEntry(ctx, re)
+		if err != nil {
+			return err
+		}
+		if err = q.AttachReportEntryToFieldReport(ctx, imsdb.AttachReportEntryToFieldReportParams{
+			Event:             imp.event,
+			FieldReportNumber: fr.Number,
+			ReportEntry:       id,
+		}); err != nil {
+			return fmt.Errorf("[AttachReportEntryToFieldReport]: %w", err)
+		}
+	}
+	imp.summary.FieldReports++
+	return nil
+}
+
+// reportEntry creates a report entry and returns its new ID. An entry that
+// appears more than once in the archive is only created the first time.
+func (imp importer) reportEntry(ctx context.Context, re ReportEntry) (int32, error) {
+	if id, ok := imp.entryIDs[re.ID]; ok {
+		return id, nil
+	}
+	id, err := imp.q.CreateReportEntry(ctx, imsdb.CreateReportEntryParams{
+		Author:           re.Author,
+		Text:             re.Text,
+		Created:          re.Created,
+		Generated:        re.Generated,
+		Stricken:         re.Stricken,
+		AttachedFile:     toNullString(re.AttachedFile),
+		AttachedFileName: toNullString(re.AttachedFileName),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[CreateReportEntry]: %w", err)
+	}
+	imp.entryIDs[re.ID] = int32(id)
+	imp.summary.ReportEntries++
+	return int32(id), nil
+}
@@ -0,0 +1,36 @@
+// Package attachment stores the files that are attached to report entries.
+package attachment
+
+import (
+	"context"
+	"errors"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"io"
+)
+
+// ErrNotFound is returned by BlobStore.Get when there's no blob with the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore holds opaque blobs of data, addressed by key. Keys are
+// slash-separated paths, e.g. "12/IGQHGB7SD7YUJ4Z4".
+type BlobStore interface {
+	// Put stores size bytes from r under key, replacing anything already there.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get returns the blob stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key, if there is one.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStore returns the BlobStore described by cfg, or nil if attachments
+// are turned off.
+func NewBlobStore(cfg conf.AttachmentsStore) BlobStore {
+	switch cfg.Type {
+	case conf.AttachmentsStoreTypeLocal:
+		return LocalStore{Dir: cfg.Local.Dir}
+	case conf.AttachmentsStoreTypeS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps blobs as files under a directory on the local filesystem.
+type LocalStore struct {
+	Dir string
+}
+
+var _ BlobStore = LocalStore{}
+
+func (l LocalStore) path(key string) (string, error) {
+	p := filepath.FromSlash(key)
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(l.Dir, p), nil
+}
+
+func (l LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path, err := l.path(key)
+	if err != nil {
+		return fmt.Errorf("[path]: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("[MkdirAll]: %w", err)
+	}
+	// Write to a temporary file first, so that no one ever reads half a blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("[CreateTemp]: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	written, err := io.Copy(tmp, io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("[Copy]: %w", err)
+	}
+	if written != size {
+		return fmt.Errorf("got %v bytes for blob %v, expected %v", written, key, size)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("[Close]: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("[Rename]: %w", err)
+	}
+	return nil
+}
+
+func (l LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, fmt.Errorf("[path]: %w", err)
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[Open]: %w", err)
+	}
+	return f, nil
+}
+
+func (l LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return fmt.Errorf("[path]: %w", err)
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("[Remove]: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package attachment
+
+import (
+	"bytes"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	blobs := NewBlobStore(conf.AttachmentsStore{
+		Type:  conf.AttachmentsStoreTypeLocal,
+		Local: conf.AttachmentsStoreLocal{Dir: t.TempDir()},
+	})
+	testBlobStore(t, blobs)
+
+	err := blobs.Put(t.Context(), "../escape", bytes.NewReader([]byte("x")), 1)
+	require.Error(t, err)
+}
+
+func TestNoBlobStore(t *testing.T) {
+	require.Nil(t, NewBlobStore(conf.AttachmentsStore{Type: conf.AttachmentsStoreTypeNone}))
+}
+
+// testBlobStore runs a BlobStore through its paces.
+func testBlobStore(t *testing.T, blobs BlobStore) {
+	ctx := t.Context()
+	_, err := blobs.Get(ctx, "1/missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	data := []byte("some attached file")
+	require.NoError(t, blobs.Put(ctx, "1/abc", bytes.NewReader(data), int64(len(data))))
+	r, err := blobs.Get(ctx, "1/abc")
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, data, got)
+
+	require.NoError(t, blobs.Delete(ctx, "1/abc"))
+	_, err = blobs.Get(ctx, "1/abc")
+	require.ErrorIs(t, err, ErrNotFound)
+	// Deleting something that's not there is fine
+	require.NoError(t, blobs.Delete(ctx, "1/abc"))
+}
@@ -0,0 +1,190 @@
+package attachment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// S3Store keeps blobs in an S3 bucket, or in a bucket on any service that
+// speaks the S3 API. Requests are made with path-style addressing, i.e.
+// "{endpoint}/{bucket}/{key}", which every S3-compatible service supports.
+type S3Store struct {
+	endpoint        string
+	bucket          string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	client          *http.Client
+}
+
+var _ BlobStore = (*S3Store)(nil)
+
+func NewS3Store(cfg conf.AttachmentsStoreS3) *S3Store {
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%v.amazonaws.com", cfg.S3DefaultRegion)
+	}
+	return &S3Store{
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		bucket:          cfg.S3Bucket,
+		region:          cfg.S3DefaultRegion,
+		accessKeyID:     cfg.S3AccessKeyId,
+		secretAccessKey: cfg.S3SecretAccessKey,
+		client:          &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, io.LimitReader(r, size), size)
+	if err != nil {
+		return fmt.Errorf("[do]: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("[do]: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return fmt.Errorf("[do]: %w", err)
+	}
+	defer resp.Body.Close()
+	// S3 says 204 whether or not the object existed, but not every S3-compatible service does
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Response, error) {
+	segments := []string{"", uriEncode(s.bucket)}
+	for _, segment := range strings.Split(key, "/") {
+		segments = append(segments, uriEncode(segment))
+	}
+	canonicalURI := strings.Join(segments, "/")
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+canonicalURI, body)
+	if err != nil {
+		return nil, fmt.Errorf("[NewRequestWithContext]: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	// The payload isn't signed, so that it can be streamed rather than read twice
+	signV4(req, canonicalURI, "UNSIGNED-PAYLOAD", s.region, s.accessKeyID, s.secretAccessKey, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("[Do]: %w", err)
+	}
+	return resp, nil
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 responded %v: %s", resp.Status, body)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req.
+// All the request's headers are signed, along with its host.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signV4(req *http.Request, canonicalURI, payloadHash, region, accessKeyID, secretAccessKey string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode percent-encodes everything but the characters that SigV4 leaves alone.
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' ||
+			b == '-' || b == '.' || b == '_' || b == '~' {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
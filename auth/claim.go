@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"github.com/golang-jwt/jwt/v5"
 	"strings"
 	"time"
@@ -26,8 +27,11 @@ func (c IMSClaims) WithExpiration(t time.Time) IMSClaims {
 	return c
 }
 
+// WithIssuedAt sets "iat" to the microsecond, rather than the usual second, so
+// that tokens issued just after a revocation can be told apart from those
+// issued just before it.
 func (c IMSClaims) WithIssuedAt(t time.Time) IMSClaims {
-	c.MapClaims["iat"] = t.Unix()
+	c.MapClaims["iat"] = float64(t.UnixMicro()) / 1e6
 	return c
 }
 
@@ -36,11 +40,21 @@ func (c IMSClaims) WithIssuer(s string) IMSClaims {
 	return c
 }
 
+func (c IMSClaims) WithID(s string) IMSClaims {
+	c.MapClaims["jti"] = s
+	return c
+}
+
 func (c IMSClaims) WithSubject(s string) IMSClaims {
 	c.MapClaims["sub"] = s
 	return c
 }
 
+func (c IMSClaims) WithAudience(s string) IMSClaims {
+	c.MapClaims["aud"] = s
+	return c
+}
+
 func (c IMSClaims) WithRangerHandle(s string) IMSClaims {
 	c.MapClaims[handleKey] = s
 	return c
@@ -61,6 +75,25 @@ func (c IMSClaims) WithRangerTeams(teams ...string) IMSClaims {
 	return c
 }
 
+// ID is the token's "jti", or "" if it has none.
+func (c IMSClaims) ID() string {
+	jti, _ := c.MapClaims["jti"].(string)
+	return jti
+}
+
+// IssuedAtSeconds is the token's "iat" with its fractional part, which
+// GetIssuedAt would drop. It's zero if the token has no "iat".
+func (c IMSClaims) IssuedAtSeconds() float64 {
+	switch iat := c.MapClaims["iat"].(type) {
+	case float64:
+		return iat
+	case json.Number:
+		f, _ := iat.Float64()
+		return f
+	}
+	return 0
+}
+
 func (c IMSClaims) RangerHandle() string {
 	rh, _ := c.MapClaims[handleKey].(string)
 	return rh
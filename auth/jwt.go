@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/rand"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"log"
@@ -9,8 +10,17 @@ import (
 	"time"
 )
 
+// EventSourceAudience is the "aud" claim of an EventSource ticket. Browsers'
+// EventSource API can't set an Authorization header, so clients instead pass a
+// short-lived ticket in the query string. Tickets are only accepted by the
+// EventSource endpoint, since they're more likely to leak via URLs and logs.
+const EventSourceAudience = "ims-eventsource"
+
+// JWTer makes and validates JWTs. They're signed with Keys when it's set, or
+// with the shared SecretKey (HS256) otherwise.
 type JWTer struct {
 	SecretKey string
+	Keys      *KeySet
 }
 
 func (j JWTer) CreateJWT(
@@ -21,9 +31,9 @@ func (j JWTer) CreateJWT(
 	onsite bool,
 	duration time.Duration,
 ) string {
-	token, err := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
+	return j.sign(
 		NewIMSClaims().
+			WithID(rand.Text()).
 			WithIssuedAt(time.Now()).
 			WithExpiration(time.Now().Add(duration)).
 			WithIssuer("ranger-ims-go").
@@ -32,7 +42,39 @@ func (j JWTer) CreateJWT(
 			WithRangerPositions(positions...).
 			WithRangerTeams(teams...).
 			WithSubject(strconv.FormatInt(clubhouseID, 10)),
-	).SignedString([]byte(j.SecretKey))
+	)
+}
+
+// CreateEventSourceTicket makes a short-lived JWT for the EventSource endpoint,
+// carrying the same Ranger details as the provided claims. It has the same
+// "jti" too, so revoking the access token also revokes its tickets.
+func (j JWTer) CreateEventSourceTicket(claims IMSClaims, duration time.Duration) string {
+	sub, _ := claims.GetSubject()
+	return j.sign(
+		NewIMSClaims().
+			WithID(claims.ID()).
+			WithIssuedAt(time.Now()).
+			WithExpiration(time.Now().Add(duration)).
+			WithIssuer("ranger-ims-go").
+			WithAudience(EventSourceAudience).
+			WithRangerHandle(claims.RangerHandle()).
+			WithRangerOnSite(claims.RangerOnSite()).
+			WithRangerPositions(claims.RangerPositions()...).
+			WithRangerTeams(claims.RangerTeams()...).
+			WithSubject(sub),
+	)
+}
+
+func (j JWTer) sign(claims IMSClaims) string {
+	var token string
+	var err error
+	if j.Keys != nil {
+		tok := jwt.NewWithClaims(j.Keys.signer.method, claims)
+		tok.Header["kid"] = j.Keys.signer.id
+		token, err = tok.SignedString(j.Keys.signer.private)
+	} else {
+		token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.SecretKey))
+	}
 	if err != nil {
 		log.Panic(err)
 	}
@@ -41,13 +83,44 @@ func (j JWTer) CreateJWT(
 
 func (j JWTer) AuthenticateJWT(authHeader string) (*IMSClaims, error) {
 	authHeader = strings.TrimPrefix(authHeader, "Bearer ")
-	if authHeader == "" {
+	claims, err := j.authenticate(authHeader)
+	if err != nil {
+		return nil, err
+	}
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return nil, fmt.Errorf("[GetAudience]: %w", err)
+	}
+	if len(aud) > 0 {
+		return nil, fmt.Errorf("token has an audience, so it can't be used as an access token")
+	}
+	return claims, nil
+}
+
+// AuthenticateEventSourceTicket validates a ticket made by CreateEventSourceTicket.
+func (j JWTer) AuthenticateEventSourceTicket(ticket string) (*IMSClaims, error) {
+	return j.authenticate(ticket, jwt.WithAudience(EventSourceAudience))
+}
+
+func (j JWTer) authenticate(token string, opts ...jwt.ParserOption) (*IMSClaims, error) {
+	if token == "" {
 		return nil, fmt.Errorf("no token provided")
 	}
 	claims := IMSClaims{}
-	tok, err := jwt.ParseWithClaims(authHeader, &claims, func(token *jwt.Token) (any, error) {
-		return []byte(j.SecretKey), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	var keyFunc jwt.Keyfunc
+	if j.Keys != nil {
+		// Only tokens signed by one of the keys, so never HS256
+		opts = append(opts, jwt.WithValidMethods(asymmetricMethods))
+		keyFunc = func(token *jwt.Token) (any, error) {
+			return j.Keys.verificationKey(token)
+		}
+	} else {
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+		keyFunc = func(token *jwt.Token) (any, error) {
+			return []byte(j.SecretKey), nil
+		}
+	}
+	tok, err := jwt.ParseWithClaims(token, &claims, keyFunc, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("[jwt.Parse]: %w", err)
 	}
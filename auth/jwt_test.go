@@ -7,7 +7,7 @@ import (
 )
 
 func TestCreateAndGetValidJWT(t *testing.T) {
-	jwter := JWTer{"some-secret"}
+	jwter := JWTer{SecretKey: "some-secret"}
 	j := jwter.CreateJWT(
 		"Hardware",
 		12345,
@@ -25,10 +25,16 @@ func TestCreateAndGetValidJWT(t *testing.T) {
 	require.Equal(t, []string{"Fluffer", "Operator"}, claims.RangerPositions())
 	require.Equal(t, []string{"Fluff Squad"}, claims.RangerTeams())
 	require.Equal(t, true, claims.RangerOnSite())
+	require.NotEmpty(t, claims.ID())
+	require.InDelta(t, float64(time.Now().UnixMicro())/1e6, claims.IssuedAtSeconds(), 5)
+
+	ticket, err := jwter.AuthenticateEventSourceTicket(jwter.CreateEventSourceTicket(*claims, time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, claims.ID(), ticket.ID())
 }
 
 func TestCreateAndGetInvalidJWTs(t *testing.T) {
-	jwter := JWTer{"some-secret"}
+	jwter := JWTer{SecretKey: "some-secret"}
 	expiredJWT := jwter.CreateJWT(
 		"Hardware",
 		1,
@@ -37,7 +43,7 @@ func TestCreateAndGetInvalidJWTs(t *testing.T) {
 		true,
 		-1*time.Hour,
 	)
-	differentKeyJWT := JWTer{"some-other-secret"}.CreateJWT(
+	differentKeyJWT := JWTer{SecretKey: "some-other-secret"}.CreateJWT(
 		"Hardware",
 		1,
 		nil,
@@ -52,3 +58,39 @@ func TestCreateAndGetInvalidJWTs(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "signature is invalid")
 }
+
+func TestEventSourceTicket(t *testing.T) {
+	jwter := JWTer{SecretKey: "some-secret"}
+	accessToken := jwter.CreateJWT(
+		"Hardware",
+		12345,
+		[]string{"Fluffer"},
+		[]string{"Fluff Squad"},
+		true,
+		1*time.Hour,
+	)
+	claims, err := jwter.AuthenticateJWT(accessToken)
+	require.NoError(t, err)
+
+	ticket := jwter.CreateEventSourceTicket(*claims, 1*time.Minute)
+	ticketClaims, err := jwter.AuthenticateEventSourceTicket(ticket)
+	require.NoError(t, err)
+	sub, err := ticketClaims.GetSubject()
+	require.NoError(t, err)
+	require.Equal(t, "12345", sub)
+	require.Equal(t, "Hardware", ticketClaims.RangerHandle())
+	require.Equal(t, []string{"Fluffer"}, ticketClaims.RangerPositions())
+	require.Equal(t, []string{"Fluff Squad"}, ticketClaims.RangerTeams())
+	require.True(t, ticketClaims.RangerOnSite())
+
+	// A ticket can't be used as an access token, nor vice versa
+	_, err = jwter.AuthenticateJWT(ticket)
+	require.Error(t, err)
+	_, err = jwter.AuthenticateEventSourceTicket(accessToken)
+	require.Error(t, err)
+
+	expiredTicket := jwter.CreateEventSourceTicket(*claims, -1*time.Minute)
+	_, err = jwter.AuthenticateEventSourceTicket(expiredTicket)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expired")
+}
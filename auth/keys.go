@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+)
+
+// minRSABits is the smallest RSA key that will be used for JWTs.
+const minRSABits = 2048
+
+// asymmetricMethods are the algorithms of the keys that a KeySet can hold.
+var asymmetricMethods = []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+
+// KeySet holds the asymmetric keys for JWTs: the private key that new tokens are
+// signed with, plus the public keys that tokens may still be verified with. Keeping
+// the previous signing key in the set for a while is what allows keys to be
+// rotated without logging everybody out.
+type KeySet struct {
+	signer signingKey
+	// keys has every key by its "kid", including the signing key's public key
+	keys map[string]verificationKey
+	// ids is the order in which keys were added
+	ids []string
+}
+
+type signingKey struct {
+	verificationKey
+	private crypto.Signer
+}
+
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517), which lets other services verify JWTs.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the public part of one key, as a JSON Web Key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// Crv and X are set for Ed25519 keys
+	Crv string `json:"crv,omitzero"`
+	X   string `json:"x,omitzero"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitzero"`
+	E string `json:"e,omitzero"`
+}
+
+// LoadKeySet reads the signing key and any other verification keys from PEM files.
+// The keys may be Ed25519 or RSA, and each verification key file may hold either a
+// public key or a private key.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	_, signer, err := readKeyFile(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("[readKeyFile] signing key: %w", err)
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("signing key %v is not a private key", signingKeyFile)
+	}
+	var verificationKeys []crypto.PublicKey
+	for _, file := range verificationKeyFiles {
+		public, _, err := readKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("[readKeyFile] verification key: %w", err)
+		}
+		verificationKeys = append(verificationKeys, public)
+	}
+	return NewKeySet(signer, verificationKeys...)
+}
+
+// NewKeySet makes a KeySet that signs with signer, and verifies with signer's public
+// key and the verificationKeys.
+func NewKeySet(signer crypto.Signer, verificationKeys ...crypto.PublicKey) (*KeySet, error) {
+	k := &KeySet{keys: make(map[string]verificationKey)}
+	signingPublic, err := k.add(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("[add] signing key: %w", err)
+	}
+	k.signer = signingKey{verificationKey: signingPublic, private: signer}
+	for _, public := range verificationKeys {
+		if _, err = k.add(public); err != nil {
+			return nil, fmt.Errorf("[add] verification key: %w", err)
+		}
+	}
+	return k, nil
+}
+
+// SigningKeyID is the "kid" of the key that new tokens are signed with.
+func (k *KeySet) SigningKeyID() string {
+	return k.signer.id
+}
+
+// JWKS returns the public keys that tokens may be verified with.
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range k.ids {
+		key := k.keys[id]
+		jwk := publicJWK(key.public)
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		jwk.Kid = key.id
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func (k *KeySet) add(public crypto.PublicKey) (verificationKey, error) {
+	var method jwt.SigningMethod
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return verificationKey{}, fmt.Errorf("RSA key is %v bits, but it must be at least %v", public.N.BitLen(), minRSABits)
+		}
+		method = jwt.SigningMethodRS256
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T. Use Ed25519 or RSA", public)
+	}
+	key := verificationKey{id: thumbprint(public), method: method, public: public}
+	if _, ok := k.keys[key.id]; !ok {
+		k.keys[key.id] = key
+		k.ids = append(k.ids, key.id)
+	}
+	return key, nil
+}
+
+// verificationKey finds the key for a token from its "kid" header.
+func (k *KeySet) verificationKey(token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("token is signed with %v, but key %v is for %v", token.Method.Alg(), kid, key.method.Alg())
+	}
+	return key.public, nil
+}
+
+func publicJWK(public crypto.PublicKey) JWK {
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)}
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}
+	}
+	return JWK{}
+}
+
+// thumbprint is the JWK thumbprint of a key (RFC 7638), which serves as its "kid".
+// It's derived from the key itself, so every server with the same key agrees on it.
+func thumbprint(public crypto.PublicKey) string {
+	jwk := publicJWK(public)
+	// The required members only, in lexicographic order
+	var members any
+	switch jwk.Kty {
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	}
+	marshalled, _ := json.Marshal(members)
+	sum := sha256.Sum256(marshalled)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// readKeyFile reads a PEM file holding a public key or a private key. The private
+// key is nil if it holds a public key.
+func readKeyFile(file string) (crypto.PublicKey, crypto.Signer, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[ReadFile]: %w", err)
+	}
+	public, private, err := ParseKeyPEM(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[ParseKeyPEM] %v: %w", file, err)
+	}
+	return public, private, nil
+}
+
+// ParseKeyPEM parses a PKIX public key, or a PKCS #8 or PKCS #1 private key.
+// The private key is nil if it's a public key.
+func ParseKeyPEM(b []byte) (crypto.PublicKey, crypto.Signer, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("[ParsePKIXPublicKey]: %w", err)
+		}
+		return public, nil, nil
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("[ParsePKCS8PrivateKey]: %w", err)
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported private key type %T", private)
+		}
+		return signer.Public(), signer, nil
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("[ParsePKCS1PrivateKey]: %w", err)
+		}
+		return private.Public(), private, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
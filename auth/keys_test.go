@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+func newKeyedJWTer(t *testing.T, signer crypto.Signer, verificationKeys ...crypto.PublicKey) JWTer {
+	t.Helper()
+	keys, err := NewKeySet(signer, verificationKeys...)
+	require.NoError(t, err)
+	return JWTer{Keys: keys}
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	for name, tc := range map[string]struct {
+		signer crypto.Signer
+		alg    string
+	}{
+		"Ed25519": {signer: newEd25519Key(t), alg: "EdDSA"},
+		"RSA":     {signer: rsaKey, alg: "RS256"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			jwter := newKeyedJWTer(t, tc.signer)
+			token := jwter.CreateJWT("Hardware", 12345, nil, nil, true, time.Hour)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &IMSClaims{})
+			require.NoError(t, err)
+			require.Equal(t, tc.alg, parsed.Header["alg"])
+			require.Equal(t, jwter.Keys.SigningKeyID(), parsed.Header["kid"])
+
+			claims, err := jwter.AuthenticateJWT(token)
+			require.NoError(t, err)
+			require.Equal(t, "Hardware", claims.RangerHandle())
+
+			ticket := jwter.CreateEventSourceTicket(*claims, time.Minute)
+			_, err = jwter.AuthenticateEventSourceTicket(ticket)
+			require.NoError(t, err)
+
+			jwks := jwter.Keys.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			require.Equal(t, tc.alg, jwks.Keys[0].Alg)
+			require.Equal(t, "sig", jwks.Keys[0].Use)
+			require.Equal(t, jwter.Keys.SigningKeyID(), jwks.Keys[0].Kid)
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := newEd25519Key(t)
+	newKey := newEd25519Key(t)
+	oldToken := newKeyedJWTer(t, oldKey).CreateJWT("Hardware", 1, nil, nil, true, time.Hour)
+
+	// With the old key kept for verification, its tokens still work
+	rotated := newKeyedJWTer(t, newKey, oldKey.Public())
+	_, err := rotated.AuthenticateJWT(oldToken)
+	require.NoError(t, err)
+	_, err = rotated.AuthenticateJWT(rotated.CreateJWT("Hardware", 1, nil, nil, true, time.Hour))
+	require.NoError(t, err)
+	jwks := rotated.Keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, rotated.Keys.SigningKeyID(), jwks.Keys[0].Kid)
+
+	// Once it's dropped, they don't
+	_, err = newKeyedJWTer(t, newKey).AuthenticateJWT(oldToken)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown kid")
+}
+
+func TestKeySetRejectsSharedSecretTokens(t *testing.T) {
+	secretJWTer := JWTer{SecretKey: "some-secret"}
+	keyedJWTer := newKeyedJWTer(t, newEd25519Key(t))
+	keyedJWTer.SecretKey = "some-secret"
+
+	_, err := keyedJWTer.AuthenticateJWT(secretJWTer.CreateJWT("Hardware", 1, nil, nil, true, time.Hour))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "signing method HS256 is invalid")
+	_, err = secretJWTer.AuthenticateJWT(keyedJWTer.CreateJWT("Hardware", 1, nil, nil, true, time.Hour))
+	require.Error(t, err)
+}
+
+func TestThumbprint(t *testing.T) {
+	// From RFC 8037, Appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+	require.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint(ed25519.PublicKey(x)))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwk := publicJWK(rsaKey.Public())
+	require.Equal(t, "AQAB", jwk.E)
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	require.NoError(t, err)
+	require.Equal(t, rsaKey.N, new(big.Int).SetBytes(n))
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, b []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b}), 0o600))
+		return path
+	}
+	signer := newEd25519Key(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(signer)
+	require.NoError(t, err)
+	signingFile := writePEM("signing.pem", "PRIVATE KEY", pkcs8)
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkix, err := x509.MarshalPKIXPublicKey(oldKey.Public())
+	require.NoError(t, err)
+	publicFile := writePEM("old-public.pem", "PUBLIC KEY", pkix)
+	pkcs1File := writePEM("old-private.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(oldKey))
+
+	keys, err := LoadKeySet(signingFile, []string{publicFile, pkcs1File})
+	require.NoError(t, err)
+	require.Equal(t, thumbprint(signer.Public()), keys.SigningKeyID())
+	// The same key twice is only listed once
+	require.Len(t, keys.JWKS().Keys, 2)
+
+	_, err = LoadKeySet(publicFile, nil)
+	require.ErrorContains(t, err, "is not a private key")
+	_, err = LoadKeySet(filepath.Join(dir, "missing.pem"), nil)
+	require.Error(t, err)
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = LoadKeySet(writePEM("small.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallKey)), nil)
+	require.ErrorContains(t, err, "must be at least 2048")
+}
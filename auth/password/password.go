@@ -1,32 +1,157 @@
+// Package password verifies the hashed passwords that come from the directory.
+// It understands bcrypt, argon2id (in the PHC string format), and the legacy
+// "salt:sha1hex" format. New hashes are argon2id.
 package password
 
 import (
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 	"strings"
 )
 
 const (
 	saltPasswordSep = ":"
+	argon2idPrefix  = "$argon2id$"
 )
 
+// argon2idParams are what NewSalted uses, per the OWASP recommendation for argon2id.
+var argon2idParams = argon2Params{
+	memory:  19 * 1024,
+	time:    2,
+	threads: 1,
+	saltLen: 16,
+	keyLen:  32,
+}
+
+// These bound the argon2id parameters of a stored hash, since each login would
+// otherwise spend whatever memory and time a bad hash in the directory asks for.
+const (
+	maxArgon2idMemory  = 1024 * 1024 // KiB, i.e. 1 GiB
+	maxArgon2idTime    = 10
+	maxArgon2idThreads = 16
+)
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	saltLen uint32
+	keyLen  uint32
+}
+
+// Verify reports whether password matches the stored hash, in whichever
+// format that's in. An error means the stored hash couldn't be understood.
 func Verify(password, storedValue string) (isValid bool, err error) {
+	switch {
+	case isBcrypt(storedValue):
+		err = bcrypt.CompareHashAndPassword([]byte(storedValue), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("[CompareHashAndPassword]: %w", err)
+		}
+		return true, nil
+	case strings.HasPrefix(storedValue, argon2idPrefix):
+		params, salt, key, err := decodeArgon2id(storedValue)
+		if err != nil {
+			return false, fmt.Errorf("[decodeArgon2id]: %w", err)
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, params.keyLen)
+		return subtle.ConstantTimeCompare(computed, key) == 1, nil
+	}
 	salt, storedHash, found := strings.Cut(storedValue, saltPasswordSep)
 	if !found {
 		return false, fmt.Errorf("invalid hashed password")
 	}
-	return hash(password, salt) == storedHash, nil
+	return subtle.ConstantTimeCompare([]byte(hash(password, salt)), []byte(storedHash)) == 1, nil
+}
+
+// NeedsRehash reports whether a stored hash is weaker than what NewSalted would
+// make now, so it ought to be replaced the next time the password is known.
+// That's any hash that isn't argon2id with at least the current parameters.
+func NeedsRehash(storedValue string) bool {
+	if !strings.HasPrefix(storedValue, argon2idPrefix) {
+		return true
+	}
+	params, _, _, err := decodeArgon2id(storedValue)
+	if err != nil {
+		return true
+	}
+	return params.memory < argon2idParams.memory ||
+		params.time < argon2idParams.time ||
+		params.keyLen < argon2idParams.keyLen
+}
+
+// NewSalted hashes a password with argon2id and a random salt.
+func NewSalted(password string) string {
+	p := argon2idParams
+	salt := make([]byte, p.saltLen)
+	_, _ = rand.Read(salt)
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
 }
 
+// hash is the legacy format's SHA-1 hash, which is only used to verify old passwords.
 func hash(password, salt string) string {
 	hasher := sha1.New()
 	hasher.Write([]byte(salt + password))
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-func NewSalted(password string) string {
-	salt := rand.Text()
-	return salt + ":" + hash(password, salt)
+func isBcrypt(storedValue string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(storedValue, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeArgon2id parses a hash like "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>",
+// with the salt and key in unpadded base64.
+func decodeArgon2id(storedValue string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(storedValue, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("[Sscanf]: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %v", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("[Sscanf]: %w", err)
+	}
+	if p.time == 0 || p.threads == 0 {
+		return p, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	if p.memory > maxArgon2idMemory || p.time > maxArgon2idTime || p.threads > maxArgon2idThreads {
+		return p, nil, nil, fmt.Errorf("argon2id parameters m=%d,t=%d,p=%d are too costly", p.memory, p.time, p.threads)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("[DecodeString]: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("[DecodeString]: %w", err)
+	}
+	if len(key) == 0 {
+		return p, nil, nil, errors.New("invalid argon2id hash")
+	}
+	p.saltLen = uint32(len(salt))
+	p.keyLen = uint32(len(key))
+	return p, salt, key, nil
 }
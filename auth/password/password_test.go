@@ -3,6 +3,7 @@ package password
 import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"testing"
 )
 
@@ -35,3 +36,60 @@ func TestNewSalted(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, isValid)
 }
+
+func TestNewSalted_argon2id(t *testing.T) {
+	saltedPw := NewSalted("Hardware")
+	require.Regexp(t, `^\$argon2id\$v=19\$m=19456,t=2,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, saltedPw)
+	require.NotEqual(t, saltedPw, NewSalted("Hardware"))
+	isValid, err := Verify("hardware", saltedPw)
+	require.NoError(t, err)
+	require.False(t, isValid)
+	require.False(t, NeedsRehash(saltedPw))
+}
+
+func TestVerifyPassword_argon2id(t *testing.T) {
+	// Made with different parameters than NewSalted uses
+	stored := "$argon2id$v=19$m=4096,t=3,p=2$c29tZXNhbHRzb21lc2FsdA$" +
+		"RYTZbCAx2mRBQv16XyYNv0T1ShZ/plRckPtilO4NI7A"
+	isValid, err := Verify("Hardware", stored)
+	require.NoError(t, err)
+	require.True(t, isValid)
+	isValid, err = Verify("Hardware ", stored)
+	require.NoError(t, err)
+	require.False(t, isValid)
+	require.True(t, NeedsRehash(stored))
+
+	for _, bad := range []string{
+		"$argon2id$v=19$m=4096,t=3,p=2$c29tZXNhbHQ",
+		"$argon2id$v=16$m=4096,t=3,p=2$c29tZXNhbHQ$c29tZXNhbHQ",
+		"$argon2id$v=19$m=4096,t=0,p=2$c29tZXNhbHQ$c29tZXNhbHQ",
+		"$argon2id$v=19$m=4096,t=3,p=2$c29tZXNhbHQ$!!!",
+		// These would take far too long, or far too much memory
+		"$argon2id$v=19$m=4294967295,t=3,p=2$c29tZXNhbHQ$c29tZXNhbHQ",
+		"$argon2id$v=19$m=4096,t=1000000,p=2$c29tZXNhbHQ$c29tZXNhbHQ",
+		"$argon2id$v=19$m=4096,t=3,p=255$c29tZXNhbHQ$c29tZXNhbHQ",
+	} {
+		_, err = Verify("Hardware", bad)
+		require.Error(t, err, bad)
+		require.True(t, NeedsRehash(bad))
+	}
+}
+
+func TestVerifyPassword_bcrypt(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Hardware"), bcrypt.MinCost)
+	require.NoError(t, err)
+	isValid, err := Verify("Hardware", string(hashed))
+	require.NoError(t, err)
+	require.True(t, isValid)
+	isValid, err = Verify("Parenthetical", string(hashed))
+	require.NoError(t, err)
+	require.False(t, isValid)
+	require.True(t, NeedsRehash(string(hashed)))
+
+	_, err = Verify("Hardware", "$2b$10$tooshort")
+	require.Error(t, err)
+}
+
+func TestNeedsRehash_legacy(t *testing.T) {
+	require.True(t, NeedsRehash("my_little_salty:ee9a23000af19a22acd0d9a22dfe9558580771dc"))
+}
@@ -45,6 +45,7 @@ const (
 	EventWriteAllFieldReports
 	EventWriteOwnFieldReports
 	EventReadEventName
+	EventAttachFiles
 )
 
 const (
@@ -57,17 +58,20 @@ const (
 	GlobalAdministrateEvents
 	GlobalAdministrateStreets
 	GlobalAdministrateIncidentTypes
+	GlobalReadAudit
+	GlobalRevokeSessions
+	GlobalReadLoginLockouts
 )
 
 var RolesToGlobalPerms = map[Role]GlobalPermissionMask{
 	AnyAuthenticatedUser: GlobalListEvents | GlobalReadIncidentTypes | GlobalReadPersonnel | GlobalReadStreets,
-	Administrator:        GlobalAdministrateEvents | GlobalAdministrateStreets | GlobalAdministrateIncidentTypes,
+	Administrator:        GlobalAdministrateEvents | GlobalAdministrateStreets | GlobalAdministrateIncidentTypes | GlobalReadAudit | GlobalRevokeSessions | GlobalReadLoginLockouts,
 }
 
 var RolesToEventPerms = map[Role]EventPermissionMask{
-	EventReporter: EventReadEventName | EventReadOwnFieldReports | EventWriteOwnFieldReports,
+	EventReporter: EventReadEventName | EventReadOwnFieldReports | EventWriteOwnFieldReports | EventAttachFiles,
 	EventReader:   EventReadEventName | EventReadIncidents | EventReadOwnFieldReports | EventReadAllFieldReports,
-	EventWriter:   EventReadEventName | EventReadIncidents | EventWriteIncidents | EventReadAllFieldReports | EventReadOwnFieldReports | EventWriteAllFieldReports | EventWriteOwnFieldReports,
+	EventWriter:   EventReadEventName | EventReadIncidents | EventWriteIncidents | EventReadAllFieldReports | EventReadOwnFieldReports | EventWriteAllFieldReports | EventWriteOwnFieldReports | EventAttachFiles,
 }
 
 func EventPermissions(
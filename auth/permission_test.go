@@ -10,10 +10,10 @@ var (
 	testAdmins = []string{"AdminCat", "AdminDog"}
 
 	readerPerm             = EventReadEventName | EventReadIncidents | EventReadOwnFieldReports | EventReadAllFieldReports
-	writerPerm             = EventReadEventName | EventReadIncidents | EventWriteIncidents | EventReadAllFieldReports | EventReadOwnFieldReports | EventWriteAllFieldReports | EventWriteOwnFieldReports
-	reporterPerm           = EventReadEventName | EventReadOwnFieldReports | EventWriteOwnFieldReports
+	writerPerm             = EventReadEventName | EventReadIncidents | EventWriteIncidents | EventReadAllFieldReports | EventReadOwnFieldReports | EventWriteAllFieldReports | EventWriteOwnFieldReports | EventAttachFiles
+	reporterPerm           = EventReadEventName | EventReadOwnFieldReports | EventWriteOwnFieldReports | EventAttachFiles
 	authenticatedUserPerms = GlobalListEvents | GlobalReadIncidentTypes | GlobalReadPersonnel | GlobalReadStreets
-	adminGlobalPerms       = GlobalAdministrateEvents | GlobalAdministrateStreets | GlobalAdministrateIncidentTypes
+	adminGlobalPerms       = GlobalAdministrateEvents | GlobalAdministrateStreets | GlobalAdministrateIncidentTypes | GlobalReadAudit | GlobalRevokeSessions | GlobalReadLoginLockouts
 )
 
 func addPerm(m map[int32][]imsdb.EventAccess, eventID int32, expr, mode, validity string) {
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"log/slog"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken means the refresh token is unknown, expired, or revoked.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means a refresh token that had already been exchanged
+	// was presented again. It might have been stolen, so every token from the
+	// same login has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+// RefreshTokens issues and exchanges refresh tokens. A refresh token is opaque
+// to the client, and the server keeps only its hash. Each is good for one
+// exchange, which gives a new refresh token along with the new access token,
+// so a session lasts for as long as it keeps being used within Lifetime.
+type RefreshTokens struct {
+	ImsDB    *store.DB
+	Lifetime time.Duration
+}
+
+// RefreshSession is who a refresh token was issued to.
+type RefreshSession struct {
+	Handle      string
+	DirectoryID int64
+	// Family is shared by all the refresh tokens that come from one login.
+	Family string
+}
+
+// Issue makes the first refresh token of a new login.
+func (r RefreshTokens) Issue(ctx context.Context, handle string, directoryID int64) (string, error) {
+	q := imsdb.New(r.ImsDB)
+	now := time.Now()
+	// There's no need to keep tokens that can't be used anymore
+	if _, err := q.PruneRefreshTokens(ctx, toFloat(now)); err != nil {
+		return "", fmt.Errorf("[PruneRefreshTokens]: %w", err)
+	}
+	session := RefreshSession{Handle: handle, DirectoryID: directoryID, Family: rand.Text()}
+	token, err := r.create(ctx, q, session, now)
+	if err != nil {
+		return "", fmt.Errorf("[create]: %w", err)
+	}
+	return token, nil
+}
+
+// Exchange uses up a refresh token, returning the session it belongs to and a new
+// refresh token for that session. If the token was already used, the whole
+// session is revoked and ErrRefreshTokenReused is returned.
+func (r RefreshTokens) Exchange(ctx context.Context, token string) (RefreshSession, string, error) {
+	var session RefreshSession
+	txn, err := r.ImsDB.BeginTx(ctx, nil)
+	if err != nil {
+		return session, "", fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+	now := time.Now()
+
+	row, err := q.RefreshToken(ctx, hashRefreshToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return session, "", fmt.Errorf("[RefreshToken]: %w", err)
+	}
+	stored := row.RefreshToken
+	session = RefreshSession{Handle: stored.RangerHandle, DirectoryID: stored.DirectoryID, Family: stored.Family}
+	if stored.Revoked.Valid || stored.Expires < toFloat(now) {
+		return session, "", ErrInvalidRefreshToken
+	}
+	used, err := q.UseRefreshToken(ctx, imsdb.UseRefreshTokenParams{Used: sql.NullFloat64{Float64: toFloat(now), Valid: true}, ID: stored.ID})
+	if err != nil {
+		return session, "", fmt.Errorf("[UseRefreshToken]: %w", err)
+	}
+	if used == 0 {
+		// Either this token was exchanged before, or it's being exchanged right now
+		// by another request. Whoever has it, neither should carry on.
+		if _, err = q.RevokeRefreshTokenFamily(ctx, imsdb.RevokeRefreshTokenFamilyParams{
+			Revoked: sql.NullFloat64{Float64: toFloat(now), Valid: true}, Family: stored.Family,
+		}); err != nil {
+			return session, "", fmt.Errorf("[RevokeRefreshTokenFamily]: %w", err)
+		}
+		if err = txn.Commit(); err != nil {
+			return session, "", fmt.Errorf("[Commit]: %w", err)
+		}
+		slog.Warn("A used refresh token was presented again, so its session was revoked",
+			"handle", stored.RangerHandle, "family", stored.Family)
+		return session, "", ErrRefreshTokenReused
+	}
+	next, err := r.create(ctx, q, session, now)
+	if err != nil {
+		return session, "", fmt.Errorf("[create]: %w", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return session, "", fmt.Errorf("[Commit]: %w", err)
+	}
+	return session, next, nil
+}
+
+// Revoke revokes every refresh token of a session.
+func (r RefreshTokens) Revoke(ctx context.Context, session RefreshSession) error {
+	_, err := imsdb.New(r.ImsDB).RevokeRefreshTokenFamily(ctx, imsdb.RevokeRefreshTokenFamilyParams{
+		Revoked: sql.NullFloat64{Float64: toFloat(time.Now()), Valid: true}, Family: session.Family,
+	})
+	if err != nil {
+		return fmt.Errorf("[RevokeRefreshTokenFamily]: %w", err)
+	}
+	return nil
+}
+
+// RevokeToken revokes the session that a refresh token belongs to. An unknown
+// token is ignored.
+func (r RefreshTokens) RevokeToken(ctx context.Context, token string) error {
+	row, err := imsdb.New(r.ImsDB).RefreshToken(ctx, hashRefreshToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("[RefreshToken]: %w", err)
+	}
+	return r.Revoke(ctx, RefreshSession{Family: row.RefreshToken.Family})
+}
+
+func (r RefreshTokens) create(ctx context.Context, q *imsdb.Queries, session RefreshSession, now time.Time) (string, error) {
+	token := rand.Text()
+	err := q.CreateRefreshToken(ctx, imsdb.CreateRefreshTokenParams{
+		TokenHash:    hashRefreshToken(token),
+		Family:       session.Family,
+		RangerHandle: session.Handle,
+		DirectoryID:  session.DirectoryID,
+		Created:      toFloat(now),
+		Expires:      toFloat(now.Add(r.Lifetime)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("[CreateRefreshToken]: %w", err)
+	}
+	return token, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toFloat(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	tokens := RefreshTokens{ImsDB: db, Lifetime: time.Hour}
+
+	first, err := tokens.Issue(ctx, "Hardware", 12345)
+	require.NoError(t, err)
+	session, second, err := tokens.Exchange(ctx, first)
+	require.NoError(t, err)
+	require.Equal(t, "Hardware", session.Handle)
+	require.Equal(t, int64(12345), session.DirectoryID)
+	require.NotEqual(t, first, second)
+
+	// Rotation keeps the session going
+	again, third, err := tokens.Exchange(ctx, second)
+	require.NoError(t, err)
+	require.Equal(t, session.Family, again.Family)
+
+	// Reusing a spent token revokes the whole session
+	_, _, err = tokens.Exchange(ctx, first)
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, _, err = tokens.Exchange(ctx, third)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Other sessions are unaffected
+	other, err := tokens.Issue(ctx, "Hardware", 12345)
+	require.NoError(t, err)
+	_, _, err = tokens.Exchange(ctx, other)
+	require.NoError(t, err)
+
+	_, _, err = tokens.Exchange(ctx, "not a token")
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	expired, err := RefreshTokens{ImsDB: db, Lifetime: -time.Minute}.Issue(ctx, "Hardware", 12345)
+	require.NoError(t, err)
+	_, _, err = tokens.Exchange(ctx, expired)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	revoked, err := tokens.Issue(ctx, "Hardware", 12345)
+	require.NoError(t, err)
+	session, _, err = tokens.Exchange(ctx, revoked)
+	require.NoError(t, err)
+	require.NoError(t, tokens.Revoke(ctx, session))
+	_, _, err = tokens.Exchange(ctx, revoked)
+	require.Error(t, err)
+}
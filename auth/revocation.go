@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"time"
+)
+
+// ErrTokenRevoked means an otherwise valid token has been revoked.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Revocations is the store of revoked access tokens. JWTs are good until they
+// expire, so this is what lets a token be cut off sooner: by its "jti" when
+// someone logs out, or all of a Ranger's tokens at once.
+type Revocations struct {
+	ImsDB *store.DB
+}
+
+// Check returns ErrTokenRevoked if the token has been revoked.
+func (r Revocations) Check(ctx context.Context, claims IMSClaims) error {
+	count, err := imsdb.New(r.ImsDB).TokenRevocationCount(ctx, imsdb.TokenRevocationCountParams{
+		Jti:          sql.NullString{String: claims.ID(), Valid: claims.ID() != ""},
+		RangerHandle: sql.NullString{String: claims.RangerHandle(), Valid: true},
+		IssuedAt:     claims.IssuedAtSeconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("[TokenRevocationCount]: %w", err)
+	}
+	if count > 0 {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// RevokeToken revokes one token, until it would have expired anyway. A token
+// without a "jti" can't be revoked on its own, and is left alone.
+func (r Revocations) RevokeToken(ctx context.Context, claims IMSClaims) error {
+	if claims.ID() == "" {
+		return nil
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return fmt.Errorf("[GetExpirationTime]: token has no valid expiration: %w", err)
+	}
+	q := imsdb.New(r.ImsDB)
+	now := time.Now()
+	if err = q.PruneTokenRevocations(ctx, toFloat(now)); err != nil {
+		return fmt.Errorf("[PruneTokenRevocations]: %w", err)
+	}
+	err = q.CreateTokenRevocation(ctx, imsdb.CreateTokenRevocationParams{
+		Jti:     sql.NullString{String: claims.ID(), Valid: true},
+		Created: toFloat(now),
+		Expires: toFloat(exp.Time),
+	})
+	if err != nil {
+		return fmt.Errorf("[CreateTokenRevocation]: %w", err)
+	}
+	return nil
+}
+
+// RevokeHandle revokes every access token and refresh token issued to a Ranger
+// so far, so they'll need to log in again. Access tokens last no longer than
+// tokenLifetime, after which the revocation is no longer needed. Pass in the
+// Queries of a transaction to have this stand or fall with other changes.
+// It returns the number of refresh tokens that were revoked.
+func RevokeHandle(ctx context.Context, q *imsdb.Queries, handle string, tokenLifetime time.Duration) (int64, error) {
+	now := time.Now()
+	if err := q.PruneTokenRevocations(ctx, toFloat(now)); err != nil {
+		return 0, fmt.Errorf("[PruneTokenRevocations]: %w", err)
+	}
+	err := q.CreateTokenRevocation(ctx, imsdb.CreateTokenRevocationParams{
+		RangerHandle: sql.NullString{String: handle, Valid: true},
+		Created:      toFloat(now),
+		Expires:      toFloat(now.Add(tokenLifetime)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[CreateTokenRevocation]: %w", err)
+	}
+	refreshTokens, err := q.RevokeRefreshTokensForHandle(ctx, imsdb.RevokeRefreshTokensForHandleParams{
+		Revoked:      sql.NullFloat64{Float64: toFloat(now), Valid: true},
+		RangerHandle: handle,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[RevokeRefreshTokensForHandle]: %w", err)
+	}
+	return refreshTokens, nil
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevocations(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	revocations := Revocations{ImsDB: db}
+	jwter := JWTer{SecretKey: "some-secret"}
+
+	claimsFor := func(handle string) IMSClaims {
+		t.Helper()
+		claims, err := jwter.AuthenticateJWT(jwter.CreateJWT(handle, 1, nil, nil, true, time.Hour))
+		require.NoError(t, err)
+		require.NotEmpty(t, claims.ID())
+		return *claims
+	}
+	first := claimsFor("Hardware")
+	second := claimsFor("Hardware")
+	require.NotEqual(t, first.ID(), second.ID())
+	require.NoError(t, revocations.Check(ctx, first))
+
+	// Revoking one token leaves others alone
+	require.NoError(t, revocations.RevokeToken(ctx, first))
+	require.ErrorIs(t, revocations.Check(ctx, first), ErrTokenRevoked)
+	require.NoError(t, revocations.Check(ctx, second))
+
+	// Revoking a handle revokes all its tokens and refresh tokens so far, but not later ones
+	refreshTokens := RefreshTokens{ImsDB: db, Lifetime: time.Hour}
+	refresh, err := refreshTokens.Issue(ctx, "Hardware", 1)
+	require.NoError(t, err)
+	parenthetical := claimsFor("Parenthetical")
+	revoked, err := RevokeHandle(ctx, imsdb.New(db), "Hardware", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revoked)
+	require.ErrorIs(t, revocations.Check(ctx, second), ErrTokenRevoked)
+	require.NoError(t, revocations.Check(ctx, parenthetical))
+	_, _, err = refreshTokens.Exchange(ctx, refresh)
+	require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	require.NoError(t, revocations.Check(ctx, claimsFor("Hardware")))
+
+	// A token without a jti can't be revoked by itself, but is by its handle
+	noID := claimsFor("Hardware")
+	delete(noID.MapClaims, "jti")
+	require.NoError(t, revocations.RevokeToken(ctx, noID))
+	require.NoError(t, revocations.Check(ctx, noID))
+}
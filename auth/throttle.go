@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The kinds of things that failed logins are counted against.
+const (
+	LoginKindRanger         = "ranger"
+	LoginKindIdentification = "identification"
+	LoginKindClient         = "client"
+)
+
+// pruneLoginFailuresEvery is how often forgotten failures are cleared out.
+const pruneLoginFailuresEvery = time.Minute
+
+// LoginThrottle limits how fast logins can be attempted, for each Ranger and
+// each client IP address. After a few failures in a row, each attempt has to
+// wait longer than the last, and after more, there's a lockout.
+type LoginThrottle struct {
+	cfg       conf.LoginThrottle
+	failures  loginFailureStore
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// LoginAttempt is who's trying to log in, and from where.
+type LoginAttempt struct {
+	// Handle is of the Ranger whose identification was given, or empty if it
+	// matched no one.
+	Handle string
+	// Identification is what was given to log in with.
+	Identification string
+	// Client is the client's IP address, if it's known.
+	Client string
+}
+
+// LoginBlock is a Ranger or client that can't try to log in again until Until.
+type LoginBlock struct {
+	// Kind is LoginKindRanger or LoginKindClient
+	Kind        string
+	Name        string
+	Failures    int32
+	LastFailure time.Time
+	Until       time.Time
+	// LockedOut is whether this is a lockout, rather than a wait between attempts.
+	LockedOut bool
+}
+
+type loginKey struct {
+	kind string
+	name string
+}
+
+type loginFailures struct {
+	failures     int32
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// loginFailureStore is where the failed logins are kept. It may be shared by
+// several servers.
+type loginFailureStore interface {
+	// update calls fn with the failures for each of the keys, then stores what
+	// fn returns for them, all atomically, so that no one else's failures can be
+	// lost in between. A key with no failures yet gets the zero loginFailures.
+	// If fn returns nil, nothing is changed.
+	update(ctx context.Context, keys []loginKey, fn func([]loginFailures) []loginFailures) error
+	remove(ctx context.Context, key loginKey) error
+	blocked(ctx context.Context, now time.Time) (map[loginKey]loginFailures, error)
+	prune(ctx context.Context, before time.Time) error
+}
+
+// NewLoginThrottle makes a LoginThrottle that keeps failed logins in memory, or
+// in the IMS database if cfg.Persist is set.
+func NewLoginThrottle(cfg conf.LoginThrottle, imsDB *store.DB) *LoginThrottle {
+	t := &LoginThrottle{cfg: cfg, failures: newMemoryLoginFailures(), now: time.Now}
+	if cfg.Persist {
+		t.failures = dbLoginFailures{imsDB: imsDB}
+	}
+	return t
+}
+
+// Attempt is called before a password is checked. If there have been too many
+// failed logins, it returns how long to wait before trying again. Otherwise, the
+// attempt counts as a failure until Succeeded is called, so that attempts made
+// in parallel can't get around the throttle.
+func (t *LoginThrottle) Attempt(ctx context.Context, attempt LoginAttempt) (time.Duration, error) {
+	if err := t.prune(ctx, t.now()); err != nil {
+		return 0, fmt.Errorf("[prune]: %w", err)
+	}
+
+	keys := attempt.keys()
+	var wait time.Duration
+	err := t.failures.update(ctx, keys, func(records []loginFailures) []loginFailures {
+		// This is only read once the records are locked, so that a failure
+		// can't be recorded as happening before the one ahead of it
+		now := t.now()
+		wait = 0
+		for i, f := range records {
+			if now.Sub(f.last) >= t.forgetAfter() {
+				records[i] = loginFailures{}
+			}
+			wait = max(wait, records[i].blockedUntil.Sub(now))
+		}
+		if wait > 0 {
+			return nil
+		}
+		for i, key := range keys {
+			f := &records[i]
+			f.failures++
+			f.last = now
+			f.blockedUntil = now.Add(t.delay(key.kind, f.failures))
+			if t.lockedOut(key.kind, f.failures) && !t.lockedOut(key.kind, f.failures-1) && key.kind != LoginKindIdentification {
+				slog.Warn("Locked out of logging in after too many failures",
+					"kind", key.kind, "name", key.name, "until", f.blockedUntil)
+			}
+		}
+		return records
+	})
+	if err != nil {
+		return 0, fmt.Errorf("[update]: %w", err)
+	}
+	return wait, nil
+}
+
+// Succeeded takes back the failure that Attempt counted. All of the Ranger's
+// failures are forgotten, but a client's are only reduced by one, since others
+// might be failing to log in from the same address.
+func (t *LoginThrottle) Succeeded(ctx context.Context, attempt LoginAttempt) error {
+	for _, key := range attempt.keys() {
+		if key.kind != LoginKindClient {
+			if err := t.failures.remove(ctx, key); err != nil {
+				return fmt.Errorf("[remove]: %w", err)
+			}
+			continue
+		}
+		err := t.failures.update(ctx, []loginKey{key}, func(records []loginFailures) []loginFailures {
+			f := &records[0]
+			if f.failures == 0 {
+				return nil
+			}
+			f.failures--
+			f.blockedUntil = f.last.Add(t.delay(key.kind, f.failures))
+			return records
+		})
+		if err != nil {
+			return fmt.Errorf("[update]: %w", err)
+		}
+	}
+	return nil
+}
+
+// Blocked returns the Rangers and clients that can't try to log in right now,
+// the longest blocked first.
+func (t *LoginThrottle) Blocked(ctx context.Context) ([]LoginBlock, error) {
+	now := t.now()
+	blocked, err := t.failures.blocked(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("[blocked]: %w", err)
+	}
+	result := []LoginBlock{}
+	for key, f := range blocked {
+		// These are hashes, so there'd be nothing to recognize
+		if key.kind == LoginKindIdentification {
+			continue
+		}
+		result = append(result, LoginBlock{
+			Kind:        key.kind,
+			Name:        key.name,
+			Failures:    f.failures,
+			LastFailure: f.last,
+			Until:       f.blockedUntil,
+			LockedOut:   t.lockedOut(key.kind, f.failures),
+		})
+	}
+	slices.SortFunc(result, func(a, b LoginBlock) int {
+		return cmp.Or(b.Until.Compare(a.Until), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+	return result, nil
+}
+
+// delay is how long to wait after some number of failures in a row.
+func (t *LoginThrottle) delay(kind string, failures int32) time.Duration {
+	free := t.cfg.FreeAttempts
+	if kind == LoginKindClient {
+		free = t.cfg.ClientFreeAttempts
+	}
+	if t.lockedOut(kind, failures) {
+		return t.cfg.LockoutDuration
+	}
+	if failures < free {
+		return 0
+	}
+	delay := t.cfg.BaseDelay
+	for range failures - free {
+		if delay >= t.cfg.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, t.cfg.MaxDelay)
+}
+
+func (t *LoginThrottle) lockedOut(kind string, failures int32) bool {
+	lockoutAfter := t.cfg.LockoutAfter
+	if kind == LoginKindClient {
+		lockoutAfter = t.cfg.ClientLockoutAfter
+	}
+	return lockoutAfter > 0 && failures >= lockoutAfter
+}
+
+// forgetAfter is how long it takes for failures to be forgotten. That's never
+// before a wait or lockout has ended.
+func (t *LoginThrottle) forgetAfter() time.Duration {
+	return max(t.cfg.LockoutDuration, t.cfg.MaxDelay)
+}
+
+func (t *LoginThrottle) prune(ctx context.Context, now time.Time) error {
+	t.pruneMu.Lock()
+	defer t.pruneMu.Unlock()
+	if now.Sub(t.lastPrune) < pruneLoginFailuresEvery {
+		return nil
+	}
+	if err := t.failures.prune(ctx, now.Add(-t.forgetAfter())); err != nil {
+		return fmt.Errorf("[prune]: %w", err)
+	}
+	t.lastPrune = now
+	return nil
+}
+
+func (a LoginAttempt) keys() []loginKey {
+	var keys []loginKey
+	if a.Handle != "" {
+		keys = append(keys, loginKey{kind: LoginKindRanger, name: a.Handle})
+	} else {
+		// This could be a password typed into the wrong field, so only a hash is kept
+		sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(a.Identification))))
+		keys = append(keys, loginKey{kind: LoginKindIdentification, name: hex.EncodeToString(sum[:])})
+	}
+	if a.Client != "" {
+		keys = append(keys, loginKey{kind: LoginKindClient, name: a.Client})
+	}
+	return keys
+}
+
+// memoryLoginFailures keeps failures for just this server. Its mutex makes each
+// call atomic.
+type memoryLoginFailures struct {
+	mu       sync.Mutex
+	failures map[loginKey]loginFailures
+}
+
+func newMemoryLoginFailures() *memoryLoginFailures {
+	return &memoryLoginFailures{failures: make(map[loginKey]loginFailures)}
+}
+
+func (m *memoryLoginFailures) update(_ context.Context, keys []loginKey, fn func([]loginFailures) []loginFailures) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := make([]loginFailures, len(keys))
+	for i, key := range keys {
+		records[i] = m.failures[key]
+	}
+	for i, f := range fn(records) {
+		m.failures[keys[i]] = f
+	}
+	return nil
+}
+
+func (m *memoryLoginFailures) remove(_ context.Context, key loginKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, key)
+	return nil
+}
+
+func (m *memoryLoginFailures) blocked(_ context.Context, now time.Time) (map[loginKey]loginFailures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	blocked := make(map[loginKey]loginFailures)
+	for key, f := range m.failures {
+		if f.blockedUntil.After(now) {
+			blocked[key] = f
+		}
+	}
+	return blocked, nil
+}
+
+func (m *memoryLoginFailures) prune(_ context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, f := range m.failures {
+		if f.last.Before(before) {
+			delete(m.failures, key)
+		}
+	}
+	return nil
+}
+
+type dbLoginFailures struct {
+	imsDB *store.DB
+}
+
+// update locks the keys' rows for the length of a transaction, so servers
+// sharing the database take turns. The keys always come in the same order of
+// kinds, so two logins can't each be waiting on a row the other has locked.
+func (d dbLoginFailures) update(ctx context.Context, keys []loginKey, fn func([]loginFailures) []loginFailures) error {
+	txn, err := d.imsDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+
+	records := make([]loginFailures, len(keys))
+	for i, key := range keys {
+		err = q.CreateLoginFailureOrIgnore(ctx, imsdb.CreateLoginFailureOrIgnoreParams{Kind: key.kind, Name: key.name})
+		if err != nil {
+			return fmt.Errorf("[CreateLoginFailureOrIgnore]: %w", err)
+		}
+		row, err := q.LockLoginFailure(ctx, imsdb.LockLoginFailureParams{Kind: key.kind, Name: key.name})
+		if err != nil {
+			return fmt.Errorf("[LockLoginFailure]: %w", err)
+		}
+		records[i] = fromLoginFailureRow(row.LoginFailure)
+	}
+	for i, f := range fn(records) {
+		err = q.UpdateLoginFailure(ctx, imsdb.UpdateLoginFailureParams{
+			Failures:     f.failures,
+			LastFailure:  toFloat(f.last),
+			BlockedUntil: toFloat(f.blockedUntil),
+			Kind:         keys[i].kind,
+			Name:         keys[i].name,
+		})
+		if err != nil {
+			return fmt.Errorf("[UpdateLoginFailure]: %w", err)
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("[Commit]: %w", err)
+	}
+	return nil
+}
+
+func (d dbLoginFailures) remove(ctx context.Context, key loginKey) error {
+	err := imsdb.New(d.imsDB).DeleteLoginFailure(ctx, imsdb.DeleteLoginFailureParams{Kind: key.kind, Name: key.name})
+	if err != nil {
+		return fmt.Errorf("[DeleteLoginFailure]: %w", err)
+	}
+	return nil
+}
+
+func (d dbLoginFailures) blocked(ctx context.Context, now time.Time) (map[loginKey]loginFailures, error) {
+	rows, err := imsdb.New(d.imsDB).BlockedLoginFailures(ctx, toFloat(now))
+	if err != nil {
+		return nil, fmt.Errorf("[BlockedLoginFailures]: %w", err)
+	}
+	blocked := make(map[loginKey]loginFailures)
+	for _, row := range rows {
+		blocked[loginKey{kind: row.LoginFailure.Kind, name: row.LoginFailure.Name}] = fromLoginFailureRow(row.LoginFailure)
+	}
+	return blocked, nil
+}
+
+func (d dbLoginFailures) prune(ctx context.Context, before time.Time) error {
+	if err := imsdb.New(d.imsDB).PruneLoginFailures(ctx, toFloat(before)); err != nil {
+		return fmt.Errorf("[PruneLoginFailures]: %w", err)
+	}
+	return nil
+}
+
+func fromLoginFailureRow(row imsdb.LoginFailure) loginFailures {
+	return loginFailures{
+		failures:     row.Failures,
+		last:         time.UnixMicro(int64(row.LastFailure * 1e6)),
+		blockedUntil: time.UnixMicro(int64(row.BlockedUntil * 1e6)),
+	}
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testThrottleCfg = conf.LoginThrottle{
+	FreeAttempts:       2,
+	BaseDelay:          time.Second,
+	MaxDelay:           4 * time.Second,
+	LockoutAfter:       6,
+	LockoutDuration:    time.Minute,
+	ClientFreeAttempts: 10,
+	ClientLockoutAfter: 20,
+}
+
+// fakeClock returns a LoginThrottle's clock, and a way to move it forward.
+func fakeClock(throttle *LoginThrottle) func(time.Duration) {
+	now := time.Now()
+	throttle.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLoginThrottleBackoffAndLockout(t *testing.T) {
+	ctx := t.Context()
+	throttle := NewLoginThrottle(testThrottleCfg, nil)
+	advance := fakeClock(throttle)
+	hardware := LoginAttempt{Handle: "Hardware", Identification: "hardware@example.com", Client: "10.0.0.1"}
+
+	// Each failure after the free ones has to wait twice as long, up to the max
+	for _, wantWait := range []time.Duration{0, 0, 1 * time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		wait, err := throttle.Attempt(ctx, hardware)
+		require.NoError(t, err)
+		require.Equal(t, wantWait, wait)
+		if wait > 0 {
+			advance(wait)
+			wait, err = throttle.Attempt(ctx, hardware)
+			require.NoError(t, err)
+			require.Zero(t, wait)
+		}
+	}
+
+	// That was the sixth failure, so now it's a lockout
+	wait, err := throttle.Attempt(ctx, hardware)
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, wait)
+	blocked, err := throttle.Blocked(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocked, 1)
+	require.Equal(t, LoginKindRanger, blocked[0].Kind)
+	require.Equal(t, "Hardware", blocked[0].Name)
+	require.Equal(t, int32(6), blocked[0].Failures)
+	require.True(t, blocked[0].LockedOut)
+
+	// Someone else from the same client isn't affected
+	wait, err = throttle.Attempt(ctx, LoginAttempt{Handle: "Loosy", Client: "10.0.0.1"})
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	// Once the lockout is over, the failures are forgotten
+	advance(time.Minute)
+	for range testThrottleCfg.FreeAttempts {
+		wait, err = throttle.Attempt(ctx, hardware)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+	blocked, err = throttle.Blocked(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocked, 1)
+	require.False(t, blocked[0].LockedOut)
+}
+
+func TestLoginThrottleSucceeded(t *testing.T) {
+	ctx := t.Context()
+	throttle := NewLoginThrottle(testThrottleCfg, nil)
+	advance := fakeClock(throttle)
+	hardware := LoginAttempt{Handle: "Hardware", Client: "10.0.0.1"}
+
+	for range testThrottleCfg.FreeAttempts {
+		wait, err := throttle.Attempt(ctx, hardware)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+	advance(time.Second)
+	wait, err := throttle.Attempt(ctx, hardware)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+	require.NoError(t, throttle.Succeeded(ctx, hardware))
+
+	// The Ranger's failures are gone, but the client's other ones are kept
+	wait, err = throttle.Attempt(ctx, hardware)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+	client := throttle.failures.(*memoryLoginFailures).failures[loginKey{kind: LoginKindClient, name: "10.0.0.1"}]
+	require.Equal(t, int32(3), client.failures)
+	ranger := throttle.failures.(*memoryLoginFailures).failures[loginKey{kind: LoginKindRanger, name: "Hardware"}]
+	require.Equal(t, int32(1), ranger.failures)
+}
+
+func TestLoginThrottleClient(t *testing.T) {
+	ctx := t.Context()
+	throttle := NewLoginThrottle(testThrottleCfg, nil)
+	fakeClock(throttle)
+
+	// Guessing at many identifications from one client gets it throttled
+	for i := range testThrottleCfg.ClientFreeAttempts {
+		wait, err := throttle.Attempt(ctx, LoginAttempt{Identification: string(rune('a' + i)), Client: "10.0.0.2"})
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+	wait, err := throttle.Attempt(ctx, LoginAttempt{Identification: "z", Client: "10.0.0.2"})
+	require.NoError(t, err)
+	require.Equal(t, time.Second, wait)
+	wait, err = throttle.Attempt(ctx, LoginAttempt{Identification: "z", Client: "10.0.0.3"})
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	// The identifications aren't shown, or even kept
+	blocked, err := throttle.Blocked(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocked, 1)
+	require.Equal(t, LoginKindClient, blocked[0].Kind)
+	require.Equal(t, "10.0.0.2", blocked[0].Name)
+	for key := range throttle.failures.(*memoryLoginFailures).failures {
+		require.NotEqual(t, "z", key.name)
+	}
+}
+
+func TestLoginThrottlePersist(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	cfg := testThrottleCfg
+	cfg.Persist = true
+	hardware := LoginAttempt{Handle: "Hardware", Client: "10.0.0.1"}
+
+	first := NewLoginThrottle(cfg, db)
+	for range cfg.FreeAttempts {
+		wait, err := first.Attempt(ctx, hardware)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+
+	// Another server, or this one after a restart, sees the same failures
+	second := NewLoginThrottle(cfg, db)
+	wait, err := second.Attempt(ctx, hardware)
+	require.NoError(t, err)
+	require.Greater(t, wait, 500*time.Millisecond)
+	blocked, err := second.Blocked(ctx)
+	require.NoError(t, err)
+	require.Len(t, blocked, 1)
+	require.Equal(t, "Hardware", blocked[0].Name)
+	require.Equal(t, int32(2), blocked[0].Failures)
+
+	require.NoError(t, second.Succeeded(ctx, hardware))
+	blocked, err = first.Blocked(ctx)
+	require.NoError(t, err)
+	require.Empty(t, blocked)
+}
+
+func TestLoginThrottlePersistConcurrent(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	cfg := testThrottleCfg
+	cfg.Persist = true
+	cfg.FreeAttempts = 100
+	cfg.ClientFreeAttempts = 100
+	cfg.LockoutAfter = 0
+	cfg.ClientLockoutAfter = 0
+	hardware := LoginAttempt{Handle: "Hardware", Client: "10.0.0.1"}
+
+	// Two servers, with even the very first failures racing each other
+	throttles := []*LoginThrottle{NewLoginThrottle(cfg, db), NewLoginThrottle(cfg, db)}
+	const attempts = 20
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := throttles[i%2].Attempt(ctx, hardware)
+			assert.NoError(t, err)
+			assert.Zero(t, wait)
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range hardware.keys() {
+		row, err := imsdb.New(db).LockLoginFailure(ctx, imsdb.LockLoginFailureParams{Kind: key.kind, Name: key.name})
+		require.NoError(t, err)
+		require.Equal(t, int32(attempts), row.LoginFailure.Failures, key.kind)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup FILE",
+	Short: "Write a backup of the whole IMS database",
+	Long: "Write a backup of every IMS table to FILE, as a consistent snapshot taken in one transaction. " +
+		"It's safe to run while the server is up.\n\n" +
+		"The backup is compressed, and carries a checksum and the database's schema version. " +
+		"Attached files aren't included; back up the attachment store separately.",
+	Args: cobra.ExactArgs(1),
+	Run:  runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore FILE",
+	Short: "Restore the IMS database from a backup",
+	Long: "Restore the IMS database from a backup written by the backup command.\n\n" +
+		"The backup is checked in full before anything changes, and is refused if it's corrupt or was taken " +
+		"at a different schema version than this build uses. A database with no IMS schema gets the schema " +
+		"first. A database that already has events is only overwritten with --replace, and then everything " +
+		"in it is replaced. Stop the server before restoring.",
+	Args: cobra.ExactArgs(1),
+	Run:  runRestore,
+}
+
+var restoreReplace bool
+
+func runBackup(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	must(store.CheckSchemaVersion(ctx, imsDB))
+
+	// Write to a temporary file first, so a failed backup never looks like a good one
+	path := args[0]
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	must(err)
+	defer os.Remove(f.Name())
+	summary, err := store.Backup(ctx, imsDB, f)
+	if err != nil {
+		_ = f.Close()
+		must(err)
+	}
+	must(f.Close())
+	must(os.Rename(f.Name(), path))
+	printBackupSummary(cmd, "Backed up", path, summary)
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+
+	f, err := os.Open(args[0])
+	must(err)
+	defer f.Close()
+	summary, err := store.Restore(ctx, imsDB, f, restoreReplace)
+	must(err)
+	printBackupSummary(cmd, "Restored", args[0], summary)
+}
+
+func printBackupSummary(cmd *cobra.Command, verb, path string, summary store.BackupSummary) {
+	var total int64
+	var tables []string
+	for table, rows := range summary.Rows {
+		total += rows
+		tables = append(tables, fmt.Sprintf("%v %v", table, rows))
+	}
+	slices.Sort(tables)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%v %v (schema version %v, taken %v): %v rows\n  %v\n",
+		verb, path, summary.Header.SchemaVersion, summary.Header.Created.Format("2006-01-02 15:04:05 MST"),
+		total, strings.Join(tables, "\n  "))
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVar(&restoreReplace, "replace", false, "replace everything in a database that already has events")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/srabraham/ranger-ims-go/archive"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"io"
+	"os"
+	"slices"
+)
+
+var exportEventCmd = &cobra.Command{
+	Use:   "export-event EVENT",
+	Short: "Write an archive of an event's data",
+	Long: "Write an archive of an event's data: its streets, access rules, incidents, field reports, and report entries.\n\n" +
+		"The archive is JSON lines. Attached files aren't included, only their names in the attachment store.\n\n" +
+		"With --anonymize, the archive can be shared without exposing anyone: Ranger handles and report entry " +
+		"authors become pseudonyms, email addresses, phone numbers, and the configured scrub patterns are taken " +
+		"out of text, and stricken entries, attached file names, and access rules are left out.",
+	Args: cobra.ExactArgs(1),
+	Run:  runExportEvent,
+}
+
+var importEventCmd = &cobra.Command{
+	Use:   "import-event FILE",
+	Short: "Load an event's data from an archive",
+	Long: "Load an event's data from an archive written by export-event, as a new event.\n\n" +
+		"Report entries get new IDs. Use \"-\" as FILE to read the archive from stdin.",
+	Args: cobra.ExactArgs(1),
+	Run:  runImportEvent,
+}
+
+var (
+	exportEventOutput    string
+	exportEventAnonymize bool
+	exportEventScrub     []string
+	importEventAs        string
+)
+
+func runExportEvent(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	must(store.CheckSchemaVersion(ctx, imsDB))
+
+	var w io.Writer = cmd.OutOrStdout()
+	if exportEventOutput != "" {
+		f, err := os.Create(exportEventOutput)
+		must(err)
+		defer f.Close()
+		w = f
+	}
+	var anon *archive.Anonymizer
+	if exportEventAnonymize {
+		key := conf.Cfg.Anonymize.PseudonymKey
+		if key == "" {
+			key = rand.Text()
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "No pseudonym key is configured, so this export's pseudonyms won't match any other's")
+		}
+		var err error
+		anon, err = archive.NewAnonymizer(key, slices.Concat(conf.Cfg.Anonymize.ScrubPatterns, exportEventScrub))
+		must(err)
+	}
+	summary, err := archive.Export(ctx, imsDB, args[0], w, anon)
+	must(err)
+	if exportEventOutput != "" {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Exported event %v to %v: %v incidents, %v field reports, %v report entries\n",
+			summary.Event, exportEventOutput, summary.Incidents, summary.FieldReports, summary.ReportEntries)
+	}
+}
+
+func runImportEvent(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	must(store.CheckSchemaVersion(ctx, imsDB))
+
+	var r io.Reader = cmd.InOrStdin()
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		must(err)
+		defer f.Close()
+		r = f
+	}
+	summary, err := archive.Import(ctx, imsDB, r, importEventAs)
+	must(err)
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Imported event %v: %v incidents, %v field reports, %v report entries\n",
+		summary.Event, summary.Incidents, summary.FieldReports, summary.ReportEntries)
+}
+
+func init() {
+	rootCmd.AddCommand(exportEventCmd)
+	rootCmd.AddCommand(importEventCmd)
+
+	exportEventCmd.Flags().StringVarP(&exportEventOutput, "output", "o", "", "file to write the archive to, instead of stdout")
+	exportEventCmd.Flags().BoolVar(&exportEventAnonymize, "anonymize", false, "leave out what identifies people, so the archive can be shared")
+	exportEventCmd.Flags().StringArrayVar(&exportEventScrub, "scrub", nil, "regular expression to scrub from text when anonymizing, on top of the configured ones (repeatable)")
+	importEventCmd.Flags().StringVar(&importEventAs, "as", "", "name for the new event, instead of the archived event's name")
+}
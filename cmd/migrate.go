@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the IMS database schema",
+	Long: "Manage the IMS database schema\n\n" +
+		"The IMS server will only start once the database is at the schema version that it expects.",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Long: "Apply all pending migrations, bringing the database up to the schema version this build expects.\n\n" +
+		"A database with no IMS schema gets the full current schema.",
+	Run: runMigrateUp,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the database's schema version and any pending migrations",
+	Run:   runMigrateStatus,
+}
+
+var migrateDryRun bool
+
+func runMigrateUp(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	pending, err := store.PendingMigrations(ctx, imsDB)
+	must(err)
+	out := cmd.OutOrStdout()
+	if len(pending) == 0 {
+		_, _ = fmt.Fprintf(out, "Database is already at schema version %v\n", store.ExpectedSchemaVersion())
+		return
+	}
+	if migrateDryRun {
+		for _, m := range pending {
+			_, _ = fmt.Fprintf(out, "-- Migration to version %v: %v\n%v\n", m.Version, m.Name, m.Script)
+		}
+		_, _ = fmt.Fprintf(out, "Dry run: %v migration(s) would be applied\n", len(pending))
+		return
+	}
+	must(store.Migrate(ctx, imsDB))
+	for _, m := range pending {
+		_, _ = fmt.Fprintf(out, "Applied migration to version %v: %v\n", m.Version, m.Name)
+	}
+}
+
+func runMigrateStatus(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	current, err := store.SchemaVersion(ctx, imsDB)
+	must(err)
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Database schema version:  %v\n", current)
+	_, _ = fmt.Fprintf(out, "Expected schema version:  %v\n", store.ExpectedSchemaVersion())
+	pending, err := store.PendingMigrations(ctx, imsDB)
+	must(err)
+	for _, m := range pending {
+		_, _ = fmt.Fprintf(out, "Pending migration to version %v: %v\n", m.Version, m.Name)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+
+	migrateUpCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print the pending migrations without applying them")
+}
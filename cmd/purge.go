@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/srabraham/ranger-ims-go/attachment"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/retention"
+	"github.com/srabraham/ranger-ims-go/store"
+	"os/user"
+	"slices"
+	"time"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Purge identifying details from old events, per the retention policy",
+	Long: "Purge identifying details from events whose last activity was longer ago than the retention policy allows.\n\n" +
+		"Report entries written by people are redacted or deleted, per the retention action. Summaries, location " +
+		"descriptions, and the values in the audit log are redacted, and attached files are deleted. Everything " +
+		"needed for statistics is kept. Each purged event gets a \"purge\" record in the audit log.",
+	Run: runPurge,
+}
+
+var (
+	purgeDryRun bool
+	purgeYears  int32
+	purgeEvents []string
+)
+
+func runPurge(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	years := conf.Cfg.Retention.Years
+	if cmd.Flags().Changed("years") {
+		years = purgeYears
+	}
+	if years <= 0 {
+		_, _ = fmt.Fprintln(out, "No retention period is configured, so nothing will be purged")
+		return
+	}
+	imsDB := store.Open(conf.Cfg)
+	defer imsDB.Close()
+	must(store.CheckSchemaVersion(ctx, imsDB))
+
+	cutoff := time.Now().AddDate(-int(years), 0, 0)
+	events, err := retention.Expired(ctx, imsDB, cutoff)
+	must(err)
+	if len(purgeEvents) > 0 {
+		events = slices.DeleteFunc(events, func(e retention.Event) bool {
+			return !slices.Contains(purgeEvents, e.Name)
+		})
+	}
+
+	actor := "purge"
+	if u, err := user.Current(); err == nil {
+		actor = fmt.Sprintf("%v (purge)", u.Username)
+	}
+	purger := &retention.Purger{
+		ImsDB:  imsDB,
+		Blobs:  attachment.NewBlobStore(conf.Cfg.AttachmentsStore),
+		Action: conf.Cfg.Retention.Action,
+		Actor:  actor,
+		DryRun: purgeDryRun,
+	}
+	verb := "Purged"
+	if purgeDryRun {
+		verb = "Would purge"
+	}
+	_, _ = fmt.Fprintf(out, "Purging events with no activity since %v (action: %v)\n", cutoff.UTC().Format(time.DateOnly), purger.Action)
+	for _, event := range events {
+		result, err := purger.Purge(ctx, event)
+		if result.Empty() && err == nil {
+			_, _ = fmt.Fprintf(out, "Event %v (last activity %v): nothing to purge\n",
+				event.Name, event.LastActivity.UTC().Format(time.DateOnly))
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "%v event %v (last activity %v): %v report entries, %v summaries, "+
+			"%v location descriptions, %v incident changes, %v audit values, %v attached files\n",
+			verb, event.Name, event.LastActivity.UTC().Format(time.DateOnly), result.ReportEntries, result.Summaries,
+			result.LocationDescriptions, result.IncidentChanges, result.AuditValues, len(result.Attachments))
+		must(err)
+	}
+	if len(events) == 0 {
+		_, _ = fmt.Fprintln(out, "No events are due to be purged")
+	}
+	if purgeDryRun {
+		_, _ = fmt.Fprintln(out, "Dry run: nothing was changed")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+
+	purgeCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false, "print what would be purged without changing anything")
+	purgeCmd.Flags().Int32Var(&purgeYears, "years", 0, "retention period in years, instead of the configured one")
+	purgeCmd.Flags().StringArrayVar(&purgeEvents, "event", nil, "only purge this event, if it's due (repeatable)")
+}
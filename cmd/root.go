@@ -69,9 +69,17 @@ func initConfig() {
 		must(err)
 		newCfg.Core.TokenLifetime = time.Duration(seconds) * time.Second
 	}
+	if v, ok := os.LookupEnv("IMS_REFRESH_TOKEN_LIFETIME"); ok {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		must(err)
+		newCfg.Core.RefreshTokenLifetime = time.Duration(seconds) * time.Second
+	}
 	if v, ok := os.LookupEnv("IMS_LOG_LEVEL"); ok {
 		newCfg.Core.LogLevel = v
 	}
+	if v, ok := os.LookupEnv("IMS_BROADCAST"); ok {
+		newCfg.Core.Broadcast = conf.BroadcastType(strings.ToLower(v))
+	}
 	if v, ok := os.LookupEnv("IMS_DIRECTORY"); ok {
 		newCfg.Directory.Directory = conf.DirectoryType(strings.ToLower(v))
 	}
@@ -81,6 +89,18 @@ func initConfig() {
 	if v, ok := os.LookupEnv("IMS_JWT_SECRET"); ok {
 		newCfg.Core.JWTSecret = v
 	}
+	if v, ok := os.LookupEnv("IMS_JWT_SIGNING_KEY"); ok {
+		newCfg.Core.JWTSigningKey = v
+	}
+	if v, ok := os.LookupEnv("IMS_JWT_VERIFICATION_KEYS"); ok {
+		newCfg.Core.JWTVerificationKeys = strings.Split(v, ",")
+	}
+	if v, ok := os.LookupEnv("IMS_DB_TYPE"); ok {
+		newCfg.Store.Type = conf.StoreType(strings.ToLower(v))
+	}
+	if v, ok := os.LookupEnv("IMS_DB_SQLITE_PATH"); ok {
+		newCfg.Store.SQLite.Path = v
+	}
 	if v, ok := os.LookupEnv("IMS_DB_HOST_NAME"); ok {
 		newCfg.Store.MySQL.HostName = v
 	}
@@ -98,6 +118,35 @@ func initConfig() {
 	if v, ok := os.LookupEnv("IMS_DB_PASSWORD"); ok {
 		newCfg.Store.MySQL.Password = v
 	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_STORE"); ok {
+		newCfg.AttachmentsStore.Type = conf.AttachmentsStoreType(strings.ToLower(v))
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_LOCAL_DIR"); ok {
+		newCfg.AttachmentsStore.Local.Dir = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_S3_ACCESS_KEY_ID"); ok {
+		newCfg.AttachmentsStore.S3.S3AccessKeyId = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_S3_SECRET_ACCESS_KEY"); ok {
+		newCfg.AttachmentsStore.S3.S3SecretAccessKey = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_S3_DEFAULT_REGION"); ok {
+		newCfg.AttachmentsStore.S3.S3DefaultRegion = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_S3_BUCKET"); ok {
+		newCfg.AttachmentsStore.S3.S3Bucket = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_S3_ENDPOINT"); ok {
+		newCfg.AttachmentsStore.S3.S3Endpoint = v
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_MAX_SIZE"); ok {
+		num, err := strconv.ParseInt(v, 10, 64)
+		must(err)
+		newCfg.AttachmentsStore.MaxSize = num
+	}
+	if v, ok := os.LookupEnv("IMS_ATTACHMENTS_ALLOWED_TYPES"); ok {
+		newCfg.AttachmentsStore.AllowedTypes = strings.Split(v, ",")
+	}
 	if v, ok := os.LookupEnv("IMS_DMS_HOSTNAME"); ok {
 		newCfg.Directory.ClubhouseDB.Hostname = v
 	}
@@ -110,9 +159,65 @@ func initConfig() {
 	if v, ok := os.LookupEnv("IMS_DMS_PASSWORD"); ok {
 		newCfg.Directory.ClubhouseDB.Password = v
 	}
+	if v, ok := os.LookupEnv("IMS_ANONYMIZE_PSEUDONYM_KEY"); ok {
+		newCfg.Anonymize.PseudonymKey = v
+	}
+	if v, ok := os.LookupEnv("IMS_ANONYMIZE_SCRUB_PATTERNS"); ok {
+		newCfg.Anonymize.ScrubPatterns = strings.Fields(v)
+	}
+	if v, ok := os.LookupEnv("IMS_RETENTION_YEARS"); ok {
+		num, err := strconv.ParseInt(v, 10, 32)
+		must(err)
+		newCfg.Retention.Years = int32(num)
+	}
+	if v, ok := os.LookupEnv("IMS_RETENTION_ACTION"); ok {
+		newCfg.Retention.Action = conf.RetentionAction(strings.ToLower(v))
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_FREE_ATTEMPTS"); ok {
+		num, err := strconv.ParseInt(v, 10, 32)
+		must(err)
+		newCfg.LoginThrottle.FreeAttempts = int32(num)
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_MAX_DELAY"); ok {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		must(err)
+		newCfg.LoginThrottle.MaxDelay = time.Duration(seconds) * time.Second
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_LOCKOUT_AFTER"); ok {
+		num, err := strconv.ParseInt(v, 10, 32)
+		must(err)
+		newCfg.LoginThrottle.LockoutAfter = int32(num)
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_LOCKOUT_DURATION"); ok {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		must(err)
+		newCfg.LoginThrottle.LockoutDuration = time.Duration(seconds) * time.Second
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_CLIENT_FREE_ATTEMPTS"); ok {
+		num, err := strconv.ParseInt(v, 10, 32)
+		must(err)
+		newCfg.LoginThrottle.ClientFreeAttempts = int32(num)
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_CLIENT_LOCKOUT_AFTER"); ok {
+		num, err := strconv.ParseInt(v, 10, 32)
+		must(err)
+		newCfg.LoginThrottle.ClientLockoutAfter = int32(num)
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_CLIENT_IP_HEADER"); ok {
+		newCfg.LoginThrottle.ClientIPHeader = v
+	}
+	if v, ok := os.LookupEnv("IMS_LOGIN_THROTTLE_PERSIST"); ok {
+		persist, err := strconv.ParseBool(v)
+		must(err)
+		newCfg.LoginThrottle.Persist = persist
+	}
 
 	// Validations on the config created above
 	must(newCfg.Directory.Directory.Validate())
+	must(newCfg.Core.Broadcast.Validate())
+	must(newCfg.Store.Type.Validate())
+	must(newCfg.AttachmentsStore.Type.Validate())
+	must(newCfg.Retention.Action.Validate())
 	if newCfg.Core.Deployment != "dev" {
 		if newCfg.Directory.Directory == conf.DirectoryTypeTestUsers {
 			must(fmt.Errorf("do not use TestUsers outside dev! A ClubhouseDB must be provided"))
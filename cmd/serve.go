@@ -31,7 +31,9 @@ func runServer(cmd *cobra.Command, args []string) {
 	slog.SetLogLoggerLevel(logLevel)
 
 	log.Printf("Have config\n%v", imsCfg)
-	log.Printf("With JWTSecret: %v...%v", imsCfg.Core.JWTSecret[:1], imsCfg.Core.JWTSecret[len(imsCfg.Core.JWTSecret)-1:])
+	if imsCfg.Core.JWTSigningKey == "" {
+		log.Printf("With JWTSecret: %v...%v", imsCfg.Core.JWTSecret[:1], imsCfg.Core.JWTSecret[len(imsCfg.Core.JWTSecret)-1:])
+	}
 
 	var userStore *directory.UserStore
 	var err error
@@ -44,10 +46,11 @@ func runServer(cmd *cobra.Command, args []string) {
 		err = fmt.Errorf("unknown directory %v", imsCfg.Directory.Directory)
 	}
 	must(err)
-	imsDB := store.MariaDB(imsCfg)
+	imsDB := store.Open(imsCfg)
+	must(store.CheckSchemaVersion(cmd.Context(), imsDB))
 
 	mux := http.NewServeMux()
-	api.AddToMux(mux, imsCfg, &store.DB{DB: imsDB}, userStore)
+	api.AddToMux(mux, imsCfg, imsDB, userStore)
 	web.AddToMux(mux, imsCfg)
 
 	addr := fmt.Sprintf("%v:%v", imsCfg.Core.Host, imsCfg.Core.Port)
@@ -18,20 +18,53 @@ var (
 func DefaultIMS() *IMSConfig {
 	return &IMSConfig{
 		Core: ConfigCore{
-			Host:          "localhost",
-			Port:          80,
-			JWTSecret:     rand.Text(),
-			Deployment:    "dev",
-			LogLevel:      "INFO",
-			TokenLifetime: 1 * time.Hour,
+			Host:                 "localhost",
+			Port:                 80,
+			JWTSecret:            rand.Text(),
+			Deployment:           "dev",
+			LogLevel:             "INFO",
+			TokenLifetime:        1 * time.Hour,
+			RefreshTokenLifetime: 24 * time.Hour,
+			Broadcast:            BroadcastTypeLocal,
+		},
+		AttachmentsStore: AttachmentsStore{
+			Type: AttachmentsStoreTypeNone,
+			Local: AttachmentsStoreLocal{
+				Dir: "attachments",
+			},
+			MaxSize: 10 << 20,
+			AllowedTypes: []string{
+				"image/gif",
+				"image/jpeg",
+				"image/png",
+				"image/webp",
+				"application/pdf",
+				"text/plain",
+			},
 		},
 		Store: Store{
+			Type: StoreTypeMySQL,
+			SQLite: StoreSQLite{
+				Path: "ims.sqlite",
+			},
 			MySQL: StoreMySQL{
 				HostName: "localhost",
 				HostPort: 3306,
 				Database: "ims",
 			},
 		},
+		Retention: Retention{
+			Action: RetentionActionRedact,
+		},
+		LoginThrottle: LoginThrottle{
+			FreeAttempts:       3,
+			BaseDelay:          1 * time.Second,
+			MaxDelay:           5 * time.Minute,
+			LockoutAfter:       10,
+			LockoutDuration:    15 * time.Minute,
+			ClientFreeAttempts: 20,
+			ClientLockoutAfter: 100,
+		},
 		Directory: Directory{
 			Directory: DirectoryTypeClubhouseDB,
 			TestUsers: testUsers,
@@ -55,22 +88,21 @@ func (c *IMSConfig) String() string {
 }
 
 type IMSConfig struct {
-	Core ConfigCore
-	// TODO: finish attachments feature
-	AttachmentsStore struct {
-		S3 struct {
-			S3AccessKeyId     string
-			S3SecretAccessKey string
-			S3DefaultRegion   string
-			S3Bucket          string
-		}
-	}
-	Store     Store
-	Directory Directory
+	Core             ConfigCore
+	AttachmentsStore AttachmentsStore
+	Store            Store
+	Directory        Directory
+	Anonymize        Anonymize
+	Retention        Retention
+	LoginThrottle    LoginThrottle
 }
 
 type DirectoryType string
 type DeploymentType string
+type BroadcastType string
+type StoreType string
+type AttachmentsStoreType string
+type RetentionAction string
 
 const (
 	DirectoryTypeClubhouseDB DirectoryType = "clubhousedb"
@@ -78,6 +110,17 @@ const (
 	DeploymentTypeDev                      = "dev"
 	DeploymentTypeStaging                  = "staging"
 	DeploymentTypeProduction               = "production"
+	BroadcastTypeLocal       BroadcastType = "local"
+	BroadcastTypeDBPoll      BroadcastType = "dbpoll"
+	StoreTypeMySQL           StoreType     = "mysql"
+	StoreTypeSQLite          StoreType     = "sqlite"
+
+	AttachmentsStoreTypeNone  AttachmentsStoreType = "none"
+	AttachmentsStoreTypeLocal AttachmentsStoreType = "local"
+	AttachmentsStoreTypeS3    AttachmentsStoreType = "s3"
+
+	RetentionActionRedact RetentionAction = "redact"
+	RetentionActionDelete RetentionAction = "delete"
 )
 
 func (d DirectoryType) Validate() error {
@@ -89,6 +132,42 @@ func (d DirectoryType) Validate() error {
 	}
 }
 
+func (s StoreType) Validate() error {
+	switch s {
+	case StoreTypeMySQL, StoreTypeSQLite:
+		return nil
+	default:
+		return fmt.Errorf("unknown store type %v", s)
+	}
+}
+
+func (a AttachmentsStoreType) Validate() error {
+	switch a {
+	case AttachmentsStoreTypeNone, AttachmentsStoreTypeLocal, AttachmentsStoreTypeS3:
+		return nil
+	default:
+		return fmt.Errorf("unknown attachments store type %v", a)
+	}
+}
+
+func (r RetentionAction) Validate() error {
+	switch r {
+	case RetentionActionRedact, RetentionActionDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown retention action %v", r)
+	}
+}
+
+func (b BroadcastType) Validate() error {
+	switch b {
+	case BroadcastTypeLocal, BroadcastTypeDBPoll:
+		return nil
+	default:
+		return fmt.Errorf("unknown broadcast type %v", b)
+	}
+}
+
 func (d DeploymentType) Validate() error {
 	switch d {
 	case DeploymentTypeDev, DeploymentTypeStaging, DeploymentTypeProduction:
@@ -102,19 +181,71 @@ type ConfigCore struct {
 	Host          string
 	Port          int32
 	TokenLifetime time.Duration
-	Admins        []string
-	MasterKey     string
+	// RefreshTokenLifetime is how long a session can go unused before its refresh
+	// token expires and the user has to log in again. Zero turns refresh tokens off.
+	RefreshTokenLifetime time.Duration
+	Admins               []string
+	MasterKey            string
 	// JWTSecret won't get marshalled as part of String() due to the json "-" tag.
-	JWTSecret        string `json:"-"`
-	AttachmentsStore string
-	Deployment       string
+	JWTSecret string `json:"-"`
+	// JWTSigningKey is a PEM file with the private key that JWTs are signed with,
+	// either Ed25519 or RSA. Other services can verify these JWTs using the public
+	// keys from /ims/api/auth/jwks.json. When it's unset, JWTs are signed with
+	// JWTSecret instead.
+	JWTSigningKey string
+	// JWTVerificationKeys are PEM files with more keys that JWTs are accepted from.
+	// To rotate keys, keep the old signing key here until its JWTs have expired.
+	JWTVerificationKeys []string
+	Deployment          string
 
 	// LogLevel should be one of DEBUG, INFO, WARN, or ERROR
 	LogLevel string
+
+	// Broadcast is how updates get to the EventSource clients. BroadcastTypeLocal
+	// only reaches clients of this server, so use BroadcastTypeDBPoll when running
+	// more than one IMS server against the same database.
+	Broadcast BroadcastType
 }
 
 type Store struct {
-	MySQL StoreMySQL
+	Type   StoreType
+	MySQL  StoreMySQL
+	SQLite StoreSQLite
+}
+
+type AttachmentsStore struct {
+	// Type is where attached files are kept. With AttachmentsStoreTypeNone,
+	// files can't be attached at all.
+	Type  AttachmentsStoreType
+	Local AttachmentsStoreLocal
+	S3    AttachmentsStoreS3
+
+	// MaxSize is the largest file that may be attached, in bytes
+	MaxSize int64
+	// AllowedTypes are the media types that may be attached, e.g. "image/png".
+	// A file's type is detected from its contents, not from its name.
+	AllowedTypes []string
+}
+
+type AttachmentsStoreLocal struct {
+	// Dir is the directory that holds the files, which will be created if it doesn't exist
+	Dir string
+}
+
+type AttachmentsStoreS3 struct {
+	S3AccessKeyId string
+	// S3SecretAccessKey won't get marshalled as part of String() due to the json "-" tag.
+	S3SecretAccessKey string `json:"-"`
+	S3DefaultRegion   string
+	S3Bucket          string
+	// S3Endpoint is the base URL of the S3 service. Set it to use an S3-compatible
+	// service other than AWS, e.g. "http://localhost:9000" for a local MinIO.
+	S3Endpoint string
+}
+
+type StoreSQLite struct {
+	// Path is the SQLite database file, which will be created if it doesn't exist
+	Path string
 }
 
 type StoreMySQL struct {
@@ -126,6 +257,58 @@ type StoreMySQL struct {
 	Password string `json:"-"`
 }
 
+// Anonymize configures anonymized event exports.
+type Anonymize struct {
+	// PseudonymKey is the secret that Ranger handles' pseudonyms are derived from.
+	// Exports made with the same key use the same pseudonyms. When it's unset,
+	// each export gets a random one.
+	// PseudonymKey won't get marshalled as part of String() due to the json "-" tag.
+	PseudonymKey string `json:"-"`
+	// ScrubPatterns are regular expressions for anything else to take out of
+	// text, on top of email addresses and phone numbers.
+	ScrubPatterns []string
+}
+
+// Retention is the data retention policy, which the purge command applies.
+type Retention struct {
+	// Years is how long an event's identifying details are kept after its last
+	// activity. Zero means forever.
+	Years int32
+	// Action is what happens to the text of report entries written by people.
+	// RetentionActionRedact replaces it, and RetentionActionDelete deletes the
+	// entries altogether. Either way, summaries are redacted, attached files are
+	// deleted, and everything needed for statistics is kept.
+	Action RetentionAction
+}
+
+// LoginThrottle slows down and then locks out repeated failed logins, both for
+// each Ranger and for each client IP address.
+type LoginThrottle struct {
+	// FreeAttempts is how many failed logins in a row a Ranger gets before they
+	// have to wait between attempts. The wait starts at BaseDelay and doubles
+	// with each further failure, up to MaxDelay.
+	FreeAttempts int32
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// LockoutAfter is how many failed logins in a row lock a Ranger out for
+	// LockoutDuration. Zero turns lockout off. Failures are forgotten once there
+	// have been none for LockoutDuration.
+	LockoutAfter    int32
+	LockoutDuration time.Duration
+	// ClientFreeAttempts and ClientLockoutAfter are the same, but count every
+	// failed login from one client IP address. They're higher, since a whole
+	// camp can share one address.
+	ClientFreeAttempts int32
+	ClientLockoutAfter int32
+	// ClientIPHeader is the request header with the client's IP address, e.g.
+	// "X-Forwarded-For", when IMS is behind a reverse proxy. The last address in
+	// it is used. Leave it unset otherwise, since clients can set any header.
+	ClientIPHeader string
+	// Persist keeps failed logins in the IMS database rather than only in
+	// memory, so they survive restarts and are shared by all IMS servers.
+	Persist bool
+}
+
 type TestUser struct {
 	Handle      string
 	Email       string
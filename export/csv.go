@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CSVWriter writes a table as RFC 4180 CSV.
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+var _ TableWriter = (*CSVWriter)(nil)
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteRow(cells []string) error {
+	defused := make([]string, len(cells))
+	for i, cell := range cells {
+		defused[i] = defuseFormula(cell)
+	}
+	if err := c.w.Write(defused); err != nil {
+		return fmt.Errorf("[Write]: %w", err)
+	}
+	return nil
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("[Flush]: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package export writes tables of IMS data in formats that spreadsheet
+// programs can open. Rows are written out as they come, so that a large table
+// never has to be held in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+func (f Format) Valid() bool {
+	switch f {
+	case FormatCSV, FormatXLSX:
+		return true
+	default:
+		return false
+	}
+}
+
+// ContentType is the MIME type of a file in this format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// TableWriter writes a table one row at a time. The first row written is
+// usually the header.
+type TableWriter interface {
+	WriteRow(cells []string) error
+	// Close finishes the table. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewTableWriter returns a TableWriter that writes to w in the given format.
+// The sheet name is only used by formats that have named sheets.
+func NewTableWriter(w io.Writer, format Format, sheetName string) (TableWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w, sheetName)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// defuseFormula stops a spreadsheet program from treating a text cell as a
+// formula, by prefixing cells that would look like one with an apostrophe.
+// Otherwise text typed into IMS by anyone could run in whoever opens the file.
+func defuseFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTableWriter(&buf, FormatCSV, "ignored")
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteRow([]string{"Number", "Summary"}))
+	require.NoError(t, tw.WriteRow([]string{"1", "Lost \"camera\", maybe"}))
+	require.NoError(t, tw.WriteRow([]string{"2", "=HYPERLINK(\"http://example.com\")"}))
+	require.NoError(t, tw.Close())
+	require.Equal(t,
+		"Number,Summary\n"+
+			"1,\"Lost \"\"camera\"\", maybe\"\n"+
+			"2,\"'=HYPERLINK(\"\"http://example.com\"\")\"\n",
+		buf.String(),
+	)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTableWriter(&buf, FormatXLSX, "2025: incidents")
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteRow([]string{"Number", "Summary"}))
+	require.NoError(t, tw.WriteRow([]string{"1", "Fire <big> & \x00hot"}))
+	require.NoError(t, tw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		parts[f.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+		// Every part must be well-formed XML
+		require.NoError(t, xml.Unmarshal(parts[f.Name], new(any)), f.Name)
+	}
+	require.Contains(t, parts, "[Content_Types].xml")
+	require.Contains(t, string(parts["xl/workbook.xml"]), `name="2025_ incidents"`)
+
+	var sheet struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				R    string `xml:"r,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 2)
+	require.Equal(t, "2", sheet.Rows[1].R)
+	require.Equal(t, "B2", sheet.Rows[1].Cells[1].R)
+	require.Equal(t, "Fire <big> & �hot", sheet.Rows[1].Cells[1].Text)
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		require.Equal(t, want, xlsxColumn(i))
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewTableWriter(io.Discard, "pdf", "")
+	require.Error(t, err)
+	require.False(t, Format("pdf").Valid())
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxXLSXCellLength is the most characters that Excel allows in a cell.
+const maxXLSXCellLength = 32767
+
+// XLSXWriter writes a table as an Office Open XML workbook with a single
+// sheet. The cells are inline strings, so unlike a workbook with a shared
+// string table, it can be written in a single pass.
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+var _ TableWriter = (*XLSXWriter)(nil)
+
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+	sheetName = xlsxSheetName(sheetName)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("[Create]: %w", err)
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("[WriteString]: %w", err)
+		}
+	}
+	// The sheet has to be the last part, since it's still being written when
+	// the rows come in
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("[Create]: %w", err)
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err = sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, fmt.Errorf("[WriteString]: %w", err)
+	}
+	return &XLSXWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *XLSXWriter) WriteRow(cells []string) error {
+	x.rows++
+	row := strconv.Itoa(x.rows)
+	var b strings.Builder
+	b.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		if utf8.RuneCountInString(cell) > maxXLSXCellLength {
+			cell = string([]rune(cell)[:maxXLSXCellLength])
+		}
+		b.WriteString(`<c r="` + xlsxColumn(i) + row + `" t="inlineStr"><is><t xml:space="preserve">`)
+		b.WriteString(xmlEscape(cell))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	if _, err := x.sheet.WriteString(b.String()); err != nil {
+		return fmt.Errorf("[WriteString]: %w", err)
+	}
+	return nil
+}
+
+func (x *XLSXWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return fmt.Errorf("[WriteString]: %w", err)
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return fmt.Errorf("[Flush]: %w", err)
+	}
+	if err := x.zw.Close(); err != nil {
+		return fmt.Errorf("[Close]: %w", err)
+	}
+	return nil
+}
+
+// xlsxColumn gives the letters of the zero-indexed column, e.g. "A", "Z", "AA".
+func xlsxColumn(i int) string {
+	var letters []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		letters = append([]byte{byte('A' + (i-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// xlsxSheetName makes name acceptable to Excel as the name of a sheet.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if utf8.RuneCountInString(name) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+// xmlEscape escapes s for use as XML text. Characters that aren't allowed
+// in XML at all become U+FFFD.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
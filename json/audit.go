@@ -0,0 +1,31 @@
+package json
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditRecords []AuditRecord
+
+// AuditRecord describes one change made through the API.
+type AuditRecord struct {
+	ID      int64     `json:"id"`
+	Created time.Time `json:"created"`
+	// Actor is the Ranger handle of whoever made the change
+	Actor string `json:"actor"`
+	// RequestID is the X-Request-ID of the request that made the change.
+	// One request may make many changes.
+	RequestID string `json:"request_id"`
+	// Action is what was done, e.g. "create" or "update"
+	Action string `json:"action"`
+	// EntityType is the kind of thing that was changed, e.g. "incident" or "event_access"
+	EntityType string `json:"entity_type"`
+	// Event is the name of the event the change was made in, if any
+	Event    string `json:"event,omitempty"`
+	EntityID string `json:"entity_id"`
+	// Before and After hold the changed values, as they were before and after the
+	// change. Either may be null where it doesn't apply or isn't known, e.g.
+	// Before is null for a creation.
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
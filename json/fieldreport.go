@@ -11,5 +11,9 @@ type FieldReport struct {
 	// to distinguish empty from unset.
 	Summary       *string       `json:"summary"`
 	Incident      int32         `json:"incident,omitzero"`
+	LastModified  time.Time     `json:"last_modified,omitzero"`
 	ReportEntries []ReportEntry `json:"report_entries"`
+	// Version is incremented with each change to the field report. It's read-only,
+	// and it's also provided as the ETag, for use in an If-Match header.
+	Version int32 `json:"version,omitzero"`
 }
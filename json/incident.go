@@ -38,4 +38,7 @@ type Incident struct {
 	FieldReports  *[]int32      `json:"field_reports"`
 	RangerHandles *[]string     `json:"ranger_handles"`
 	ReportEntries []ReportEntry `json:"report_entries"`
+	// Version is incremented with each change to the incident. It's read-only,
+	// and it's also provided as the ETag, for use in an If-Match header.
+	Version int32 `json:"version,omitzero"`
 }
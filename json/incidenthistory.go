@@ -0,0 +1,21 @@
+package json
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IncidentHistory is the changes made to an incident's fields, oldest first.
+type IncidentHistory []IncidentChange
+
+// IncidentChange is a change to one of an incident's fields.
+type IncidentChange struct {
+	// Field is named as in Incident's JSON, e.g. "state" or "ranger_handles",
+	// except that Location's fields are prefixed with "location_".
+	Field string `json:"field"`
+	// OldValue and NewValue are the field's values, as they'd appear in Incident's JSON
+	OldValue json.RawMessage `json:"old_value"`
+	NewValue json.RawMessage `json:"new_value"`
+	Author   string          `json:"author"`
+	Created  time.Time       `json:"created"`
+}
@@ -0,0 +1,45 @@
+package json
+
+import "time"
+
+// IncidentMetrics summarizes how quickly an event's incidents were handled.
+type IncidentMetrics struct {
+	Overall ResponseTimes `json:"overall"`
+	// ByPriority and ByIncidentType break down the same numbers as Overall.
+	// An incident with several types counts toward each of them.
+	ByPriority     map[int8]ResponseTimes   `json:"by_priority"`
+	ByIncidentType map[string]ResponseTimes `json:"by_incident_type"`
+	// Hourly covers each hour, in UTC, from when the first incident was created
+	// through the last change to any of them.
+	Hourly []HourlyIncidents `json:"hourly"`
+}
+
+type ResponseTimes struct {
+	Incidents int `json:"incidents"`
+	// TimeToDispatch is from creation until the incident was first dispatched,
+	// or first on scene if it was never marked dispatched.
+	TimeToDispatch DurationStats `json:"time_to_dispatch"`
+	// TimeToOnScene is from creation until the incident was first on scene.
+	TimeToOnScene DurationStats `json:"time_to_on_scene"`
+	// TimeToClose is from creation until the incident was last closed. Only
+	// incidents that are closed now count toward it.
+	TimeToClose DurationStats `json:"time_to_close"`
+}
+
+// DurationStats describes a set of durations. Count is the number of incidents
+// that reached the point being measured, and the rest are zero if it's zero.
+type DurationStats struct {
+	Count         int     `json:"count"`
+	MeanSeconds   float64 `json:"mean_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	P90Seconds    float64 `json:"p90_seconds"`
+	MaxSeconds    float64 `json:"max_seconds"`
+}
+
+type HourlyIncidents struct {
+	Hour time.Time `json:"hour"`
+	// Created is the number of incidents created during the hour
+	Created int `json:"created"`
+	// Open is the number of incidents that weren't closed at the end of the hour
+	Open int `json:"open"`
+}
@@ -0,0 +1,17 @@
+package json
+
+type SearchResults []SearchResult
+
+// SearchResult is an incident or field report that matched a search.
+type SearchResult struct {
+	// Type is either "incident" or "field_report"
+	Type    string  `json:"type"`
+	Number  int32   `json:"number"`
+	Summary *string `json:"summary"`
+	// Score ranks the results, higher being better. It only means something
+	// relative to the other results of the same search.
+	Score float64 `json:"score"`
+	// Snippets are HTML excerpts of the matching text, with the matched words
+	// wrapped in <mark> elements. Everything else in them is escaped.
+	Snippets []string `json:"snippets"`
+}
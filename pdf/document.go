@@ -0,0 +1,308 @@
+// Package pdf lays out plain text documents as PDF. It only uses the standard
+// Courier fonts, which every PDF reader has built in. They're monospaced, so
+// lines can be wrapped without any font metrics.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// The pages are US Letter, and all lengths are in points
+	pageWidth  = 612.0
+	pageHeight = 792.0
+	margin     = 54.0
+
+	defaultSize = 10.0
+	footerSize  = 8.0
+	// lineSpacing is the distance between baselines, per point of font size
+	lineSpacing = 1.25
+	// courierAdvance is the width of every Courier glyph, per point of font size
+	courierAdvance = 0.6
+)
+
+// Style says how a run of text looks.
+type Style struct {
+	// Size is the font size in points. Zero means 10.
+	Size   float64
+	Bold   bool
+	Italic bool
+	// Strike draws a line through the text.
+	Strike bool
+	// Indent is how far in from the left margin the text starts, in points.
+	Indent float64
+}
+
+func (s Style) size() float64 {
+	if s.Size <= 0 {
+		return defaultSize
+	}
+	return s.Size
+}
+
+// font gives the resource name of the style's font. These are set up in
+// fontResources.
+func (s Style) font() string {
+	switch {
+	case s.Bold && s.Italic:
+		return "/F4"
+	case s.Italic:
+		return "/F3"
+	case s.Bold:
+		return "/F2"
+	default:
+		return "/F1"
+	}
+}
+
+const fontResources = "<< /F1 3 0 R /F2 4 0 R /F3 5 0 R /F4 6 0 R >>"
+
+var fontNames = []string{"Courier", "Courier-Bold", "Courier-Oblique", "Courier-BoldOblique"}
+
+// Document is a PDF that's laid out from the top of the first page down, one
+// block of text at a time.
+type Document struct {
+	// pages are the content streams of the pages so far
+	pages []*bytes.Buffer
+	// y is the top of the space that's left on the last page
+	y float64
+	// footer goes at the bottom of every page, followed by the page number
+	footer string
+}
+
+// New starts a document with footer at the bottom of each page.
+func New(footer string) *Document {
+	d := &Document{footer: footer}
+	d.NewPage()
+	return d
+}
+
+// NewPage starts a new page.
+func (d *Document) NewPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// Pages is the number of pages so far.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Text adds text in the given style, wrapped to fit the page. Each line break
+// in text starts a new line.
+func (d *Document) Text(text string, style Style) {
+	size := style.size()
+	maxChars := max(1, int((pageWidth-2*margin-style.Indent)/(size*courierAdvance)))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, line := range wrap(paragraph, maxChars) {
+			d.line(line, style)
+		}
+	}
+}
+
+// Space leaves a gap of the given height.
+func (d *Document) Space(height float64) {
+	d.y -= height
+}
+
+// Rule draws a horizontal line across the page.
+func (d *Document) Rule() {
+	d.ensureRoom(defaultSize)
+	d.y -= defaultSize / 2
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y, pageWidth-margin, d.y)
+	d.y -= defaultSize / 2
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// ensureRoom starts a new page if there isn't the given height left on this one.
+func (d *Document) ensureRoom(height float64) {
+	// Leave room for the footer
+	bottom := margin + 2*footerSize
+	if d.y-height < bottom {
+		d.NewPage()
+	}
+}
+
+func (d *Document) line(line string, style Style) {
+	size := style.size()
+	height := size * lineSpacing
+	d.ensureRoom(height)
+	d.y -= height
+	x := margin + style.Indent
+	// The baseline sits a little above the bottom of the line
+	baseline := d.y + size*(lineSpacing-1)
+	if line != "" {
+		fmt.Fprintf(d.page(), "BT %s %.2f Tf %.2f %.2f Td %s Tj ET\n", style.font(), size, x, baseline, pdfString(line))
+	}
+	if style.Strike && line != "" {
+		strikeY := baseline + size*0.3
+		width := float64(utf8.RuneCountInString(line)) * size * courierAdvance
+		fmt.Fprintf(d.page(), "0.75 w %.2f %.2f m %.2f %.2f l S\n", x, strikeY, x+width, strikeY)
+	}
+}
+
+// wrap breaks text into lines of at most maxChars characters, at spaces where
+// it can.
+func wrap(text string, maxChars int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	var current []rune
+	for _, word := range words {
+		w := []rune(word)
+		if len(current) > 0 && len(current)+1+len(w) <= maxChars {
+			current = append(append(current, ' '), w...)
+			continue
+		}
+		if len(current) > 0 {
+			lines = append(lines, string(current))
+		}
+		// A word that's too long for a line of its own has to be split
+		for len(w) > maxChars {
+			lines = append(lines, string(w[:maxChars]))
+			w = w[maxChars:]
+		}
+		current = w
+	}
+	return append(lines, string(current))
+}
+
+// WriteTo writes out the finished document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	startObj := func() int {
+		offsets = append(offsets, out.Len())
+		num := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n", num)
+		return num
+	}
+	endObj := func() {
+		out.WriteString("endobj\n")
+	}
+
+	// The pages' objects come after the catalog, the page tree, and the fonts,
+	// with each page followed by its content stream
+	const firstPageObj = 7
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPageObj+2*i))
+	}
+
+	// The binary comment marks the file as binary for transfer programs
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	startObj()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	endObj()
+	startObj()
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	endObj()
+	for _, name := range fontNames {
+		startObj()
+		fmt.Fprintf(&out, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", name)
+		endObj()
+	}
+	for i, page := range d.pages {
+		pageObj := startObj()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font %s >> /Contents %d 0 R >>\n",
+			pageWidth, pageHeight, fontResources, pageObj+1)
+		endObj()
+
+		content := bytes.NewBuffer(bytes.Clone(page.Bytes()))
+		d.writeFooter(content, i+1)
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return 0, fmt.Errorf("[Write]: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return 0, fmt.Errorf("[Close]: %w", err)
+		}
+		startObj()
+		fmt.Fprintf(&out, "<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\n")
+		endObj()
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(out.Bytes())
+	if err != nil {
+		return int64(n), fmt.Errorf("[Write]: %w", err)
+	}
+	return int64(n), nil
+}
+
+func (d *Document) writeFooter(content *bytes.Buffer, pageNum int) {
+	pageText := fmt.Sprintf("Page %d of %d", pageNum, len(d.pages))
+	maxChars := int((pageWidth - 2*margin) / (footerSize * courierAdvance))
+	footer := []rune(d.footer)
+	// Keep the page number, even if the rest doesn't fit
+	if room := maxChars - len(pageText) - 2; len(footer) > room {
+		footer = footer[:max(0, room)]
+	}
+	text := pageText
+	if len(footer) > 0 {
+		text = string(footer) + "  " + pageText
+	}
+	fmt.Fprintf(content, "BT /F1 %.2f Tf %.2f %.2f Td %s Tj ET\n", footerSize, margin, margin, pdfString(text))
+}
+
+// pdfString encodes s as a PDF literal string in WinAnsiEncoding. Characters
+// that the encoding lacks become question marks.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsiExtras are the characters that WinAnsiEncoding puts where Latin-1
+// has control characters.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	if (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff) {
+		return byte(r), true
+	}
+	c, ok := winAnsiExtras[r]
+	return c, ok
+}
@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	d := New("Generated by Tester")
+	d.Text("Incident #1 (Lost camera)", Style{Size: 14, Bold: true})
+	d.Rule()
+	d.Text("Struck", Style{Strike: true, Italic: true})
+	for i := range 100 {
+		d.Text(fmt.Sprintf("Line %d", i), Style{Indent: 18})
+	}
+	require.Equal(t, 2, d.Pages())
+
+	var buf bytes.Buffer
+	_, err := d.WriteTo(&buf)
+	require.NoError(t, err)
+	pdf := buf.Bytes()
+	require.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+
+	// The cross-reference table must point at each object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n0 11\n")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(pdf[xref:], -1)
+	require.Len(t, entries, 10)
+	for i, e := range entries {
+		offset, err := strconv.Atoi(string(e[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(pdf[offset:], fmt.Appendf(nil, "%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+
+	contents := pageContents(t, pdf)
+	require.Len(t, contents, 2)
+	require.Contains(t, contents[0], `/F2 14.00 Tf 54.00 724.00 Td (Incident #1 \(Lost camera\)) Tj`)
+	require.Contains(t, contents[0], "(Struck) Tj")
+	require.Contains(t, contents[0], "/F3 10.00 Tf")
+	require.Contains(t, contents[0], "(Generated by Tester  Page 1 of 2) Tj")
+	require.Contains(t, contents[1], "(Line 99) Tj")
+	require.Contains(t, contents[1], "(Generated by Tester  Page 2 of 2) Tj")
+}
+
+// pageContents decompresses each of the document's content streams.
+func pageContents(t *testing.T, pdf []byte) []string {
+	var contents []string
+	streams := regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(pdf, -1)
+	for _, s := range streams {
+		length, err := strconv.Atoi(string(pdf[s[2]:s[3]]))
+		require.NoError(t, err)
+		zr, err := zlib.NewReader(bytes.NewReader(pdf[s[1] : s[1]+length]))
+		require.NoError(t, err)
+		content, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		contents = append(contents, string(content))
+	}
+	return contents
+}
+
+func TestWrap(t *testing.T) {
+	require.Equal(t, []string{""}, wrap("   ", 10))
+	require.Equal(t, []string{"the quick", "brown fox"}, wrap("the quick brown fox", 10))
+	require.Equal(t, []string{"a", "abcdefghij", "klm b"}, wrap("a abcdefghijklm b", 10))
+	require.Equal(t, []string{strings.Repeat("é", 3), "x"}, wrap("ééé x", 4))
+}
+
+func TestPDFString(t *testing.T) {
+	require.Equal(t, `(a\(b\)c\\)`, pdfString(`a(b)c\`))
+	require.Equal(t, `(caf\351 \223hi\224 \200 ?)`, pdfString("café “hi” € 日"))
+}
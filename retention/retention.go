@@ -0,0 +1,266 @@
+// Package retention applies the data retention policy, purging the details that
+// identify people from events that are old enough. What's needed for
+// statistics is kept: incidents and field reports themselves, with their
+// times, states, priorities, types, locations, and Rangers, and the system's
+// report entries that record changes to them.
+package retention
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/attachment"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"strings"
+	"time"
+)
+
+// Redacted replaces text that's been purged.
+const Redacted = "[purged]"
+
+// keptLines start the lines of system report entries that are kept as they are.
+var keptLines = []string{
+	"Changed state: ",
+	"Changed priority: ",
+	"Changed location name: ",
+	"Changed location concentric: ",
+	"Changed location radial hour: ",
+	"Changed location radial minute: ",
+	"Added Ranger: ",
+	"Removed Ranger: ",
+	"Added type: ",
+	"Removed type: ",
+	"Attached to incident: ",
+	"Detached from incident: ",
+}
+
+// redactedLines start the lines of system report entries that keep what was
+// changed, but not what it was changed to. Any other line is redacted whole.
+var redactedLines = []string{
+	"Changed summary: ",
+	"Changed summary to: ",
+	"Changed location description: ",
+}
+
+// Event is an event that's due to be purged.
+type Event struct {
+	ID   int32
+	Name string
+	// LastActivity is when the event's last incident or field report was
+	// created or changed.
+	LastActivity time.Time
+}
+
+// Expired returns the events with no activity since the cutoff. Events with no
+// activity at all are left out, since there's nothing in them to purge.
+func Expired(ctx context.Context, imsDB *store.DB, cutoff time.Time) ([]Event, error) {
+	rows, err := imsdb.New(imsDB).EventsLastActivity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[EventsLastActivity]: %w", err)
+	}
+	var events []Event
+	for _, r := range rows {
+		last := max(r.IncidentsLastModified, r.IncidentsLastCreated, r.FieldReportsLastModified, r.FieldReportsLastCreated)
+		if last == 0 {
+			continue
+		}
+		lastActivity := time.UnixMicro(int64(last * 1e6))
+		if lastActivity.Before(cutoff) {
+			events = append(events, Event{ID: r.Event.ID, Name: r.Event.Name, LastActivity: lastActivity})
+		}
+	}
+	return events, nil
+}
+
+// Result counts what a purge changed.
+type Result struct {
+	// ReportEntries are the report entries that were redacted or deleted.
+	ReportEntries        int64 `json:"report_entries"`
+	Summaries            int64 `json:"summaries"`
+	LocationDescriptions int64 `json:"location_descriptions"`
+	IncidentChanges      int64 `json:"incident_changes"`
+	AuditValues          int64 `json:"audit_values"`
+	// Attachments are the keys of the attached files that were deleted.
+	Attachments []string `json:"attachments"`
+}
+
+// Empty is whether there was nothing to purge.
+func (r Result) Empty() bool {
+	return r.ReportEntries == 0 && r.Summaries == 0 && r.LocationDescriptions == 0 &&
+		r.IncidentChanges == 0 && r.AuditValues == 0 && len(r.Attachments) == 0
+}
+
+// Purger purges events, recording each purge in the audit log.
+type Purger struct {
+	ImsDB *store.DB
+	// Blobs holds the attached files. If it's nil, the files are left where
+	// they are, though the report entries no longer refer to them.
+	Blobs  attachment.BlobStore
+	Action conf.RetentionAction
+	// Actor is who's doing the purge, for the audit log.
+	Actor string
+	// DryRun works out what would be purged, without changing anything.
+	DryRun bool
+
+	// runID ties together the audit records of one run, in place of a request ID.
+	runID string
+}
+
+// Purge purges one event, all in one transaction. The attached files are deleted
+// once that's committed, so an error deleting them leaves the database purged.
+// An event that's already been purged is left alone, with nothing audited.
+func (p *Purger) Purge(ctx context.Context, event Event) (Result, error) {
+	if p.runID == "" {
+		p.runID = rand.Text()
+	}
+	var result Result
+	txn, err := p.ImsDB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	q := imsdb.New(txn)
+
+	if err = p.purgeReportEntries(ctx, q, event, &result); err != nil {
+		return result, err
+	}
+	redacted := sql.NullString{String: Redacted, Valid: true}
+	result.Summaries, err = q.RedactIncidentSummaries(ctx, imsdb.RedactIncidentSummariesParams{Redacted: redacted, Event: event.ID})
+	if err != nil {
+		return result, fmt.Errorf("[RedactIncidentSummaries]: %w", err)
+	}
+	frSummaries, err := q.RedactFieldReportSummaries(ctx, imsdb.RedactFieldReportSummariesParams{Redacted: redacted, Event: event.ID})
+	if err != nil {
+		return result, fmt.Errorf("[RedactFieldReportSummaries]: %w", err)
+	}
+	result.Summaries += frSummaries
+	result.LocationDescriptions, err = q.RedactIncidentLocationDescriptions(ctx, imsdb.RedactIncidentLocationDescriptionsParams{
+		Redacted: redacted, Event: event.ID,
+	})
+	if err != nil {
+		return result, fmt.Errorf("[RedactIncidentLocationDescriptions]: %w", err)
+	}
+	redactedJSON, _ := json.Marshal(Redacted)
+	result.IncidentChanges, err = q.RedactIncidentChanges(ctx, imsdb.RedactIncidentChangesParams{
+		Redacted: string(redactedJSON), Event: event.ID,
+	})
+	if err != nil {
+		return result, fmt.Errorf("[RedactIncidentChanges]: %w", err)
+	}
+	result.AuditValues, err = q.RedactAuditValues(ctx, sql.NullInt32{Int32: event.ID, Valid: true})
+	if err != nil {
+		return result, fmt.Errorf("[RedactAuditValues]: %w", err)
+	}
+	if result.Empty() {
+		return result, nil
+	}
+
+	after, err := json.Marshal(map[string]any{
+		"action":        p.Action,
+		"last_activity": event.LastActivity.UTC(),
+		"result":        result,
+	})
+	if err != nil {
+		return result, fmt.Errorf("[Marshal]: %w", err)
+	}
+	if err = q.CreateAudit(ctx, imsdb.CreateAuditParams{
+		Created:    float64(time.Now().UnixMicro()) / 1e6,
+		Actor:      p.Actor,
+		RequestID:  p.runID,
+		Action:     "purge",
+		EntityType: "event",
+		Event:      sql.NullInt32{Int32: event.ID, Valid: true},
+		EntityID:   event.Name,
+		AfterValue: sql.NullString{String: string(after), Valid: true},
+	}); err != nil {
+		return result, fmt.Errorf("[CreateAudit]: %w", err)
+	}
+
+	if p.DryRun {
+		return result, nil
+	}
+	if err = txn.Commit(); err != nil {
+		return result, fmt.Errorf("[Commit]: %w", err)
+	}
+	if p.Blobs != nil {
+		var errs []error
+		for _, key := range result.Attachments {
+			if err = p.Blobs.Delete(ctx, key); err != nil {
+				errs = append(errs, fmt.Errorf("[Delete]: %v: %w", key, err))
+			}
+		}
+		if err = errors.Join(errs...); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (p *Purger) purgeReportEntries(ctx context.Context, q *imsdb.Queries, event Event, result *Result) error {
+	rows, err := q.EventReportEntries(ctx, imsdb.EventReportEntriesParams{Event: event.ID})
+	if err != nil {
+		return fmt.Errorf("[EventReportEntries]: %w", err)
+	}
+	for _, r := range rows {
+		re := r.ReportEntry
+		if re.AttachedFile.Valid && re.AttachedFile.String != "" {
+			result.Attachments = append(result.Attachments, re.AttachedFile.String)
+		}
+		if !re.Generated && p.Action == conf.RetentionActionDelete {
+			if err = q.DetachReportEntry(ctx, re.ID); err != nil {
+				return fmt.Errorf("[DetachReportEntry]: %w", err)
+			}
+			if err = q.DetachFieldReportReportEntry(ctx, re.ID); err != nil {
+				return fmt.Errorf("[DetachFieldReportReportEntry]: %w", err)
+			}
+			if err = q.DeleteReportEntry(ctx, re.ID); err != nil {
+				return fmt.Errorf("[DeleteReportEntry]: %w", err)
+			}
+			result.ReportEntries++
+			continue
+		}
+		text := Redacted
+		if re.Generated {
+			text = redactSystemEntry(re.Text)
+		}
+		if text == re.Text && !re.AttachedFile.Valid {
+			continue
+		}
+		if err = q.SetReportEntryText(ctx, imsdb.SetReportEntryTextParams{Text: text, ID: re.ID}); err != nil {
+			return fmt.Errorf("[SetReportEntryText]: %w", err)
+		}
+		result.ReportEntries++
+	}
+	return nil
+}
+
+// redactSystemEntry redacts the lines of a system report entry that might
+// identify someone, e.g. a change of summary.
+func redactSystemEntry(text string) string {
+	lines := strings.Split(text, "\n")
+lines:
+	for i, line := range lines {
+		for _, prefix := range keptLines {
+			if strings.HasPrefix(line, prefix) {
+				continue lines
+			}
+		}
+		for _, prefix := range redactedLines {
+			if strings.HasPrefix(line, prefix) {
+				if line != prefix+Redacted {
+					lines[i] = prefix + Redacted
+				}
+				continue lines
+			}
+		}
+		if line != "" {
+			lines[i] = Redacted
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,191 @@
+package retention
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/srabraham/ranger-ims-go/attachment"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"github.com/srabraham/ranger-ims-go/store"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactSystemEntry(t *testing.T) {
+	require.Equal(t,
+		"Changed state: on_scene\nChanged summary: [purged]\nAdded Ranger: Tool",
+		redactSystemEntry("Changed state: on_scene\nChanged summary: Bob's camera\nAdded Ranger: Tool"))
+	require.Equal(t, "[purged]", redactSystemEntry(`Attached file "bob.jpg" (image/jpeg, 1234 bytes)`))
+	require.Equal(t, "Changed location description: [purged]", redactSystemEntry("Changed location description: [purged]"))
+}
+
+func TestPurge(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	blobs := attachment.LocalStore{Dir: t.TempDir()}
+	q := imsdb.New(db)
+
+	old := float64(time.Date(2015, 8, 30, 0, 0, 0, 0, time.UTC).Unix())
+	oldEvent := createEvent(t, q, "2015", old)
+	recent := createEvent(t, q, "Recent", float64(time.Now().Unix()))
+	_, err = q.CreateEvent(ctx, "Empty")
+	require.NoError(t, err)
+
+	written := addEntry(t, q, oldEvent, imsdb.CreateReportEntryParams{Author: "Tool", Text: "Bob's camera, call 555-0100"})
+	system := addEntry(t, q, oldEvent, imsdb.CreateReportEntryParams{
+		Author: "Tool", Text: "Changed state: closed\nChanged summary: Bob's camera", Generated: true,
+	})
+	require.NoError(t, blobs.Put(ctx, "1/PHOTO", strings.NewReader("jpeg"), 4))
+	photo := addEntry(t, q, oldEvent, imsdb.CreateReportEntryParams{
+		Author: "Tool", Text: `Attached file "bob.jpg" (image/jpeg, 4 bytes)`, Generated: true,
+		AttachedFile: sql.NullString{String: "1/PHOTO", Valid: true},
+	})
+	recentEntry := addEntry(t, q, recent, imsdb.CreateReportEntryParams{Author: "Tool", Text: "Keep me"})
+	require.NoError(t, q.CreateIncidentChange(ctx, imsdb.CreateIncidentChangeParams{
+		Event: oldEvent, IncidentNumber: 1, Created: old, Author: "Tool", Field: "summary", OldValue: "null", NewValue: `"Bob's camera"`,
+	}))
+	require.NoError(t, q.CreateAudit(ctx, imsdb.CreateAuditParams{
+		Created: old, Actor: "Tool", Action: "update", EntityType: "incident", EntityID: "1",
+		Event:      sql.NullInt32{Int32: oldEvent, Valid: true},
+		AfterValue: sql.NullString{String: `{"summary":"Bob's camera"}`, Valid: true},
+	}))
+	// Who was given access isn't personal information, so it's kept
+	require.NoError(t, q.CreateAudit(ctx, imsdb.CreateAuditParams{
+		Created: old, Actor: "Tool", Action: "update", EntityType: "event_access", EntityID: "2015",
+		Event:      sql.NullInt32{Int32: oldEvent, Valid: true},
+		AfterValue: sql.NullString{String: `{"writers":["person:Tool"]}`, Valid: true},
+	}))
+
+	events, err := Expired(ctx, db, time.Now().AddDate(-7, 0, 0))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "2015", events[0].Name)
+	require.Equal(t, old, float64(events[0].LastActivity.Unix()))
+
+	// A dry run changes nothing
+	purger := &Purger{ImsDB: db, Blobs: blobs, Action: conf.RetentionActionRedact, Actor: "Tester", DryRun: true}
+	result, err := purger.Purge(ctx, events[0])
+	require.NoError(t, err)
+	expected := Result{
+		ReportEntries: 3, Summaries: 1, LocationDescriptions: 1, IncidentChanges: 1, AuditValues: 1,
+		Attachments: []string{"1/PHOTO"},
+	}
+	require.Equal(t, expected, result)
+	require.Equal(t, "Bob's camera, call 555-0100", entryText(t, q, oldEvent, written))
+	audits, err := q.AuditEntries(ctx, imsdb.AuditEntriesParams{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, audits, 2)
+
+	purger.DryRun = false
+	result, err = purger.Purge(ctx, events[0])
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+	require.Equal(t, Redacted, entryText(t, q, oldEvent, written))
+	require.Equal(t, "Changed state: closed\nChanged summary: [purged]", entryText(t, q, oldEvent, system))
+	require.Equal(t, Redacted, entryText(t, q, oldEvent, photo))
+	require.Equal(t, "Keep me", entryText(t, q, recent, recentEntry))
+	_, err = blobs.Get(ctx, "1/PHOTO")
+	require.True(t, errors.Is(err, attachment.ErrNotFound))
+
+	incident, err := q.Incident(ctx, imsdb.IncidentParams{Event: oldEvent, Number: 1})
+	require.NoError(t, err)
+	require.Equal(t, Redacted, incident.Incident.Summary.String)
+	require.Equal(t, Redacted, incident.Incident.LocationDescription.String)
+	require.Equal(t, "Esplanade", incident.Incident.LocationName.String)
+	require.Equal(t, imsdb.IncidentStateClosed, incident.Incident.State)
+	require.Equal(t, old, incident.Incident.LastModified)
+	changes, err := q.IncidentChanges(ctx, imsdb.IncidentChangesParams{Event: oldEvent, IncidentNumber: 1})
+	require.NoError(t, err)
+	require.Equal(t, `"[purged]"`, changes[0].IncidentChange.NewValue)
+
+	audits, err = q.AuditEntries(ctx, imsdb.AuditEntriesParams{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, audits, 3)
+	require.Equal(t, "purge", audits[0].Audit.Action)
+	require.Equal(t, "Tester", audits[0].Audit.Actor)
+	require.Equal(t, "2015", audits[0].Audit.EntityID)
+	require.Contains(t, audits[0].Audit.AfterValue.String, `"report_entries":3`)
+	for _, audit := range audits[1:] {
+		if audit.Audit.EntityType == "event_access" {
+			require.True(t, audit.Audit.AfterValue.Valid)
+		} else {
+			require.False(t, audit.Audit.AfterValue.Valid)
+		}
+	}
+
+	// Purging again finds nothing to do, and audits nothing
+	result, err = purger.Purge(ctx, events[0])
+	require.NoError(t, err)
+	require.True(t, result.Empty())
+	audits, err = q.AuditEntries(ctx, imsdb.AuditEntriesParams{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, audits, 3)
+}
+
+func TestPurgeDelete(t *testing.T) {
+	ctx := t.Context()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, store.Migrate(ctx, db))
+	q := imsdb.New(db)
+
+	old := float64(time.Date(2015, 8, 30, 0, 0, 0, 0, time.UTC).Unix())
+	event := createEvent(t, q, "2015", old)
+	addEntry(t, q, event, imsdb.CreateReportEntryParams{Author: "Tool", Text: "Bob's camera"})
+	system := addEntry(t, q, event, imsdb.CreateReportEntryParams{Author: "Tool", Text: "Changed state: closed", Generated: true})
+
+	purger := &Purger{ImsDB: db, Action: conf.RetentionActionDelete, Actor: "Tester"}
+	result, err := purger.Purge(ctx, Event{ID: event, Name: "2015"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.ReportEntries)
+	entries, err := q.Incident_ReportEntries(ctx, imsdb.Incident_ReportEntriesParams{Event: event, IncidentNumber: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, system, entries[0].ReportEntry.ID)
+	require.Equal(t, "Changed state: closed", entries[0].ReportEntry.Text)
+}
+
+// createEvent makes an event with one closed incident, last changed at lastModified.
+func createEvent(t *testing.T, q *imsdb.Queries, name string, lastModified float64) int32 {
+	t.Helper()
+	id, err := q.CreateEvent(t.Context(), name)
+	require.NoError(t, err)
+	require.NoError(t, q.ImportIncident(t.Context(), imsdb.ImportIncidentParams{
+		Event: int32(id), Number: 1, Created: lastModified - 3600, Priority: 3, State: imsdb.IncidentStateClosed,
+		Summary:             sql.NullString{String: "Bob's camera", Valid: true},
+		LocationName:        sql.NullString{String: "Esplanade", Valid: true},
+		LocationDescription: sql.NullString{String: "Bob's tent", Valid: true},
+		Version:             1, LastModified: lastModified,
+	}))
+	return int32(id)
+}
+
+func addEntry(t *testing.T, q *imsdb.Queries, event int32, entry imsdb.CreateReportEntryParams) int32 {
+	t.Helper()
+	id, err := q.CreateReportEntry(t.Context(), entry)
+	require.NoError(t, err)
+	require.NoError(t, q.AttachReportEntryToIncident(t.Context(), imsdb.AttachReportEntryToIncidentParams{
+		Event: event, IncidentNumber: 1, ReportEntry: int32(id),
+	}))
+	return int32(id)
+}
+
+func entryText(t *testing.T, q *imsdb.Queries, event, id int32) string {
+	t.Helper()
+	entries, err := q.Incident_ReportEntries(t.Context(), imsdb.Incident_ReportEntriesParams{Event: event, IncidentNumber: 1})
+	require.NoError(t, err)
+	for _, e := range entries {
+		if e.ReportEntry.ID == id {
+			return e.ReportEntry.Text
+		}
+	}
+	t.Fatalf("no report entry %v", id)
+	return ""
+}
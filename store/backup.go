@@ -0,0 +1,402 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"hash"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A backup is a gzipped JSON-lines file. It starts with a header, then for each
+// table there's a line naming the table and its columns, followed by a line
+// per row. Last comes a trailer with the SHA-256 of everything before it, as
+// it was before compression.
+
+const (
+	backupFormat  = "ranger-ims-backup"
+	backupVersion = 1
+)
+
+// backupTables are all the IMS tables, apart from SCHEMA_INFO, in an order that
+// lets each one be restored after the tables it refers to.
+var backupTables = []string{
+	"EVENT",
+	"CONCENTRIC_STREET",
+	"INCIDENT_TYPE",
+	"REPORT_ENTRY",
+	"INCIDENT",
+	"INCIDENT__RANGER",
+	"INCIDENT__INCIDENT_TYPE",
+	"INCIDENT__REPORT_ENTRY",
+	"EVENT_ACCESS",
+	"FIELD_REPORT",
+	"FIELD_REPORT__REPORT_ENTRY",
+	"SSE_EVENT",
+	"EVENT_SEQUENCE",
+	"AUDIT",
+	"INCIDENT_CHANGE",
+	"REFRESH_TOKEN",
+	"TOKEN_REVOCATION",
+	"LOGIN_FAILURE",
+}
+
+var backupColumnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type BackupHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// SchemaVersion is the database's SCHEMA_INFO version. A backup can only
+	// be restored to a database at the same version.
+	SchemaVersion int16          `json:"schema_version"`
+	StoreType     conf.StoreType `json:"store_type"`
+	Created       time.Time      `json:"created"`
+}
+
+type backupTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+type backupTrailer struct {
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// backupLine is one line of a backup, with just one of its fields set.
+type backupLine struct {
+	Header  *BackupHeader  `json:"header,omitempty"`
+	Table   *backupTable   `json:"table,omitempty"`
+	Row     []any          `json:"row,omitempty"`
+	Trailer *backupTrailer `json:"trailer,omitempty"`
+}
+
+// BackupSummary describes a backup that was written or restored.
+type BackupSummary struct {
+	Header BackupHeader
+	// Rows counts the rows of each table.
+	Rows map[string]int64
+}
+
+// Backup writes a backup of every IMS table to w. The tables are all read in one
+// repeatable-read transaction, so the backup is a consistent snapshot, even
+// while IMS is running.
+func Backup(ctx context.Context, db *DB, w io.Writer) (BackupSummary, error) {
+	summary := BackupSummary{Rows: make(map[string]int64)}
+	txn, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return summary, fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+
+	var schemaVersion int16
+	if err = txn.QueryRowContext(ctx, "select VERSION from SCHEMA_INFO").Scan(&schemaVersion); err != nil {
+		return summary, fmt.Errorf("[QueryRowContext]: %w", err)
+	}
+	summary.Header = BackupHeader{
+		Format:        backupFormat,
+		Version:       backupVersion,
+		SchemaVersion: schemaVersion,
+		StoreType:     db.storeType,
+		Created:       time.Now().UTC(),
+	}
+	if summary.Header.StoreType == "" {
+		summary.Header.StoreType = conf.StoreTypeMySQL
+	}
+
+	gz := gzip.NewWriter(w)
+	sum := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(gz, sum))
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(backupLine{Header: &summary.Header}); err != nil {
+		return summary, fmt.Errorf("[Encode]: %w", err)
+	}
+	var total int64
+	for _, table := range backupTables {
+		rows, err := backupTableRows(ctx, txn, table, enc)
+		if err != nil {
+			return summary, fmt.Errorf("table %v: %w", table, err)
+		}
+		summary.Rows[table] = rows
+		total += rows
+	}
+	if err = bw.Flush(); err != nil {
+		return summary, fmt.Errorf("[Flush]: %w", err)
+	}
+	// The trailer goes straight to the compressor, since it isn't part of the checksum
+	if err = json.NewEncoder(gz).Encode(backupLine{Trailer: &backupTrailer{
+		Rows:   total,
+		SHA256: hex.EncodeToString(sum.Sum(nil)),
+	}}); err != nil {
+		return summary, fmt.Errorf("[Encode]: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return summary, fmt.Errorf("[Close]: %w", err)
+	}
+	return summary, nil
+}
+
+func backupTableRows(ctx context.Context, txn *Tx, table string, enc *json.Encoder) (int64, error) {
+	rows, err := txn.QueryContext(ctx, "select * from "+table)
+	if err != nil {
+		return 0, fmt.Errorf("[QueryContext]: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("[Columns]: %w", err)
+	}
+	if err = enc.Encode(backupLine{Table: &backupTable{Name: table, Columns: columns}}); err != nil {
+		return 0, fmt.Errorf("[Encode]: %w", err)
+	}
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var count int64
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return 0, fmt.Errorf("[Scan]: %w", err)
+		}
+		// MariaDB gives back most values as text, which is how they're kept
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err = enc.Encode(backupLine{Row: values}); err != nil {
+			return 0, fmt.Errorf("[Encode]: %w", err)
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("[Err]: %w", err)
+	}
+	return count, nil
+}
+
+// ErrDatabaseNotEmpty is returned by Restore when the database already has events in it.
+var ErrDatabaseNotEmpty = errors.New("the database already has events in it")
+
+// Restore replaces the contents of the database with a backup. The backup is
+// checked in full before anything changes, and it must be of the schema
+// version this build uses. A database with no IMS schema gets the schema
+// first. A database that already has events is only overwritten if replace
+// is set. It all happens in one transaction.
+func Restore(ctx context.Context, db *DB, r io.ReadSeeker, replace bool) (BackupSummary, error) {
+	summary, err := VerifyBackup(r)
+	if err != nil {
+		return summary, fmt.Errorf("[VerifyBackup]: %w", err)
+	}
+	if expected := ExpectedSchemaVersion(); summary.Header.SchemaVersion != expected {
+		return summary, fmt.Errorf("the backup is of schema version %v, but this IMS build uses version %v. "+
+			"Restore it with an IMS build that uses version %v, then migrate",
+			summary.Header.SchemaVersion, expected, summary.Header.SchemaVersion)
+	}
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return summary, fmt.Errorf("[SchemaVersion]: %w", err)
+	}
+	if current == 0 {
+		if err = Migrate(ctx, db); err != nil {
+			return summary, fmt.Errorf("[Migrate]: %w", err)
+		}
+	} else if err = CheckSchemaVersion(ctx, db); err != nil {
+		return summary, fmt.Errorf("[CheckSchemaVersion]: %w", err)
+	}
+	if !replace {
+		var events int
+		if err = db.QueryRowContext(ctx, "select count(*) from EVENT").Scan(&events); err != nil {
+			return summary, fmt.Errorf("[QueryRowContext]: %w", err)
+		}
+		if events > 0 {
+			return summary, ErrDatabaseNotEmpty
+		}
+	}
+
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return summary, fmt.Errorf("[Seek]: %w", err)
+	}
+	txn, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return summary, fmt.Errorf("[BeginTx]: %w", err)
+	}
+	defer txn.Rollback()
+	for _, table := range slices.Backward(backupTables) {
+		if _, err = txn.ExecContext(ctx, "delete from "+table); err != nil {
+			return summary, fmt.Errorf("[ExecContext]: delete from %v: %w", table, err)
+		}
+	}
+	var stmt *sql.Stmt
+	var stmtTable *backupTable
+	defer func() {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}()
+	err = readBackup(r, func(table *backupTable, row []any) error {
+		if table != stmtTable {
+			if stmt != nil {
+				_ = stmt.Close()
+			}
+			stmt, err = txn.PrepareContext(ctx, restoreInsert(table))
+			if err != nil {
+				stmt = nil
+				return fmt.Errorf("[PrepareContext]: %w", err)
+			}
+			stmtTable = table
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("[ExecContext]: insert into %v: %w", table.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("[readBackup]: %w", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return summary, fmt.Errorf("[Commit]: %w", err)
+	}
+	return summary, nil
+}
+
+func restoreInsert(table *backupTable) string {
+	var columns []string
+	for _, c := range table.Columns {
+		columns = append(columns, "`"+c+"`")
+	}
+	return fmt.Sprintf("insert into %v (%v) values (%v)",
+		table.Name, strings.Join(columns, ", "), strings.Repeat("?, ", len(columns)-1)+"?")
+}
+
+// VerifyBackup reads through a backup, checking that it's complete and intact.
+func VerifyBackup(r io.Reader) (BackupSummary, error) {
+	summary := BackupSummary{Rows: make(map[string]int64)}
+	header, err := readBackupWith(r, func(table *backupTable, _ []any) error {
+		summary.Rows[table.Name]++
+		return nil
+	}, func(table *backupTable) {
+		summary.Rows[table.Name] = 0
+	})
+	summary.Header = header
+	return summary, err
+}
+
+func readBackup(r io.Reader, row func(table *backupTable, row []any) error) error {
+	_, err := readBackupWith(r, row, nil)
+	return err
+}
+
+// readBackupWith calls row for each row of the backup, and table (if it's set)
+// for each table. It fails if the backup doesn't match its checksum, but only
+// at the end, so callers that change things must check it first.
+func readBackupWith(r io.Reader, row func(*backupTable, []any) error, table func(*backupTable)) (BackupHeader, error) {
+	var header BackupHeader
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return header, fmt.Errorf("[gzip.NewReader]: %w", err)
+	}
+	defer gz.Close()
+	br := bufio.NewReader(gz)
+	sum := sha256.New()
+
+	first, err := readBackupLine(br, sum)
+	if err != nil {
+		return header, err
+	}
+	if first.Header == nil || first.Header.Format != backupFormat {
+		return header, errors.New("not an IMS backup")
+	}
+	header = *first.Header
+	if header.Version < 1 || header.Version > backupVersion {
+		return header, fmt.Errorf("backup format version %v isn't supported by this build", header.Version)
+	}
+
+	var current *backupTable
+	var rows int64
+	for {
+		line, err := readBackupLine(br, sum)
+		if errors.Is(err, io.EOF) {
+			return header, errors.New("the backup is incomplete: it has no trailer")
+		}
+		if err != nil {
+			return header, err
+		}
+		switch {
+		case line.Table != nil:
+			if !slices.Contains(backupTables, line.Table.Name) {
+				return header, fmt.Errorf("unknown table %q", line.Table.Name)
+			}
+			if len(line.Table.Columns) == 0 {
+				return header, fmt.Errorf("table %v has no columns", line.Table.Name)
+			}
+			for _, c := range line.Table.Columns {
+				if !backupColumnName.MatchString(c) {
+					return header, fmt.Errorf("table %v has an invalid column name %q", line.Table.Name, c)
+				}
+			}
+			current = line.Table
+			if table != nil {
+				table(current)
+			}
+		case line.Row != nil:
+			if current == nil || len(line.Row) != len(current.Columns) {
+				return header, errors.New("a row doesn't match its table")
+			}
+			rows++
+			if err = row(current, line.Row); err != nil {
+				return header, err
+			}
+		case line.Trailer != nil:
+			if got := hex.EncodeToString(sum.Sum(nil)); got != line.Trailer.SHA256 {
+				return header, fmt.Errorf("the backup is corrupt: its checksum is %v, but should be %v", got, line.Trailer.SHA256)
+			}
+			if rows != line.Trailer.Rows {
+				return header, fmt.Errorf("the backup is corrupt: it has %v rows, but should have %v", rows, line.Trailer.Rows)
+			}
+			return header, nil
+		default:
+			return header, errors.New("the backup has an empty line")
+		}
+	}
+}
+
+// readBackupLine reads and decodes one line, adding it to the checksum unless
+// it's the trailer. Numbers are kept as text, so they're restored exactly.
+func readBackupLine(br *bufio.Reader, sum hash.Hash) (backupLine, error) {
+	var line backupLine
+	b, err := br.ReadBytes('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(b) == 0 {
+			return line, io.EOF
+		}
+		return line, fmt.Errorf("[ReadBytes]: %w", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	if err = dec.Decode(&line); err != nil {
+		return line, fmt.Errorf("[Decode]: %w", err)
+	}
+	if line.Trailer == nil {
+		sum.Write(b)
+	}
+	for i, v := range line.Row {
+		if n, ok := v.(json.Number); ok {
+			line.Row[i] = n.String()
+		}
+	}
+	return line, nil
+}
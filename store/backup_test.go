@@ -0,0 +1,149 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBackupTablesCoverSchema(t *testing.T) {
+	createTable := regexp.MustCompile("(?i)create table (?:if not exists )?`?(\\w+)`?")
+	for _, schema := range []string{CurrentSchema, CurrentSQLiteSchema} {
+		var tables []string
+		for _, m := range createTable.FindAllStringSubmatch(schema, -1) {
+			if m[1] != "SCHEMA_INFO" {
+				tables = append(tables, m[1])
+			}
+		}
+		require.ElementsMatch(t, backupTables, tables)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := t.Context()
+	src := openTestDB(t)
+	_, err := src.ExecContext(ctx, "insert into EVENT (NAME) values ('2024')")
+	require.NoError(t, err)
+	_, err = src.ExecContext(ctx, `insert into INCIDENT (EVENT, NUMBER, CREATED, PRIORITY, STATE, SUMMARY, VERSION, LAST_MODIFIED)
+		values (1, 1, 1724990000.25, 3, 'new', 'Lost "camera" ☃', 1, 1724990000.25)`)
+	require.NoError(t, err)
+	_, err = src.ExecContext(ctx, "insert into REPORT_ENTRY (AUTHOR, TEXT, CREATED, GENERATED, STRICKEN) values ('Tool', 'Found it', 1724990001.5, 0, 0)")
+	require.NoError(t, err)
+	_, err = src.ExecContext(ctx, "insert into INCIDENT__REPORT_ENTRY (EVENT, INCIDENT_NUMBER, REPORT_ENTRY) values (1, 1, 1)")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	written, err := Backup(ctx, src, &buf)
+	require.NoError(t, err)
+	require.Equal(t, ExpectedSchemaVersion(), written.Header.SchemaVersion)
+	require.Equal(t, int64(1), written.Rows["INCIDENT"])
+
+	verified, err := VerifyBackup(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, written.Rows, verified.Rows)
+
+	// A new database gets the schema, then the data
+	dst, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "restored.sqlite"))
+	require.NoError(t, err)
+	defer dst.Close()
+	_, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()), false)
+	require.NoError(t, err)
+	var summary string
+	var created float64
+	require.NoError(t, dst.QueryRowContext(ctx, "select SUMMARY, CREATED from INCIDENT").Scan(&summary, &created))
+	require.Equal(t, `Lost "camera" ☃`, summary)
+	require.Equal(t, 1724990000.25, created)
+	var text string
+	require.NoError(t, dst.QueryRowContext(ctx,
+		"select re.TEXT from REPORT_ENTRY re join INCIDENT__REPORT_ENTRY ire on ire.REPORT_ENTRY = re.ID").Scan(&text))
+	require.Equal(t, "Found it", text)
+
+	// A database with events in it is only replaced when asked
+	_, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()), false)
+	require.True(t, errors.Is(err, ErrDatabaseNotEmpty))
+	_, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+	var incidents int
+	require.NoError(t, dst.QueryRowContext(ctx, "select count(*) from INCIDENT").Scan(&incidents))
+	require.Equal(t, 1, incidents)
+}
+
+func TestRestoreRefusesBadBackups(t *testing.T) {
+	ctx := t.Context()
+	src := openTestDB(t)
+	var buf bytes.Buffer
+	_, err := Backup(ctx, src, &buf)
+	require.NoError(t, err)
+	plain := gunzip(t, buf.Bytes())
+
+	tampered := strings.Replace(plain, `"Admin"`, `"Pwned"`, 1)
+	require.NotEqual(t, plain, tampered)
+	_, err = VerifyBackup(bytes.NewReader(gzipped(t, tampered)))
+	require.ErrorContains(t, err, "checksum")
+
+	lines := strings.SplitAfter(plain, "\n")
+	truncated := strings.Join(lines[:len(lines)-2], "")
+	_, err = VerifyBackup(bytes.NewReader(gzipped(t, truncated)))
+	require.ErrorContains(t, err, "incomplete")
+
+	// Another schema version is refused, even with a valid checksum
+	dst := openTestDB(t)
+	other := resum(t, strings.Replace(plain, `"schema_version":`, `"schema_version":1`, 1))
+	_, err = VerifyBackup(bytes.NewReader(gzipped(t, other)))
+	require.NoError(t, err)
+	_, err = Restore(ctx, dst, bytes.NewReader(gzipped(t, other)), true)
+	require.ErrorContains(t, err, "schema version")
+	_, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+}
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenSQLite(t.Context(), filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	require.NoError(t, Migrate(t.Context(), db))
+	return db
+}
+
+// resum replaces the checksum in a backup's trailer with the right one.
+func resum(t *testing.T, plain string) string {
+	t.Helper()
+	lines := strings.SplitAfter(strings.TrimSuffix(plain, "\n"), "\n")
+	body := strings.Join(lines[:len(lines)-1], "")
+	var trailer backupLine
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &trailer))
+	sum := sha256.Sum256([]byte(body))
+	trailer.Trailer.SHA256 = hex.EncodeToString(sum[:])
+	b, err := json.Marshal(trailer)
+	require.NoError(t, err)
+	return body + string(b) + "\n"
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(plain)
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
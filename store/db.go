@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Open connects to the IMS database configured in imsCfg.Store.
+func Open(imsCfg *conf.IMSConfig) *DB {
+	switch imsCfg.Store.Type {
+	case conf.StoreTypeSQLite:
+		return SQLite(imsCfg)
+	case conf.StoreTypeMySQL:
+		return &DB{DB: MariaDB(imsCfg)}
+	default:
+		slog.Error("Unknown IMS DB type", "type", imsCfg.Store.Type)
+		os.Exit(1)
+		return nil
+	}
+}
+
+type DB struct {
+	*sql.DB
+
+	// storeType is the kind of database. The zero value means MariaDB.
+	storeType conf.StoreType
+
+	// queryOverrides holds replacements for sqlc queries, keyed by query name,
+	// for databases that don't speak the MariaDB dialect. It's nil for MariaDB.
+	queryOverrides map[string]string
+}
+
+func (l DB) isSQLite() bool {
+	return l.storeType == conf.StoreTypeSQLite
+}
+
+func (l DB) ExecContext(ctx context.Context, s string, i ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	execContext, err := l.DB.ExecContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, err)
+	return execContext, err
+}
+
+func (l DB) PrepareContext(ctx context.Context, s string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := l.DB.PrepareContext(ctx, translate(l.queryOverrides, s))
+	logQuery(s, start, err)
+	return stmt, err
+}
+
+func (l DB) QueryContext(ctx context.Context, s string, i ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := l.DB.QueryContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, err)
+	return rows, err
+}
+
+func (l DB) QueryRowContext(ctx context.Context, s string, i ...interface{}) *sql.Row {
+	start := time.Now()
+	row := l.DB.QueryRowContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, nil)
+	return row
+}
+
+func (l DB) Begin() (*Tx, error) {
+	return l.BeginTx(context.Background(), nil)
+}
+
+func (l DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	txn, err := l.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: txn, queryOverrides: l.queryOverrides}, nil
+}
+
+// Tx is a transaction on a DB. Like DB, it logs and translates queries.
+type Tx struct {
+	*sql.Tx
+
+	queryOverrides map[string]string
+}
+
+func (l Tx) ExecContext(ctx context.Context, s string, i ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	execContext, err := l.Tx.ExecContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, err)
+	return execContext, err
+}
+
+func (l Tx) PrepareContext(ctx context.Context, s string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := l.Tx.PrepareContext(ctx, translate(l.queryOverrides, s))
+	logQuery(s, start, err)
+	return stmt, err
+}
+
+func (l Tx) QueryContext(ctx context.Context, s string, i ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := l.Tx.QueryContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, err)
+	return rows, err
+}
+
+func (l Tx) QueryRowContext(ctx context.Context, s string, i ...interface{}) *sql.Row {
+	start := time.Now()
+	row := l.Tx.QueryRowContext(ctx, translate(l.queryOverrides, s), i...)
+	logQuery(s, start, nil)
+	return row
+}
+
+// translate swaps out an sqlc query for its override, if there is one.
+// sqlc queries start with a line like "-- name: QueryName :one".
+func translate(queryOverrides map[string]string, s string) string {
+	if queryOverrides == nil {
+		return s
+	}
+	if override, ok := queryOverrides[sqlcQueryName(s)]; ok {
+		return override
+	}
+	return s
+}
+
+// sqlcQueryName returns the name of an sqlc query, or "" if s isn't one.
+func sqlcQueryName(s string) string {
+	firstLine, _, _ := strings.Cut(s, "\n")
+	nameAndKind, ok := strings.CutPrefix(firstLine, "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(nameAndKind, " ")
+	return name
+}
+
+func logQuery(s string, start time.Time, err error) {
+	queryName, _, _ := strings.Cut(s, "\n")
+	queryName = strings.TrimPrefix(queryName, "-- name: ")
+	slog.Debug("Done", "query", queryName, "ms", time.Since(start).Milliseconds(), "err", err)
+}
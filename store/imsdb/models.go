@@ -196,6 +196,19 @@ func AllIncidentStateValues() []IncidentState {
 	}
 }
 
+type Audit struct {
+	ID          int64
+	Created     float64
+	Actor       string
+	RequestID   string
+	Action      string
+	EntityType  string
+	Event       sql.NullInt32
+	EntityID    string
+	BeforeValue sql.NullString
+	AfterValue  sql.NullString
+}
+
 type ConcentricStreet struct {
 	Event int32
 	ID    string
@@ -215,12 +228,20 @@ type EventAccess struct {
 	Validity   EventAccessValidity
 }
 
+type EventSequence struct {
+	Event                 int32
+	LastIncidentNumber    int32
+	LastFieldReportNumber int32
+}
+
 type FieldReport struct {
 	Event          int32
 	Number         int32
 	Created        float64
 	Summary        sql.NullString
 	IncidentNumber sql.NullInt32
+	Version        int32
+	LastModified   float64
 }
 
 type FieldReportReportEntry struct {
@@ -241,6 +262,19 @@ type Incident struct {
 	LocationRadialHour   sql.NullInt16
 	LocationRadialMinute sql.NullInt16
 	LocationDescription  sql.NullString
+	Version              int32
+	LastModified         float64
+}
+
+type IncidentChange struct {
+	ID             int64
+	Event          int32
+	IncidentNumber int32
+	Created        float64
+	Author         string
+	Field          string
+	OldValue       string
+	NewValue       string
 }
 
 type IncidentIncidentType struct {
@@ -268,16 +302,54 @@ type IncidentType struct {
 	Hidden bool
 }
 
-type ReportEntry struct {
-	ID           int32
-	Author       string
-	Text         string
+type LoginFailure struct {
+	ID           int64
+	Kind         string
+	Name         string
+	Failures     int32
+	LastFailure  float64
+	BlockedUntil float64
+}
+
+type RefreshToken struct {
+	ID           int64
+	TokenHash    string
+	Family       string
+	RangerHandle string
+	DirectoryID  int64
 	Created      float64
-	Generated    bool
-	Stricken     bool
-	AttachedFile sql.NullString
+	Expires      float64
+	Used         sql.NullFloat64
+	Revoked      sql.NullFloat64
+}
+
+type ReportEntry struct {
+	ID               int32
+	Author           string
+	Text             string
+	Created          float64
+	Generated        bool
+	Stricken         bool
+	AttachedFile     sql.NullString
+	AttachedFileName sql.NullString
 }
 
 type SchemaInfo struct {
 	Version int16
 }
+
+type SseEvent struct {
+	ID                int64
+	Created           float64
+	Event             int32
+	IncidentNumber    sql.NullInt32
+	FieldReportNumber sql.NullInt32
+}
+
+type TokenRevocation struct {
+	ID           int64
+	Jti          sql.NullString
+	RangerHandle sql.NullString
+	Created      float64
+	Expires      float64
+}
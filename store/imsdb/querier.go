@@ -6,49 +6,133 @@ package imsdb
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
 	AddEventAccess(ctx context.Context, arg AddEventAccessParams) (int64, error)
-	AttachFieldReportToIncident(ctx context.Context, arg AttachFieldReportToIncidentParams) error
+	ArchiveIncidentChanges(ctx context.Context, event int32) ([]ArchiveIncidentChangesRow, error)
+	ArchiveIncidentRangers(ctx context.Context, event int32) ([]ArchiveIncidentRangersRow, error)
+	ArchiveIncidentTypes(ctx context.Context, event int32) ([]ArchiveIncidentTypesRow, error)
+	ArchiveIncidents(ctx context.Context, event int32) ([]ArchiveIncidentsRow, error)
+	// The update is skipped if expected_version is set and doesn't match.
+	AttachFieldReportToIncident(ctx context.Context, arg AttachFieldReportToIncidentParams) (int64, error)
 	AttachIncidentTypeToIncident(ctx context.Context, arg AttachIncidentTypeToIncidentParams) error
 	AttachRangerHandleToIncident(ctx context.Context, arg AttachRangerHandleToIncidentParams) error
 	AttachReportEntryToFieldReport(ctx context.Context, arg AttachReportEntryToFieldReportParams) error
 	AttachReportEntryToIncident(ctx context.Context, arg AttachReportEntryToIncidentParams) error
 	AttachedFieldReportNumbers(ctx context.Context, arg AttachedFieldReportNumbersParams) ([]int32, error)
+	AuditEntries(ctx context.Context, arg AuditEntriesParams) ([]AuditEntriesRow, error)
+	BlockedLoginFailures(ctx context.Context, blockedUntil float64) ([]BlockedLoginFailuresRow, error)
 	ClearEventAccessForExpression(ctx context.Context, arg ClearEventAccessForExpressionParams) error
 	ClearEventAccessForMode(ctx context.Context, arg ClearEventAccessForModeParams) error
 	ConcentricStreets(ctx context.Context, event int32) ([]ConcentricStreetsRow, error)
+	CreateAudit(ctx context.Context, arg CreateAuditParams) error
 	CreateConcentricStreet(ctx context.Context, arg CreateConcentricStreetParams) error
 	CreateEvent(ctx context.Context, name string) (int64, error)
+	CreateEventSequenceOrIgnore(ctx context.Context, id int32) error
 	CreateFieldReport(ctx context.Context, arg CreateFieldReportParams) error
 	CreateIncident(ctx context.Context, arg CreateIncidentParams) (int64, error)
+	CreateIncidentChange(ctx context.Context, arg CreateIncidentChangeParams) error
 	CreateIncidentTypeOrIgnore(ctx context.Context, arg CreateIncidentTypeOrIgnoreParams) error
+	// This makes sure there's a row to lock, even before the first failure. The
+	// no-op update still locks the row if it already exists.
+	CreateLoginFailureOrIgnore(ctx context.Context, arg CreateLoginFailureOrIgnoreParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error
 	CreateReportEntry(ctx context.Context, arg CreateReportEntryParams) (int64, error)
+	CreateSSEEvent(ctx context.Context, arg CreateSSEEventParams) (int64, error)
+	CreateTokenRevocation(ctx context.Context, arg CreateTokenRevocationParams) error
+	DeleteLoginFailure(ctx context.Context, arg DeleteLoginFailureParams) error
+	DeleteReportEntry(ctx context.Context, id int32) error
+	DetachFieldReportReportEntry(ctx context.Context, reportEntry int32) error
 	DetachIncidentTypeFromIncident(ctx context.Context, arg DetachIncidentTypeFromIncidentParams) error
 	DetachRangerHandleFromIncident(ctx context.Context, arg DetachRangerHandleFromIncidentParams) error
+	// This takes a report entry off every incident that has it. See
+	// DetachFieldReportReportEntry for field reports.
+	DetachReportEntry(ctx context.Context, reportEntry int32) error
 	DetachedFieldReportNumbers(ctx context.Context, event int32) ([]int32, error)
 	EventAccess(ctx context.Context, event int32) ([]EventAccessRow, error)
 	EventAccessAll(ctx context.Context) ([]EventAccessAllRow, error)
+	// These are all the report entries of an event's incidents and field reports.
+	EventReportEntries(ctx context.Context, arg EventReportEntriesParams) ([]EventReportEntriesRow, error)
 	Events(ctx context.Context) ([]EventsRow, error)
+	// An event's last activity is the latest creation or change of any of its
+	// incidents or field reports. It's 0 for an event with neither.
+	EventsLastActivity(ctx context.Context) ([]EventsLastActivityRow, error)
 	FieldReport(ctx context.Context, arg FieldReportParams) (FieldReportRow, error)
 	FieldReport_ReportEntries(ctx context.Context, arg FieldReport_ReportEntriesParams) ([]FieldReport_ReportEntriesRow, error)
-	FieldReports(ctx context.Context, event int32) ([]FieldReportsRow, error)
+	// A page after the previous one starts with after_number, the number of the
+	// previous page's last field report.
+	FieldReports(ctx context.Context, arg FieldReportsParams) ([]FieldReportsRow, error)
+	FieldReportsByNumber(ctx context.Context, arg FieldReportsByNumberParams) ([]FieldReportsByNumberRow, error)
+	FieldReportsByNumber_ReportEntries(ctx context.Context, arg FieldReportsByNumber_ReportEntriesParams) ([]FieldReportsByNumber_ReportEntriesRow, error)
 	FieldReports_ReportEntries(ctx context.Context, arg FieldReports_ReportEntriesParams) ([]FieldReports_ReportEntriesRow, error)
 	HideShowIncidentType(ctx context.Context, arg HideShowIncidentTypeParams) error
+	ImportFieldReport(ctx context.Context, arg ImportFieldReportParams) error
+	// Unlike CreateIncident, this sets every column, so that an archived incident
+	// comes back exactly as it was.
+	ImportIncident(ctx context.Context, arg ImportIncidentParams) error
 	Incident(ctx context.Context, arg IncidentParams) (IncidentRow, error)
+	IncidentChanges(ctx context.Context, arg IncidentChangesParams) ([]IncidentChangesRow, error)
+	IncidentStateChanges(ctx context.Context, event int32) ([]IncidentStateChangesRow, error)
+	// These are the system report entries that may record changes of state. They're
+	// all there is for changes made before INCIDENT_CHANGE existed.
+	IncidentStateReportEntries(ctx context.Context, event int32) ([]IncidentStateReportEntriesRow, error)
 	IncidentTypes(ctx context.Context) ([]IncidentTypesRow, error)
 	Incident_ReportEntries(ctx context.Context, arg Incident_ReportEntriesParams) ([]Incident_ReportEntriesRow, error)
-	Incidents(ctx context.Context, event int32) ([]IncidentsRow, error)
+	// Each filter is skipped when its parameter is null. Incidents are sorted on
+	// a key that's the sum of the weighted CREATED, LAST_MODIFIED, PRIORITY, and NUMBER, with ties
+	// broken by NUMBER * number_direction. Setting one weight to 1 or -1 and the
+	// others to 0 sorts by that column. A page after the previous one starts with
+	// after_sort_key and after_number from the previous page's last incident.
+	// No index can serve that computed key, so every page reads and sorts all of the
+	// event's incidents that pass the filters. That's fine for the few thousand
+	// incidents an event has, but a limit doesn't make a page any cheaper to find.
+	Incidents(ctx context.Context, arg IncidentsParams) ([]IncidentsRow, error)
+	IncidentsByNumber_ReportEntries(ctx context.Context, arg IncidentsByNumber_ReportEntriesParams) ([]IncidentsByNumber_ReportEntriesRow, error)
 	Incidents_ReportEntries(ctx context.Context, arg Incidents_ReportEntriesParams) ([]Incidents_ReportEntriesRow, error)
-	MaxFieldReportNumber(ctx context.Context, event int32) (interface{}, error)
-	MaxIncidentNumber(ctx context.Context, event int32) (interface{}, error)
+	IncrementFieldReportNumber(ctx context.Context, event int32) error
+	IncrementIncidentNumber(ctx context.Context, event int32) error
+	LastFieldReportNumber(ctx context.Context, event int32) (int32, error)
+	LastIncidentNumber(ctx context.Context, event int32) (int32, error)
+	LockLoginFailure(ctx context.Context, arg LockLoginFailureParams) (LockLoginFailureRow, error)
+	PruneLoginFailures(ctx context.Context, lastFailure float64) error
+	PruneRefreshTokens(ctx context.Context, expires float64) (int64, error)
+	PruneSSEEvents(ctx context.Context, id int64) error
+	PruneTokenRevocations(ctx context.Context, expires float64) error
 	QueryEventID(ctx context.Context, name string) (QueryEventIDRow, error)
+	// The values recorded for changes to an event's incidents, field reports, and
+	// report entries hold whatever text was written. The records of the changes
+	// themselves are kept, as are the values for everything else, such as who was
+	// given access to the event.
+	RedactAuditValues(ctx context.Context, event sql.NullInt32) (int64, error)
+	RedactFieldReportSummaries(ctx context.Context, arg RedactFieldReportSummariesParams) (int64, error)
+	// redacted is a JSON value here, as OLD_VALUE and NEW_VALUE are.
+	RedactIncidentChanges(ctx context.Context, arg RedactIncidentChangesParams) (int64, error)
+	RedactIncidentLocationDescriptions(ctx context.Context, arg RedactIncidentLocationDescriptionsParams) (int64, error)
+	// The redact queries replace an event's free text with the redacted marker,
+	// leaving LAST_MODIFIED alone so that the event doesn't look newer for it.
+	RedactIncidentSummaries(ctx context.Context, arg RedactIncidentSummariesParams) (int64, error)
+	RefreshToken(ctx context.Context, tokenHash string) (RefreshTokenRow, error)
+	RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error)
+	RevokeRefreshTokensForHandle(ctx context.Context, arg RevokeRefreshTokensForHandleParams) (int64, error)
+	SSEEventFieldReportAuthorsAfter(ctx context.Context, id int64) ([]SSEEventFieldReportAuthorsAfterRow, error)
+	SSEEventIDRange(ctx context.Context) (SSEEventIDRangeRow, error)
+	SSEEventsAfter(ctx context.Context, id int64) ([]SSEEventsAfterRow, error)
 	SchemaVersion(ctx context.Context) (int16, error)
+	SetFieldReportLastModified(ctx context.Context, arg SetFieldReportLastModifiedParams) error
 	SetFieldReportReportEntryStricken(ctx context.Context, arg SetFieldReportReportEntryStrickenParams) error
+	SetIncidentLastModified(ctx context.Context, arg SetIncidentLastModifiedParams) error
 	SetIncidentReportEntryStricken(ctx context.Context, arg SetIncidentReportEntryStrickenParams) error
-	UpdateFieldReport(ctx context.Context, arg UpdateFieldReportParams) error
-	UpdateIncident(ctx context.Context, arg UpdateIncidentParams) error
+	SetReportEntryText(ctx context.Context, arg SetReportEntryTextParams) error
+	TokenRevocationCount(ctx context.Context, arg TokenRevocationCountParams) (int64, error)
+	// This is for changes to an incident that are made from elsewhere, such as
+	// to its list of field reports, so it gets a new version too.
+	TouchIncident(ctx context.Context, arg TouchIncidentParams) error
+	UpdateFieldReport(ctx context.Context, arg UpdateFieldReportParams) (int64, error)
+	UpdateIncident(ctx context.Context, arg UpdateIncidentParams) (int64, error)
+	UpdateLoginFailure(ctx context.Context, arg UpdateLoginFailureParams) error
+	UseRefreshToken(ctx context.Context, arg UseRefreshTokenParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -8,6 +8,7 @@ package imsdb
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
 const addEventAccess = `-- name: AddEventAccess :execlastid
@@ -35,21 +36,204 @@ func (q *Queries) AddEventAccess(ctx context.Context, arg AddEventAccessParams)
 	return result.LastInsertId()
 }
 
-const attachFieldReportToIncident = `-- name: AttachFieldReportToIncident :exec
+const archiveIncidentChanges = `-- name: ArchiveIncidentChanges :many
+select ic.id, ic.event, ic.incident_number, ic.created, ic.author, ic.field, ic.old_value, ic.new_value
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+order by ic.ID
+`
+
+type ArchiveIncidentChangesRow struct {
+	IncidentChange IncidentChange
+}
+
+func (q *Queries) ArchiveIncidentChanges(ctx context.Context, event int32) ([]ArchiveIncidentChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, archiveIncidentChanges, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArchiveIncidentChangesRow
+	for rows.Next() {
+		var i ArchiveIncidentChangesRow
+		if err := rows.Scan(
+			&i.IncidentChange.ID,
+			&i.IncidentChange.Event,
+			&i.IncidentChange.IncidentNumber,
+			&i.IncidentChange.Created,
+			&i.IncidentChange.Author,
+			&i.IncidentChange.Field,
+			&i.IncidentChange.OldValue,
+			&i.IncidentChange.NewValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const archiveIncidentRangers = `-- name: ArchiveIncidentRangers :many
+select ir.INCIDENT_NUMBER, ir.RANGER_HANDLE
+from INCIDENT__RANGER ir
+where ir.EVENT = ?
+order by ir.ID
+`
+
+type ArchiveIncidentRangersRow struct {
+	IncidentNumber int32
+	RangerHandle   string
+}
+
+func (q *Queries) ArchiveIncidentRangers(ctx context.Context, event int32) ([]ArchiveIncidentRangersRow, error) {
+	rows, err := q.db.QueryContext(ctx, archiveIncidentRangers, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArchiveIncidentRangersRow
+	for rows.Next() {
+		var i ArchiveIncidentRangersRow
+		if err := rows.Scan(&i.IncidentNumber, &i.RangerHandle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const archiveIncidentTypes = `-- name: ArchiveIncidentTypes :many
+select iit.INCIDENT_NUMBER, it.id, it.name, it.hidden
+from INCIDENT__INCIDENT_TYPE iit
+    join INCIDENT_TYPE it
+        on it.ID = iit.INCIDENT_TYPE
+where iit.EVENT = ?
+order by iit.INCIDENT_NUMBER, it.NAME
+`
+
+type ArchiveIncidentTypesRow struct {
+	IncidentNumber int32
+	IncidentType   IncidentType
+}
+
+func (q *Queries) ArchiveIncidentTypes(ctx context.Context, event int32) ([]ArchiveIncidentTypesRow, error) {
+	rows, err := q.db.QueryContext(ctx, archiveIncidentTypes, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArchiveIncidentTypesRow
+	for rows.Next() {
+		var i ArchiveIncidentTypesRow
+		if err := rows.Scan(
+			&i.IncidentNumber,
+			&i.IncidentType.ID,
+			&i.IncidentType.Name,
+			&i.IncidentType.Hidden,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const archiveIncidents = `-- name: ArchiveIncidents :many
+select i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description, i.version, i.last_modified
+from INCIDENT i
+where i.EVENT = ?
+order by i.NUMBER
+`
+
+type ArchiveIncidentsRow struct {
+	Incident Incident
+}
+
+func (q *Queries) ArchiveIncidents(ctx context.Context, event int32) ([]ArchiveIncidentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, archiveIncidents, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArchiveIncidentsRow
+	for rows.Next() {
+		var i ArchiveIncidentsRow
+		if err := rows.Scan(
+			&i.Incident.Event,
+			&i.Incident.Number,
+			&i.Incident.Created,
+			&i.Incident.Priority,
+			&i.Incident.State,
+			&i.Incident.Summary,
+			&i.Incident.LocationName,
+			&i.Incident.LocationConcentric,
+			&i.Incident.LocationRadialHour,
+			&i.Incident.LocationRadialMinute,
+			&i.Incident.LocationDescription,
+			&i.Incident.Version,
+			&i.Incident.LastModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const attachFieldReportToIncident = `-- name: AttachFieldReportToIncident :execrows
 update FIELD_REPORT
-set INCIDENT_NUMBER = ?
-where EVENT = ? and NUMBER = ?
+set INCIDENT_NUMBER = ?, LAST_MODIFIED = ?, VERSION = VERSION + 1
+where EVENT = ?
+    and NUMBER = ?
+    and (? is null or VERSION = ?)
 `
 
 type AttachFieldReportToIncidentParams struct {
-	IncidentNumber sql.NullInt32
-	Event          int32
-	Number         int32
+	IncidentNumber  sql.NullInt32
+	LastModified    float64
+	Event           int32
+	Number          int32
+	ExpectedVersion sql.NullInt32
 }
 
-func (q *Queries) AttachFieldReportToIncident(ctx context.Context, arg AttachFieldReportToIncidentParams) error {
-	_, err := q.db.ExecContext(ctx, attachFieldReportToIncident, arg.IncidentNumber, arg.Event, arg.Number)
-	return err
+// The update is skipped if expected_version is set and doesn't match.
+func (q *Queries) AttachFieldReportToIncident(ctx context.Context, arg AttachFieldReportToIncidentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, attachFieldReportToIncident,
+		arg.IncidentNumber,
+		arg.LastModified,
+		arg.Event,
+		arg.Number,
+		arg.ExpectedVersion,
+		arg.ExpectedVersion,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const attachIncidentTypeToIncident = `-- name: AttachIncidentTypeToIncident :exec
@@ -160,6 +344,130 @@ func (q *Queries) AttachedFieldReportNumbers(ctx context.Context, arg AttachedFi
 	return items, nil
 }
 
+const auditEntries = `-- name: AuditEntries :many
+select a.id, a.created, a.actor, a.request_id, a.action, a.entity_type, a.event, a.entity_id, a.before_value, a.after_value
+from AUDIT a
+where (? is null or a.ACTOR = ?)
+    and (? is null or a.ENTITY_TYPE = ?)
+    and (? is null or a.ENTITY_ID = ?)
+    and (? is null or a.EVENT = ?)
+    and (? is null or a.REQUEST_ID = ?)
+    and (? is null or a.CREATED >= ?)
+    and (? is null or a.CREATED < ?)
+    and (? is null or a.ID < ?)
+order by a.ID desc
+limit ?
+`
+
+type AuditEntriesParams struct {
+	Actor         sql.NullString
+	EntityType    sql.NullString
+	EntityID      sql.NullString
+	Event         sql.NullInt32
+	RequestID     sql.NullString
+	CreatedAfter  sql.NullFloat64
+	CreatedBefore sql.NullFloat64
+	BeforeID      sql.NullInt64
+	Limit         int32
+}
+
+type AuditEntriesRow struct {
+	Audit Audit
+}
+
+func (q *Queries) AuditEntries(ctx context.Context, arg AuditEntriesParams) ([]AuditEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, auditEntries,
+		arg.Actor,
+		arg.Actor,
+		arg.EntityType,
+		arg.EntityType,
+		arg.EntityID,
+		arg.EntityID,
+		arg.Event,
+		arg.Event,
+		arg.RequestID,
+		arg.RequestID,
+		arg.CreatedAfter,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.CreatedBefore,
+		arg.BeforeID,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEntriesRow
+	for rows.Next() {
+		var i AuditEntriesRow
+		if err := rows.Scan(
+			&i.Audit.ID,
+			&i.Audit.Created,
+			&i.Audit.Actor,
+			&i.Audit.RequestID,
+			&i.Audit.Action,
+			&i.Audit.EntityType,
+			&i.Audit.Event,
+			&i.Audit.EntityID,
+			&i.Audit.BeforeValue,
+			&i.Audit.AfterValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const blockedLoginFailures = `-- name: BlockedLoginFailures :many
+select lf.id, lf.kind, lf.name, lf.failures, lf.last_failure, lf.blocked_until
+from LOGIN_FAILURE lf
+where lf.BLOCKED_UNTIL > ?
+order by lf.BLOCKED_UNTIL desc
+`
+
+type BlockedLoginFailuresRow struct {
+	LoginFailure LoginFailure
+}
+
+func (q *Queries) BlockedLoginFailures(ctx context.Context, blockedUntil float64) ([]BlockedLoginFailuresRow, error) {
+	rows, err := q.db.QueryContext(ctx, blockedLoginFailures, blockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlockedLoginFailuresRow
+	for rows.Next() {
+		var i BlockedLoginFailuresRow
+		if err := rows.Scan(
+			&i.LoginFailure.ID,
+			&i.LoginFailure.Kind,
+			&i.LoginFailure.Name,
+			&i.LoginFailure.Failures,
+			&i.LoginFailure.LastFailure,
+			&i.LoginFailure.BlockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const clearEventAccessForExpression = `-- name: ClearEventAccessForExpression :exec
 delete from EVENT_ACCESS
 where EVENT = ? and EXPRESSION = ?
@@ -223,6 +531,40 @@ func (q *Queries) ConcentricStreets(ctx context.Context, event int32) ([]Concent
 	return items, nil
 }
 
+const createAudit = `-- name: CreateAudit :exec
+insert into AUDIT (
+    CREATED, ACTOR, REQUEST_ID, ACTION, ENTITY_TYPE, EVENT, ENTITY_ID, BEFORE_VALUE, AFTER_VALUE
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateAuditParams struct {
+	Created     float64
+	Actor       string
+	RequestID   string
+	Action      string
+	EntityType  string
+	Event       sql.NullInt32
+	EntityID    string
+	BeforeValue sql.NullString
+	AfterValue  sql.NullString
+}
+
+func (q *Queries) CreateAudit(ctx context.Context, arg CreateAuditParams) error {
+	_, err := q.db.ExecContext(ctx, createAudit,
+		arg.Created,
+		arg.Actor,
+		arg.RequestID,
+		arg.Action,
+		arg.EntityType,
+		arg.Event,
+		arg.EntityID,
+		arg.BeforeValue,
+		arg.AfterValue,
+	)
+	return err
+}
+
 const createConcentricStreet = `-- name: CreateConcentricStreet :exec
 insert into CONCENTRIC_STREET (EVENT, ID, NAME)
 values (?, ?, ?)
@@ -251,11 +593,27 @@ func (q *Queries) CreateEvent(ctx context.Context, name string) (int64, error) {
 	return result.LastInsertId()
 }
 
+const createEventSequenceOrIgnore = `-- name: CreateEventSequenceOrIgnore :exec
+insert into EVENT_SEQUENCE (EVENT, LAST_INCIDENT_NUMBER, LAST_FIELD_REPORT_NUMBER)
+select
+    e.ID,
+    (select coalesce(max(i.NUMBER), 0) from INCIDENT i where i.EVENT = e.ID),
+    (select coalesce(max(fr.NUMBER), 0) from FIELD_REPORT fr where fr.EVENT = e.ID)
+from EVENT e
+where e.ID = ?
+on duplicate key update EVENT = EVENT_SEQUENCE.EVENT
+`
+
+func (q *Queries) CreateEventSequenceOrIgnore(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, createEventSequenceOrIgnore, id)
+	return err
+}
+
 const createFieldReport = `-- name: CreateFieldReport :exec
 insert into FIELD_REPORT (
-    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER
+    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER, LAST_MODIFIED
 )
-values (?, ?, ?, ?, ?)
+values (?, ?, ?, ?, ?, ?)
 `
 
 type CreateFieldReportParams struct {
@@ -264,6 +622,7 @@ type CreateFieldReportParams struct {
 	Created        float64
 	Summary        sql.NullString
 	IncidentNumber sql.NullInt32
+	LastModified   float64
 }
 
 func (q *Queries) CreateFieldReport(ctx context.Context, arg CreateFieldReportParams) error {
@@ -273,6 +632,7 @@ func (q *Queries) CreateFieldReport(ctx context.Context, arg CreateFieldReportPa
 		arg.Created,
 		arg.Summary,
 		arg.IncidentNumber,
+		arg.LastModified,
 	)
 	return err
 }
@@ -283,19 +643,21 @@ insert into INCIDENT (
     NUMBER,
     CREATED,
     PRIORITY,
-    STATE
+    STATE,
+    LAST_MODIFIED
 )
 values (
-   ?,?,?,?,?
+   ?,?,?,?,?,?
 )
 `
 
 type CreateIncidentParams struct {
-	Event    int32
-	Number   int32
-	Created  float64
-	Priority int8
-	State    IncidentState
+	Event        int32
+	Number       int32
+	Created      float64
+	Priority     int8
+	State        IncidentState
+	LastModified float64
 }
 
 func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams) (int64, error) {
@@ -305,6 +667,7 @@ func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams)
 		arg.Created,
 		arg.Priority,
 		arg.State,
+		arg.LastModified,
 	)
 	if err != nil {
 		return 0, err
@@ -312,6 +675,36 @@ func (q *Queries) CreateIncident(ctx context.Context, arg CreateIncidentParams)
 	return result.LastInsertId()
 }
 
+const createIncidentChange = `-- name: CreateIncidentChange :exec
+insert into INCIDENT_CHANGE (
+    EVENT, INCIDENT_NUMBER, CREATED, AUTHOR, FIELD, OLD_VALUE, NEW_VALUE
+)
+values (?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateIncidentChangeParams struct {
+	Event          int32
+	IncidentNumber int32
+	Created        float64
+	Author         string
+	Field          string
+	OldValue       string
+	NewValue       string
+}
+
+func (q *Queries) CreateIncidentChange(ctx context.Context, arg CreateIncidentChangeParams) error {
+	_, err := q.db.ExecContext(ctx, createIncidentChange,
+		arg.Event,
+		arg.IncidentNumber,
+		arg.Created,
+		arg.Author,
+		arg.Field,
+		arg.OldValue,
+		arg.NewValue,
+	)
+	return err
+}
+
 const createIncidentTypeOrIgnore = `-- name: CreateIncidentTypeOrIgnore :exec
 insert into INCIDENT_TYPE (NAME, HIDDEN)
 values (?, ?)
@@ -328,21 +721,70 @@ func (q *Queries) CreateIncidentTypeOrIgnore(ctx context.Context, arg CreateInci
 	return err
 }
 
+const createLoginFailureOrIgnore = `-- name: CreateLoginFailureOrIgnore :exec
+insert into LOGIN_FAILURE (
+    KIND, NAME, FAILURES, LAST_FAILURE, BLOCKED_UNTIL
+)
+values (?, ?, 0, 0, 0)
+on duplicate key update FAILURES = LOGIN_FAILURE.FAILURES
+`
+
+type CreateLoginFailureOrIgnoreParams struct {
+	Kind string
+	Name string
+}
+
+// This makes sure there's a row to lock, even before the first failure. The
+// no-op update still locks the row if it already exists.
+func (q *Queries) CreateLoginFailureOrIgnore(ctx context.Context, arg CreateLoginFailureOrIgnoreParams) error {
+	_, err := q.db.ExecContext(ctx, createLoginFailureOrIgnore, arg.Kind, arg.Name)
+	return err
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :exec
+insert into REFRESH_TOKEN (
+    TOKEN_HASH, FAMILY, RANGER_HANDLE, DIRECTORY_ID, CREATED, EXPIRES
+)
+values (?, ?, ?, ?, ?, ?)
+`
+
+type CreateRefreshTokenParams struct {
+	TokenHash    string
+	Family       string
+	RangerHandle string
+	DirectoryID  int64
+	Created      float64
+	Expires      float64
+}
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.Family,
+		arg.RangerHandle,
+		arg.DirectoryID,
+		arg.Created,
+		arg.Expires,
+	)
+	return err
+}
+
 const createReportEntry = `-- name: CreateReportEntry :execlastid
 insert into REPORT_ENTRY (
-    AUTHOR, TEXT, CREATED, ` + "`" + `GENERATED` + "`" + `, STRICKEN, ATTACHED_FILE
+    AUTHOR, TEXT, CREATED, ` + "`" + `GENERATED` + "`" + `, STRICKEN, ATTACHED_FILE, ATTACHED_FILE_NAME
 ) values (
-   ?, ?, ?, ?, ?, ?
+   ?, ?, ?, ?, ?, ?, ?
 )
 `
 
 type CreateReportEntryParams struct {
-	Author       string
-	Text         string
-	Created      float64
-	Generated    bool
-	Stricken     bool
-	AttachedFile sql.NullString
+	Author           string
+	Text             string
+	Created          float64
+	Generated        bool
+	Stricken         bool
+	AttachedFile     sql.NullString
+	AttachedFileName sql.NullString
 }
 
 func (q *Queries) CreateReportEntry(ctx context.Context, arg CreateReportEntryParams) (int64, error) {
@@ -353,6 +795,7 @@ func (q *Queries) CreateReportEntry(ctx context.Context, arg CreateReportEntryPa
 		arg.Generated,
 		arg.Stricken,
 		arg.AttachedFile,
+		arg.AttachedFileName,
 	)
 	if err != nil {
 		return 0, err
@@ -360,28 +803,110 @@ func (q *Queries) CreateReportEntry(ctx context.Context, arg CreateReportEntryPa
 	return result.LastInsertId()
 }
 
-const detachIncidentTypeFromIncident = `-- name: DetachIncidentTypeFromIncident :exec
-delete from INCIDENT__INCIDENT_TYPE
-where
-    EVENT = ?
-    and INCIDENT_NUMBER = ?
-    and INCIDENT_TYPE = (select it.ID from INCIDENT_TYPE it where it.NAME = ?)
+const createSSEEvent = `-- name: CreateSSEEvent :execlastid
+insert into SSE_EVENT (CREATED, EVENT, INCIDENT_NUMBER, FIELD_REPORT_NUMBER)
+values (?, ?, ?, ?)
 `
 
-type DetachIncidentTypeFromIncidentParams struct {
-	Event          int32
-	IncidentNumber int32
-	Name           string
+type CreateSSEEventParams struct {
+	Created           float64
+	Event             int32
+	IncidentNumber    sql.NullInt32
+	FieldReportNumber sql.NullInt32
 }
 
-func (q *Queries) DetachIncidentTypeFromIncident(ctx context.Context, arg DetachIncidentTypeFromIncidentParams) error {
-	_, err := q.db.ExecContext(ctx, detachIncidentTypeFromIncident, arg.Event, arg.IncidentNumber, arg.Name)
-	return err
+func (q *Queries) CreateSSEEvent(ctx context.Context, arg CreateSSEEventParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createSSEEvent,
+		arg.Created,
+		arg.Event,
+		arg.IncidentNumber,
+		arg.FieldReportNumber,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
 }
 
-const detachRangerHandleFromIncident = `-- name: DetachRangerHandleFromIncident :exec
-delete from INCIDENT__RANGER
-where
+const createTokenRevocation = `-- name: CreateTokenRevocation :exec
+insert into TOKEN_REVOCATION (
+    JTI, RANGER_HANDLE, CREATED, EXPIRES
+)
+values (?, ?, ?, ?)
+`
+
+type CreateTokenRevocationParams struct {
+	Jti          sql.NullString
+	RangerHandle sql.NullString
+	Created      float64
+	Expires      float64
+}
+
+func (q *Queries) CreateTokenRevocation(ctx context.Context, arg CreateTokenRevocationParams) error {
+	_, err := q.db.ExecContext(ctx, createTokenRevocation,
+		arg.Jti,
+		arg.RangerHandle,
+		arg.Created,
+		arg.Expires,
+	)
+	return err
+}
+
+const deleteLoginFailure = `-- name: DeleteLoginFailure :exec
+delete from LOGIN_FAILURE
+where KIND = ? and NAME = ?
+`
+
+type DeleteLoginFailureParams struct {
+	Kind string
+	Name string
+}
+
+func (q *Queries) DeleteLoginFailure(ctx context.Context, arg DeleteLoginFailureParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginFailure, arg.Kind, arg.Name)
+	return err
+}
+
+const deleteReportEntry = `-- name: DeleteReportEntry :exec
+delete from REPORT_ENTRY where ID = ?
+`
+
+func (q *Queries) DeleteReportEntry(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteReportEntry, id)
+	return err
+}
+
+const detachFieldReportReportEntry = `-- name: DetachFieldReportReportEntry :exec
+delete from FIELD_REPORT__REPORT_ENTRY where REPORT_ENTRY = ?
+`
+
+func (q *Queries) DetachFieldReportReportEntry(ctx context.Context, reportEntry int32) error {
+	_, err := q.db.ExecContext(ctx, detachFieldReportReportEntry, reportEntry)
+	return err
+}
+
+const detachIncidentTypeFromIncident = `-- name: DetachIncidentTypeFromIncident :exec
+delete from INCIDENT__INCIDENT_TYPE
+where
+    EVENT = ?
+    and INCIDENT_NUMBER = ?
+    and INCIDENT_TYPE = (select it.ID from INCIDENT_TYPE it where it.NAME = ?)
+`
+
+type DetachIncidentTypeFromIncidentParams struct {
+	Event          int32
+	IncidentNumber int32
+	Name           string
+}
+
+func (q *Queries) DetachIncidentTypeFromIncident(ctx context.Context, arg DetachIncidentTypeFromIncidentParams) error {
+	_, err := q.db.ExecContext(ctx, detachIncidentTypeFromIncident, arg.Event, arg.IncidentNumber, arg.Name)
+	return err
+}
+
+const detachRangerHandleFromIncident = `-- name: DetachRangerHandleFromIncident :exec
+delete from INCIDENT__RANGER
+where
     EVENT = ?
     and INCIDENT_NUMBER = ?
     and RANGER_HANDLE = ?
@@ -398,6 +923,17 @@ func (q *Queries) DetachRangerHandleFromIncident(ctx context.Context, arg Detach
 	return err
 }
 
+const detachReportEntry = `-- name: DetachReportEntry :exec
+delete from INCIDENT__REPORT_ENTRY where REPORT_ENTRY = ?
+`
+
+// This takes a report entry off every incident that has it. See
+// DetachFieldReportReportEntry for field reports.
+func (q *Queries) DetachReportEntry(ctx context.Context, reportEntry int32) error {
+	_, err := q.db.ExecContext(ctx, detachReportEntry, reportEntry)
+	return err
+}
+
 const detachedFieldReportNumbers = `-- name: DetachedFieldReportNumbers :many
 select NUMBER from FIELD_REPORT
 where EVENT = ? and INCIDENT_NUMBER is null
@@ -503,6 +1039,59 @@ func (q *Queries) EventAccessAll(ctx context.Context) ([]EventAccessAllRow, erro
 	return items, nil
 }
 
+const eventReportEntries = `-- name: EventReportEntries :many
+select re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
+from REPORT_ENTRY re
+where re.ID in (
+        select ire.REPORT_ENTRY from INCIDENT__REPORT_ENTRY ire where ire.EVENT = ?
+    )
+    or re.ID in (
+        select frre.REPORT_ENTRY from FIELD_REPORT__REPORT_ENTRY frre where frre.EVENT = ?
+    )
+order by re.ID
+`
+
+type EventReportEntriesParams struct {
+	Event int32
+}
+
+type EventReportEntriesRow struct {
+	ReportEntry ReportEntry
+}
+
+// These are all the report entries of an event's incidents and field reports.
+func (q *Queries) EventReportEntries(ctx context.Context, arg EventReportEntriesParams) ([]EventReportEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, eventReportEntries, arg.Event, arg.Event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventReportEntriesRow
+	for rows.Next() {
+		var i EventReportEntriesRow
+		if err := rows.Scan(
+			&i.ReportEntry.ID,
+			&i.ReportEntry.Author,
+			&i.ReportEntry.Text,
+			&i.ReportEntry.Created,
+			&i.ReportEntry.Generated,
+			&i.ReportEntry.Stricken,
+			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const events = `-- name: Events :many
 select e.id, e.name from EVENT e
 `
@@ -534,8 +1123,59 @@ func (q *Queries) Events(ctx context.Context) ([]EventsRow, error) {
 	return items, nil
 }
 
+const eventsLastActivity = `-- name: EventsLastActivity :many
+select
+    e.id, e.name,
+    cast((select coalesce(max(i.LAST_MODIFIED), 0) from INCIDENT i where i.EVENT = e.ID) as double) as INCIDENTS_LAST_MODIFIED,
+    cast((select coalesce(max(i.CREATED), 0) from INCIDENT i where i.EVENT = e.ID) as double) as INCIDENTS_LAST_CREATED,
+    cast((select coalesce(max(fr.LAST_MODIFIED), 0) from FIELD_REPORT fr where fr.EVENT = e.ID) as double) as FIELD_REPORTS_LAST_MODIFIED,
+    cast((select coalesce(max(fr.CREATED), 0) from FIELD_REPORT fr where fr.EVENT = e.ID) as double) as FIELD_REPORTS_LAST_CREATED
+from EVENT e
+order by e.ID
+`
+
+type EventsLastActivityRow struct {
+	Event                    Event
+	IncidentsLastModified    float64
+	IncidentsLastCreated     float64
+	FieldReportsLastModified float64
+	FieldReportsLastCreated  float64
+}
+
+// An event's last activity is the latest creation or change of any of its
+// incidents or field reports. It's 0 for an event with neither.
+func (q *Queries) EventsLastActivity(ctx context.Context) ([]EventsLastActivityRow, error) {
+	rows, err := q.db.QueryContext(ctx, eventsLastActivity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventsLastActivityRow
+	for rows.Next() {
+		var i EventsLastActivityRow
+		if err := rows.Scan(
+			&i.Event.ID,
+			&i.Event.Name,
+			&i.IncidentsLastModified,
+			&i.IncidentsLastCreated,
+			&i.FieldReportsLastModified,
+			&i.FieldReportsLastCreated,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const fieldReport = `-- name: FieldReport :one
-select fr.event, fr.number, fr.created, fr.summary, fr.incident_number
+select fr.event, fr.number, fr.created, fr.summary, fr.incident_number, fr.version, fr.last_modified
 from FIELD_REPORT fr
 where fr.EVENT = ?
     and fr.NUMBER = ?
@@ -559,13 +1199,15 @@ func (q *Queries) FieldReport(ctx context.Context, arg FieldReportParams) (Field
 		&i.FieldReport.Created,
 		&i.FieldReport.Summary,
 		&i.FieldReport.IncidentNumber,
+		&i.FieldReport.Version,
+		&i.FieldReport.LastModified,
 	)
 	return i, err
 }
 
 const fieldReport_ReportEntries = `-- name: FieldReport_ReportEntries :many
 select
-    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
 from
     FIELD_REPORT__REPORT_ENTRY irre
         join REPORT_ENTRY re
@@ -601,6 +1243,7 @@ func (q *Queries) FieldReport_ReportEntries(ctx context.Context, arg FieldReport
 			&i.ReportEntry.Generated,
 			&i.ReportEntry.Stricken,
 			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
 		); err != nil {
 			return nil, err
 		}
@@ -616,17 +1259,37 @@ func (q *Queries) FieldReport_ReportEntries(ctx context.Context, arg FieldReport
 }
 
 const fieldReports = `-- name: FieldReports :many
-select fr.event, fr.number, fr.created, fr.summary, fr.incident_number
+select fr.event, fr.number, fr.created, fr.summary, fr.incident_number, fr.version, fr.last_modified
 from FIELD_REPORT fr
 where fr.EVENT = ?
+    and (? is null or fr.LAST_MODIFIED >= ?)
+    and (? is null or fr.NUMBER > ?)
+order by fr.NUMBER
+limit ?
 `
 
+type FieldReportsParams struct {
+	Event         int32
+	ModifiedSince sql.NullFloat64
+	AfterNumber   sql.NullInt32
+	Limit         int32
+}
+
 type FieldReportsRow struct {
 	FieldReport FieldReport
 }
 
-func (q *Queries) FieldReports(ctx context.Context, event int32) ([]FieldReportsRow, error) {
-	rows, err := q.db.QueryContext(ctx, fieldReports, event)
+// A page after the previous one starts with after_number, the number of the
+// previous page's last field report.
+func (q *Queries) FieldReports(ctx context.Context, arg FieldReportsParams) ([]FieldReportsRow, error) {
+	rows, err := q.db.QueryContext(ctx, fieldReports,
+		arg.Event,
+		arg.ModifiedSince,
+		arg.ModifiedSince,
+		arg.AfterNumber,
+		arg.AfterNumber,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -640,6 +1303,136 @@ func (q *Queries) FieldReports(ctx context.Context, event int32) ([]FieldReports
 			&i.FieldReport.Created,
 			&i.FieldReport.Summary,
 			&i.FieldReport.IncidentNumber,
+			&i.FieldReport.Version,
+			&i.FieldReport.LastModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fieldReportsByNumber = `-- name: FieldReportsByNumber :many
+select fr.event, fr.number, fr.created, fr.summary, fr.incident_number, fr.version, fr.last_modified
+from FIELD_REPORT fr
+where fr.EVENT = ?
+    and fr.NUMBER in (/*SLICE:numbers*/?)
+`
+
+type FieldReportsByNumberParams struct {
+	Event   int32
+	Numbers []int32
+}
+
+type FieldReportsByNumberRow struct {
+	FieldReport FieldReport
+}
+
+func (q *Queries) FieldReportsByNumber(ctx context.Context, arg FieldReportsByNumberParams) ([]FieldReportsByNumberRow, error) {
+	query := fieldReportsByNumber
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Event)
+	if len(arg.Numbers) > 0 {
+		for _, v := range arg.Numbers {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:numbers*/?", strings.Repeat(",?", len(arg.Numbers))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:numbers*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FieldReportsByNumberRow
+	for rows.Next() {
+		var i FieldReportsByNumberRow
+		if err := rows.Scan(
+			&i.FieldReport.Event,
+			&i.FieldReport.Number,
+			&i.FieldReport.Created,
+			&i.FieldReport.Summary,
+			&i.FieldReport.IncidentNumber,
+			&i.FieldReport.Version,
+			&i.FieldReport.LastModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fieldReportsByNumber_ReportEntries = `-- name: FieldReportsByNumber_ReportEntries :many
+select
+    irre.FIELD_REPORT_NUMBER,
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
+from
+    FIELD_REPORT__REPORT_ENTRY irre
+        join REPORT_ENTRY re
+             on irre.REPORT_ENTRY = re.ID
+where
+    irre.EVENT = ?
+    and re.GENERATED <= ?
+    and irre.FIELD_REPORT_NUMBER in (/*SLICE:field_report_numbers*/?)
+`
+
+type FieldReportsByNumber_ReportEntriesParams struct {
+	Event              int32
+	Generated          bool
+	FieldReportNumbers []int32
+}
+
+type FieldReportsByNumber_ReportEntriesRow struct {
+	FieldReportNumber int32
+	ReportEntry       ReportEntry
+}
+
+func (q *Queries) FieldReportsByNumber_ReportEntries(ctx context.Context, arg FieldReportsByNumber_ReportEntriesParams) ([]FieldReportsByNumber_ReportEntriesRow, error) {
+	query := fieldReportsByNumber_ReportEntries
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Event)
+	queryParams = append(queryParams, arg.Generated)
+	if len(arg.FieldReportNumbers) > 0 {
+		for _, v := range arg.FieldReportNumbers {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:field_report_numbers*/?", strings.Repeat(",?", len(arg.FieldReportNumbers))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:field_report_numbers*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FieldReportsByNumber_ReportEntriesRow
+	for rows.Next() {
+		var i FieldReportsByNumber_ReportEntriesRow
+		if err := rows.Scan(
+			&i.FieldReportNumber,
+			&i.ReportEntry.ID,
+			&i.ReportEntry.Author,
+			&i.ReportEntry.Text,
+			&i.ReportEntry.Created,
+			&i.ReportEntry.Generated,
+			&i.ReportEntry.Stricken,
+			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
 		); err != nil {
 			return nil, err
 		}
@@ -657,7 +1450,7 @@ func (q *Queries) FieldReports(ctx context.Context, event int32) ([]FieldReports
 const fieldReports_ReportEntries = `-- name: FieldReports_ReportEntries :many
 select
     irre.FIELD_REPORT_NUMBER,
-    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
 from
     FIELD_REPORT__REPORT_ENTRY irre
         join REPORT_ENTRY re
@@ -665,11 +1458,15 @@ from
 where
     irre.EVENT = ?
     and re.GENERATED <= ?
+    and (? is null or irre.FIELD_REPORT_NUMBER >= ?)
+    and (? is null or irre.FIELD_REPORT_NUMBER <= ?)
 `
 
 type FieldReports_ReportEntriesParams struct {
-	Event     int32
-	Generated bool
+	Event                int32
+	Generated            bool
+	MinFieldReportNumber sql.NullInt32
+	MaxFieldReportNumber sql.NullInt32
 }
 
 type FieldReports_ReportEntriesRow struct {
@@ -678,7 +1475,14 @@ type FieldReports_ReportEntriesRow struct {
 }
 
 func (q *Queries) FieldReports_ReportEntries(ctx context.Context, arg FieldReports_ReportEntriesParams) ([]FieldReports_ReportEntriesRow, error) {
-	rows, err := q.db.QueryContext(ctx, fieldReports_ReportEntries, arg.Event, arg.Generated)
+	rows, err := q.db.QueryContext(ctx, fieldReports_ReportEntries,
+		arg.Event,
+		arg.Generated,
+		arg.MinFieldReportNumber,
+		arg.MinFieldReportNumber,
+		arg.MaxFieldReportNumber,
+		arg.MaxFieldReportNumber,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -695,6 +1499,7 @@ func (q *Queries) FieldReports_ReportEntries(ctx context.Context, arg FieldRepor
 			&i.ReportEntry.Generated,
 			&i.ReportEntry.Stricken,
 			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
 		); err != nil {
 			return nil, err
 		}
@@ -724,48 +1529,124 @@ func (q *Queries) HideShowIncidentType(ctx context.Context, arg HideShowIncident
 	return err
 }
 
-const incident = `-- name: Incident :one
-select
-    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description,
-    (
-        select coalesce(json_arrayagg(it.NAME), "[]")
-        from INCIDENT__INCIDENT_TYPE iit
-                 join INCIDENT_TYPE it
-                      on i.EVENT = iit.EVENT
-                          and i.NUMBER = iit.INCIDENT_NUMBER
-                          and iit.INCIDENT_TYPE = it.ID
-    ) as INCIDENT_TYPES,
-    (
-        select coalesce(json_arrayagg(irep.NUMBER), "[]")
-        from FIELD_REPORT irep
-        where i.EVENT = irep.EVENT
-          and i.NUMBER = irep.INCIDENT_NUMBER
-    ) as FIELD_REPORT_NUMBERS,
-    (
-        select coalesce(json_arrayagg(ir.RANGER_HANDLE), "[]")
-        from INCIDENT__RANGER ir
-        where i.EVENT = ir.EVENT
-          and i.NUMBER = ir.INCIDENT_NUMBER
-    ) as RANGER_HANDLES
-from INCIDENT i
-where i.EVENT = ?
-    and i.NUMBER = ?
+const importFieldReport = `-- name: ImportFieldReport :exec
+insert into FIELD_REPORT (
+    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER, VERSION, LAST_MODIFIED
+)
+values (?, ?, ?, ?, ?, ?, ?)
 `
 
-type IncidentParams struct {
-	Event  int32
-	Number int32
+type ImportFieldReportParams struct {
+	Event          int32
+	Number         int32
+	Created        float64
+	Summary        sql.NullString
+	IncidentNumber sql.NullInt32
+	Version        int32
+	LastModified   float64
 }
 
-type IncidentRow struct {
-	Incident           Incident
-	IncidentTypes      interface{}
-	FieldReportNumbers interface{}
-	RangerHandles      interface{}
+func (q *Queries) ImportFieldReport(ctx context.Context, arg ImportFieldReportParams) error {
+	_, err := q.db.ExecContext(ctx, importFieldReport,
+		arg.Event,
+		arg.Number,
+		arg.Created,
+		arg.Summary,
+		arg.IncidentNumber,
+		arg.Version,
+		arg.LastModified,
+	)
+	return err
 }
 
-func (q *Queries) Incident(ctx context.Context, arg IncidentParams) (IncidentRow, error) {
-	row := q.db.QueryRowContext(ctx, incident, arg.Event, arg.Number)
+const importIncident = `-- name: ImportIncident :exec
+insert into INCIDENT (
+    EVENT, NUMBER, CREATED, PRIORITY, STATE, SUMMARY,
+    LOCATION_NAME, LOCATION_CONCENTRIC, LOCATION_RADIAL_HOUR, LOCATION_RADIAL_MINUTE, LOCATION_DESCRIPTION,
+    VERSION, LAST_MODIFIED
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type ImportIncidentParams struct {
+	Event                int32
+	Number               int32
+	Created              float64
+	Priority             int8
+	State                IncidentState
+	Summary              sql.NullString
+	LocationName         sql.NullString
+	LocationConcentric   sql.NullString
+	LocationRadialHour   sql.NullInt16
+	LocationRadialMinute sql.NullInt16
+	LocationDescription  sql.NullString
+	Version              int32
+	LastModified         float64
+}
+
+// Unlike CreateIncident, this sets every column, so that an archived incident
+// comes back exactly as it was.
+func (q *Queries) ImportIncident(ctx context.Context, arg ImportIncidentParams) error {
+	_, err := q.db.ExecContext(ctx, importIncident,
+		arg.Event,
+		arg.Number,
+		arg.Created,
+		arg.Priority,
+		arg.State,
+		arg.Summary,
+		arg.LocationName,
+		arg.LocationConcentric,
+		arg.LocationRadialHour,
+		arg.LocationRadialMinute,
+		arg.LocationDescription,
+		arg.Version,
+		arg.LastModified,
+	)
+	return err
+}
+
+const incident = `-- name: Incident :one
+select
+    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description, i.version, i.last_modified,
+    (
+        select coalesce(json_arrayagg(it.NAME), "[]")
+        from INCIDENT__INCIDENT_TYPE iit
+                 join INCIDENT_TYPE it
+                      on i.EVENT = iit.EVENT
+                          and i.NUMBER = iit.INCIDENT_NUMBER
+                          and iit.INCIDENT_TYPE = it.ID
+    ) as INCIDENT_TYPES,
+    (
+        select coalesce(json_arrayagg(irep.NUMBER), "[]")
+        from FIELD_REPORT irep
+        where i.EVENT = irep.EVENT
+          and i.NUMBER = irep.INCIDENT_NUMBER
+    ) as FIELD_REPORT_NUMBERS,
+    (
+        select coalesce(json_arrayagg(ir.RANGER_HANDLE), "[]")
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+          and i.NUMBER = ir.INCIDENT_NUMBER
+    ) as RANGER_HANDLES
+from INCIDENT i
+where i.EVENT = ?
+    and i.NUMBER = ?
+`
+
+type IncidentParams struct {
+	Event  int32
+	Number int32
+}
+
+type IncidentRow struct {
+	Incident           Incident
+	IncidentTypes      interface{}
+	FieldReportNumbers interface{}
+	RangerHandles      interface{}
+}
+
+func (q *Queries) Incident(ctx context.Context, arg IncidentParams) (IncidentRow, error) {
+	row := q.db.QueryRowContext(ctx, incident, arg.Event, arg.Number)
 	var i IncidentRow
 	err := row.Scan(
 		&i.Incident.Event,
@@ -779,6 +1660,8 @@ func (q *Queries) Incident(ctx context.Context, arg IncidentParams) (IncidentRow
 		&i.Incident.LocationRadialHour,
 		&i.Incident.LocationRadialMinute,
 		&i.Incident.LocationDescription,
+		&i.Incident.Version,
+		&i.Incident.LastModified,
 		&i.IncidentTypes,
 		&i.FieldReportNumbers,
 		&i.RangerHandles,
@@ -786,6 +1669,140 @@ func (q *Queries) Incident(ctx context.Context, arg IncidentParams) (IncidentRow
 	return i, err
 }
 
+const incidentChanges = `-- name: IncidentChanges :many
+select ic.id, ic.event, ic.incident_number, ic.created, ic.author, ic.field, ic.old_value, ic.new_value
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+    and ic.INCIDENT_NUMBER = ?
+order by ic.ID
+`
+
+type IncidentChangesParams struct {
+	Event          int32
+	IncidentNumber int32
+}
+
+type IncidentChangesRow struct {
+	IncidentChange IncidentChange
+}
+
+func (q *Queries) IncidentChanges(ctx context.Context, arg IncidentChangesParams) ([]IncidentChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, incidentChanges, arg.Event, arg.IncidentNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IncidentChangesRow
+	for rows.Next() {
+		var i IncidentChangesRow
+		if err := rows.Scan(
+			&i.IncidentChange.ID,
+			&i.IncidentChange.Event,
+			&i.IncidentChange.IncidentNumber,
+			&i.IncidentChange.Created,
+			&i.IncidentChange.Author,
+			&i.IncidentChange.Field,
+			&i.IncidentChange.OldValue,
+			&i.IncidentChange.NewValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incidentStateChanges = `-- name: IncidentStateChanges :many
+select
+    ic.INCIDENT_NUMBER,
+    ic.CREATED,
+    ic.NEW_VALUE
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+    and ic.FIELD = 'state'
+order by ic.ID
+`
+
+type IncidentStateChangesRow struct {
+	IncidentNumber int32
+	Created        float64
+	NewValue       string
+}
+
+func (q *Queries) IncidentStateChanges(ctx context.Context, event int32) ([]IncidentStateChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, incidentStateChanges, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IncidentStateChangesRow
+	for rows.Next() {
+		var i IncidentStateChangesRow
+		if err := rows.Scan(&i.IncidentNumber, &i.Created, &i.NewValue); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incidentStateReportEntries = `-- name: IncidentStateReportEntries :many
+select
+    ire.INCIDENT_NUMBER,
+    re.CREATED,
+    re.TEXT
+from INCIDENT__REPORT_ENTRY ire
+    join REPORT_ENTRY re
+        on re.ID = ire.REPORT_ENTRY
+where ire.EVENT = ?
+    and re.GENERATED
+    and re.TEXT like '%Changed state: %'
+order by re.CREATED, re.ID
+`
+
+type IncidentStateReportEntriesRow struct {
+	IncidentNumber int32
+	Created        float64
+	Text           string
+}
+
+// These are the system report entries that may record changes of state. They're
+// all there is for changes made before INCIDENT_CHANGE existed.
+func (q *Queries) IncidentStateReportEntries(ctx context.Context, event int32) ([]IncidentStateReportEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, incidentStateReportEntries, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IncidentStateReportEntriesRow
+	for rows.Next() {
+		var i IncidentStateReportEntriesRow
+		if err := rows.Scan(&i.IncidentNumber, &i.Created, &i.Text); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const incidentTypes = `-- name: IncidentTypes :many
 select it.id, it.name, it.hidden
 from INCIDENT_TYPE it
@@ -821,7 +1838,7 @@ func (q *Queries) IncidentTypes(ctx context.Context) ([]IncidentTypesRow, error)
 const incident_ReportEntries = `-- name: Incident_ReportEntries :many
 select
     ire.INCIDENT_NUMBER,
-    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
 from
     INCIDENT__REPORT_ENTRY ire
         join REPORT_ENTRY re
@@ -859,6 +1876,7 @@ func (q *Queries) Incident_ReportEntries(ctx context.Context, arg Incident_Repor
 			&i.ReportEntry.Generated,
 			&i.ReportEntry.Stricken,
 			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
 		); err != nil {
 			return nil, err
 		}
@@ -875,7 +1893,7 @@ func (q *Queries) Incident_ReportEntries(ctx context.Context, arg Incident_Repor
 
 const incidents = `-- name: Incidents :many
 select
-    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description,
+    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description, i.version, i.last_modified,
     (
         select coalesce(json_arrayagg(it.NAME), "[]")
         from INCIDENT__INCIDENT_TYPE iit
@@ -900,10 +1918,85 @@ from
     INCIDENT i
 where
     i.EVENT = ?
+    and (? is null
+        or instr(concat(',', ?, ','), concat(',', i.STATE, ',')) > 0)
+    and (? is null or i.PRIORITY = ?)
+    and (? is null or exists (
+        select 1
+        from INCIDENT__INCIDENT_TYPE iit
+        join INCIDENT_TYPE it
+            on iit.INCIDENT_TYPE = it.ID
+        where i.EVENT = iit.EVENT
+            and i.NUMBER = iit.INCIDENT_NUMBER
+            and it.NAME = ?
+    ))
+    and (? is null or exists (
+        select 1
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+            and i.NUMBER = ir.INCIDENT_NUMBER
+            and ir.RANGER_HANDLE = ?
+    ))
+    and (? is null or i.CREATED >= ?)
+    and (? is null or i.CREATED < ?)
+    and (? is null or i.LAST_MODIFIED >= ?)
+    and (? is null or i.LAST_MODIFIED < ?)
+    and (? is null
+        or i.SUMMARY like ? escape '!'
+        or i.LOCATION_NAME like ? escape '!'
+        or i.LOCATION_DESCRIPTION like ? escape '!'
+        or exists (
+            select 1
+            from INCIDENT__REPORT_ENTRY ire
+            join REPORT_ENTRY re
+                on re.ID = ire.REPORT_ENTRY
+            where i.EVENT = ire.EVENT
+                and i.NUMBER = ire.INCIDENT_NUMBER
+                and not re.STRICKEN
+                and re.TEXT like ? escape '!'
+        ))
+    and (? is null
+        or i.CREATED * ?
+            + i.LAST_MODIFIED * ?
+            + i.PRIORITY * ?
+            + i.NUMBER * ? > ?
+        or (i.CREATED * ?
+                + i.LAST_MODIFIED * ?
+                + i.PRIORITY * ?
+                + i.NUMBER * ? = ?
+            and i.NUMBER * ? > ?))
 group by
     i.NUMBER
+order by
+    i.CREATED * ?
+        + i.LAST_MODIFIED * ?
+        + i.PRIORITY * ?
+        + i.NUMBER * ?,
+    i.NUMBER * ?
+limit ?
 `
 
+type IncidentsParams struct {
+	Event           int32
+	States          interface{}
+	Priority        sql.NullInt16
+	IncidentType    sql.NullString
+	RangerHandle    sql.NullString
+	CreatedAfter    sql.NullFloat64
+	CreatedBefore   sql.NullFloat64
+	ModifiedSince   sql.NullFloat64
+	ModifiedBefore  sql.NullFloat64
+	Text            sql.NullString
+	AfterSortKey    sql.NullFloat64
+	CreatedWeight   float64
+	ModifiedWeight  float64
+	PriorityWeight  int8
+	NumberWeight    int32
+	NumberDirection int32
+	AfterNumber     sql.NullInt32
+	Limit           int32
+}
+
 type IncidentsRow struct {
 	Incident           Incident
 	IncidentTypes      interface{}
@@ -911,8 +2004,58 @@ type IncidentsRow struct {
 	RangerHandles      interface{}
 }
 
-func (q *Queries) Incidents(ctx context.Context, event int32) ([]IncidentsRow, error) {
-	rows, err := q.db.QueryContext(ctx, incidents, event)
+// Each filter is skipped when its parameter is null. Incidents are sorted on
+// a key that's the sum of the weighted CREATED, LAST_MODIFIED, PRIORITY, and NUMBER, with ties
+// broken by NUMBER * number_direction. Setting one weight to 1 or -1 and the
+// others to 0 sorts by that column. A page after the previous one starts with
+// after_sort_key and after_number from the previous page's last incident.
+// No index can serve that computed key, so every page reads and sorts all of the
+// event's incidents that pass the filters. That's fine for the few thousand
+// incidents an event has, but a limit doesn't make a page any cheaper to find.
+func (q *Queries) Incidents(ctx context.Context, arg IncidentsParams) ([]IncidentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, incidents,
+		arg.Event,
+		arg.States,
+		arg.States,
+		arg.Priority,
+		arg.Priority,
+		arg.IncidentType,
+		arg.IncidentType,
+		arg.RangerHandle,
+		arg.RangerHandle,
+		arg.CreatedAfter,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.CreatedBefore,
+		arg.ModifiedSince,
+		arg.ModifiedSince,
+		arg.ModifiedBefore,
+		arg.ModifiedBefore,
+		arg.Text,
+		arg.Text,
+		arg.Text,
+		arg.Text,
+		arg.Text,
+		arg.AfterSortKey,
+		arg.CreatedWeight,
+		arg.ModifiedWeight,
+		arg.PriorityWeight,
+		arg.NumberWeight,
+		arg.AfterSortKey,
+		arg.CreatedWeight,
+		arg.ModifiedWeight,
+		arg.PriorityWeight,
+		arg.NumberWeight,
+		arg.AfterSortKey,
+		arg.NumberDirection,
+		arg.AfterNumber,
+		arg.CreatedWeight,
+		arg.ModifiedWeight,
+		arg.PriorityWeight,
+		arg.NumberWeight,
+		arg.NumberDirection,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -932,6 +2075,8 @@ func (q *Queries) Incidents(ctx context.Context, event int32) ([]IncidentsRow, e
 			&i.Incident.LocationRadialHour,
 			&i.Incident.LocationRadialMinute,
 			&i.Incident.LocationDescription,
+			&i.Incident.Version,
+			&i.Incident.LastModified,
 			&i.IncidentTypes,
 			&i.FieldReportNumbers,
 			&i.RangerHandles,
@@ -949,10 +2094,80 @@ func (q *Queries) Incidents(ctx context.Context, event int32) ([]IncidentsRow, e
 	return items, nil
 }
 
+const incidentsByNumber_ReportEntries = `-- name: IncidentsByNumber_ReportEntries :many
+select
+    ire.INCIDENT_NUMBER,
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
+from
+    INCIDENT__REPORT_ENTRY ire
+        join REPORT_ENTRY re
+             on re.ID = ire.REPORT_ENTRY
+where
+    ire.EVENT = ?
+    and re.GENERATED <= ?
+    and ire.INCIDENT_NUMBER in (/*SLICE:incident_numbers*/?)
+`
+
+type IncidentsByNumber_ReportEntriesParams struct {
+	Event           int32
+	Generated       bool
+	IncidentNumbers []int32
+}
+
+type IncidentsByNumber_ReportEntriesRow struct {
+	IncidentNumber int32
+	ReportEntry    ReportEntry
+}
+
+func (q *Queries) IncidentsByNumber_ReportEntries(ctx context.Context, arg IncidentsByNumber_ReportEntriesParams) ([]IncidentsByNumber_ReportEntriesRow, error) {
+	query := incidentsByNumber_ReportEntries
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Event)
+	queryParams = append(queryParams, arg.Generated)
+	if len(arg.IncidentNumbers) > 0 {
+		for _, v := range arg.IncidentNumbers {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:incident_numbers*/?", strings.Repeat(",?", len(arg.IncidentNumbers))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:incident_numbers*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IncidentsByNumber_ReportEntriesRow
+	for rows.Next() {
+		var i IncidentsByNumber_ReportEntriesRow
+		if err := rows.Scan(
+			&i.IncidentNumber,
+			&i.ReportEntry.ID,
+			&i.ReportEntry.Author,
+			&i.ReportEntry.Text,
+			&i.ReportEntry.Created,
+			&i.ReportEntry.Generated,
+			&i.ReportEntry.Stricken,
+			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const incidents_ReportEntries = `-- name: Incidents_ReportEntries :many
 select
     ire.INCIDENT_NUMBER,
-    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file
+    re.id, re.author, re.text, re.created, re.` + "`" + `generated` + "`" + `, re.stricken, re.attached_file, re.attached_file_name
 from
     INCIDENT__REPORT_ENTRY ire
         join REPORT_ENTRY re
@@ -960,11 +2175,15 @@ from
 where
     ire.EVENT = ?
     and re.GENERATED <= ?
+    and (? is null or ire.INCIDENT_NUMBER >= ?)
+    and (? is null or ire.INCIDENT_NUMBER <= ?)
 `
 
 type Incidents_ReportEntriesParams struct {
-	Event     int32
-	Generated bool
+	Event             int32
+	Generated         bool
+	MinIncidentNumber sql.NullInt32
+	MaxIncidentNumber sql.NullInt32
 }
 
 type Incidents_ReportEntriesRow struct {
@@ -973,7 +2192,14 @@ type Incidents_ReportEntriesRow struct {
 }
 
 func (q *Queries) Incidents_ReportEntries(ctx context.Context, arg Incidents_ReportEntriesParams) ([]Incidents_ReportEntriesRow, error) {
-	rows, err := q.db.QueryContext(ctx, incidents_ReportEntries, arg.Event, arg.Generated)
+	rows, err := q.db.QueryContext(ctx, incidents_ReportEntries,
+		arg.Event,
+		arg.Generated,
+		arg.MinIncidentNumber,
+		arg.MinIncidentNumber,
+		arg.MaxIncidentNumber,
+		arg.MaxIncidentNumber,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -990,6 +2216,7 @@ func (q *Queries) Incidents_ReportEntries(ctx context.Context, arg Incidents_Rep
 			&i.ReportEntry.Generated,
 			&i.ReportEntry.Stricken,
 			&i.ReportEntry.AttachedFile,
+			&i.ReportEntry.AttachedFileName,
 		); err != nil {
 			return nil, err
 		}
@@ -1004,28 +2231,123 @@ func (q *Queries) Incidents_ReportEntries(ctx context.Context, arg Incidents_Rep
 	return items, nil
 }
 
-const maxFieldReportNumber = `-- name: MaxFieldReportNumber :one
-select coalesce(max(NUMBER), 0) from FIELD_REPORT
+const incrementFieldReportNumber = `-- name: IncrementFieldReportNumber :exec
+update EVENT_SEQUENCE
+set LAST_FIELD_REPORT_NUMBER = LAST_FIELD_REPORT_NUMBER + 1
+where EVENT = ?
+`
+
+func (q *Queries) IncrementFieldReportNumber(ctx context.Context, event int32) error {
+	_, err := q.db.ExecContext(ctx, incrementFieldReportNumber, event)
+	return err
+}
+
+const incrementIncidentNumber = `-- name: IncrementIncidentNumber :exec
+update EVENT_SEQUENCE
+set LAST_INCIDENT_NUMBER = LAST_INCIDENT_NUMBER + 1
+where EVENT = ?
+`
+
+func (q *Queries) IncrementIncidentNumber(ctx context.Context, event int32) error {
+	_, err := q.db.ExecContext(ctx, incrementIncidentNumber, event)
+	return err
+}
+
+const lastFieldReportNumber = `-- name: LastFieldReportNumber :one
+select LAST_FIELD_REPORT_NUMBER from EVENT_SEQUENCE
 where EVENT = ?
 `
 
-func (q *Queries) MaxFieldReportNumber(ctx context.Context, event int32) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, maxFieldReportNumber, event)
-	var coalesce interface{}
-	err := row.Scan(&coalesce)
-	return coalesce, err
+func (q *Queries) LastFieldReportNumber(ctx context.Context, event int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, lastFieldReportNumber, event)
+	var last_field_report_number int32
+	err := row.Scan(&last_field_report_number)
+	return last_field_report_number, err
 }
 
-const maxIncidentNumber = `-- name: MaxIncidentNumber :one
-select coalesce(max(NUMBER), 0) from INCIDENT
+const lastIncidentNumber = `-- name: LastIncidentNumber :one
+select LAST_INCIDENT_NUMBER from EVENT_SEQUENCE
 where EVENT = ?
 `
 
-func (q *Queries) MaxIncidentNumber(ctx context.Context, event int32) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, maxIncidentNumber, event)
-	var coalesce interface{}
-	err := row.Scan(&coalesce)
-	return coalesce, err
+func (q *Queries) LastIncidentNumber(ctx context.Context, event int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, lastIncidentNumber, event)
+	var last_incident_number int32
+	err := row.Scan(&last_incident_number)
+	return last_incident_number, err
+}
+
+const lockLoginFailure = `-- name: LockLoginFailure :one
+select lf.id, lf.kind, lf.name, lf.failures, lf.last_failure, lf.blocked_until
+from LOGIN_FAILURE lf
+where lf.KIND = ? and lf.NAME = ?
+for update
+`
+
+type LockLoginFailureParams struct {
+	Kind string
+	Name string
+}
+
+type LockLoginFailureRow struct {
+	LoginFailure LoginFailure
+}
+
+func (q *Queries) LockLoginFailure(ctx context.Context, arg LockLoginFailureParams) (LockLoginFailureRow, error) {
+	row := q.db.QueryRowContext(ctx, lockLoginFailure, arg.Kind, arg.Name)
+	var i LockLoginFailureRow
+	err := row.Scan(
+		&i.LoginFailure.ID,
+		&i.LoginFailure.Kind,
+		&i.LoginFailure.Name,
+		&i.LoginFailure.Failures,
+		&i.LoginFailure.LastFailure,
+		&i.LoginFailure.BlockedUntil,
+	)
+	return i, err
+}
+
+const pruneLoginFailures = `-- name: PruneLoginFailures :exec
+delete from LOGIN_FAILURE
+where LAST_FAILURE < ?
+`
+
+func (q *Queries) PruneLoginFailures(ctx context.Context, lastFailure float64) error {
+	_, err := q.db.ExecContext(ctx, pruneLoginFailures, lastFailure)
+	return err
+}
+
+const pruneRefreshTokens = `-- name: PruneRefreshTokens :execrows
+delete from REFRESH_TOKEN
+where EXPIRES < ?
+`
+
+func (q *Queries) PruneRefreshTokens(ctx context.Context, expires float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, pruneRefreshTokens, expires)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const pruneSSEEvents = `-- name: PruneSSEEvents :exec
+delete from SSE_EVENT
+where ID <= ?
+`
+
+func (q *Queries) PruneSSEEvents(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, pruneSSEEvents, id)
+	return err
+}
+
+const pruneTokenRevocations = `-- name: PruneTokenRevocations :exec
+delete from TOKEN_REVOCATION
+where EXPIRES < ?
+`
+
+func (q *Queries) PruneTokenRevocations(ctx context.Context, expires float64) error {
+	_, err := q.db.ExecContext(ctx, pruneTokenRevocations, expires)
+	return err
 }
 
 const queryEventID = `-- name: QueryEventID :one
@@ -1043,6 +2365,289 @@ func (q *Queries) QueryEventID(ctx context.Context, name string) (QueryEventIDRo
 	return i, err
 }
 
+const redactAuditValues = `-- name: RedactAuditValues :execrows
+update AUDIT set BEFORE_VALUE = null, AFTER_VALUE = null
+where EVENT = ?
+    and ENTITY_TYPE in ('incident', 'field_report', 'report_entry')
+    and (BEFORE_VALUE is not null or AFTER_VALUE is not null)
+`
+
+// The values recorded for changes to an event's incidents, field reports, and
+// report entries hold whatever text was written. The records of the changes
+// themselves are kept, as are the values for everything else, such as who was
+// given access to the event.
+func (q *Queries) RedactAuditValues(ctx context.Context, event sql.NullInt32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, redactAuditValues, event)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const redactFieldReportSummaries = `-- name: RedactFieldReportSummaries :execrows
+update FIELD_REPORT set SUMMARY = ?
+where EVENT = ?
+    and SUMMARY is not null
+    and SUMMARY <> ?
+`
+
+type RedactFieldReportSummariesParams struct {
+	Redacted sql.NullString
+	Event    int32
+}
+
+func (q *Queries) RedactFieldReportSummaries(ctx context.Context, arg RedactFieldReportSummariesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, redactFieldReportSummaries, arg.Redacted, arg.Event, arg.Redacted)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const redactIncidentChanges = `-- name: RedactIncidentChanges :execrows
+update INCIDENT_CHANGE set OLD_VALUE = ?, NEW_VALUE = ?
+where EVENT = ?
+    and FIELD in ('summary', 'location_description')
+    and (OLD_VALUE <> ? or NEW_VALUE <> ?)
+`
+
+type RedactIncidentChangesParams struct {
+	Redacted string
+	Event    int32
+}
+
+// redacted is a JSON value here, as OLD_VALUE and NEW_VALUE are.
+func (q *Queries) RedactIncidentChanges(ctx context.Context, arg RedactIncidentChangesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, redactIncidentChanges,
+		arg.Redacted,
+		arg.Redacted,
+		arg.Event,
+		arg.Redacted,
+		arg.Redacted,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const redactIncidentLocationDescriptions = `-- name: RedactIncidentLocationDescriptions :execrows
+update INCIDENT set LOCATION_DESCRIPTION = ?
+where EVENT = ?
+    and LOCATION_DESCRIPTION is not null
+    and LOCATION_DESCRIPTION <> ?
+`
+
+type RedactIncidentLocationDescriptionsParams struct {
+	Redacted sql.NullString
+	Event    int32
+}
+
+func (q *Queries) RedactIncidentLocationDescriptions(ctx context.Context, arg RedactIncidentLocationDescriptionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, redactIncidentLocationDescriptions, arg.Redacted, arg.Event, arg.Redacted)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const redactIncidentSummaries = `-- name: RedactIncidentSummaries :execrows
+update INCIDENT set SUMMARY = ?
+where EVENT = ?
+    and SUMMARY is not null
+    and SUMMARY <> ?
+`
+
+type RedactIncidentSummariesParams struct {
+	Redacted sql.NullString
+	Event    int32
+}
+
+// The redact queries replace an event's free text with the redacted marker,
+// leaving LAST_MODIFIED alone so that the event doesn't look newer for it.
+func (q *Queries) RedactIncidentSummaries(ctx context.Context, arg RedactIncidentSummariesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, redactIncidentSummaries, arg.Redacted, arg.Event, arg.Redacted)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const refreshToken = `-- name: RefreshToken :one
+select rt.id, rt.token_hash, rt.family, rt.ranger_handle, rt.directory_id, rt.created, rt.expires, rt.used, rt.revoked
+from REFRESH_TOKEN rt
+where rt.TOKEN_HASH = ?
+`
+
+type RefreshTokenRow struct {
+	RefreshToken RefreshToken
+}
+
+func (q *Queries) RefreshToken(ctx context.Context, tokenHash string) (RefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, refreshToken, tokenHash)
+	var i RefreshTokenRow
+	err := row.Scan(
+		&i.RefreshToken.ID,
+		&i.RefreshToken.TokenHash,
+		&i.RefreshToken.Family,
+		&i.RefreshToken.RangerHandle,
+		&i.RefreshToken.DirectoryID,
+		&i.RefreshToken.Created,
+		&i.RefreshToken.Expires,
+		&i.RefreshToken.Used,
+		&i.RefreshToken.Revoked,
+	)
+	return i, err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+update REFRESH_TOKEN
+set REVOKED = ?
+where FAMILY = ?
+    and REVOKED is null
+`
+
+type RevokeRefreshTokenFamilyParams struct {
+	Revoked sql.NullFloat64
+	Family  string
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.Revoked, arg.Family)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeRefreshTokensForHandle = `-- name: RevokeRefreshTokensForHandle :execrows
+update REFRESH_TOKEN
+set REVOKED = ?
+where RANGER_HANDLE = ?
+    and REVOKED is null
+`
+
+type RevokeRefreshTokensForHandleParams struct {
+	Revoked      sql.NullFloat64
+	RangerHandle string
+}
+
+func (q *Queries) RevokeRefreshTokensForHandle(ctx context.Context, arg RevokeRefreshTokensForHandleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokensForHandle, arg.Revoked, arg.RangerHandle)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const sSEEventFieldReportAuthorsAfter = `-- name: SSEEventFieldReportAuthorsAfter :many
+select distinct
+    s.EVENT,
+    s.FIELD_REPORT_NUMBER,
+    re.AUTHOR
+from SSE_EVENT s
+    join FIELD_REPORT__REPORT_ENTRY fre
+        on fre.EVENT = s.EVENT
+        and fre.FIELD_REPORT_NUMBER = s.FIELD_REPORT_NUMBER
+    join REPORT_ENTRY re
+        on re.ID = fre.REPORT_ENTRY
+where s.ID > ?
+`
+
+type SSEEventFieldReportAuthorsAfterRow struct {
+	Event             int32
+	FieldReportNumber sql.NullInt32
+	Author            string
+}
+
+func (q *Queries) SSEEventFieldReportAuthorsAfter(ctx context.Context, id int64) ([]SSEEventFieldReportAuthorsAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, sSEEventFieldReportAuthorsAfter, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SSEEventFieldReportAuthorsAfterRow
+	for rows.Next() {
+		var i SSEEventFieldReportAuthorsAfterRow
+		if err := rows.Scan(&i.Event, &i.FieldReportNumber, &i.Author); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sSEEventIDRange = `-- name: SSEEventIDRange :one
+select
+    cast(coalesce(min(ID), 0) as signed) as MIN_ID,
+    cast(coalesce(max(ID), 0) as signed) as MAX_ID
+from SSE_EVENT
+`
+
+type SSEEventIDRangeRow struct {
+	MinID int64
+	MaxID int64
+}
+
+func (q *Queries) SSEEventIDRange(ctx context.Context) (SSEEventIDRangeRow, error) {
+	row := q.db.QueryRowContext(ctx, sSEEventIDRange)
+	var i SSEEventIDRangeRow
+	err := row.Scan(&i.MinID, &i.MaxID)
+	return i, err
+}
+
+const sSEEventsAfter = `-- name: SSEEventsAfter :many
+select
+    s.id, s.created, s.event, s.incident_number, s.field_report_number,
+    e.NAME as EVENT_NAME
+from SSE_EVENT s
+    join EVENT e
+        on s.EVENT = e.ID
+where s.ID > ?
+order by s.ID
+`
+
+type SSEEventsAfterRow struct {
+	SseEvent  SseEvent
+	EventName string
+}
+
+func (q *Queries) SSEEventsAfter(ctx context.Context, id int64) ([]SSEEventsAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, sSEEventsAfter, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SSEEventsAfterRow
+	for rows.Next() {
+		var i SSEEventsAfterRow
+		if err := rows.Scan(
+			&i.SseEvent.ID,
+			&i.SseEvent.Created,
+			&i.SseEvent.Event,
+			&i.SseEvent.IncidentNumber,
+			&i.SseEvent.FieldReportNumber,
+			&i.EventName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const schemaVersion = `-- name: SchemaVersion :one
 select VERSION from SCHEMA_INFO
 `
@@ -1054,6 +2659,22 @@ func (q *Queries) SchemaVersion(ctx context.Context) (int16, error) {
 	return version, err
 }
 
+const setFieldReportLastModified = `-- name: SetFieldReportLastModified :exec
+update FIELD_REPORT set LAST_MODIFIED = ?
+where EVENT = ? and NUMBER = ?
+`
+
+type SetFieldReportLastModifiedParams struct {
+	LastModified float64
+	Event        int32
+	Number       int32
+}
+
+func (q *Queries) SetFieldReportLastModified(ctx context.Context, arg SetFieldReportLastModifiedParams) error {
+	_, err := q.db.ExecContext(ctx, setFieldReportLastModified, arg.LastModified, arg.Event, arg.Number)
+	return err
+}
+
 const setFieldReportReportEntryStricken = `-- name: SetFieldReportReportEntryStricken :exec
 update REPORT_ENTRY
 set STRICKEN = ?
@@ -1083,6 +2704,22 @@ func (q *Queries) SetFieldReportReportEntryStricken(ctx context.Context, arg Set
 	return err
 }
 
+const setIncidentLastModified = `-- name: SetIncidentLastModified :exec
+update INCIDENT set LAST_MODIFIED = ?
+where EVENT = ? and NUMBER = ?
+`
+
+type SetIncidentLastModifiedParams struct {
+	LastModified float64
+	Event        int32
+	Number       int32
+}
+
+func (q *Queries) SetIncidentLastModified(ctx context.Context, arg SetIncidentLastModifiedParams) error {
+	_, err := q.db.ExecContext(ctx, setIncidentLastModified, arg.LastModified, arg.Event, arg.Number)
+	return err
+}
+
 const setIncidentReportEntryStricken = `-- name: SetIncidentReportEntryStricken :exec
 /*
    The "stricken" queries seem bloated at first blush, because the whole
@@ -1119,30 +2756,97 @@ func (q *Queries) SetIncidentReportEntryStricken(ctx context.Context, arg SetInc
 	return err
 }
 
-const updateFieldReport = `-- name: UpdateFieldReport :exec
-update FIELD_REPORT
-set SUMMARY = ?, INCIDENT_NUMBER = ?
+const setReportEntryText = `-- name: SetReportEntryText :exec
+update REPORT_ENTRY set TEXT = ?, ATTACHED_FILE = ?, ATTACHED_FILE_NAME = ?
+where ID = ?
+`
+
+type SetReportEntryTextParams struct {
+	Text             string
+	AttachedFile     sql.NullString
+	AttachedFileName sql.NullString
+	ID               int32
+}
+
+func (q *Queries) SetReportEntryText(ctx context.Context, arg SetReportEntryTextParams) error {
+	_, err := q.db.ExecContext(ctx, setReportEntryText,
+		arg.Text,
+		arg.AttachedFile,
+		arg.AttachedFileName,
+		arg.ID,
+	)
+	return err
+}
+
+const tokenRevocationCount = `-- name: TokenRevocationCount :one
+select count(*)
+from TOKEN_REVOCATION
+where JTI = ?
+    or (RANGER_HANDLE = ? and CREATED > ?)
+`
+
+type TokenRevocationCountParams struct {
+	Jti          sql.NullString
+	RangerHandle sql.NullString
+	IssuedAt     float64
+}
+
+func (q *Queries) TokenRevocationCount(ctx context.Context, arg TokenRevocationCountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, tokenRevocationCount, arg.Jti, arg.RangerHandle, arg.IssuedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const touchIncident = `-- name: TouchIncident :exec
+update INCIDENT set LAST_MODIFIED = ?, VERSION = VERSION + 1
 where EVENT = ? and NUMBER = ?
 `
 
+type TouchIncidentParams struct {
+	LastModified float64
+	Event        int32
+	Number       int32
+}
+
+// This is for changes to an incident that are made from elsewhere, such as
+// to its list of field reports, so it gets a new version too.
+func (q *Queries) TouchIncident(ctx context.Context, arg TouchIncidentParams) error {
+	_, err := q.db.ExecContext(ctx, touchIncident, arg.LastModified, arg.Event, arg.Number)
+	return err
+}
+
+const updateFieldReport = `-- name: UpdateFieldReport :execrows
+update FIELD_REPORT
+set SUMMARY = ?, INCIDENT_NUMBER = ?, LAST_MODIFIED = ?, VERSION = VERSION + 1
+where EVENT = ? and NUMBER = ? and VERSION = ?
+`
+
 type UpdateFieldReportParams struct {
 	Summary        sql.NullString
 	IncidentNumber sql.NullInt32
+	LastModified   float64
 	Event          int32
 	Number         int32
+	Version        int32
 }
 
-func (q *Queries) UpdateFieldReport(ctx context.Context, arg UpdateFieldReportParams) error {
-	_, err := q.db.ExecContext(ctx, updateFieldReport,
+func (q *Queries) UpdateFieldReport(ctx context.Context, arg UpdateFieldReportParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateFieldReport,
 		arg.Summary,
 		arg.IncidentNumber,
+		arg.LastModified,
 		arg.Event,
 		arg.Number,
+		arg.Version,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const updateIncident = `-- name: UpdateIncident :exec
+const updateIncident = `-- name: UpdateIncident :execrows
 update INCIDENT set
     CREATED = ?,
     PRIORITY = ?,
@@ -1152,10 +2856,13 @@ update INCIDENT set
     LOCATION_CONCENTRIC = ?,
     LOCATION_RADIAL_HOUR = ?,
     LOCATION_RADIAL_MINUTE = ?,
-    LOCATION_DESCRIPTION = ?
+    LOCATION_DESCRIPTION = ?,
+    LAST_MODIFIED = ?,
+    VERSION = VERSION + 1
 where
     EVENT = ?
     and NUMBER = ?
+    and VERSION = ?
 `
 
 type UpdateIncidentParams struct {
@@ -1168,12 +2875,14 @@ type UpdateIncidentParams struct {
 	LocationRadialHour   sql.NullInt16
 	LocationRadialMinute sql.NullInt16
 	LocationDescription  sql.NullString
+	LastModified         float64
 	Event                int32
 	Number               int32
+	Version              int32
 }
 
-func (q *Queries) UpdateIncident(ctx context.Context, arg UpdateIncidentParams) error {
-	_, err := q.db.ExecContext(ctx, updateIncident,
+func (q *Queries) UpdateIncident(ctx context.Context, arg UpdateIncidentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateIncident,
 		arg.Created,
 		arg.Priority,
 		arg.State,
@@ -1183,8 +2892,59 @@ func (q *Queries) UpdateIncident(ctx context.Context, arg UpdateIncidentParams)
 		arg.LocationRadialHour,
 		arg.LocationRadialMinute,
 		arg.LocationDescription,
+		arg.LastModified,
 		arg.Event,
 		arg.Number,
+		arg.Version,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateLoginFailure = `-- name: UpdateLoginFailure :exec
+update LOGIN_FAILURE
+set FAILURES = ?, LAST_FAILURE = ?, BLOCKED_UNTIL = ?
+where KIND = ? and NAME = ?
+`
+
+type UpdateLoginFailureParams struct {
+	Failures     int32
+	LastFailure  float64
+	BlockedUntil float64
+	Kind         string
+	Name         string
+}
+
+func (q *Queries) UpdateLoginFailure(ctx context.Context, arg UpdateLoginFailureParams) error {
+	_, err := q.db.ExecContext(ctx, updateLoginFailure,
+		arg.Failures,
+		arg.LastFailure,
+		arg.BlockedUntil,
+		arg.Kind,
+		arg.Name,
 	)
 	return err
 }
+
+const useRefreshToken = `-- name: UseRefreshToken :execrows
+update REFRESH_TOKEN
+set USED = ?
+where ID = ?
+    and USED is null
+    and REVOKED is null
+`
+
+type UseRefreshTokenParams struct {
+	Used sql.NullFloat64
+	ID   int64
+}
+
+func (q *Queries) UseRefreshToken(ctx context.Context, arg UseRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRefreshToken, arg.Used, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
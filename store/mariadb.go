@@ -1,7 +1,6 @@
 package store
 
 import (
-	"context"
 	"database/sql"
 	_ "embed"
 	"fmt"
@@ -9,8 +8,6 @@ import (
 	"github.com/srabraham/ranger-ims-go/conf"
 	"log/slog"
 	"os"
-	"strings"
-	"time"
 )
 
 //go:embed schema.sql
@@ -44,41 +41,3 @@ func MariaDB(imsCfg *conf.IMSConfig) *sql.DB {
 	slog.Info("Connected to IMS MariaDB")
 	return db
 }
-
-type DB struct {
-	*sql.DB
-}
-
-func (l DB) ExecContext(ctx context.Context, s string, i ...interface{}) (sql.Result, error) {
-	start := time.Now()
-	execContext, err := l.DB.ExecContext(ctx, s, i...)
-	logQuery(s, start, err)
-	return execContext, err
-}
-
-func (l DB) PrepareContext(ctx context.Context, s string) (*sql.Stmt, error) {
-	start := time.Now()
-	stmt, err := l.DB.PrepareContext(ctx, s)
-	logQuery(s, start, err)
-	return stmt, err
-}
-
-func (l DB) QueryContext(ctx context.Context, s string, i ...interface{}) (*sql.Rows, error) {
-	start := time.Now()
-	rows, err := l.DB.QueryContext(ctx, s, i...)
-	logQuery(s, start, err)
-	return rows, err
-}
-
-func (l DB) QueryRowContext(ctx context.Context, s string, i ...interface{}) *sql.Row {
-	start := time.Now()
-	row := l.DB.QueryRowContext(ctx, s, i...)
-	logQuery(s, start, nil)
-	return row
-}
-
-func logQuery(s string, start time.Time, err error) {
-	queryName, _, _ := strings.Cut(s, "\n")
-	queryName = strings.TrimPrefix(queryName, "-- name: ")
-	slog.Debug("Done", "query", queryName, "ms", time.Since(start).Milliseconds(), "err", err)
-}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/store/imsdb"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Migrations are numbered by the schema version they produce, e.g. "014_sse_event.sql"
+// takes a database from version 13 to version 14. Every migration must also be
+// reflected in the corresponding full schema file (schema.sql or sqlite/schema.sql),
+// which is what a brand-new database gets instead.
+
+//go:embed migrations/*.sql
+var mariaDBMigrations embed.FS
+
+//go:embed sqlite/migrations/*.sql
+var sqliteMigrations embed.FS
+
+type Migration struct {
+	// Version is the schema version that the database will have after this migration.
+	Version int16
+	Name    string
+	Script  string
+}
+
+// ExpectedSchemaVersion is the schema version that this build of IMS works with.
+func ExpectedSchemaVersion() int16 {
+	migrations, err := readMigrations(mariaDBMigrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the current schema version of the database,
+// or 0 if the database has no IMS schema at all.
+func SchemaVersion(ctx context.Context, db *DB) (int16, error) {
+	var tables int
+	tablesQuery := "select count(*) from information_schema.TABLES " +
+		"where TABLE_SCHEMA = database() and TABLE_NAME = 'SCHEMA_INFO'"
+	if db.isSQLite() {
+		tablesQuery = "select count(*) from sqlite_master where type = 'table' and name = 'SCHEMA_INFO'"
+	}
+	if err := db.QueryRowContext(ctx, tablesQuery).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("[QueryRowContext]: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	version, err := imsdb.New(db).SchemaVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("[SchemaVersion]: %w", err)
+	}
+	return version, nil
+}
+
+// PendingMigrations returns the migrations that would bring the database up to
+// ExpectedSchemaVersion, in the order they need to be applied. A database with
+// no IMS schema gets the full current schema as a single migration.
+func PendingMigrations(ctx context.Context, db *DB) ([]Migration, error) {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("[SchemaVersion]: %w", err)
+	}
+	expected := ExpectedSchemaVersion()
+	if current > expected {
+		return nil, fmt.Errorf("database schema version %v is newer than this IMS build's version %v", current, expected)
+	}
+	if current == 0 {
+		schema := CurrentSchema
+		if db.isSQLite() {
+			schema = CurrentSQLiteSchema
+		}
+		return []Migration{{Version: expected, Name: "initial schema", Script: schema}}, nil
+	}
+	all, err := dialectMigrations(db)
+	if err != nil {
+		return nil, fmt.Errorf("[dialectMigrations]: %w", err)
+	}
+	var pending []Migration
+	for _, m := range all {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) > 0 && pending[0].Version != current+1 {
+		return nil, fmt.Errorf("no migration is available from schema version %v", current)
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration, bumping SCHEMA_INFO as it goes.
+// It stops at the first failure, leaving the database at the last successful version.
+func Migrate(ctx context.Context, db *DB) error {
+	pending, err := PendingMigrations(ctx, db)
+	if err != nil {
+		return fmt.Errorf("[PendingMigrations]: %w", err)
+	}
+	for _, m := range pending {
+		if err = applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("[applyMigration] version %v (%v): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckSchemaVersion returns an error unless the database is at ExpectedSchemaVersion.
+func CheckSchemaVersion(ctx context.Context, db *DB) error {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("[SchemaVersion]: %w", err)
+	}
+	if expected := ExpectedSchemaVersion(); current != expected {
+		return fmt.Errorf("database schema version is %v, but this IMS build requires version %v. "+
+			"Run the \"migrate up\" command to upgrade the database", current, expected)
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *DB, m Migration) error {
+	script := strings.TrimSpace(m.Script)
+	if !strings.HasSuffix(script, ";") {
+		script += ";"
+	}
+	script += fmt.Sprintf("\nupdate SCHEMA_INFO set VERSION = %d;", m.Version)
+	if db.isSQLite() {
+		// SQLite DDL is transactional, so a failed migration leaves no trace
+		txn, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("[BeginTx]: %w", err)
+		}
+		defer txn.Rollback()
+		if _, err = txn.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("[ExecContext]: %w", err)
+		}
+		if err = txn.Commit(); err != nil {
+			return fmt.Errorf("[Commit]: %w", err)
+		}
+		return nil
+	}
+	// MariaDB can't roll back DDL, but this at least runs the whole script as one statement
+	if _, err := db.ExecContext(ctx, "BEGIN NOT ATOMIC\n"+script+"\nEND"); err != nil {
+		return fmt.Errorf("[ExecContext]: %w", err)
+	}
+	return nil
+}
+
+func dialectMigrations(db *DB) ([]Migration, error) {
+	if db.isSQLite() {
+		return readMigrations(sqliteMigrations, "sqlite/migrations")
+	}
+	return readMigrations(mariaDBMigrations, "migrations")
+}
+
+func readMigrations(fsys embed.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("[ReadDir]: %w", err)
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration file name %v must look like 014_description.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("[ParseInt] %v: %w", entry.Name(), err)
+		}
+		script, err := fsys.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("[ReadFile]: %w", err)
+		}
+		migrations = append(migrations, Migration{
+			Version: int16(version),
+			Name:    strings.ReplaceAll(name, "_", " "),
+			Script:  string(script),
+		})
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return int(a.Version) - int(b.Version) })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version != migrations[i-1].Version+1 {
+			return nil, fmt.Errorf("migrations in %v skip from version %v to %v",
+				dir, migrations[i-1].Version, migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
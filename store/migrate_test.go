@@ -0,0 +1,133 @@
+package store
+
+import (
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestMigrationSetsMatch(t *testing.T) {
+	mariaDB, err := readMigrations(mariaDBMigrations, "migrations")
+	require.NoError(t, err)
+	sqlite, err := readMigrations(sqliteMigrations, "sqlite/migrations")
+	require.NoError(t, err)
+	require.Len(t, sqlite, len(mariaDB))
+	for i := range mariaDB {
+		require.Equal(t, mariaDB[i].Version, sqlite[i].Version)
+	}
+
+	// The full schema files must be at the version of the latest migration
+	schemaVersion := regexp.MustCompile(`insert into SCHEMA_INFO \(VERSION\) values \((\d+)\)`)
+	expected := strconv.Itoa(int(ExpectedSchemaVersion()))
+	for _, schema := range []string{CurrentSchema, CurrentSQLiteSchema} {
+		match := schemaVersion.FindStringSubmatch(schema)
+		require.NotNil(t, match)
+		require.Equal(t, expected, match[1])
+	}
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	ctx := t.Context()
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := SchemaVersion(ctx, db)
+	require.NoError(t, err)
+	require.Zero(t, version)
+	require.Error(t, CheckSchemaVersion(ctx, db))
+
+	pending, err := PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, ExpectedSchemaVersion(), pending[0].Version)
+
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, CheckSchemaVersion(ctx, db))
+	pending, err = PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// Migrating again is a no-op
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, CheckSchemaVersion(ctx, db))
+}
+
+func TestMigrateExistingDatabase(t *testing.T) {
+	ctx := t.Context()
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(ctx, db))
+
+	// Turn this back into a version 13 database
+	_, err = db.ExecContext(ctx, `
+		alter table REPORT_ENTRY drop column ATTACHED_FILE_NAME;
+		drop index LOGIN_FAILURE_LAST_FAILURE_index;
+		drop table LOGIN_FAILURE;
+		drop index TOKEN_REVOCATION_JTI_index;
+		drop index TOKEN_REVOCATION_RANGER_HANDLE_index;
+		drop index TOKEN_REVOCATION_EXPIRES_index;
+		drop table TOKEN_REVOCATION;
+		drop index REFRESH_TOKEN_FAMILY_index;
+		drop index REFRESH_TOKEN_RANGER_HANDLE_index;
+		drop index REFRESH_TOKEN_EXPIRES_index;
+		drop table REFRESH_TOKEN;
+		drop index INCIDENT_CHANGE_EVENT_INCIDENT_NUMBER_index;
+		drop table INCIDENT_CHANGE;
+		drop index AUDIT_CREATED_index;
+		drop index AUDIT_ENTITY_index;
+		drop index AUDIT_ACTOR_index;
+		drop table AUDIT;
+		drop trigger INCIDENT_FTS_insert;
+		drop trigger INCIDENT_FTS_update;
+		drop trigger INCIDENT_FTS_delete;
+		drop trigger FIELD_REPORT_FTS_insert;
+		drop trigger FIELD_REPORT_FTS_update;
+		drop trigger FIELD_REPORT_FTS_delete;
+		drop trigger REPORT_ENTRY_FTS_insert;
+		drop trigger REPORT_ENTRY_FTS_update;
+		drop trigger REPORT_ENTRY_FTS_delete;
+		drop table INCIDENT_FTS;
+		drop table FIELD_REPORT_FTS;
+		drop table REPORT_ENTRY_FTS;
+		drop index INCIDENT_EVENT_LAST_MODIFIED_index;
+		drop index FIELD_REPORT_EVENT_LAST_MODIFIED_index;
+		alter table INCIDENT drop column LAST_MODIFIED;
+		alter table FIELD_REPORT drop column LAST_MODIFIED;
+		drop table EVENT_SEQUENCE;
+		drop table SSE_EVENT;
+		alter table INCIDENT drop column VERSION;
+		alter table FIELD_REPORT drop column VERSION;
+		update SCHEMA_INFO set VERSION = 13;
+	`)
+	require.NoError(t, err)
+	require.Error(t, CheckSchemaVersion(ctx, db))
+
+	pending, err := PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+	require.Equal(t, int16(14), pending[0].Version)
+
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, CheckSchemaVersion(ctx, db))
+	_, err = db.ExecContext(ctx, "select count(*) from SSE_EVENT")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "select VERSION from INCIDENT")
+	require.NoError(t, err)
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	ctx := t.Context()
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(ctx, db))
+
+	_, err = db.ExecContext(ctx, "update SCHEMA_INFO set VERSION = VERSION + 1")
+	require.NoError(t, err)
+	require.Error(t, CheckSchemaVersion(ctx, db))
+	require.Error(t, Migrate(ctx, db))
+}
@@ -0,0 +1,11 @@
+create table SSE_EVENT (
+    ID                  bigint  not null auto_increment,
+    CREATED             double  not null,
+    EVENT               integer not null,
+    INCIDENT_NUMBER     integer,
+    FIELD_REPORT_NUMBER integer,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
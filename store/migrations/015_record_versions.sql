@@ -0,0 +1,2 @@
+alter table INCIDENT add column VERSION integer not null default 1;
+alter table FIELD_REPORT add column VERSION integer not null default 1;
@@ -0,0 +1,9 @@
+create table EVENT_SEQUENCE (
+    EVENT                    integer not null,
+    LAST_INCIDENT_NUMBER     integer not null,
+    LAST_FIELD_REPORT_NUMBER integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    primary key (EVENT)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+create fulltext index INCIDENT_SUMMARY_LOCATION_fulltext
+    on INCIDENT (SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION);
+create fulltext index FIELD_REPORT_SUMMARY_fulltext
+    on FIELD_REPORT (SUMMARY);
+create fulltext index REPORT_ENTRY_TEXT_fulltext
+    on REPORT_ENTRY (TEXT);
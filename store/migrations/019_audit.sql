@@ -0,0 +1,21 @@
+create table AUDIT (
+    ID           bigint       not null auto_increment,
+    CREATED      double       not null,
+    ACTOR        varchar(128) not null,
+    REQUEST_ID   varchar(64)  not null,
+    ACTION       varchar(64)  not null,
+    ENTITY_TYPE  varchar(64)  not null,
+    EVENT        integer,
+    ENTITY_ID    varchar(128) not null,
+    BEFORE_VALUE mediumtext,
+    AFTER_VALUE  mediumtext,
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index AUDIT_CREATED_index
+    on AUDIT (CREATED);
+create index AUDIT_ENTITY_index
+    on AUDIT (ENTITY_TYPE, ENTITY_ID);
+create index AUDIT_ACTOR_index
+    on AUDIT (ACTOR);
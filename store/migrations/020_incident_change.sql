@@ -0,0 +1,18 @@
+create table INCIDENT_CHANGE (
+    ID              bigint      not null auto_increment,
+    EVENT           integer     not null,
+    INCIDENT_NUMBER integer     not null,
+    CREATED         double      not null,
+    AUTHOR          varchar(64) not null,
+    FIELD           varchar(64) not null,
+    OLD_VALUE       text        not null,
+    NEW_VALUE       text        not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index INCIDENT_CHANGE_EVENT_INCIDENT_NUMBER_index
+    on INCIDENT_CHANGE (EVENT, INCIDENT_NUMBER);
@@ -0,0 +1,21 @@
+create table REFRESH_TOKEN (
+    ID            bigint      not null auto_increment,
+    TOKEN_HASH    char(64)    not null,
+    FAMILY        varchar(64) not null,
+    RANGER_HANDLE varchar(64) not null,
+    DIRECTORY_ID  bigint      not null,
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+    USED          double,
+    REVOKED       double,
+
+    primary key (ID),
+    unique key (TOKEN_HASH)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index REFRESH_TOKEN_FAMILY_index
+    on REFRESH_TOKEN (FAMILY);
+create index REFRESH_TOKEN_RANGER_HANDLE_index
+    on REFRESH_TOKEN (RANGER_HANDLE);
+create index REFRESH_TOKEN_EXPIRES_index
+    on REFRESH_TOKEN (EXPIRES);
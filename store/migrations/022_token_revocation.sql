@@ -0,0 +1,16 @@
+create table TOKEN_REVOCATION (
+    ID            bigint      not null auto_increment,
+    JTI           varchar(64),
+    RANGER_HANDLE varchar(64),
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index TOKEN_REVOCATION_JTI_index
+    on TOKEN_REVOCATION (JTI);
+create index TOKEN_REVOCATION_RANGER_HANDLE_index
+    on TOKEN_REVOCATION (RANGER_HANDLE);
+create index TOKEN_REVOCATION_EXPIRES_index
+    on TOKEN_REVOCATION (EXPIRES);
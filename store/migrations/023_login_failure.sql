@@ -0,0 +1,14 @@
+create table LOGIN_FAILURE (
+    ID            bigint      not null auto_increment,
+    KIND          varchar(16) not null,
+    NAME          varchar(64) not null,
+    FAILURES      integer     not null,
+    LAST_FAILURE  double      not null,
+    BLOCKED_UNTIL double      not null,
+
+    primary key (ID),
+    unique key (KIND, NAME)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index LOGIN_FAILURE_LAST_FAILURE_index
+    on LOGIN_FAILURE (LAST_FAILURE);
@@ -38,13 +38,14 @@ insert into INCIDENT (
     NUMBER,
     CREATED,
     PRIORITY,
-    STATE
+    STATE,
+    LAST_MODIFIED
 )
 values (
-   ?,?,?,?,?
+   ?,?,?,?,?,?
 );
 
--- name: UpdateIncident :exec
+-- name: UpdateIncident :execrows
 update INCIDENT set
     CREATED = ?,
     PRIORITY = ?,
@@ -54,12 +55,25 @@ update INCIDENT set
     LOCATION_CONCENTRIC = ?,
     LOCATION_RADIAL_HOUR = ?,
     LOCATION_RADIAL_MINUTE = ?,
-    LOCATION_DESCRIPTION = ?
+    LOCATION_DESCRIPTION = ?,
+    LAST_MODIFIED = ?,
+    VERSION = VERSION + 1
 where
     EVENT = ?
     and NUMBER = ?
+    and VERSION = ?
 ;
 
+-- name: SetIncidentLastModified :exec
+update INCIDENT set LAST_MODIFIED = ?
+where EVENT = ? and NUMBER = ?;
+
+-- name: TouchIncident :exec
+-- This is for changes to an incident that are made from elsewhere, such as
+-- to its list of field reports, so it gets a new version too.
+update INCIDENT set LAST_MODIFIED = ?, VERSION = VERSION + 1
+where EVENT = ? and NUMBER = ?;
+
 -- name: Incident :one
 select
     sqlc.embed(i),
@@ -88,6 +102,14 @@ where i.EVENT = ?
     and i.NUMBER = ?;
 
 -- name: Incidents :many
+-- Each filter is skipped when its parameter is null. Incidents are sorted on
+-- a key that's the sum of the weighted CREATED, LAST_MODIFIED, PRIORITY, and NUMBER, with ties
+-- broken by NUMBER * number_direction. Setting one weight to 1 or -1 and the
+-- others to 0 sorts by that column. A page after the previous one starts with
+-- after_sort_key and after_number from the previous page's last incident.
+-- No index can serve that computed key, so every page reads and sorts all of the
+-- event's incidents that pass the filters. That's fine for the few thousand
+-- incidents an event has, but a limit doesn't make a page any cheaper to find.
 select
     sqlc.embed(i),
     (
@@ -113,9 +135,64 @@ select
 from
     INCIDENT i
 where
-    i.EVENT = ?
+    i.EVENT = sqlc.arg(event)
+    and (sqlc.narg(states) is null
+        or instr(concat(',', sqlc.narg(states), ','), concat(',', i.STATE, ',')) > 0)
+    and (sqlc.narg(priority) is null or i.PRIORITY = sqlc.narg(priority))
+    and (sqlc.narg(incident_type) is null or exists (
+        select 1
+        from INCIDENT__INCIDENT_TYPE iit
+        join INCIDENT_TYPE it
+            on iit.INCIDENT_TYPE = it.ID
+        where i.EVENT = iit.EVENT
+            and i.NUMBER = iit.INCIDENT_NUMBER
+            and it.NAME = sqlc.narg(incident_type)
+    ))
+    and (sqlc.narg(ranger_handle) is null or exists (
+        select 1
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+            and i.NUMBER = ir.INCIDENT_NUMBER
+            and ir.RANGER_HANDLE = sqlc.narg(ranger_handle)
+    ))
+    and (sqlc.narg(created_after) is null or i.CREATED >= sqlc.narg(created_after))
+    and (sqlc.narg(created_before) is null or i.CREATED < sqlc.narg(created_before))
+    and (sqlc.narg(modified_since) is null or i.LAST_MODIFIED >= sqlc.narg(modified_since))
+    and (sqlc.narg(modified_before) is null or i.LAST_MODIFIED < sqlc.narg(modified_before))
+    and (sqlc.narg(text) is null
+        or i.SUMMARY like sqlc.narg(text) escape '!'
+        or i.LOCATION_NAME like sqlc.narg(text) escape '!'
+        or i.LOCATION_DESCRIPTION like sqlc.narg(text) escape '!'
+        or exists (
+            select 1
+            from INCIDENT__REPORT_ENTRY ire
+            join REPORT_ENTRY re
+                on re.ID = ire.REPORT_ENTRY
+            where i.EVENT = ire.EVENT
+                and i.NUMBER = ire.INCIDENT_NUMBER
+                and not re.STRICKEN
+                and re.TEXT like sqlc.narg(text) escape '!'
+        ))
+    and (sqlc.narg(after_sort_key) is null
+        or i.CREATED * sqlc.arg(created_weight)
+            + i.LAST_MODIFIED * sqlc.arg(modified_weight)
+            + i.PRIORITY * sqlc.arg(priority_weight)
+            + i.NUMBER * sqlc.arg(number_weight) > sqlc.narg(after_sort_key)
+        or (i.CREATED * sqlc.arg(created_weight)
+                + i.LAST_MODIFIED * sqlc.arg(modified_weight)
+                + i.PRIORITY * sqlc.arg(priority_weight)
+                + i.NUMBER * sqlc.arg(number_weight) = sqlc.narg(after_sort_key)
+            and i.NUMBER * sqlc.arg(number_direction) > sqlc.narg(after_number)))
 group by
-    i.NUMBER;
+    i.NUMBER
+order by
+    i.CREATED * sqlc.arg(created_weight)
+        + i.LAST_MODIFIED * sqlc.arg(modified_weight)
+        + i.PRIORITY * sqlc.arg(priority_weight)
+        + i.NUMBER * sqlc.arg(number_weight),
+    i.NUMBER * sqlc.arg(number_direction)
+limit ?
+;
 
 -- name: Incidents_ReportEntries :many
 select
@@ -126,8 +203,24 @@ from
         join REPORT_ENTRY re
              on re.ID = ire.REPORT_ENTRY
 where
-    ire.EVENT = ?
+    ire.EVENT = sqlc.arg(event)
     and re.GENERATED <= ?
+    and (sqlc.narg(min_incident_number) is null or ire.INCIDENT_NUMBER >= sqlc.narg(min_incident_number))
+    and (sqlc.narg(max_incident_number) is null or ire.INCIDENT_NUMBER <= sqlc.narg(max_incident_number))
+;
+
+-- name: IncidentsByNumber_ReportEntries :many
+select
+    ire.INCIDENT_NUMBER,
+    sqlc.embed(re)
+from
+    INCIDENT__REPORT_ENTRY ire
+        join REPORT_ENTRY re
+             on re.ID = ire.REPORT_ENTRY
+where
+    ire.EVENT = sqlc.arg(event)
+    and re.GENERATED <= ?
+    and ire.INCIDENT_NUMBER in (sqlc.slice(incident_numbers))
 ;
 
 -- name: Incident_ReportEntries :many
@@ -153,9 +246,15 @@ select sqlc.embed(it)
 from INCIDENT_TYPE it;
 
 -- name: FieldReports :many
+-- A page after the previous one starts with after_number, the number of the
+-- previous page's last field report.
 select sqlc.embed(fr)
 from FIELD_REPORT fr
-where fr.EVENT = ?;
+where fr.EVENT = sqlc.arg(event)
+    and (sqlc.narg(modified_since) is null or fr.LAST_MODIFIED >= sqlc.narg(modified_since))
+    and (sqlc.narg(after_number) is null or fr.NUMBER > sqlc.narg(after_number))
+order by fr.NUMBER
+limit ?;
 
 -- name: FieldReport :one
 select sqlc.embed(fr)
@@ -172,8 +271,30 @@ from
         join REPORT_ENTRY re
              on irre.REPORT_ENTRY = re.ID
 where
-    irre.EVENT = ?
+    irre.EVENT = sqlc.arg(event)
+    and re.GENERATED <= ?
+    and (sqlc.narg(min_field_report_number) is null or irre.FIELD_REPORT_NUMBER >= sqlc.narg(min_field_report_number))
+    and (sqlc.narg(max_field_report_number) is null or irre.FIELD_REPORT_NUMBER <= sqlc.narg(max_field_report_number))
+;
+
+-- name: FieldReportsByNumber :many
+select sqlc.embed(fr)
+from FIELD_REPORT fr
+where fr.EVENT = sqlc.arg(event)
+    and fr.NUMBER in (sqlc.slice(numbers));
+
+-- name: FieldReportsByNumber_ReportEntries :many
+select
+    irre.FIELD_REPORT_NUMBER,
+    sqlc.embed(re)
+from
+    FIELD_REPORT__REPORT_ENTRY irre
+        join REPORT_ENTRY re
+             on irre.REPORT_ENTRY = re.ID
+where
+    irre.EVENT = sqlc.arg(event)
     and re.GENERATED <= ?
+    and irre.FIELD_REPORT_NUMBER in (sqlc.slice(field_report_numbers))
 ;
 
 -- name: FieldReport_ReportEntries :many
@@ -188,25 +309,49 @@ where
     and irre.FIELD_REPORT_NUMBER = ?
 ;
 
--- name: AttachFieldReportToIncident :exec
+-- name: AttachFieldReportToIncident :execrows
+-- The update is skipped if expected_version is set and doesn't match.
 update FIELD_REPORT
-set INCIDENT_NUMBER = ?
-where EVENT = ? and NUMBER = ?
+set INCIDENT_NUMBER = ?, LAST_MODIFIED = ?, VERSION = VERSION + 1
+where EVENT = sqlc.arg(event)
+    and NUMBER = sqlc.arg(number)
+    and (sqlc.narg(expected_version) is null or VERSION = sqlc.narg(expected_version))
 ;
 
--- name: MaxFieldReportNumber :one
-select coalesce(max(NUMBER), 0) from FIELD_REPORT
+-- name: CreateEventSequenceOrIgnore :exec
+insert into EVENT_SEQUENCE (EVENT, LAST_INCIDENT_NUMBER, LAST_FIELD_REPORT_NUMBER)
+select
+    e.ID,
+    (select coalesce(max(i.NUMBER), 0) from INCIDENT i where i.EVENT = e.ID),
+    (select coalesce(max(fr.NUMBER), 0) from FIELD_REPORT fr where fr.EVENT = e.ID)
+from EVENT e
+where e.ID = ?
+on duplicate key update EVENT = EVENT_SEQUENCE.EVENT
+;
+
+-- name: IncrementIncidentNumber :exec
+update EVENT_SEQUENCE
+set LAST_INCIDENT_NUMBER = LAST_INCIDENT_NUMBER + 1
+where EVENT = ?;
+
+-- name: LastIncidentNumber :one
+select LAST_INCIDENT_NUMBER from EVENT_SEQUENCE
+where EVENT = ?;
+
+-- name: IncrementFieldReportNumber :exec
+update EVENT_SEQUENCE
+set LAST_FIELD_REPORT_NUMBER = LAST_FIELD_REPORT_NUMBER + 1
 where EVENT = ?;
 
--- name: MaxIncidentNumber :one
-select coalesce(max(NUMBER), 0) from INCIDENT
+-- name: LastFieldReportNumber :one
+select LAST_FIELD_REPORT_NUMBER from EVENT_SEQUENCE
 where EVENT = ?;
 
 -- name: CreateFieldReport :exec
 insert into FIELD_REPORT (
-    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER
+    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER, LAST_MODIFIED
 )
-values (?, ?, ?, ?, ?);
+values (?, ?, ?, ?, ?, ?);
 
 -- name: DetachedFieldReportNumbers :many
 select NUMBER from FIELD_REPORT
@@ -218,16 +363,20 @@ where
     EVENT = ? and
     INCIDENT_NUMBER = ?;
 
--- name: UpdateFieldReport :exec
+-- name: UpdateFieldReport :execrows
 update FIELD_REPORT
-set SUMMARY = ?, INCIDENT_NUMBER = ?
+set SUMMARY = ?, INCIDENT_NUMBER = ?, LAST_MODIFIED = ?, VERSION = VERSION + 1
+where EVENT = ? and NUMBER = ? and VERSION = ?;
+
+-- name: SetFieldReportLastModified :exec
+update FIELD_REPORT set LAST_MODIFIED = ?
 where EVENT = ? and NUMBER = ?;
 
 -- name: CreateReportEntry :execlastid
 insert into REPORT_ENTRY (
-    AUTHOR, TEXT, CREATED, `GENERATED`, STRICKEN, ATTACHED_FILE
+    AUTHOR, TEXT, CREATED, `GENERATED`, STRICKEN, ATTACHED_FILE, ATTACHED_FILE_NAME
 ) values (
-   ?, ?, ?, ?, ?, ?
+   ?, ?, ?, ?, ?, ?, ?
 );
 
 -- name: AttachReportEntryToFieldReport :exec
@@ -313,3 +462,300 @@ where NAME = ?;
 -- name: CreateConcentricStreet :exec
 insert into CONCENTRIC_STREET (EVENT, ID, NAME)
 values (?, ?, ?);
+
+-- name: CreateSSEEvent :execlastid
+insert into SSE_EVENT (CREATED, EVENT, INCIDENT_NUMBER, FIELD_REPORT_NUMBER)
+values (?, ?, ?, ?);
+
+-- name: SSEEventsAfter :many
+select
+    sqlc.embed(s),
+    e.NAME as EVENT_NAME
+from SSE_EVENT s
+    join EVENT e
+        on s.EVENT = e.ID
+where s.ID > ?
+order by s.ID;
+
+-- name: SSEEventFieldReportAuthorsAfter :many
+select distinct
+    s.EVENT,
+    s.FIELD_REPORT_NUMBER,
+    re.AUTHOR
+from SSE_EVENT s
+    join FIELD_REPORT__REPORT_ENTRY fre
+        on fre.EVENT = s.EVENT
+        and fre.FIELD_REPORT_NUMBER = s.FIELD_REPORT_NUMBER
+    join REPORT_ENTRY re
+        on re.ID = fre.REPORT_ENTRY
+where s.ID > ?;
+
+-- name: SSEEventIDRange :one
+select
+    cast(coalesce(min(ID), 0) as signed) as MIN_ID,
+    cast(coalesce(max(ID), 0) as signed) as MAX_ID
+from SSE_EVENT;
+
+-- name: PruneSSEEvents :exec
+delete from SSE_EVENT
+where ID <= ?;
+
+-- name: CreateAudit :exec
+insert into AUDIT (
+    CREATED, ACTOR, REQUEST_ID, ACTION, ENTITY_TYPE, EVENT, ENTITY_ID, BEFORE_VALUE, AFTER_VALUE
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: AuditEntries :many
+select sqlc.embed(a)
+from AUDIT a
+where (sqlc.narg(actor) is null or a.ACTOR = sqlc.narg(actor))
+    and (sqlc.narg(entity_type) is null or a.ENTITY_TYPE = sqlc.narg(entity_type))
+    and (sqlc.narg(entity_id) is null or a.ENTITY_ID = sqlc.narg(entity_id))
+    and (sqlc.narg(event) is null or a.EVENT = sqlc.narg(event))
+    and (sqlc.narg(request_id) is null or a.REQUEST_ID = sqlc.narg(request_id))
+    and (sqlc.narg(created_after) is null or a.CREATED >= sqlc.narg(created_after))
+    and (sqlc.narg(created_before) is null or a.CREATED < sqlc.narg(created_before))
+    and (sqlc.narg(before_id) is null or a.ID < sqlc.narg(before_id))
+order by a.ID desc
+limit ?;
+
+-- name: CreateIncidentChange :exec
+insert into INCIDENT_CHANGE (
+    EVENT, INCIDENT_NUMBER, CREATED, AUTHOR, FIELD, OLD_VALUE, NEW_VALUE
+)
+values (?, ?, ?, ?, ?, ?, ?);
+
+-- name: IncidentChanges :many
+select sqlc.embed(ic)
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+    and ic.INCIDENT_NUMBER = ?
+order by ic.ID;
+
+-- name: IncidentStateChanges :many
+select
+    ic.INCIDENT_NUMBER,
+    ic.CREATED,
+    ic.NEW_VALUE
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+    and ic.FIELD = 'state'
+order by ic.ID;
+
+-- name: IncidentStateReportEntries :many
+-- These are the system report entries that may record changes of state. They're
+-- all there is for changes made before INCIDENT_CHANGE existed.
+select
+    ire.INCIDENT_NUMBER,
+    re.CREATED,
+    re.TEXT
+from INCIDENT__REPORT_ENTRY ire
+    join REPORT_ENTRY re
+        on re.ID = ire.REPORT_ENTRY
+where ire.EVENT = ?
+    and re.GENERATED
+    and re.TEXT like '%Changed state: %'
+order by re.CREATED, re.ID;
+
+-- name: ArchiveIncidents :many
+select sqlc.embed(i)
+from INCIDENT i
+where i.EVENT = ?
+order by i.NUMBER;
+
+-- name: ArchiveIncidentRangers :many
+select ir.INCIDENT_NUMBER, ir.RANGER_HANDLE
+from INCIDENT__RANGER ir
+where ir.EVENT = ?
+order by ir.ID;
+
+-- name: ArchiveIncidentTypes :many
+select iit.INCIDENT_NUMBER, sqlc.embed(it)
+from INCIDENT__INCIDENT_TYPE iit
+    join INCIDENT_TYPE it
+        on it.ID = iit.INCIDENT_TYPE
+where iit.EVENT = ?
+order by iit.INCIDENT_NUMBER, it.NAME;
+
+-- name: ArchiveIncidentChanges :many
+select sqlc.embed(ic)
+from INCIDENT_CHANGE ic
+where ic.EVENT = ?
+order by ic.ID;
+
+-- name: ImportIncident :exec
+-- Unlike CreateIncident, this sets every column, so that an archived incident
+-- comes back exactly as it was.
+insert into INCIDENT (
+    EVENT, NUMBER, CREATED, PRIORITY, STATE, SUMMARY,
+    LOCATION_NAME, LOCATION_CONCENTRIC, LOCATION_RADIAL_HOUR, LOCATION_RADIAL_MINUTE, LOCATION_DESCRIPTION,
+    VERSION, LAST_MODIFIED
+)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ImportFieldReport :exec
+insert into FIELD_REPORT (
+    EVENT, NUMBER, CREATED, SUMMARY, INCIDENT_NUMBER, VERSION, LAST_MODIFIED
+)
+values (?, ?, ?, ?, ?, ?, ?);
+
+-- name: EventsLastActivity :many
+-- An event's last activity is the latest creation or change of any of its
+-- incidents or field reports. It's 0 for an event with neither.
+select
+    sqlc.embed(e),
+    cast((select coalesce(max(i.LAST_MODIFIED), 0) from INCIDENT i where i.EVENT = e.ID) as double) as INCIDENTS_LAST_MODIFIED,
+    cast((select coalesce(max(i.CREATED), 0) from INCIDENT i where i.EVENT = e.ID) as double) as INCIDENTS_LAST_CREATED,
+    cast((select coalesce(max(fr.LAST_MODIFIED), 0) from FIELD_REPORT fr where fr.EVENT = e.ID) as double) as FIELD_REPORTS_LAST_MODIFIED,
+    cast((select coalesce(max(fr.CREATED), 0) from FIELD_REPORT fr where fr.EVENT = e.ID) as double) as FIELD_REPORTS_LAST_CREATED
+from EVENT e
+order by e.ID;
+
+-- name: EventReportEntries :many
+-- These are all the report entries of an event's incidents and field reports.
+select sqlc.embed(re)
+from REPORT_ENTRY re
+where re.ID in (
+        select ire.REPORT_ENTRY from INCIDENT__REPORT_ENTRY ire where ire.EVENT = sqlc.arg(event)
+    )
+    or re.ID in (
+        select frre.REPORT_ENTRY from FIELD_REPORT__REPORT_ENTRY frre where frre.EVENT = sqlc.arg(event)
+    )
+order by re.ID;
+
+-- name: SetReportEntryText :exec
+update REPORT_ENTRY set TEXT = ?, ATTACHED_FILE = ?, ATTACHED_FILE_NAME = ?
+where ID = ?;
+
+-- name: DetachReportEntry :exec
+-- This takes a report entry off every incident that has it. See
+-- DetachFieldReportReportEntry for field reports.
+delete from INCIDENT__REPORT_ENTRY where REPORT_ENTRY = ?;
+
+-- name: DetachFieldReportReportEntry :exec
+delete from FIELD_REPORT__REPORT_ENTRY where REPORT_ENTRY = ?;
+
+-- name: DeleteReportEntry :exec
+delete from REPORT_ENTRY where ID = ?;
+
+-- name: RedactIncidentSummaries :execrows
+-- The redact queries replace an event's free text with the redacted marker,
+-- leaving LAST_MODIFIED alone so that the event doesn't look newer for it.
+update INCIDENT set SUMMARY = sqlc.arg(redacted)
+where EVENT = sqlc.arg(event)
+    and SUMMARY is not null
+    and SUMMARY <> sqlc.arg(redacted);
+
+-- name: RedactIncidentLocationDescriptions :execrows
+update INCIDENT set LOCATION_DESCRIPTION = sqlc.arg(redacted)
+where EVENT = sqlc.arg(event)
+    and LOCATION_DESCRIPTION is not null
+    and LOCATION_DESCRIPTION <> sqlc.arg(redacted);
+
+-- name: RedactFieldReportSummaries :execrows
+update FIELD_REPORT set SUMMARY = sqlc.arg(redacted)
+where EVENT = sqlc.arg(event)
+    and SUMMARY is not null
+    and SUMMARY <> sqlc.arg(redacted);
+
+-- name: RedactIncidentChanges :execrows
+-- redacted is a JSON value here, as OLD_VALUE and NEW_VALUE are.
+update INCIDENT_CHANGE set OLD_VALUE = sqlc.arg(redacted), NEW_VALUE = sqlc.arg(redacted)
+where EVENT = sqlc.arg(event)
+    and FIELD in ('summary', 'location_description')
+    and (OLD_VALUE <> sqlc.arg(redacted) or NEW_VALUE <> sqlc.arg(redacted));
+
+-- name: RedactAuditValues :execrows
+-- The values recorded for changes to an event's incidents, field reports, and
+-- report entries hold whatever text was written. The records of the changes
+-- themselves are kept, as are the values for everything else, such as who was
+-- given access to the event.
+update AUDIT set BEFORE_VALUE = null, AFTER_VALUE = null
+where EVENT = ?
+    and ENTITY_TYPE in ('incident', 'field_report', 'report_entry')
+    and (BEFORE_VALUE is not null or AFTER_VALUE is not null);
+
+-- name: CreateRefreshToken :exec
+insert into REFRESH_TOKEN (
+    TOKEN_HASH, FAMILY, RANGER_HANDLE, DIRECTORY_ID, CREATED, EXPIRES
+)
+values (?, ?, ?, ?, ?, ?);
+
+-- name: RefreshToken :one
+select sqlc.embed(rt)
+from REFRESH_TOKEN rt
+where rt.TOKEN_HASH = ?;
+
+-- name: UseRefreshToken :execrows
+update REFRESH_TOKEN
+set USED = sqlc.arg(used)
+where ID = sqlc.arg(id)
+    and USED is null
+    and REVOKED is null;
+
+-- name: RevokeRefreshTokenFamily :execrows
+update REFRESH_TOKEN
+set REVOKED = sqlc.arg(revoked)
+where FAMILY = sqlc.arg(family)
+    and REVOKED is null;
+
+-- name: PruneRefreshTokens :execrows
+delete from REFRESH_TOKEN
+where EXPIRES < ?;
+
+-- name: RevokeRefreshTokensForHandle :execrows
+update REFRESH_TOKEN
+set REVOKED = sqlc.arg(revoked)
+where RANGER_HANDLE = sqlc.arg(ranger_handle)
+    and REVOKED is null;
+
+-- name: CreateTokenRevocation :exec
+insert into TOKEN_REVOCATION (
+    JTI, RANGER_HANDLE, CREATED, EXPIRES
+)
+values (?, ?, ?, ?);
+
+-- name: TokenRevocationCount :one
+select count(*)
+from TOKEN_REVOCATION
+where JTI = sqlc.arg(jti)
+    or (RANGER_HANDLE = sqlc.arg(ranger_handle) and CREATED > sqlc.arg(issued_at));
+
+-- name: PruneTokenRevocations :exec
+delete from TOKEN_REVOCATION
+where EXPIRES < ?;
+
+-- name: CreateLoginFailureOrIgnore :exec
+-- This makes sure there's a row to lock, even before the first failure. The
+-- no-op update still locks the row if it already exists.
+insert into LOGIN_FAILURE (
+    KIND, NAME, FAILURES, LAST_FAILURE, BLOCKED_UNTIL
+)
+values (?, ?, 0, 0, 0)
+on duplicate key update FAILURES = LOGIN_FAILURE.FAILURES;
+
+-- name: LockLoginFailure :one
+select sqlc.embed(lf)
+from LOGIN_FAILURE lf
+where lf.KIND = ? and lf.NAME = ?
+for update;
+
+-- name: UpdateLoginFailure :exec
+update LOGIN_FAILURE
+set FAILURES = ?, LAST_FAILURE = ?, BLOCKED_UNTIL = ?
+where KIND = ? and NAME = ?;
+
+-- name: DeleteLoginFailure :exec
+delete from LOGIN_FAILURE
+where KIND = ? and NAME = ?;
+
+-- name: BlockedLoginFailures :many
+select sqlc.embed(lf)
+from LOGIN_FAILURE lf
+where lf.BLOCKED_UNTIL > ?
+order by lf.BLOCKED_UNTIL desc;
+
+-- name: PruneLoginFailures :exec
+delete from LOGIN_FAILURE
+where LAST_FAILURE < ?;
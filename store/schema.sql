@@ -2,7 +2,7 @@ create table SCHEMA_INFO (
     VERSION smallint not null
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
-insert into SCHEMA_INFO (VERSION) values (13);
+insert into SCHEMA_INFO (VERSION) values (24);
 
 
 create table EVENT (
@@ -45,6 +45,8 @@ create table REPORT_ENTRY (
     STRICKEN  boolean     not null,
 
     ATTACHED_FILE varchar(128),
+    -- ATTACHED_FILE_NAME is the name the file was uploaded with
+    ATTACHED_FILE_NAME varchar(255),
 
     -- FIXME: AUTHOR is an external non-primary key.
     -- Primary key is DMS Person ID.
@@ -52,6 +54,9 @@ create table REPORT_ENTRY (
     primary key (ID)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+create fulltext index `REPORT_ENTRY_TEXT_fulltext`
+    on `REPORT_ENTRY` (TEXT);
+
 
 create table INCIDENT (
     EVENT    integer  not null,
@@ -71,6 +76,14 @@ create table INCIDENT (
     LOCATION_RADIAL_MINUTE tinyint,
     LOCATION_DESCRIPTION   varchar(1024),
 
+    -- VERSION is incremented on every change to the incident's own fields,
+    -- which lets clients make conditional edits.
+    VERSION integer not null default 1,
+
+    -- LAST_MODIFIED is the time of the latest change to the incident,
+    -- including to its report entries.
+    LAST_MODIFIED double not null default 0,
+
     foreign key (EVENT) references EVENT(ID),
 
     foreign key (EVENT, LOCATION_CONCENTRIC)
@@ -79,6 +92,12 @@ create table INCIDENT (
     primary key (EVENT, NUMBER)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+create index `INCIDENT_EVENT_LAST_MODIFIED_index`
+    on `INCIDENT` (EVENT, LAST_MODIFIED);
+
+create fulltext index `INCIDENT_SUMMARY_LOCATION_fulltext`
+    on `INCIDENT` (SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION);
+
 
 create table INCIDENT__RANGER (
     ID              integer     not null auto_increment,
@@ -147,12 +166,26 @@ create table FIELD_REPORT (
     SUMMARY         varchar(1024),
     INCIDENT_NUMBER integer,
 
+    -- VERSION is incremented on every change to the field report's own fields,
+    -- which lets clients make conditional edits.
+    VERSION integer not null default 1,
+
+    -- LAST_MODIFIED is the time of the latest change to the field report,
+    -- including to its report entries.
+    LAST_MODIFIED double not null default 0,
+
     foreign key (EVENT) references EVENT(ID),
     foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
 
     primary key (EVENT, NUMBER)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+create index `FIELD_REPORT_EVENT_LAST_MODIFIED_index`
+    on `FIELD_REPORT` (EVENT, LAST_MODIFIED);
+
+create fulltext index `FIELD_REPORT_SUMMARY_fulltext`
+    on `FIELD_REPORT` (SUMMARY);
+
 
 create table FIELD_REPORT__REPORT_ENTRY (
     EVENT                  integer not null,
@@ -166,3 +199,146 @@ create table FIELD_REPORT__REPORT_ENTRY (
 
     primary key (EVENT, FIELD_REPORT_NUMBER, REPORT_ENTRY)
 ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+create table SSE_EVENT (
+    ID                  bigint  not null auto_increment,
+    CREATED             double  not null,
+    EVENT               integer not null,
+    INCIDENT_NUMBER     integer,
+    FIELD_REPORT_NUMBER integer,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-- EVENT_SEQUENCE holds the last incident and field report numbers handed out
+-- for each event. Updating it serializes the creation of new numbers.
+create table EVENT_SEQUENCE (
+    EVENT                    integer not null,
+    LAST_INCIDENT_NUMBER     integer not null,
+    LAST_FIELD_REPORT_NUMBER integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    primary key (EVENT)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-- AUDIT is an append-only record of every change made through the API. The
+-- before and after values are JSON, and are null for creations and deletions
+-- respectively. The one exception to append-only is the retention purge, which
+-- nulls out the values of an old event's incident, field report, and report
+-- entry records, but keeps the records. There's no foreign key on EVENT, so
+-- that the record outlives whatever it describes.
+create table AUDIT (
+    ID           bigint       not null auto_increment,
+    CREATED      double       not null,
+    ACTOR        varchar(128) not null,
+    REQUEST_ID   varchar(64)  not null,
+    ACTION       varchar(64)  not null,
+    ENTITY_TYPE  varchar(64)  not null,
+    EVENT        integer,
+    ENTITY_ID    varchar(128) not null,
+    BEFORE_VALUE mediumtext,
+    AFTER_VALUE  mediumtext,
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index `AUDIT_CREATED_index`
+    on `AUDIT` (CREATED);
+create index `AUDIT_ENTITY_index`
+    on `AUDIT` (ENTITY_TYPE, ENTITY_ID);
+create index `AUDIT_ACTOR_index`
+    on `AUDIT` (ACTOR);
+
+
+-- INCIDENT_CHANGE holds the history of changes to incidents' fields, one row
+-- per field changed. The old and new values are JSON.
+create table INCIDENT_CHANGE (
+    ID              bigint      not null auto_increment,
+    EVENT           integer     not null,
+    INCIDENT_NUMBER integer     not null,
+    CREATED         double      not null,
+    AUTHOR          varchar(64) not null,
+    FIELD           varchar(64) not null,
+    OLD_VALUE       text        not null,
+    NEW_VALUE       text        not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index `INCIDENT_CHANGE_EVENT_INCIDENT_NUMBER_index`
+    on `INCIDENT_CHANGE` (EVENT, INCIDENT_NUMBER);
+
+-- REFRESH_TOKEN holds the refresh tokens issued at login, which are exchanged
+-- for new access tokens. Only a hash of each token is kept. Tokens from one
+-- login share a FAMILY, so the whole family can be revoked if one is reused.
+create table REFRESH_TOKEN (
+    ID            bigint      not null auto_increment,
+    TOKEN_HASH    char(64)    not null,
+    FAMILY        varchar(64) not null,
+    RANGER_HANDLE varchar(64) not null,
+    DIRECTORY_ID  bigint      not null,
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+    USED          double,
+    REVOKED       double,
+
+    primary key (ID),
+    unique key (TOKEN_HASH)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index `REFRESH_TOKEN_FAMILY_index`
+    on `REFRESH_TOKEN` (FAMILY);
+create index `REFRESH_TOKEN_RANGER_HANDLE_index`
+    on `REFRESH_TOKEN` (RANGER_HANDLE);
+create index `REFRESH_TOKEN_EXPIRES_index`
+    on `REFRESH_TOKEN` (EXPIRES);
+
+-- TOKEN_REVOCATION holds revoked access tokens, either one token by its JTI,
+-- or every token of a Ranger issued before CREATED. A row is only needed
+-- until EXPIRES, when the tokens it revokes would have expired anyway.
+create table TOKEN_REVOCATION (
+    ID            bigint      not null auto_increment,
+    JTI           varchar(64),
+    RANGER_HANDLE varchar(64),
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+
+    primary key (ID)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index `TOKEN_REVOCATION_JTI_index`
+    on `TOKEN_REVOCATION` (JTI);
+create index `TOKEN_REVOCATION_RANGER_HANDLE_index`
+    on `TOKEN_REVOCATION` (RANGER_HANDLE);
+create index `TOKEN_REVOCATION_EXPIRES_index`
+    on `TOKEN_REVOCATION` (EXPIRES);
+
+-- LOGIN_FAILURE holds recent failed logins, when the login throttle keeps them
+-- in the IMS database rather than in memory. KIND is "ranger" for a Ranger's
+-- handle, "identification" for a hash of an identification that matched no one,
+-- or "client" for a client IP address. No logins are allowed until BLOCKED_UNTIL.
+create table LOGIN_FAILURE (
+    ID            bigint      not null auto_increment,
+    KIND          varchar(16) not null,
+    NAME          varchar(64) not null,
+    FAILURES      integer     not null,
+    LAST_FAILURE  double      not null,
+    BLOCKED_UNTIL double      not null,
+
+    primary key (ID),
+    unique key (KIND, NAME)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+create index `LOGIN_FAILURE_LAST_FAILURE_index`
+    on `LOGIN_FAILURE` (LAST_FAILURE);
+
+
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// These queries are written by hand, since sqlc doesn't understand the
+// parameters in MariaDB's "match ... against (?)". Each dialect gets its own
+// version: MariaDB searches its FULLTEXT indexes, while SQLite searches the
+// FTS5 tables that shadow INCIDENT, FIELD_REPORT, and REPORT_ENTRY.
+//
+// Stricken and system-generated report entries are never searched.
+
+const searchIncidentsMariaDB = `-- name: SearchIncidents :many
+select
+    i.NUMBER,
+    i.SUMMARY,
+    concat_ws(' | ', i.SUMMARY, i.LOCATION_NAME, i.LOCATION_DESCRIPTION) as TEXT,
+    match (i.SUMMARY, i.LOCATION_NAME, i.LOCATION_DESCRIPTION) against (?) as SCORE
+from INCIDENT i
+where i.EVENT = ?
+    and match (i.SUMMARY, i.LOCATION_NAME, i.LOCATION_DESCRIPTION) against (?)
+union all
+select
+    i.NUMBER,
+    i.SUMMARY,
+    re.TEXT,
+    match (re.TEXT) against (?) as SCORE
+from INCIDENT__REPORT_ENTRY ire
+join REPORT_ENTRY re
+    on re.ID = ire.REPORT_ENTRY
+join INCIDENT i
+    on i.EVENT = ire.EVENT
+    and i.NUMBER = ire.INCIDENT_NUMBER
+where ire.EVENT = ?
+    and not re.STRICKEN
+    and not re.GENERATED
+    and match (re.TEXT) against (?)
+order by SCORE desc
+limit ?
+`
+
+const searchIncidentsSQLite = `-- name: SearchIncidents :many
+select
+    i.NUMBER,
+    i.SUMMARY,
+    concat_ws(' | ', i.SUMMARY, i.LOCATION_NAME, i.LOCATION_DESCRIPTION) as TEXT,
+    -bm25(INCIDENT_FTS) as SCORE
+from INCIDENT_FTS
+join INCIDENT i
+    on i.EVENT = INCIDENT_FTS.EVENT
+    and i.NUMBER = INCIDENT_FTS.NUMBER
+where INCIDENT_FTS match ?
+    and INCIDENT_FTS.EVENT = ?
+union all
+select
+    i.NUMBER,
+    i.SUMMARY,
+    re.TEXT,
+    -bm25(REPORT_ENTRY_FTS) as SCORE
+from REPORT_ENTRY_FTS
+join REPORT_ENTRY re
+    on re.ID = REPORT_ENTRY_FTS.rowid
+join INCIDENT__REPORT_ENTRY ire
+    on ire.REPORT_ENTRY = re.ID
+join INCIDENT i
+    on i.EVENT = ire.EVENT
+    and i.NUMBER = ire.INCIDENT_NUMBER
+where REPORT_ENTRY_FTS match ?
+    and ire.EVENT = ?
+    and not re.STRICKEN
+    and not re."GENERATED"
+order by SCORE desc
+limit ?
+`
+
+// A null author matches every field report. Otherwise, only field reports
+// with a report entry by the author match.
+const searchFieldReportsMariaDB = `-- name: SearchFieldReports :many
+select
+    fr.NUMBER,
+    fr.SUMMARY,
+    fr.SUMMARY as TEXT,
+    match (fr.SUMMARY) against (?) as SCORE
+from FIELD_REPORT fr
+where fr.EVENT = ?
+    and match (fr.SUMMARY) against (?)
+    and (? is null or exists (
+        select 1
+        from FIELD_REPORT__REPORT_ENTRY frre
+        join REPORT_ENTRY are
+            on are.ID = frre.REPORT_ENTRY
+        where frre.EVENT = fr.EVENT
+            and frre.FIELD_REPORT_NUMBER = fr.NUMBER
+            and are.AUTHOR = ?
+    ))
+union all
+select
+    fr.NUMBER,
+    fr.SUMMARY,
+    re.TEXT,
+    match (re.TEXT) against (?) as SCORE
+from FIELD_REPORT__REPORT_ENTRY frre
+join REPORT_ENTRY re
+    on re.ID = frre.REPORT_ENTRY
+join FIELD_REPORT fr
+    on fr.EVENT = frre.EVENT
+    and fr.NUMBER = frre.FIELD_REPORT_NUMBER
+where frre.EVENT = ?
+    and not re.STRICKEN
+    and not re.GENERATED
+    and match (re.TEXT) against (?)
+    and (? is null or exists (
+        select 1
+        from FIELD_REPORT__REPORT_ENTRY afrre
+        join REPORT_ENTRY are
+            on are.ID = afrre.REPORT_ENTRY
+        where afrre.EVENT = fr.EVENT
+            and afrre.FIELD_REPORT_NUMBER = fr.NUMBER
+            and are.AUTHOR = ?
+    ))
+order by SCORE desc
+limit ?
+`
+
+const searchFieldReportsSQLite = `-- name: SearchFieldReports :many
+select
+    fr.NUMBER,
+    fr.SUMMARY,
+    fr.SUMMARY as TEXT,
+    -bm25(FIELD_REPORT_FTS) as SCORE
+from FIELD_REPORT_FTS
+join FIELD_REPORT fr
+    on fr.EVENT = FIELD_REPORT_FTS.EVENT
+    and fr.NUMBER = FIELD_REPORT_FTS.NUMBER
+where FIELD_REPORT_FTS match ?
+    and FIELD_REPORT_FTS.EVENT = ?
+    and (? is null or exists (
+        select 1
+        from FIELD_REPORT__REPORT_ENTRY frre
+        join REPORT_ENTRY are
+            on are.ID = frre.REPORT_ENTRY
+        where frre.EVENT = fr.EVENT
+            and frre.FIELD_REPORT_NUMBER = fr.NUMBER
+            and are.AUTHOR = ?
+    ))
+union all
+select
+    fr.NUMBER,
+    fr.SUMMARY,
+    re.TEXT,
+    -bm25(REPORT_ENTRY_FTS) as SCORE
+from REPORT_ENTRY_FTS
+join REPORT_ENTRY re
+    on re.ID = REPORT_ENTRY_FTS.rowid
+join FIELD_REPORT__REPORT_ENTRY frre
+    on frre.REPORT_ENTRY = re.ID
+join FIELD_REPORT fr
+    on fr.EVENT = frre.EVENT
+    and fr.NUMBER = frre.FIELD_REPORT_NUMBER
+where REPORT_ENTRY_FTS match ?
+    and frre.EVENT = ?
+    and not re.STRICKEN
+    and not re."GENERATED"
+    and (? is null or exists (
+        select 1
+        from FIELD_REPORT__REPORT_ENTRY afrre
+        join REPORT_ENTRY are
+            on are.ID = afrre.REPORT_ENTRY
+        where afrre.EVENT = fr.EVENT
+            and afrre.FIELD_REPORT_NUMBER = fr.NUMBER
+            and are.AUTHOR = ?
+    ))
+order by SCORE desc
+limit ?
+`
+
+// SearchHit is a piece of an incident or field report that matched a search.
+// A record can have many hits, e.g. one for its summary and one for each
+// matching report entry.
+type SearchHit struct {
+	Number  int32
+	Summary sql.NullString
+	// Text is the text that matched, either the record's own fields or a report entry
+	Text string
+	// Score ranks the hits, higher being better. Scores only mean anything
+	// relative to the other hits from the same database.
+	Score float64
+}
+
+// SearchIncidents finds the incidents in an event that mention any of the
+// terms, in their summary, location, or report entries.
+func (l DB) SearchIncidents(ctx context.Context, event int32, terms []string, limit int32) ([]SearchHit, error) {
+	var query string
+	var args []any
+	if l.isSQLite() {
+		match := fts5Query(terms)
+		query = searchIncidentsSQLite
+		args = []any{match, event, match, event, limit}
+	} else {
+		against := strings.Join(terms, " ")
+		query = searchIncidentsMariaDB
+		args = []any{against, event, against, against, event, against, limit}
+	}
+	hits, err := l.search(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("[search]: %w", err)
+	}
+	return hits, nil
+}
+
+// SearchFieldReports finds the field reports in an event that mention any of
+// the terms, in their summary or report entries. If author is set, only field
+// reports with a report entry by that author are returned.
+func (l DB) SearchFieldReports(ctx context.Context, event int32, terms []string, author sql.NullString, limit int32) ([]SearchHit, error) {
+	var query string
+	var args []any
+	if l.isSQLite() {
+		match := fts5Query(terms)
+		query = searchFieldReportsSQLite
+		args = []any{match, event, author, author, match, event, author, author, limit}
+	} else {
+		against := strings.Join(terms, " ")
+		query = searchFieldReportsMariaDB
+		args = []any{against, event, against, author, author, against, event, against, author, author, limit}
+	}
+	hits, err := l.search(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("[search]: %w", err)
+	}
+	return hits, nil
+}
+
+func (l DB) search(ctx context.Context, query string, args []any) ([]SearchHit, error) {
+	rows, err := l.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[QueryContext]: %w", err)
+	}
+	defer rows.Close()
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		if err = rows.Scan(&hit.Number, &hit.Summary, &hit.Text, &hit.Score); err != nil {
+			return nil, fmt.Errorf("[Scan]: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[Err]: %w", err)
+	}
+	return hits, nil
+}
+
+// fts5Query builds an FTS5 query that matches any of the terms. Each term is
+// quoted, so that nothing in it is taken as FTS5 syntax.
+func fts5Query(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(quoted, " OR ")
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchSQLite(t *testing.T) {
+	ctx := t.Context()
+	db, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(ctx, db))
+
+	_, err = db.ExecContext(ctx, `
+		insert into EVENT (ID, NAME) values (1, 'Event1'), (2, 'Event2');
+		insert into INCIDENT (EVENT, NUMBER, CREATED, PRIORITY, STATE, SUMMARY, LOCATION_NAME)
+			values (1, 1, 0, 3, 'new', 'Stolen blue bike', 'Center Camp'),
+			       (1, 2, 0, 3, 'new', 'Lost keys', null),
+			       (2, 1, 0, 3, 'new', 'Another blue bike', null);
+		insert into REPORT_ENTRY (ID, AUTHOR, TEXT, CREATED, "GENERATED", STRICKEN)
+			values (1, 'Alice', 'Found near the bike rack', 0, 0, 0),
+			       (2, 'Bob', 'bike', 0, 0, 1),
+			       (3, 'Carol', 'The bike is red', 0, 0, 0);
+		insert into INCIDENT__REPORT_ENTRY (EVENT, INCIDENT_NUMBER, REPORT_ENTRY)
+			values (1, 2, 1), (1, 2, 2);
+		insert into FIELD_REPORT (EVENT, NUMBER, CREATED, SUMMARY)
+			values (1, 1, 0, 'A bike report'), (1, 2, 0, null);
+		insert into FIELD_REPORT__REPORT_ENTRY (EVENT, FIELD_REPORT_NUMBER, REPORT_ENTRY)
+			values (1, 2, 3);
+	`)
+	require.NoError(t, err)
+
+	// Only unstricken entries count, and only from this event
+	hits, err := db.SearchIncidents(ctx, 1, []string{"bike"}, 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	numbers := map[int32]string{}
+	for _, hit := range hits {
+		numbers[hit.Number] = hit.Text
+	}
+	require.Equal(t, "Stolen blue bike | Center Camp", numbers[1])
+	require.Equal(t, "Found near the bike rack", numbers[2])
+
+	// The index follows updates
+	_, err = db.ExecContext(ctx, "update INCIDENT set SUMMARY = 'Lost wallet' where EVENT = 1 and NUMBER = 1")
+	require.NoError(t, err)
+	hits, err = db.SearchIncidents(ctx, 1, []string{"blue", "wallet"}, 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, "Lost wallet | Center Camp", hits[0].Text)
+
+	hits, err = db.SearchFieldReports(ctx, 1, []string{"bike"}, sql.NullString{}, 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	hits, err = db.SearchFieldReports(ctx, 1, []string{"bike"}, sql.NullString{String: "Carol", Valid: true}, 10)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, int32(2), hits[0].Number)
+	require.Equal(t, "The bike is red", hits[0].Text)
+
+	// Quotes can't break out of the FTS5 query
+	_, err = db.SearchIncidents(ctx, 1, []string{`"bike`, "OR", "*"}, 10)
+	require.NoError(t, err)
+}
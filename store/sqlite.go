@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"github.com/srabraham/ranger-ims-go/conf"
+	"log/slog"
+	_ "modernc.org/sqlite"
+	"net/url"
+	"os"
+	"strings"
+)
+
+//go:embed sqlite/schema.sql
+var CurrentSQLiteSchema string
+
+//go:embed sqlite/queries.sql
+var sqliteQueries string
+
+// SQLite opens the IMS SQLite database file, creating the file if it doesn't exist yet.
+// This lets IMS run on a single machine with no database server.
+func SQLite(imsCfg *conf.IMSConfig) *DB {
+	slog.Info("Setting up IMS DB connection")
+	db, err := OpenSQLite(context.Background(), imsCfg.Store.SQLite.Path)
+	if err != nil {
+		slog.Error("Failed to open IMS SQLite DB", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Connected to IMS SQLite", "path", imsCfg.Store.SQLite.Path)
+	return db
+}
+
+// OpenSQLite opens the SQLite database at path, creating the file if it doesn't exist yet.
+// Use Migrate to set up the schema.
+func OpenSQLite(ctx context.Context, path string) (*DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	// Wait on other writers rather than failing right away
+	params.Add("_pragma", "busy_timeout(10000)")
+	// Take the write lock at the start of each transaction. Otherwise, two
+	// transactions that both read and then write can deadlock.
+	params.Add("_txlock", "immediate")
+	sqlDB, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("[sql.Open]: %w", err)
+	}
+	if err = sqlDB.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("[PingContext]: %w", err)
+	}
+	return &DB{
+		DB:             sqlDB,
+		storeType:      conf.StoreTypeSQLite,
+		queryOverrides: parseQueries(sqliteQueries),
+	}, nil
+}
+
+// parseQueries splits an sqlc-style queries file into its queries, keyed by name.
+// Each query keeps its "-- name: " line, just like the sqlc-generated ones.
+func parseQueries(file string) map[string]string {
+	queries := make(map[string]string)
+	for _, chunk := range strings.Split(file, "-- name: ")[1:] {
+		query := "-- name: " + strings.TrimSpace(chunk)
+		queries[sqlcQueryName(query)] = query
+	}
+	return queries
+}
@@ -0,0 +1,9 @@
+create table SSE_EVENT (
+    ID                  integer not null primary key autoincrement,
+    CREATED             double  not null,
+    EVENT               integer not null,
+    INCIDENT_NUMBER     integer,
+    FIELD_REPORT_NUMBER integer,
+
+    foreign key (EVENT) references EVENT(ID)
+);
@@ -0,0 +1,25 @@
+alter table INCIDENT add column LAST_MODIFIED double not null default 0;
+alter table FIELD_REPORT add column LAST_MODIFIED double not null default 0;
+
+update INCIDENT as i set LAST_MODIFIED = max(i.CREATED, coalesce((
+    select max(re.CREATED)
+    from INCIDENT__REPORT_ENTRY ire
+    join REPORT_ENTRY re
+        on re.ID = ire.REPORT_ENTRY
+    where ire.EVENT = i.EVENT
+        and ire.INCIDENT_NUMBER = i.NUMBER
+), 0));
+
+update FIELD_REPORT as fr set LAST_MODIFIED = max(fr.CREATED, coalesce((
+    select max(re.CREATED)
+    from FIELD_REPORT__REPORT_ENTRY frre
+    join REPORT_ENTRY re
+        on re.ID = frre.REPORT_ENTRY
+    where frre.EVENT = fr.EVENT
+        and frre.FIELD_REPORT_NUMBER = fr.NUMBER
+), 0));
+
+create index INCIDENT_EVENT_LAST_MODIFIED_index
+    on INCIDENT (EVENT, LAST_MODIFIED);
+create index FIELD_REPORT_EVENT_LAST_MODIFIED_index
+    on FIELD_REPORT (EVENT, LAST_MODIFIED);
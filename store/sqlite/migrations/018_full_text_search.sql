@@ -0,0 +1,76 @@
+create virtual table INCIDENT_FTS using fts5(
+    EVENT unindexed,
+    NUMBER unindexed,
+    SUMMARY,
+    LOCATION_NAME,
+    LOCATION_DESCRIPTION
+);
+
+create trigger INCIDENT_FTS_insert after insert on INCIDENT begin
+    insert into INCIDENT_FTS (EVENT, NUMBER, SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION)
+    values (new.EVENT, new.NUMBER, new.SUMMARY, new.LOCATION_NAME, new.LOCATION_DESCRIPTION);
+end;
+
+create trigger INCIDENT_FTS_update after update of SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION on INCIDENT begin
+    update INCIDENT_FTS
+    set SUMMARY = new.SUMMARY,
+        LOCATION_NAME = new.LOCATION_NAME,
+        LOCATION_DESCRIPTION = new.LOCATION_DESCRIPTION
+    where EVENT = new.EVENT and NUMBER = new.NUMBER;
+end;
+
+create trigger INCIDENT_FTS_delete after delete on INCIDENT begin
+    delete from INCIDENT_FTS where EVENT = old.EVENT and NUMBER = old.NUMBER;
+end;
+
+insert into INCIDENT_FTS (EVENT, NUMBER, SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION)
+select EVENT, NUMBER, SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION from INCIDENT;
+
+
+create virtual table FIELD_REPORT_FTS using fts5(
+    EVENT unindexed,
+    NUMBER unindexed,
+    SUMMARY
+);
+
+create trigger FIELD_REPORT_FTS_insert after insert on FIELD_REPORT begin
+    insert into FIELD_REPORT_FTS (EVENT, NUMBER, SUMMARY)
+    values (new.EVENT, new.NUMBER, new.SUMMARY);
+end;
+
+create trigger FIELD_REPORT_FTS_update after update of SUMMARY on FIELD_REPORT begin
+    update FIELD_REPORT_FTS
+    set SUMMARY = new.SUMMARY
+    where EVENT = new.EVENT and NUMBER = new.NUMBER;
+end;
+
+create trigger FIELD_REPORT_FTS_delete after delete on FIELD_REPORT begin
+    delete from FIELD_REPORT_FTS where EVENT = old.EVENT and NUMBER = old.NUMBER;
+end;
+
+insert into FIELD_REPORT_FTS (EVENT, NUMBER, SUMMARY)
+select EVENT, NUMBER, SUMMARY from FIELD_REPORT;
+
+
+-- REPORT_ENTRY has a stable integer ID, so its index can read the text
+-- straight out of REPORT_ENTRY rather than keeping a copy.
+create virtual table REPORT_ENTRY_FTS using fts5(
+    TEXT,
+    content = 'REPORT_ENTRY',
+    content_rowid = 'ID'
+);
+
+create trigger REPORT_ENTRY_FTS_insert after insert on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (rowid, TEXT) values (new.ID, new.TEXT);
+end;
+
+create trigger REPORT_ENTRY_FTS_update after update of TEXT on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (REPORT_ENTRY_FTS, rowid, TEXT) values ('delete', old.ID, old.TEXT);
+    insert into REPORT_ENTRY_FTS (rowid, TEXT) values (new.ID, new.TEXT);
+end;
+
+create trigger REPORT_ENTRY_FTS_delete after delete on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (REPORT_ENTRY_FTS, rowid, TEXT) values ('delete', old.ID, old.TEXT);
+end;
+
+insert into REPORT_ENTRY_FTS (REPORT_ENTRY_FTS) values ('rebuild');
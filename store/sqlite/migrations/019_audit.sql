@@ -0,0 +1,19 @@
+create table AUDIT (
+    ID           integer      not null primary key autoincrement,
+    CREATED      double       not null,
+    ACTOR        varchar(128) not null,
+    REQUEST_ID   varchar(64)  not null,
+    ACTION       varchar(64)  not null,
+    ENTITY_TYPE  varchar(64)  not null,
+    EVENT        integer,
+    ENTITY_ID    varchar(128) not null,
+    BEFORE_VALUE text,
+    AFTER_VALUE  text
+);
+
+create index AUDIT_CREATED_index
+    on AUDIT (CREATED);
+create index AUDIT_ENTITY_index
+    on AUDIT (ENTITY_TYPE, ENTITY_ID);
+create index AUDIT_ACTOR_index
+    on AUDIT (ACTOR);
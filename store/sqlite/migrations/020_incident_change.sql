@@ -0,0 +1,16 @@
+create table INCIDENT_CHANGE (
+    ID              integer     not null primary key autoincrement,
+    EVENT           integer     not null,
+    INCIDENT_NUMBER integer     not null,
+    CREATED         double      not null,
+    AUTHOR          varchar(64) not null,
+    FIELD           varchar(64) not null,
+    OLD_VALUE       text        not null,
+    NEW_VALUE       text        not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER)
+);
+
+create index INCIDENT_CHANGE_EVENT_INCIDENT_NUMBER_index
+    on INCIDENT_CHANGE (EVENT, INCIDENT_NUMBER);
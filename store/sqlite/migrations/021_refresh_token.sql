@@ -0,0 +1,20 @@
+create table REFRESH_TOKEN (
+    ID            integer     not null primary key autoincrement,
+    TOKEN_HASH    char(64)    not null,
+    FAMILY        varchar(64) not null,
+    RANGER_HANDLE varchar(64) not null,
+    DIRECTORY_ID  bigint      not null,
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+    USED          double,
+    REVOKED       double,
+
+    unique (TOKEN_HASH)
+);
+
+create index REFRESH_TOKEN_FAMILY_index
+    on REFRESH_TOKEN (FAMILY);
+create index REFRESH_TOKEN_RANGER_HANDLE_index
+    on REFRESH_TOKEN (RANGER_HANDLE);
+create index REFRESH_TOKEN_EXPIRES_index
+    on REFRESH_TOKEN (EXPIRES);
@@ -0,0 +1,14 @@
+create table TOKEN_REVOCATION (
+    ID            integer     not null primary key autoincrement,
+    JTI           varchar(64),
+    RANGER_HANDLE varchar(64),
+    CREATED       double      not null,
+    EXPIRES       double      not null
+);
+
+create index TOKEN_REVOCATION_JTI_index
+    on TOKEN_REVOCATION (JTI);
+create index TOKEN_REVOCATION_RANGER_HANDLE_index
+    on TOKEN_REVOCATION (RANGER_HANDLE);
+create index TOKEN_REVOCATION_EXPIRES_index
+    on TOKEN_REVOCATION (EXPIRES);
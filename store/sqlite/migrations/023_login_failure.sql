@@ -0,0 +1,13 @@
+create table LOGIN_FAILURE (
+    ID            integer     not null primary key autoincrement,
+    KIND          varchar(16) not null,
+    NAME          varchar(64) not null,
+    FAILURES      integer     not null,
+    LAST_FAILURE  double      not null,
+    BLOCKED_UNTIL double      not null,
+
+    unique (KIND, NAME)
+);
+
+create index LOGIN_FAILURE_LAST_FAILURE_index
+    on LOGIN_FAILURE (LAST_FAILURE);
@@ -0,0 +1 @@
+alter table REPORT_ENTRY add column ATTACHED_FILE_NAME varchar(255);
@@ -0,0 +1,149 @@
+-- These are SQLite translations of the queries in store/queries.sql that
+-- don't work as-is on SQLite. Each one replaces the query with the same
+-- name, so it must take the same parameters and return the same columns
+-- in the same order as the sqlc-generated MariaDB query. Every query not
+-- listed here is run verbatim on SQLite.
+
+-- name: Incident :one
+select
+    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description, i.version, i.last_modified,
+    (
+        select cast(json_group_array(it.NAME) as blob)
+        from INCIDENT__INCIDENT_TYPE iit
+                 join INCIDENT_TYPE it
+                      on i.EVENT = iit.EVENT
+                          and i.NUMBER = iit.INCIDENT_NUMBER
+                          and iit.INCIDENT_TYPE = it.ID
+    ) as INCIDENT_TYPES,
+    (
+        select cast(json_group_array(irep.NUMBER) as blob)
+        from FIELD_REPORT irep
+        where i.EVENT = irep.EVENT
+          and i.NUMBER = irep.INCIDENT_NUMBER
+    ) as FIELD_REPORT_NUMBERS,
+    (
+        select cast(json_group_array(ir.RANGER_HANDLE) as blob)
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+          and i.NUMBER = ir.INCIDENT_NUMBER
+    ) as RANGER_HANDLES
+from INCIDENT i
+where i.EVENT = ?
+    and i.NUMBER = ?;
+
+-- name: Incidents :many
+select
+    i.event, i.number, i.created, i.priority, i.state, i.summary, i.location_name, i.location_concentric, i.location_radial_hour, i.location_radial_minute, i.location_description, i.version, i.last_modified,
+    (
+        select cast(json_group_array(it.NAME) as blob)
+        from INCIDENT__INCIDENT_TYPE iit
+        join INCIDENT_TYPE it
+            on i.EVENT = iit.EVENT
+            and i.NUMBER = iit.INCIDENT_NUMBER
+            and iit.INCIDENT_TYPE = it.ID
+    ) as INCIDENT_TYPES,
+    (
+        select cast(json_group_array(irep.NUMBER) as blob)
+        from FIELD_REPORT irep
+        where i.EVENT = irep.EVENT
+            and i.NUMBER = irep.INCIDENT_NUMBER
+    ) as FIELD_REPORT_NUMBERS,
+    (
+        select cast(json_group_array(ir.RANGER_HANDLE) as blob)
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+            and i.NUMBER = ir.INCIDENT_NUMBER
+    ) as RANGER_HANDLES
+from
+    INCIDENT i
+where
+    i.EVENT = ?
+    and (? is null
+        or instr(concat(',', ?, ','), concat(',', i.STATE, ',')) > 0)
+    and (? is null or i.PRIORITY = ?)
+    and (? is null or exists (
+        select 1
+        from INCIDENT__INCIDENT_TYPE iit
+        join INCIDENT_TYPE it
+            on iit.INCIDENT_TYPE = it.ID
+        where i.EVENT = iit.EVENT
+            and i.NUMBER = iit.INCIDENT_NUMBER
+            and it.NAME = ?
+    ))
+    and (? is null or exists (
+        select 1
+        from INCIDENT__RANGER ir
+        where i.EVENT = ir.EVENT
+            and i.NUMBER = ir.INCIDENT_NUMBER
+            and ir.RANGER_HANDLE = ?
+    ))
+    and (? is null or i.CREATED >= ?)
+    and (? is null or i.CREATED < ?)
+    and (? is null or i.LAST_MODIFIED >= ?)
+    and (? is null or i.LAST_MODIFIED < ?)
+    and (? is null
+        or i.SUMMARY like ? escape '!'
+        or i.LOCATION_NAME like ? escape '!'
+        or i.LOCATION_DESCRIPTION like ? escape '!'
+        or exists (
+            select 1
+            from INCIDENT__REPORT_ENTRY ire
+            join REPORT_ENTRY re
+                on re.ID = ire.REPORT_ENTRY
+            where i.EVENT = ire.EVENT
+                and i.NUMBER = ire.INCIDENT_NUMBER
+                and not re.STRICKEN
+                and re.TEXT like ? escape '!'
+        ))
+    and (? is null
+        or i.CREATED * ?
+            + i.LAST_MODIFIED * ?
+            + i.PRIORITY * ?
+            + i.NUMBER * ? > ?
+        or (i.CREATED * ?
+                + i.LAST_MODIFIED * ?
+                + i.PRIORITY * ?
+                + i.NUMBER * ? = ?
+            and i.NUMBER * ? > ?))
+group by
+    i.NUMBER
+order by
+    i.CREATED * ?
+        + i.LAST_MODIFIED * ?
+        + i.PRIORITY * ?
+        + i.NUMBER * ?,
+    i.NUMBER * ?
+limit ?
+;
+
+-- name: CreateIncidentTypeOrIgnore :exec
+insert into INCIDENT_TYPE (NAME, HIDDEN)
+values (?, ?)
+    on conflict (NAME) do nothing
+;
+
+-- name: CreateEventSequenceOrIgnore :exec
+insert into EVENT_SEQUENCE (EVENT, LAST_INCIDENT_NUMBER, LAST_FIELD_REPORT_NUMBER)
+select
+    e.ID,
+    (select coalesce(max(i.NUMBER), 0) from INCIDENT i where i.EVENT = e.ID),
+    (select coalesce(max(fr.NUMBER), 0) from FIELD_REPORT fr where fr.EVENT = e.ID)
+from EVENT e
+where e.ID = ?
+    on conflict (EVENT) do nothing
+;
+
+-- name: CreateLoginFailureOrIgnore :exec
+insert into LOGIN_FAILURE (
+    KIND, NAME, FAILURES, LAST_FAILURE, BLOCKED_UNTIL
+)
+values (?, ?, 0, 0, 0)
+    on conflict (KIND, NAME) do nothing
+;
+
+-- name: LockLoginFailure :one
+-- SQLite has no row locks, but the transaction already holds the write lock.
+select lf.id, lf.kind, lf.name, lf.failures, lf.last_failure, lf.blocked_until
+from LOGIN_FAILURE lf
+where lf.KIND = ? and lf.NAME = ?
+;
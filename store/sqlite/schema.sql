@@ -0,0 +1,382 @@
+-- This is the SQLite translation of store/schema.sql. The two must be kept
+-- in sync, including the SCHEMA_INFO version.
+
+create table SCHEMA_INFO (
+    VERSION smallint not null
+);
+
+insert into SCHEMA_INFO (VERSION) values (24);
+
+
+create table EVENT (
+    ID   integer      not null primary key autoincrement,
+    NAME varchar(128) not null,
+
+    unique (NAME)
+);
+
+
+create table CONCENTRIC_STREET (
+    EVENT integer      not null,
+    ID    varchar(16)  not null,
+    NAME  varchar(128) not null,
+
+    primary key (EVENT, ID)
+);
+
+
+create table INCIDENT_TYPE (
+    ID     integer      not null primary key autoincrement,
+    NAME   varchar(128) not null,
+    HIDDEN boolean      not null,
+
+    unique (NAME)
+);
+
+insert into INCIDENT_TYPE (NAME, HIDDEN) values ('Admin', 0);
+insert into INCIDENT_TYPE (NAME, HIDDEN) values ('Junk' , 0);
+
+
+create table REPORT_ENTRY (
+    ID          integer     not null primary key autoincrement,
+    AUTHOR      varchar(64) not null,
+    TEXT        text        not null,
+    CREATED     double      not null,
+    "GENERATED" boolean     not null,
+    STRICKEN    boolean     not null,
+
+    ATTACHED_FILE varchar(128),
+    -- ATTACHED_FILE_NAME is the name the file was uploaded with
+    ATTACHED_FILE_NAME varchar(255)
+);
+
+
+create table INCIDENT (
+    EVENT    integer  not null,
+    NUMBER   integer  not null,
+    CREATED  double   not null,
+    PRIORITY tinyint  not null,
+
+    STATE text not null check (
+        STATE in ('new', 'on_hold', 'dispatched', 'on_scene', 'closed')
+    ),
+
+    SUMMARY varchar(1024),
+
+    LOCATION_NAME          varchar(1024),
+    LOCATION_CONCENTRIC    varchar(64),
+    LOCATION_RADIAL_HOUR   tinyint,
+    LOCATION_RADIAL_MINUTE tinyint,
+    LOCATION_DESCRIPTION   varchar(1024),
+
+    VERSION integer not null default 1,
+
+    -- LAST_MODIFIED is the time of the latest change to the incident,
+    -- including to its report entries.
+    LAST_MODIFIED double not null default 0,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    foreign key (EVENT, LOCATION_CONCENTRIC)
+    references CONCENTRIC_STREET(EVENT, ID),
+
+    primary key (EVENT, NUMBER)
+);
+
+create index INCIDENT_EVENT_LAST_MODIFIED_index
+    on INCIDENT (EVENT, LAST_MODIFIED);
+
+
+create table INCIDENT__RANGER (
+    ID              integer     not null primary key autoincrement,
+    EVENT           integer     not null,
+    INCIDENT_NUMBER integer     not null,
+    RANGER_HANDLE   varchar(64) not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER)
+);
+
+create index INCIDENT__RANGER_EVENT_INCIDENT_NUMBER_index
+    on INCIDENT__RANGER (EVENT, INCIDENT_NUMBER);
+
+
+create table INCIDENT__INCIDENT_TYPE (
+    EVENT           integer not null,
+    INCIDENT_NUMBER integer not null,
+    INCIDENT_TYPE   integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
+    foreign key (INCIDENT_TYPE) references INCIDENT_TYPE(ID),
+
+    primary key (EVENT, INCIDENT_NUMBER, INCIDENT_TYPE)
+);
+
+
+create table INCIDENT__REPORT_ENTRY (
+    EVENT           integer not null,
+    INCIDENT_NUMBER integer not null,
+    REPORT_ENTRY    integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
+    foreign key (REPORT_ENTRY) references REPORT_ENTRY(ID),
+
+    primary key (EVENT, INCIDENT_NUMBER, REPORT_ENTRY)
+);
+
+
+create table EVENT_ACCESS (
+    ID         integer      not null primary key autoincrement,
+    EVENT      integer      not null,
+    EXPRESSION varchar(128) not null,
+
+    MODE     text not null check (MODE in ('read', 'write', 'report')),
+    VALIDITY text not null default 'always' check (VALIDITY in ('always', 'onsite')),
+
+    foreign key (EVENT) references EVENT(ID)
+);
+
+
+create table FIELD_REPORT (
+    EVENT   integer  not null,
+    NUMBER  integer  not null,
+    CREATED double   not null,
+
+    SUMMARY         varchar(1024),
+    INCIDENT_NUMBER integer,
+
+    VERSION integer not null default 1,
+
+    -- LAST_MODIFIED is the time of the latest change to the field report,
+    -- including to its report entries.
+    LAST_MODIFIED double not null default 0,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER),
+
+    primary key (EVENT, NUMBER)
+);
+
+create index FIELD_REPORT_EVENT_LAST_MODIFIED_index
+    on FIELD_REPORT (EVENT, LAST_MODIFIED);
+
+
+create table FIELD_REPORT__REPORT_ENTRY (
+    EVENT                  integer not null,
+    FIELD_REPORT_NUMBER    integer not null,
+    REPORT_ENTRY           integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, FIELD_REPORT_NUMBER)
+        references FIELD_REPORT(EVENT, NUMBER),
+    foreign key (REPORT_ENTRY) references REPORT_ENTRY(ID),
+
+    primary key (EVENT, FIELD_REPORT_NUMBER, REPORT_ENTRY)
+);
+
+
+create table SSE_EVENT (
+    ID                  integer not null primary key autoincrement,
+    CREATED             double  not null,
+    EVENT               integer not null,
+    INCIDENT_NUMBER     integer,
+    FIELD_REPORT_NUMBER integer,
+
+    foreign key (EVENT) references EVENT(ID)
+);
+
+
+-- EVENT_SEQUENCE holds the last incident and field report numbers handed out
+-- for each event. Updating it serializes the creation of new numbers.
+create table EVENT_SEQUENCE (
+    EVENT                    integer not null,
+    LAST_INCIDENT_NUMBER     integer not null,
+    LAST_FIELD_REPORT_NUMBER integer not null,
+
+    foreign key (EVENT) references EVENT(ID),
+
+    primary key (EVENT)
+);
+
+
+-- AUDIT is an append-only record of every change made through the API. The
+-- before and after values are JSON, and are null for creations and deletions
+-- respectively. The one exception to append-only is the retention purge, which
+-- nulls out the values of an old event's incident, field report, and report
+-- entry records, but keeps the records. There's no foreign key on EVENT, so
+-- that the record outlives whatever it describes.
+create table AUDIT (
+    ID           integer      not null primary key autoincrement,
+    CREATED      double       not null,
+    ACTOR        varchar(128) not null,
+    REQUEST_ID   varchar(64)  not null,
+    ACTION       varchar(64)  not null,
+    ENTITY_TYPE  varchar(64)  not null,
+    EVENT        integer,
+    ENTITY_ID    varchar(128) not null,
+    BEFORE_VALUE text,
+    AFTER_VALUE  text
+);
+
+create index AUDIT_CREATED_index
+    on AUDIT (CREATED);
+create index AUDIT_ENTITY_index
+    on AUDIT (ENTITY_TYPE, ENTITY_ID);
+create index AUDIT_ACTOR_index
+    on AUDIT (ACTOR);
+
+
+-- INCIDENT_CHANGE holds the history of changes to incidents' fields, one row
+-- per field changed. The old and new values are JSON.
+create table INCIDENT_CHANGE (
+    ID              integer     not null primary key autoincrement,
+    EVENT           integer     not null,
+    INCIDENT_NUMBER integer     not null,
+    CREATED         double      not null,
+    AUTHOR          varchar(64) not null,
+    FIELD           varchar(64) not null,
+    OLD_VALUE       text        not null,
+    NEW_VALUE       text        not null,
+
+    foreign key (EVENT) references EVENT(ID),
+    foreign key (EVENT, INCIDENT_NUMBER) references INCIDENT(EVENT, NUMBER)
+);
+
+create index INCIDENT_CHANGE_EVENT_INCIDENT_NUMBER_index
+    on INCIDENT_CHANGE (EVENT, INCIDENT_NUMBER);
+
+-- REFRESH_TOKEN holds the refresh tokens issued at login, which are exchanged
+-- for new access tokens. Only a hash of each token is kept. Tokens from one
+-- login share a FAMILY, so the whole family can be revoked if one is reused.
+create table REFRESH_TOKEN (
+    ID            integer     not null primary key autoincrement,
+    TOKEN_HASH    char(64)    not null,
+    FAMILY        varchar(64) not null,
+    RANGER_HANDLE varchar(64) not null,
+    DIRECTORY_ID  bigint      not null,
+    CREATED       double      not null,
+    EXPIRES       double      not null,
+    USED          double,
+    REVOKED       double,
+
+    unique (TOKEN_HASH)
+);
+
+create index REFRESH_TOKEN_FAMILY_index
+    on REFRESH_TOKEN (FAMILY);
+create index REFRESH_TOKEN_RANGER_HANDLE_index
+    on REFRESH_TOKEN (RANGER_HANDLE);
+create index REFRESH_TOKEN_EXPIRES_index
+    on REFRESH_TOKEN (EXPIRES);
+
+-- TOKEN_REVOCATION holds revoked access tokens, either one token by its JTI,
+-- or every token of a Ranger issued before CREATED. A row is only needed
+-- until EXPIRES, when the tokens it revokes would have expired anyway.
+create table TOKEN_REVOCATION (
+    ID            integer     not null primary key autoincrement,
+    JTI           varchar(64),
+    RANGER_HANDLE varchar(64),
+    CREATED       double      not null,
+    EXPIRES       double      not null
+);
+
+create index TOKEN_REVOCATION_JTI_index
+    on TOKEN_REVOCATION (JTI);
+create index TOKEN_REVOCATION_RANGER_HANDLE_index
+    on TOKEN_REVOCATION (RANGER_HANDLE);
+create index TOKEN_REVOCATION_EXPIRES_index
+    on TOKEN_REVOCATION (EXPIRES);
+
+-- LOGIN_FAILURE holds recent failed logins, when the login throttle keeps them
+-- in the IMS database rather than in memory. KIND is "ranger" for a Ranger's
+-- handle, "identification" for a hash of an identification that matched no one,
+-- or "client" for a client IP address. No logins are allowed until BLOCKED_UNTIL.
+create table LOGIN_FAILURE (
+    ID            integer     not null primary key autoincrement,
+    KIND          varchar(16) not null,
+    NAME          varchar(64) not null,
+    FAILURES      integer     not null,
+    LAST_FAILURE  double      not null,
+    BLOCKED_UNTIL double      not null,
+
+    unique (KIND, NAME)
+);
+
+create index LOGIN_FAILURE_LAST_FAILURE_index
+    on LOGIN_FAILURE (LAST_FAILURE);
+
+
+
+
+-- These FTS5 tables back full-text search. SQLite has no FULLTEXT indexes,
+-- so triggers keep the search tables in step with the tables they index.
+create virtual table INCIDENT_FTS using fts5(
+    EVENT unindexed,
+    NUMBER unindexed,
+    SUMMARY,
+    LOCATION_NAME,
+    LOCATION_DESCRIPTION
+);
+
+create trigger INCIDENT_FTS_insert after insert on INCIDENT begin
+    insert into INCIDENT_FTS (EVENT, NUMBER, SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION)
+    values (new.EVENT, new.NUMBER, new.SUMMARY, new.LOCATION_NAME, new.LOCATION_DESCRIPTION);
+end;
+
+create trigger INCIDENT_FTS_update after update of SUMMARY, LOCATION_NAME, LOCATION_DESCRIPTION on INCIDENT begin
+    update INCIDENT_FTS
+    set SUMMARY = new.SUMMARY,
+        LOCATION_NAME = new.LOCATION_NAME,
+        LOCATION_DESCRIPTION = new.LOCATION_DESCRIPTION
+    where EVENT = new.EVENT and NUMBER = new.NUMBER;
+end;
+
+create trigger INCIDENT_FTS_delete after delete on INCIDENT begin
+    delete from INCIDENT_FTS where EVENT = old.EVENT and NUMBER = old.NUMBER;
+end;
+
+
+create virtual table FIELD_REPORT_FTS using fts5(
+    EVENT unindexed,
+    NUMBER unindexed,
+    SUMMARY
+);
+
+create trigger FIELD_REPORT_FTS_insert after insert on FIELD_REPORT begin
+    insert into FIELD_REPORT_FTS (EVENT, NUMBER, SUMMARY)
+    values (new.EVENT, new.NUMBER, new.SUMMARY);
+end;
+
+create trigger FIELD_REPORT_FTS_update after update of SUMMARY on FIELD_REPORT begin
+    update FIELD_REPORT_FTS
+    set SUMMARY = new.SUMMARY
+    where EVENT = new.EVENT and NUMBER = new.NUMBER;
+end;
+
+create trigger FIELD_REPORT_FTS_delete after delete on FIELD_REPORT begin
+    delete from FIELD_REPORT_FTS where EVENT = old.EVENT and NUMBER = old.NUMBER;
+end;
+
+
+-- REPORT_ENTRY has a stable integer ID, so its index can read the text
+-- straight out of REPORT_ENTRY rather than keeping a copy.
+create virtual table REPORT_ENTRY_FTS using fts5(
+    TEXT,
+    content = 'REPORT_ENTRY',
+    content_rowid = 'ID'
+);
+
+create trigger REPORT_ENTRY_FTS_insert after insert on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (rowid, TEXT) values (new.ID, new.TEXT);
+end;
+
+create trigger REPORT_ENTRY_FTS_update after update of TEXT on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (REPORT_ENTRY_FTS, rowid, TEXT) values ('delete', old.ID, old.TEXT);
+    insert into REPORT_ENTRY_FTS (rowid, TEXT) values (new.ID, new.TEXT);
+end;
+
+create trigger REPORT_ENTRY_FTS_delete after delete on REPORT_ENTRY begin
+    insert into REPORT_ENTRY_FTS (REPORT_ENTRY_FTS, rowid, TEXT) values ('delete', old.ID, old.TEXT);
+end;
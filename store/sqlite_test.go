@@ -0,0 +1,44 @@
+package store
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestSQLiteQueries checks that every IMS query, after translation, is valid on SQLite.
+func TestSQLiteQueries(t *testing.T) {
+	db, err := OpenSQLite(t.Context(), filepath.Join(t.TempDir(), "ims.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(t.Context(), db))
+
+	queriesFile, err := os.ReadFile("queries.sql")
+	require.NoError(t, err)
+	queries := parseQueries(string(queriesFile))
+	require.NotEmpty(t, queries)
+
+	// Every override must replace a real query
+	for name := range db.queryOverrides {
+		require.Contains(t, queries, name)
+	}
+
+	// sqlc expands sqlc.embed(x) into x's columns, but x.* is close enough to compile.
+	// Named parameters become plain placeholders, as they do in the generated code,
+	// and so does a slice of values, as it does when the slice has one value.
+	// The SQLite driver prepares statements lazily, so use "explain" to make SQLite
+	// compile each one.
+	sqlcEmbed := regexp.MustCompile(`sqlc\.embed\((\w+)\)`)
+	sqlcArg := regexp.MustCompile(`sqlc\.(n?arg|slice)\(\w+\)`)
+	for name, query := range queries {
+		query = sqlcArg.ReplaceAllString(sqlcEmbed.ReplaceAllString(query, "$1.*"), "?")
+		query = translate(db.queryOverrides, query)
+		args := make([]any, strings.Count(query, "?"))
+		rows, err := db.DB.QueryContext(t.Context(), "explain "+query, args...)
+		require.NoError(t, err, name)
+		require.NoError(t, rows.Close())
+	}
+}
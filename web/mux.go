@@ -31,6 +31,9 @@ func AddToMux(mux *http.ServeMux, cfg *conf.IMSConfig) *http.ServeMux {
 	mux.Handle("GET /ims/app/admin/events",
 		AdaptTempl(template.AdminEvents(cfg.Core.Deployment)),
 	)
+	mux.Handle("GET /ims/app/admin/lockouts",
+		AdaptTempl(template.AdminLockouts(cfg.Core.Deployment)),
+	)
 	mux.Handle("GET /ims/app/admin/streets",
 		AdaptTempl(template.AdminStreets(cfg.Core.Deployment)),
 	)
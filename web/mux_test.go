@@ -15,6 +15,7 @@ var templEndpoints = []string{
 	"/ims/app",
 	"/ims/app/admin",
 	"/ims/app/admin/events",
+	"/ims/app/admin/lockouts",
 	"/ims/app/admin/streets",
 	"/ims/app/admin/types",
 	"/ims/app/events/SomeEvent/field_reports",
@@ -23,6 +23,7 @@ export let pathIds = {
 };
 export let eventAccess = null;
 const accessTokenKey = "access_token";
+const refreshTokenKey = "refresh_token";
 //
 // HTML encoding
 //
@@ -159,6 +160,11 @@ export async function fetchJsonNoThrow(url, init) {
     let response = null;
     try {
         response = await fetch(url, init);
+        // The access token may have expired, in which case try once more with a new one.
+        if (response.status === 401 && tok && await refreshAccessToken(tok)) {
+            init.headers.set("Authorization", "Bearer " + getAccessToken());
+            response = await fetch(url, init);
+        }
         if (!response.ok) {
             return { resp: response, json: null, err: `${response.statusText} (${response.status})` };
         }
@@ -747,6 +753,10 @@ function reportEntryElement(entry) {
         const attachmentLink = document.createElement("a");
         attachmentLink.href = url;
         attachmentLink.textContent = "Attached file";
+        attachmentLink.addEventListener("click", async (e) => {
+            e.preventDefault();
+            await openAuthenticated(url, "attachment");
+        });
         entryContainer.append(attachmentLink);
     }
     // Add a horizontal line after each entry
@@ -951,8 +961,22 @@ export function requestEventSourceLock() {
 //
 // The "closed" param is a callback to notify the caller that the EventSource has
 // been closed.
-function subscribeToUpdates(closed) {
-    const eventSource = new EventSource(url_eventSource, { withCredentials: true });
+async function subscribeToUpdates(closed) {
+    // EventSource can't set an Authorization header, so we instead get a
+    // short-lived ticket from the server and put that in the query string.
+    const { json, err } = await fetchJsonNoThrow(url_eventSourceTicket, { method: "POST" });
+    if (err != null || json == null) {
+        console.log(`Failed to get EventSource ticket: ${err}`);
+        closed();
+        return;
+    }
+    let url = `${url_eventSource}?ticket=${encodeURIComponent(json.ticket)}`;
+    // Have the server replay anything we missed since the last event we saw.
+    const lastSseId = localStorage.getItem(lastSseIDKey);
+    if (lastSseId != null) {
+        url += `&last_event_id=${encodeURIComponent(lastSseId)}`;
+    }
+    const eventSource = new EventSource(url, { withCredentials: true });
     eventSource.addEventListener("open", function () {
         console.log("Event listener opened");
     });
@@ -1018,14 +1042,94 @@ export function windowFragmentParams() {
         : window.location.hash;
     return new URLSearchParams(fragment);
 }
+// Open a file from the API, e.g. an attachment, in a new tab. It can't just be
+// linked to, because the server wants an Authorization header for it.
+export async function openAuthenticated(url, what) {
+    const headers = new Headers();
+    const tok = getAccessToken();
+    if (tok) {
+        headers.set("Authorization", "Bearer " + tok);
+    }
+    try {
+        let response = await fetch(url, { headers: headers });
+        if (response.status === 401 && tok && await refreshAccessToken(tok)) {
+            headers.set("Authorization", "Bearer " + getAccessToken());
+            response = await fetch(url, { headers: headers });
+        }
+        if (!response.ok) {
+            setErrorMessage(`Failed to fetch ${what}: ${response.statusText} (${response.status})`);
+            return;
+        }
+        const blob = await response.blob();
+        window.open(URL.createObjectURL(blob), "_blank");
+    }
+    catch (err) {
+        setErrorMessage(`Failed to fetch ${what}: ${err.message}`);
+    }
+}
 function getAccessToken() {
     return localStorage.getItem(accessTokenKey);
 }
-export function setAccessToken(token) {
+export function setAccessToken(token, refreshToken) {
     localStorage.setItem(accessTokenKey, token);
+    if (refreshToken) {
+        localStorage.setItem(refreshTokenKey, refreshToken);
+    }
+    else {
+        localStorage.removeItem(refreshTokenKey);
+    }
 }
 export function clearAccessToken() {
     localStorage.removeItem(accessTokenKey);
+    localStorage.removeItem(refreshTokenKey);
+}
+// Log out, revoking the access and refresh tokens on the server as well as
+// forgetting them here.
+export async function logout() {
+    if (getAccessToken()) {
+        const { err } = await fetchJsonNoThrow(url_authLogout, {
+            body: JSON.stringify({ "refresh_token": localStorage.getItem(refreshTokenKey) ?? "" }),
+        });
+        if (err != null) {
+            console.log(`Failed to log out on the server: ${err}`);
+        }
+    }
+    clearAccessToken();
+}
+// Exchange the refresh token for a new access token, returning whether there's
+// now a new one. Each refresh token can only be used once, so only one browsing
+// context refreshes at a time, and one that finds the access token already
+// replaced by another context just uses that.
+async function refreshAccessToken(staleToken) {
+    return navigator.locks.request("ims_refresh_lock", async () => {
+        if (getAccessToken() !== staleToken) {
+            return getAccessToken() != null;
+        }
+        const refreshToken = localStorage.getItem(refreshTokenKey);
+        if (refreshToken == null) {
+            return false;
+        }
+        try {
+            const response = await fetch(url_authRefresh, {
+                method: "POST",
+                headers: { "Accept": "application/json", "Content-Type": "application/json" },
+                body: JSON.stringify({ "refresh_token": refreshToken }),
+            });
+            if (response.status === 401) {
+                localStorage.removeItem(refreshTokenKey);
+            }
+            if (!response.ok) {
+                return false;
+            }
+            const json = await response.json();
+            setAccessToken(json.token, json.refresh_token);
+            return true;
+        }
+        catch (err) {
+            console.log(`Failed to refresh access token: ${err.message}`);
+            return false;
+        }
+    });
 }
 //
 // Load incident types
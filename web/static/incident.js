@@ -41,6 +41,7 @@ async function initIncidentPage() {
     window.addRanger = addRanger;
     window.addIncidentType = addIncidentType;
     window.attachFile = attachFile;
+    window.openDossier = openDossier;
     window.drawMergedReportEntries = drawMergedReportEntries;
     window.toggleShowHistory = ims.toggleShowHistory;
     window.reportEntryEdited = ims.reportEntryEdited;
@@ -905,3 +906,12 @@ async function attachFile() {
     attachFile.value = "";
     await loadAndDisplayIncident();
 }
+// Open a printable record of the incident, either as a page or a PDF.
+async function openDossier(format) {
+    if (ims.pathIds.incidentNumber == null) {
+        return;
+    }
+    const url = ims.urlReplace(url_incidentDossier)
+        .replace("<incident_number>", ims.pathIds.incidentNumber.toString());
+    await ims.openAuthenticated(`${url}?format=${format}`, "printable incident");
+}
@@ -29,17 +29,24 @@ async function initLoginPage() {
 async function login() {
     const username = document.getElementById("username_input").value;
     const password = document.getElementById("password_input").value;
-    const { json, err } = await ims.fetchJsonNoThrow(url_auth, {
+    const { resp, json, err } = await ims.fetchJsonNoThrow(url_auth, {
         body: JSON.stringify({
             "identification": username,
             "password": password,
         }),
     });
     if (err != null || json == null) {
+        if (resp?.status === 429) {
+            document.querySelector(".login-retry-after").textContent = resp.headers.get("Retry-After") ?? "a few";
+            ims.hide(".if-authentication-failed");
+            ims.unhide(".if-login-throttled");
+            return;
+        }
+        ims.hide(".if-login-throttled");
         ims.unhide(".if-authentication-failed");
         return;
     }
-    ims.setAccessToken(json.token);
+    ims.setAccessToken(json.token, json.refresh_token);
     const redirect = new URLSearchParams(window.location.search).get("o");
     if (redirect != null) {
         window.location.replace(redirect);
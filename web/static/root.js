@@ -21,7 +21,7 @@ initRootPage();
 async function initRootPage() {
     const params = new URLSearchParams(window.location.search);
     if (params.get("logout") != null) {
-        ims.clearAccessToken();
+        await ims.logout();
         window.history.replaceState(null, "", url_app);
     }
     await ims.commonPageInit();
@@ -5,6 +5,9 @@ var url_api = "/ims/api";
 var url_ping = "/ims/api/ping";
 var url_bag = "/ims/api/bag";
 var url_auth = "/ims/api/auth";
+var url_authRefresh = "/ims/api/auth/refresh";
+var url_authLogout = "/ims/api/auth/logout";
+var url_authLockouts = "/ims/api/auth/lockouts";
 var url_acl = "/ims/api/access";
 var url_streets = "/ims/api/streets";
 var url_personnel = "/ims/api/personnel";
@@ -17,11 +20,13 @@ var url_incident_reportEntries = "/ims/api/events/<event_id>/incidents/<incident
 var url_incident_reportEntry = "/ims/api/events/<event_id>/incidents/<incident_number>/report_entries/<report_entry_id>";
 var url_incidentAttachments = "/ims/api/events/<event_id>/incidents/<incident_number>/attachments";
 var url_incidentAttachmentNumber = "/ims/api/events/<event_id>/incidents/<incident_number>/attachments/<attachment_number>";
+var url_incidentDossier = "/ims/api/events/<event_id>/incidents/<incident_number>/dossier";
 var url_fieldReports = "/ims/api/events/<event_id>/field_reports";
 var url_fieldReport = "/ims/api/events/<event_id>/field_reports/<field_report_number>";
 var url_fieldReport_reportEntries = "/ims/api/events/<event_id>/field_reports/<field_report_number>/report_entries";
 var url_fieldReport_reportEntry = "/ims/api/events/<event_id>/field_reports/<field_report_number>/report_entries/<report_entry_id>";
 var url_eventSource = "/ims/api/eventsource";
+var url_eventSourceTicket = "/ims/api/eventsource/ticket";
 var url_static = "/ims/static";
 var url_styleSheet = "/ims/static/style.css";
 var url_logo = "/ims/static/logo.png";
@@ -54,6 +59,8 @@ var url_adminIncidentTypes = "/ims/app/admin/types";
 var url_adminIncidentTypesJS = "/ims/static/admin_types.js";
 var url_adminStreets = "/ims/app/admin/streets";
 var url_adminStreetsJS = "/ims/static/admin_streets.js";
+var url_adminLockouts = "/ims/app/admin/lockouts";
+var url_adminLockoutsJS = "/ims/static/admin_lockouts.js";
 var url_viewEvents = "/ims/app/events";
 var url_viewEvent = "/ims/app/events/<event_id>";
 var url_viewIncidents = "/ims/app/events/<event_id>/incidents";
@@ -49,7 +49,7 @@ func AdminRoot(deployment string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<h1 id=\"doc-title\">Administration Tools</h1><ul><li><a href=\"/ims/app/admin/types\">Incident Types</a></li><li><a href=\"/ims/app/admin/events\">Events</a></li><li><a href=\"/ims/app/admin/streets\">Event Concentric Streets</a></li></ul>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<h1 id=\"doc-title\">Administration Tools</h1><ul><li><a href=\"/ims/app/admin/types\">Incident Types</a></li><li><a href=\"/ims/app/admin/events\">Events</a></li><li><a href=\"/ims/app/admin/streets\">Event Concentric Streets</a></li><li><a href=\"/ims/app/admin/lockouts\">Login Lockouts</a></li></ul>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
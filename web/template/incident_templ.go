@@ -49,14 +49,14 @@ func Incident(deployment string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div id=\"error_info\" class=\"hidden text-danger\"><p id=\"error_text\"></p></div><!-- Help modal for incident page --><div class=\"modal no-print\" id=\"helpModal\" tabindex=\"-1\" aria-labelledby=\"helpModalLabel\" aria-hidden=\"true\"><div class=\"modal-dialog\"><div class=\"modal-content\"><div class=\"modal-header\"><p class=\"modal-title fs-5\" id=\"helpModalLabel\">Keyboard shortcuts</p><button type=\"button\" class=\"btn-close\" data-bs-dismiss=\"modal\" aria-label=\"Close\"></button></div><div class=\"modal-body\"><code>n</code>: create (n)ew Incident <br><code>a</code>: jump to (a)dd new report text<br><code>h</code>: toggle showing system-generated (h)istory <br></div></div></div></div><!-- Incident number, state, created --><div class=\"row py-1\"><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label class=\"control-label input-group-text\">IMS #</label> <span id=\"incident_number\" aria-label=\"IMS #\" class=\"form-control form-control-static\"></span></div></div><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label for=\"incident_state\" class=\"control-label input-group-text\">State</label> <select id=\"incident_state\" class=\"form-control form-select form-select-sm auto-width\" onchange=\"editState()\"><option value=\"new\">New</option> <option value=\"on_hold\">On Hold</option> <option value=\"dispatched\">Dispatched</option> <option value=\"on_scene\">On Scene</option> <option value=\"closed\">Closed</option></select></div></div><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label class=\"control-label input-group-text\">Created</label> <span id=\"created_datetime\" class=\"form-control form-control-static\"></span></div></div></div><!-- Summary --><div class=\"row\"><div class=\"input-group\"><label for=\"incident_summary\" class=\"input-group-text control-label\">Summary</label> <input id=\"incident_summary\" class=\"form-control form-control-sm\" type=\"text\" inputmode=\"latin-prose\" placeholder=\"One-line summary of incident…\" onchange=\"editIncidentSummary()\"></div></div><!-- Attached Rangers, incident types --><div class=\"row\"><div class=\"col-sm-6 py-2\"><div class=\"card\"><label class=\"control-label card-header\">Rangers</label><ul id=\"incident_rangers_list\" class=\"list-group list-group-flush list-group-small card-body\"><li class=\"list-group-item ps-3\"><button class=\"badge btn btn-danger remove-badge float-end\" onclick=\"removeRanger(this)\">X</button></li></ul><div class=\"flex-input-container card-footer no-print\"><label for=\"ranger_add\" class=\"control-label\">Add:</label> <input type=\"text\" id=\"ranger_add\" aria-label=\"Add Ranger Handle\" list=\"ranger_handles\" class=\"form-control form-control-sm auto-width\" onchange=\"addRanger()\"> <datalist id=\"ranger_handles\"><option value=\"\"></option></datalist></div></div></div><div class=\"col-sm-6 py-2\"><div class=\"card\"><label class=\"control-label card-header\">Incident Types <a href=\"https://github.com/burningmantech/ranger-ims-server/wiki/Incident-Types\" class=\"link-body-emphasis\"><svg fill=\"currentColor\" class=\"bi\"><use href=\"#question-circle\"></use></svg></a></label><ul id=\"incident_types_list\" class=\"list-group list-group-flush list-group-small card-body\"><li class=\"list-group-item ps-3\"><button class=\"badge btn btn-danger remove-badge float-end\" onclick=\"removeIncidentType(this)\">X</button></li></ul><div class=\"card-footer flex-input-container no-print\"><label class=\"control-label\">Add:</label> <input type=\"text\" id=\"incident_type_add\" aria-label=\"Add Incident Type\" list=\"incident_types\" class=\"form-control form-control-sm auto-width\" onchange=\"addIncidentType()\"> <datalist id=\"incident_types\"><option value=\"\"></option></datalist></div></div></div></div><!-- Location --><div class=\"row py-1\"><div class=\"col-sm-12\"><div class=\"card\"><label class=\"control-label card-header\">Location</label><div class=\"card-body\"><form class=\"form-horizontal\"><div class=\"input-group row align-items-center\"><label for=\"incident_location_name\" class=\"col-sm-2 col-form-label control-label\">Name:</label><div class=\"col-sm-10\"><input id=\"incident_location_name\" class=\"form-control form-control-sm\" type=\"text\" inputmode=\"latin-prose\" placeholder=\"Name of location\" aria-label=\"Location name\" onchange=\"editLocationName()\"></div></div><div class=\"input-group row align-items-center\"><span class=\"col-sm-2 col-form-label control-label\">Address:</span><div id=\"incident_address\" class=\"col-sm-10\"><select id=\"incident_location_address_radial_hour\" class=\"form-control form-select auto-width\" aria-label=\"Incident location address radial hour\" onchange=\"editLocationAddressRadialHour()\"><option value=\"\"></option></select> ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div id=\"error_info\" class=\"hidden text-danger\"><p id=\"error_text\"></p></div><!-- Help modal for incident page --><div class=\"modal no-print\" id=\"helpModal\" tabindex=\"-1\" aria-labelledby=\"helpModalLabel\" aria-hidden=\"true\"><div class=\"modal-dialog\"><div class=\"modal-content\"><div class=\"modal-header\"><p class=\"modal-title fs-5\" id=\"helpModalLabel\">Keyboard shortcuts</p><button type=\"button\" class=\"btn-close\" data-bs-dismiss=\"modal\" aria-label=\"Close\"></button></div><div class=\"modal-body\"><code>n</code>: create (n)ew Incident <br><code>a</code>: jump to (a)dd new report text<br><code>h</code>: toggle showing system-generated (h)istory <br></div></div></div></div><!-- Incident number, state, created --><div class=\"row py-1\"><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label class=\"control-label input-group-text\">IMS #</label> <span id=\"incident_number\" aria-label=\"IMS #\" class=\"form-control form-control-static\"></span></div></div><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label for=\"incident_state\" class=\"control-label input-group-text\">State</label> <select id=\"incident_state\" class=\"form-control form-select form-select-sm auto-width\" onchange=\"editState()\"><option value=\"new\">New</option> <option value=\"on_hold\">On Hold</option> <option value=\"dispatched\">Dispatched</option> <option value=\"on_scene\">On Scene</option> <option value=\"closed\">Closed</option></select></div></div><div class=\"col-sm-4 py-1\"><div class=\"input-group\"><label class=\"control-label input-group-text\">Created</label> <span id=\"created_datetime\" class=\"form-control form-control-static\"></span></div></div></div><!-- Printable record --><div class=\"row py-1 no-print\"><div class=\"col text-end\"><button type=\"button\" id=\"dossier_html\" class=\"btn btn-sm btn-default btn-secondary\" onclick=\"openDossier(&#39;html&#39;)\">Print view</button> <button type=\"button\" id=\"dossier_pdf\" class=\"btn btn-sm btn-default btn-secondary\" onclick=\"openDossier(&#39;pdf&#39;)\">PDF</button></div></div><!-- Summary --><div class=\"row\"><div class=\"input-group\"><label for=\"incident_summary\" class=\"input-group-text control-label\">Summary</label> <input id=\"incident_summary\" class=\"form-control form-control-sm\" type=\"text\" inputmode=\"latin-prose\" placeholder=\"One-line summary of incident…\" onchange=\"editIncidentSummary()\"></div></div><!-- Attached Rangers, incident types --><div class=\"row\"><div class=\"col-sm-6 py-2\"><div class=\"card\"><label class=\"control-label card-header\">Rangers</label><ul id=\"incident_rangers_list\" class=\"list-group list-group-flush list-group-small card-body\"><li class=\"list-group-item ps-3\"><button class=\"badge btn btn-danger remove-badge float-end\" onclick=\"removeRanger(this)\">X</button></li></ul><div class=\"flex-input-container card-footer no-print\"><label for=\"ranger_add\" class=\"control-label\">Add:</label> <input type=\"text\" id=\"ranger_add\" aria-label=\"Add Ranger Handle\" list=\"ranger_handles\" class=\"form-control form-control-sm auto-width\" onchange=\"addRanger()\"> <datalist id=\"ranger_handles\"><option value=\"\"></option></datalist></div></div></div><div class=\"col-sm-6 py-2\"><div class=\"card\"><label class=\"control-label card-header\">Incident Types <a href=\"https://github.com/burningmantech/ranger-ims-server/wiki/Incident-Types\" class=\"link-body-emphasis\"><svg fill=\"currentColor\" class=\"bi\"><use href=\"#question-circle\"></use></svg></a></label><ul id=\"incident_types_list\" class=\"list-group list-group-flush list-group-small card-body\"><li class=\"list-group-item ps-3\"><button class=\"badge btn btn-danger remove-badge float-end\" onclick=\"removeIncidentType(this)\">X</button></li></ul><div class=\"card-footer flex-input-container no-print\"><label class=\"control-label\">Add:</label> <input type=\"text\" id=\"incident_type_add\" aria-label=\"Add Incident Type\" list=\"incident_types\" class=\"form-control form-control-sm auto-width\" onchange=\"addIncidentType()\"> <datalist id=\"incident_types\"><option value=\"\"></option></datalist></div></div></div></div><!-- Location --><div class=\"row py-1\"><div class=\"col-sm-12\"><div class=\"card\"><label class=\"control-label card-header\">Location</label><div class=\"card-body\"><form class=\"form-horizontal\"><div class=\"input-group row align-items-center\"><label for=\"incident_location_name\" class=\"col-sm-2 col-form-label control-label\">Name:</label><div class=\"col-sm-10\"><input id=\"incident_location_name\" class=\"form-control form-control-sm\" type=\"text\" inputmode=\"latin-prose\" placeholder=\"Name of location\" aria-label=\"Location name\" onchange=\"editLocationName()\"></div></div><div class=\"input-group row align-items-center\"><span class=\"col-sm-2 col-form-label control-label\">Address:</span><div id=\"incident_address\" class=\"col-sm-10\"><select id=\"incident_location_address_radial_hour\" class=\"form-control form-select auto-width\" aria-label=\"Incident location address radial hour\" onchange=\"editLocationAddressRadialHour()\"><option value=\"\"></option></select> ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(":")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/template/incident.templ`, Line: 176, Col: 22}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/template/incident.templ`, Line: 189, Col: 22}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
@@ -69,7 +69,7 @@ func Incident(deployment string) templ.Component {
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs("@")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/template/incident.templ`, Line: 185, Col: 22}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/template/incident.templ`, Line: 198, Col: 22}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
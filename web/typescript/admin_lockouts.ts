@@ -0,0 +1,73 @@
+// See the file COPYRIGHT for copyright information.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import * as ims from "./ims.ts";
+
+declare let url_authLockouts: string;
+
+interface LoginLockout {
+    kind: string;
+    name: string;
+    failures: number;
+    last_failure: string;
+    until: string;
+    locked_out: boolean;
+}
+
+//
+// Initialize UI
+//
+
+initAdminLockoutsPage();
+
+async function initAdminLockoutsPage(): Promise<void> {
+    const initResult = await ims.commonPageInit();
+    if (!initResult.authInfo.authenticated) {
+        ims.redirectToLogin();
+        return;
+    }
+
+    const {json, err} = await ims.fetchJsonNoThrow<LoginLockout[]>(url_authLockouts, null);
+    if (err != null || json == null) {
+        const message = `Failed to load login lockouts: ${err}`;
+        console.error(message);
+        window.alert(message);
+        return;
+    }
+    drawLockouts(json);
+}
+
+function drawLockouts(lockouts: LoginLockout[]): void {
+    const tbody = document.getElementById("lockouts_table")!.querySelector("tbody")!;
+    tbody.replaceChildren();
+    for (const lockout of lockouts) {
+        const row = document.createElement("tr");
+        for (const value of [
+            lockout.kind === "client" ? "Client IP" : "Ranger",
+            lockout.name,
+            lockout.failures.toString(),
+            ims.fullDateTime.format(Date.parse(lockout.last_failure)),
+            ims.fullDateTime.format(Date.parse(lockout.until)),
+            lockout.locked_out ? "Yes" : "No",
+        ]) {
+            const cell = document.createElement("td");
+            cell.textContent = value;
+            row.append(cell);
+        }
+        tbody.append(row);
+    }
+    if (lockouts.length === 0) {
+        ims.unhide(".if-no-lockouts");
+    }
+}
@@ -29,6 +29,7 @@ export let pathIds: {
 export let eventAccess: AuthInfoEventAccess|null = null;
 
 const accessTokenKey = "access_token";
+const refreshTokenKey = "refresh_token";
 
 //
 // HTML encoding
@@ -173,6 +174,11 @@ export async function fetchJsonNoThrow<T>(url: string, init: RequestInit|null):
     let response: Response|null = null;
     try {
         response = await fetch(url, init);
+        // The access token may have expired, in which case try once more with a new one.
+        if (response.status === 401 && tok && await refreshAccessToken(tok)) {
+            init.headers.set("Authorization", "Bearer " + getAccessToken());
+            response = await fetch(url, init);
+        }
         if (!response.ok) {
             return {resp: response, json: null, err: `${response.statusText} (${response.status})`};
         }
@@ -878,6 +884,10 @@ function reportEntryElement(entry: ReportEntry): HTMLDivElement {
         const attachmentLink: HTMLAnchorElement = document.createElement("a");
         attachmentLink.href = url;
         attachmentLink.textContent = "Attached file";
+        attachmentLink.addEventListener("click", async (e: MouseEvent): Promise<void> => {
+            e.preventDefault();
+            await openAuthenticated(url, "attachment");
+        });
 
         entryContainer.append(attachmentLink);
 
@@ -1117,10 +1127,22 @@ export function requestEventSourceLock(): void  {
 //
 // The "closed" param is a callback to notify the caller that the EventSource has
 // been closed.
-function subscribeToUpdates(closed: (_value?: undefined)=>void): void {
-    const eventSource = new EventSource(
-        url_eventSource, { withCredentials: true }
-    );
+async function subscribeToUpdates(closed: (_value?: undefined)=>void): Promise<void> {
+    // EventSource can't set an Authorization header, so we instead get a
+    // short-lived ticket from the server and put that in the query string.
+    const {json, err} = await fetchJsonNoThrow<EventSourceTicket>(url_eventSourceTicket, {method: "POST"});
+    if (err != null || json == null) {
+        console.log(`Failed to get EventSource ticket: ${err}`);
+        closed();
+        return;
+    }
+    let url = `${url_eventSource}?ticket=${encodeURIComponent(json.ticket)}`;
+    // Have the server replay anything we missed since the last event we saw.
+    const lastSseId = localStorage.getItem(lastSseIDKey);
+    if (lastSseId != null) {
+        url += `&last_event_id=${encodeURIComponent(lastSseId)}`;
+    }
+    const eventSource = new EventSource(url, { withCredentials: true });
 
     eventSource.addEventListener("open", function(): void {
         console.log("Event listener opened");
@@ -1195,16 +1217,101 @@ export function windowFragmentParams() {
     return new URLSearchParams(fragment);
 }
 
+// Open a file from the API, e.g. an attachment, in a new tab. It can't just be
+// linked to, because the server wants an Authorization header for it.
+export async function openAuthenticated(url: string, what: string): Promise<void> {
+    const headers = new Headers();
+    const tok = getAccessToken();
+    if (tok) {
+        headers.set("Authorization", "Bearer " + tok);
+    }
+    try {
+        let response = await fetch(url, {headers: headers});
+        if (response.status === 401 && tok && await refreshAccessToken(tok)) {
+            headers.set("Authorization", "Bearer " + getAccessToken());
+            response = await fetch(url, {headers: headers});
+        }
+        if (!response.ok) {
+            setErrorMessage(`Failed to fetch ${what}: ${response.statusText} (${response.status})`);
+            return;
+        }
+        const blob = await response.blob();
+        window.open(URL.createObjectURL(blob), "_blank");
+    } catch (err: any) {
+        setErrorMessage(`Failed to fetch ${what}: ${err.message}`);
+    }
+}
+
 function getAccessToken(): string|null {
     return localStorage.getItem(accessTokenKey);
 }
 
-export function setAccessToken(token: string): void {
+export function setAccessToken(token: string, refreshToken?: string): void {
     localStorage.setItem(accessTokenKey, token);
+    if (refreshToken) {
+        localStorage.setItem(refreshTokenKey, refreshToken);
+    } else {
+        localStorage.removeItem(refreshTokenKey);
+    }
 }
 
 export function clearAccessToken(): void {
     localStorage.removeItem(accessTokenKey);
+    localStorage.removeItem(refreshTokenKey);
+}
+
+// Log out, revoking the access and refresh tokens on the server as well as
+// forgetting them here.
+export async function logout(): Promise<void> {
+    if (getAccessToken()) {
+        const {err} = await fetchJsonNoThrow(url_authLogout, {
+            body: JSON.stringify({"refresh_token": localStorage.getItem(refreshTokenKey) ?? ""}),
+        });
+        if (err != null) {
+            console.log(`Failed to log out on the server: ${err}`);
+        }
+    }
+    clearAccessToken();
+}
+
+// Exchange the refresh token for a new access token, returning whether there's
+// now a new one. Each refresh token can only be used once, so only one browsing
+// context refreshes at a time, and one that finds the access token already
+// replaced by another context just uses that.
+async function refreshAccessToken(staleToken: string): Promise<boolean> {
+    return navigator.locks.request("ims_refresh_lock", async (): Promise<boolean> => {
+        if (getAccessToken() !== staleToken) {
+            return getAccessToken() != null;
+        }
+        const refreshToken = localStorage.getItem(refreshTokenKey);
+        if (refreshToken == null) {
+            return false;
+        }
+        try {
+            const response = await fetch(url_authRefresh, {
+                method: "POST",
+                headers: {"Accept": "application/json", "Content-Type": "application/json"},
+                body: JSON.stringify({"refresh_token": refreshToken}),
+            });
+            if (response.status === 401) {
+                localStorage.removeItem(refreshTokenKey);
+            }
+            if (!response.ok) {
+                return false;
+            }
+            const json: AuthTokens = await response.json();
+            setAccessToken(json.token, json.refresh_token);
+            return true;
+        } catch (err: any) {
+            console.log(`Failed to refresh access token: ${err.message}`);
+            return false;
+        }
+    });
+}
+
+export type AuthTokens = {
+    token: string;
+    refresh_token?: string;
 }
 
 
@@ -1256,8 +1363,11 @@ cleanupOldCaches();
 //
 
 declare let url_auth: string;
+declare let url_authLogout: string;
+declare let url_authRefresh: string;
 declare let url_events: string;
 declare let url_eventSource: string;
+declare let url_eventSourceTicket: string;
 declare let url_fieldReport_reportEntry: string;
 declare let url_incidentAttachmentNumber: string;
 declare let url_incidentTypes: string;
@@ -1378,6 +1488,10 @@ interface EditMap {
     [index: string]: EditMap|string;
 }
 
+type EventSourceTicket = {
+    ticket: string;
+}
+
 export type FetchRes<T> = {
     resp: Response|null;
     json: T|null;
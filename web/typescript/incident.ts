@@ -21,6 +21,7 @@ declare let url_personnel: string;
 declare let url_fieldReports: string;
 declare let url_fieldReport: string;
 declare let url_incidentAttachments: string;
+declare let url_incidentDossier: string;
 
 declare global {
     interface Window {
@@ -38,6 +39,7 @@ declare global {
         addRanger: ()=>Promise<void>;
         addIncidentType: ()=>Promise<void>;
         attachFile: ()=>Promise<void>;
+        openDossier: (format: string)=>Promise<void>;
         drawMergedReportEntries: ()=>void;
         toggleShowHistory: ()=>void;
         reportEntryEdited: ()=>void;
@@ -78,6 +80,7 @@ async function initIncidentPage(): Promise<void> {
     window.addRanger = addRanger;
     window.addIncidentType = addIncidentType;
     window.attachFile = attachFile;
+    window.openDossier = openDossier;
     window.drawMergedReportEntries = drawMergedReportEntries;
     window.toggleShowHistory = ims.toggleShowHistory;
     window.reportEntryEdited= ims.reportEntryEdited;
@@ -1152,3 +1155,13 @@ async function attachFile(): Promise<void> {
     attachFile.value = "";
     await loadAndDisplayIncident();
 }
+
+// Open a printable record of the incident, either as a page or a PDF.
+async function openDossier(format: string): Promise<void> {
+    if (ims.pathIds.incidentNumber == null) {
+        return;
+    }
+    const url = ims.urlReplace(url_incidentDossier)
+        .replace("<incident_number>", ims.pathIds.incidentNumber.toString());
+    await ims.openAuthenticated(`${url}?format=${format}`, "printable incident");
+}
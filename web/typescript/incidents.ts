@@ -56,6 +56,10 @@ const defaultRows = 25;
 
 let allIncidentTypes: string[] = [];
 
+// The latest last_modified of any Incident in the table. After a missed SSE,
+// only the Incidents modified since then need to be fetched.
+let incidentsLastModified: string|null = null;
+
 //
 // Initialize UI
 //
@@ -185,9 +189,8 @@ function initIncidentsTable() {
 
     ims.newIncidentChannel().onmessage = async function (e: MessageEvent<ims.IncidentBroadcast>): Promise<void> {
         if (e.data.update_all) {
-            console.log("Reloading the whole table to be cautious, as an SSE was missed");
-            incidentsTable!.ajax.reload();
-            ims.clearErrorMessage();
+            console.log("Loading all modified Incidents, as an SSE was missed");
+            await loadModifiedIncidents();
             return;
         }
 
@@ -197,7 +200,7 @@ function initIncidentsTable() {
             return;
         }
 
-        const {json, err} = await ims.fetchJsonNoThrow(
+        const {json, err} = await ims.fetchJsonNoThrow<ims.Incident>(
             ims.urlReplace(url_incidentNumber).replace("<incident_number>", number.toString()),
             null,
         );
@@ -209,27 +212,69 @@ function initIncidentsTable() {
         }
         // Now update/create the relevant row. This is a change from pre-2025, in that
         // we no longer reload all incidents here on any single incident update.
-        let done = false;
-        incidentsTable!.rows().every( function () {
-            // @ts-expect-error use of "this" for DataTables
-            const existingIncident = this.data();
-            if (existingIncident.number === number) {
-                console.log("Updating Incident " + number);
-                // @ts-expect-error use of "this" for DataTables
-                this.data(json);
-                done = true;
-            }
-        });
-        if (!done) {
-            console.log("Loading new Incident " + number);
-            incidentsTable!.row.add(json);
-        }
+        upsertIncidentRow(json!);
         ims.clearErrorMessage();
         incidentsTable!.processing(false);
         incidentsTable!.draw();
     };
 }
 
+// Fetch only the Incidents that changed since the table was last up to date,
+// and apply those changes to the table.
+async function loadModifiedIncidents(): Promise<void> {
+    if (incidentsLastModified == null) {
+        incidentsTable!.ajax.reload();
+        ims.clearErrorMessage();
+        return;
+    }
+    const {json, err} = await ims.fetchJsonNoThrow<ims.Incident[]>(
+        ims.urlReplace(url_incidents + "?exclude_system_entries=true&modified_since=" +
+            encodeURIComponent(incidentsLastModified)),
+        null,
+    );
+    if (err != null || json == null) {
+        const message = `Failed to load modified Incidents: ${err}`;
+        console.error(message);
+        ims.setErrorMessage(message);
+        return;
+    }
+    for (const incident of json) {
+        upsertIncidentRow(incident);
+    }
+    ims.clearErrorMessage();
+    incidentsTable!.processing(false);
+    incidentsTable!.draw();
+}
+
+function upsertIncidentRow(incident: ims.Incident): void {
+    let done = false;
+    incidentsTable!.rows().every( function () {
+        // @ts-expect-error use of "this" for DataTables
+        const existingIncident = this.data();
+        if (existingIncident.number === incident.number) {
+            console.log("Updating Incident " + incident.number);
+            // @ts-expect-error use of "this" for DataTables
+            this.data(incident);
+            done = true;
+        }
+    });
+    if (!done) {
+        console.log("Loading new Incident " + incident.number);
+        incidentsTable!.row.add(incident);
+    }
+    noteLastModified(incident);
+}
+
+function noteLastModified(incident: ims.Incident): void {
+    if (incident.last_modified == null) {
+        return;
+    }
+    if (incidentsLastModified == null ||
+        Date.parse(incident.last_modified) > Date.parse(incidentsLastModified)) {
+        incidentsLastModified = incident.last_modified;
+    }
+}
+
 declare let DataTable: any;
 
 //
@@ -274,6 +319,10 @@ function initDataTables(): void {
                             return;
                         }
                         json = res.json;
+                        incidentsLastModified = null;
+                        for (const incident of json) {
+                            noteLastModified(incident);
+                        }
                     }),
                 ]);
                 // then call the callback, only once all data sources have returned
@@ -41,17 +41,24 @@ async function initLoginPage(): Promise<void> {
 async function login(): Promise<void> {
     const username = (document.getElementById("username_input") as HTMLInputElement).value;
     const password = (document.getElementById("password_input") as HTMLInputElement).value;
-    const {json, err} = await ims.fetchJsonNoThrow<AuthResponse>(url_auth, {
+    const {resp, json, err} = await ims.fetchJsonNoThrow<ims.AuthTokens>(url_auth, {
         body: JSON.stringify({
             "identification": username,
             "password": password,
         }),
     });
     if (err != null || json == null) {
+        if (resp?.status === 429) {
+            document.querySelector(".login-retry-after")!.textContent = resp.headers.get("Retry-After") ?? "a few";
+            ims.hide(".if-authentication-failed");
+            ims.unhide(".if-login-throttled");
+            return;
+        }
+        ims.hide(".if-login-throttled");
         ims.unhide(".if-authentication-failed");
         return;
     }
-    ims.setAccessToken(json.token);
+    ims.setAccessToken(json.token, json.refresh_token);
     const redirect = new URLSearchParams(window.location.search).get("o");
     if (redirect != null) {
         window.location.replace(redirect);
@@ -59,7 +66,3 @@ async function login(): Promise<void> {
         window.location.replace(url_app);
     }
 }
-
-type AuthResponse = {
-    token: string;
-}
@@ -25,7 +25,7 @@ initRootPage();
 async function initRootPage(): Promise<void> {
     const params = new URLSearchParams(window.location.search);
     if (params.get("logout") != null) {
-        ims.clearAccessToken();
+        await ims.logout();
         window.history.replaceState(null, "", url_app);
     }
     await ims.commonPageInit();